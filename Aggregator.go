@@ -0,0 +1,706 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+	ext "github.com/mmcdole/gofeed/extensions"
+
+	"Utils"
+)
+
+// _AGGREGATOR_WATCH_MAX_AGE is for how long the stories below the thresholds are watched for crossing them.
+const _AGGREGATOR_WATCH_MAX_AGE time.Duration = 48 * time.Hour
+
+// _AGGREGATOR_WATCH_MAX_FETCHES is the maximum number of watched stories got one by one on each check (on the sites
+// that can't get them all at once).
+const _AGGREGATOR_WATCH_MAX_FETCHES int = 30
+
+// _AggregatorStory is a story of a link aggregator.
+type _AggregatorStory struct {
+	// id is the ID of the story on the site
+	id string
+	// title is the title of the story
+	title string
+	// url is the URL of the story's link ("" for text stories, like "Ask HN")
+	url string
+	// discussion_url is the URL of the story's page on the site
+	discussion_url string
+	// author is the user who submitted the story
+	author string
+	// score is the points of the story
+	score int
+	// comments is the number of comments of the story
+	comments int
+	// text is the text of the story (HTML)
+	text string
+	// published is when the story was submitted
+	published time.Time
+}
+
+// _WatchedStory is a story below the thresholds, watched for crossing them. It's exported to JSON, so the fields are
+// exported.
+type _WatchedStory struct {
+	// Id is the ID of the story on the site
+	Id string
+	// Published is when the story was published in Unix seconds
+	Published int64
+}
+
+/*
+getAggregatorFeed gets the stories of a link aggregator listing and converts them to a feed. The watched stories that
+left the listing are got too, so that they're notified when they cross the thresholds.
+
+-----------------------------------------------------------
+
+– Params:
+  - feedInfo – the information of the feed, with the listing on Feed_url
+  - feedType – the type of the feed
+
+– Returns:
+  - the feed, with the listing's stories in its order and then the watched ones
+  - an error if the listing couldn't be got, nil otherwise
+*/
+func getAggregatorFeed(feedInfo _FeedInfo, feedType _FeedType) (*gofeed.Feed, error) {
+	var listing string = strings.ToLower(strings.Trim(strings.TrimSpace(feedInfo.Feed_url), "/"))
+
+	var parsed_feed *gofeed.Feed = &gofeed.Feed{
+		FeedType: "json",
+	}
+	var stories []_AggregatorStory = nil
+	var getStories func(ids []string) ([]_AggregatorStory, error) = nil
+	var err error = nil
+	switch feedType.type_2 {
+		case _TYPE_2_AGG_HN: {
+			parsed_feed.Title = "Hacker News"
+			parsed_feed.Link = "https://news.ycombinator.com/"
+			stories, err = getHNStories(listing)
+			getStories = getHNStoriesById
+		}
+		case _TYPE_2_AGG_LOBSTERS: {
+			parsed_feed.Title = "Lobsters"
+			parsed_feed.Link = "https://lobste.rs/"
+			stories, err = getLobstersStories(listing)
+			getStories = getLobstersStoriesById
+		}
+		default: {
+			return nil, errors.New("invalid aggregator feed type: " + feedInfo.Feed_type)
+		}
+	}
+	if nil != err {
+		return nil, err
+	}
+	if "" != listing {
+		parsed_feed.Title += " (" + listing + ")"
+	}
+
+	// The watched stories that left the listing are got again to check if they crossed the thresholds meanwhile.
+	var watchedStories []_WatchedStory = readWatchedStories(feedInfo.Feed_num)
+	var missing_ids []string = nil
+	for _, watchedStory := range watchedStories {
+		if time.Since(time.Unix(watchedStory.Published, 0)) > _AGGREGATOR_WATCH_MAX_AGE {
+			continue
+		}
+		var in_listing bool = false
+		for _, story := range stories {
+			if story.id == watchedStory.Id {
+				in_listing = true
+
+				break
+			}
+		}
+		if !in_listing {
+			missing_ids = append(missing_ids, watchedStory.Id)
+		}
+	}
+	if 0 != len(missing_ids) {
+		missing_stories, err := getStories(missing_ids)
+		if nil != err {
+			fmt.Println("Error getting the watched stories of the feed " + strconv.Itoa(feedInfo.Feed_num) + ": " +
+				err.Error())
+		}
+		stories = append(stories, missing_stories...)
+	}
+
+	for _, story := range stories {
+		parsed_feed.Items = append(parsed_feed.Items, aggregatorStoryToItem(story))
+	}
+	writeWatchedStories(feedInfo.Feed_num, getWatchedStories(feedInfo, stories, watchedStories))
+
+	return parsed_feed, nil
+}
+
+/*
+getWatchedStories gets the stories to watch after a check: the ones got below the thresholds, plus the ones watched
+before that couldn't be got again (because of an error or of the maximum fetches), so that they're not forgotten. The
+ones watched before that crossed the thresholds are still watched until they're notified (see
+unwatchNotifiedStories()), in case the notification fails. The too old ones stop being watched. None are watched if
+there are only keywords - the titles don't change.
+
+-----------------------------------------------------------
+
+– Params:
+  - feedInfo – the information of the feed
+  - stories – the stories got on the check (the listing's and the watched ones got again)
+  - watchedStories – the stories watched before the check
+
+– Returns:
+  - the stories to watch
+*/
+func getWatchedStories(feedInfo _FeedInfo, stories []_AggregatorStory, watchedStories []_WatchedStory) []_WatchedStory {
+	if feedInfo.Aggregator_min_points <= 0 && feedInfo.Aggregator_min_comments <= 0 {
+		return nil
+	}
+
+	var watched_ids map[string]bool = make(map[string]bool)
+	for _, watchedStory := range watchedStories {
+		watched_ids[watchedStory.Id] = true
+	}
+
+	var watchedStories_new []_WatchedStory = nil
+	var got_ids map[string]bool = make(map[string]bool)
+	for _, story := range stories {
+		got_ids[story.id] = true
+		if (!isAggregatorStoryWanted(feedInfo, story) || watched_ids[story.id]) &&
+					time.Since(story.published) <= _AGGREGATOR_WATCH_MAX_AGE {
+			watchedStories_new = append(watchedStories_new, _WatchedStory{
+				Id:        story.id,
+				Published: story.published.Unix(),
+			})
+		}
+	}
+	for _, watchedStory := range watchedStories {
+		if !got_ids[watchedStory.Id] &&
+					time.Since(time.Unix(watchedStory.Published, 0)) <= _AGGREGATOR_WATCH_MAX_AGE {
+			watchedStories_new = append(watchedStories_new, watchedStory)
+		}
+	}
+
+	return watchedStories_new
+}
+
+/*
+isAggregatorStoryWanted checks if a story is to be notified: if it's above the points and comments thresholds or if its
+title has any of the keywords.
+
+-----------------------------------------------------------
+
+– Params:
+  - feedInfo – the information of the feed
+  - story – the story
+
+– Returns:
+  - true if the story is to be notified, false otherwise
+*/
+func isAggregatorStoryWanted(feedInfo _FeedInfo, story _AggregatorStory) bool {
+	for _, keyword := range feedInfo.Aggregator_keywords {
+		if "" != keyword && strings.Contains(strings.ToLower(story.title), strings.ToLower(keyword)) {
+			return true
+		}
+	}
+
+	var has_thresholds bool = feedInfo.Aggregator_min_points > 0 || feedInfo.Aggregator_min_comments > 0
+	if 0 != len(feedInfo.Aggregator_keywords) && !has_thresholds {
+		// Only the keywords.
+		return false
+	}
+
+	return story.score >= feedInfo.Aggregator_min_points && story.comments >= feedInfo.Aggregator_min_comments
+}
+
+/*
+aggregatorStoryToItem converts a story to a feed item. The description has the links to the story and to the
+discussion, besides the story's text.
+
+-----------------------------------------------------------
+
+– Params:
+  - story – the story
+
+– Returns:
+  - the feed item
+*/
+func aggregatorStoryToItem(story _AggregatorStory) *gofeed.Item {
+	var link string = story.url
+	if "" == link {
+		link = story.discussion_url
+	}
+
+	var description strings.Builder
+	if "" != story.url {
+		var domain string = story.url
+		if parsed_url, err := url.Parse(story.url); nil == err && "" != parsed_url.Host {
+			domain = strings.TrimPrefix(parsed_url.Host, "www.")
+		}
+		description.WriteString("<p>🔗 <a href=\"" + html.EscapeString(story.url) + "\">" + html.EscapeString(domain) +
+			"</a></p>\n")
+	}
+	description.WriteString("<p>▲ " + strconv.Itoa(story.score) + " pontos • 💬 <a href=\"" +
+		html.EscapeString(story.discussion_url) + "\">" + strconv.Itoa(story.comments) + " comentários</a></p>\n")
+	if "" != story.text {
+		description.WriteString("<div>" + story.text + "</div>\n")
+	}
+
+	var published time.Time = story.published
+
+	return &gofeed.Item{
+		Title:           story.title,
+		Link:            link,
+		GUID:            story.discussion_url,
+		Description:     description.String(),
+		Authors:         []*gofeed.Person{{Name: story.author}},
+		Published:       published.Format(time.RFC3339),
+		PublishedParsed: &published,
+		Extensions: ext.Extensions{
+			"aggregator": {
+				"id":         {{Value: story.id}},
+				"score":      {{Value: strconv.Itoa(story.score)}},
+				"comments":   {{Value: strconv.Itoa(story.comments)}},
+				"discussion": {{Value: story.discussion_url}},
+			},
+		},
+	}
+}
+
+/*
+aggregatorTreatment does the treatment of a link aggregator feed item: the general treatment, but only for the stories
+to be notified (see isAggregatorStoryWanted()).
+
+-----------------------------------------------------------
+
+– Params:
+  - feedInfo – the information of the feed
+  - parsed_feed – the parsed feed
+  - item_num – the number of the item to get
+  - title_url_only – whether to only get the title and URL of the item through _NewsInfo (can be used for optimization)
+
+– Returns:
+  - the email info (without the Mail_to field) or all fields empty if title_url_only is true
+  - the news info, or all fields empty if the story is below the thresholds (so that it's checked again later)
+*/
+func aggregatorTreatment(feedInfo _FeedInfo, parsed_feed *gofeed.Feed, item_num int, title_url_only bool) (
+			Utils.EmailInfo, _NewsInfo) {
+	var feed_item *gofeed.Item = parsed_feed.Items[item_num]
+
+	email_info, newsInfo := generalTreatment(feedInfo, parsed_feed, item_num, title_url_only, nil)
+	if title_url_only {
+		return email_info, newsInfo
+	}
+
+	score, _ := strconv.Atoi(getAggregatorExtValue(feed_item, "score"))
+	comments, _ := strconv.Atoi(getAggregatorExtValue(feed_item, "comments"))
+	var story _AggregatorStory = _AggregatorStory{
+		title:    feed_item.Title,
+		score:    score,
+		comments: comments,
+	}
+	if !isAggregatorStoryWanted(feedInfo, story) {
+		fmt.Println("Story below the thresholds (" + strconv.Itoa(score) + " points, " + strconv.Itoa(comments) +
+			" comments): " + feed_item.Title)
+
+		return Utils.EmailInfo{}, _NewsInfo{}
+	}
+
+	if "" == email_info.Subject {
+		email_info.Subject = strings.Split(parsed_feed.Title, " (")[0] + ": " + feed_item.Title + " (" +
+			strconv.Itoa(score) + " pontos)"
+	}
+
+	return email_info, newsInfo
+}
+
+/*
+getAggregatorExtValue gets a value of the "aggregator" extension of an item.
+
+-----------------------------------------------------------
+
+– Params:
+  - feed_item – the item
+  - name – the name of the value
+
+– Returns:
+  - the value or "" if there's none
+*/
+func getAggregatorExtValue(feed_item *gofeed.Item, name string) string {
+	if values := feed_item.Extensions["aggregator"][name]; 0 != len(values) {
+		return values[0].Value
+	}
+
+	return ""
+}
+
+/*
+unwatchNotifiedStories stops watching the stories of a feed that were recorded as notified on a check.
+
+-----------------------------------------------------------
+
+– Params:
+  - feed_num – the number of the feed
+  - parsed_feed – the feed got on the check
+  - notified_urls – the URLs of the news recorded as notified on the check
+*/
+func unwatchNotifiedStories(feed_num int, parsed_feed *gofeed.Feed, notified_urls map[string]bool) {
+	var notified_ids map[string]bool = make(map[string]bool)
+	for _, feed_item := range parsed_feed.Items {
+		if notified_urls[feed_item.Link] {
+			notified_ids[getAggregatorExtValue(feed_item, "id")] = true
+		}
+	}
+
+	var watchedStories []_WatchedStory = readWatchedStories(feed_num)
+	var watchedStories_new []_WatchedStory = nil
+	for _, watchedStory := range watchedStories {
+		if !notified_ids[watchedStory.Id] {
+			watchedStories_new = append(watchedStories_new, watchedStory)
+		}
+	}
+	if len(watchedStories_new) != len(watchedStories) {
+		writeWatchedStories(feed_num, watchedStories_new)
+	}
+}
+
+// _HNHit is the part used of a story of the Hacker News Algolia API.
+type _HNHit struct {
+	ObjectID     string `json:"objectID"`
+	Title        string `json:"title"`
+	Url          string `json:"url"`
+	Author       string `json:"author"`
+	Points       int    `json:"points"`
+	Num_comments int    `json:"num_comments"`
+	Story_text   string `json:"story_text"`
+	Created_at_i int64  `json:"created_at_i"`
+}
+
+/*
+getHNStories gets the stories of a Hacker News listing, from the Algolia API (the one linked by Hacker News - the
+official one needs a request per story).
+
+-----------------------------------------------------------
+
+– Params:
+  - listing – "" or "new" for the newest stories, "front" for the front page, "show" for Show HN or "ask" for Ask HN
+
+– Returns:
+  - the stories
+  - an error if the listing is invalid or if the request failed, nil otherwise
+*/
+func getHNStories(listing string) ([]_AggregatorStory, error) {
+	var params url.Values = url.Values{}
+	params.Set("hitsPerPage", "50")
+	var endpoint string = "search_by_date"
+	switch listing {
+		case "", "new": {
+			params.Set("tags", "story")
+		}
+		case "front": {
+			endpoint = "search"
+			params.Set("tags", "front_page")
+		}
+		case "show": {
+			params.Set("tags", "show_hn")
+		}
+		case "ask": {
+			params.Set("tags", "ask_hn")
+		}
+		default: {
+			return nil, errors.New("invalid Hacker News listing: " + listing)
+		}
+	}
+
+	return searchHNStories(endpoint, params)
+}
+
+/*
+getHNStoriesById gets Hacker News stories by their IDs (all in one request).
+
+-----------------------------------------------------------
+
+– Params:
+  - ids – the IDs of the stories
+
+– Returns:
+  - the stories found
+  - an error if the request failed, nil otherwise
+*/
+func getHNStoriesById(ids []string) ([]_AggregatorStory, error) {
+	var tags []string = nil
+	for _, id := range ids {
+		tags = append(tags, "story_" + id)
+	}
+	var params url.Values = url.Values{}
+	params.Set("tags", "story,(" + strings.Join(tags, ",") + ")")
+	params.Set("hitsPerPage", strconv.Itoa(len(ids)))
+
+	return searchHNStories("search_by_date", params)
+}
+
+/*
+searchHNStories searches stories on the Hacker News Algolia API.
+
+-----------------------------------------------------------
+
+– Params:
+  - endpoint – "search" or "search_by_date"
+  - params – the parameters of the search
+
+– Returns:
+  - the stories
+  - an error if the request failed, nil otherwise
+*/
+func searchHNStories(endpoint string, params url.Values) ([]_AggregatorStory, error) {
+	var response struct {
+		Hits []_HNHit `json:"hits"`
+	}
+	var api_url string = "https://hn.algolia.com/api/v1/" + endpoint + "?" + params.Encode()
+	if err := getJson(api_url, _USER_AGENT, &response); nil != err {
+		return nil, err
+	}
+
+	var stories []_AggregatorStory = nil
+	for _, hit := range response.Hits {
+		stories = append(stories, _AggregatorStory{
+			id:             hit.ObjectID,
+			title:          hit.Title,
+			url:            hit.Url,
+			discussion_url: "https://news.ycombinator.com/item?id=" + hit.ObjectID,
+			author:         hit.Author,
+			score:          hit.Points,
+			comments:       hit.Num_comments,
+			text:           hit.Story_text,
+			published:      time.Unix(hit.Created_at_i, 0),
+		})
+	}
+
+	return stories, nil
+}
+
+// _LobstersStory is the part used of a story of the Lobsters JSON.
+type _LobstersStory struct {
+	Short_id       string          `json:"short_id"`
+	Title          string          `json:"title"`
+	Url            string          `json:"url"`
+	Comments_url   string          `json:"comments_url"`
+	Score          int             `json:"score"`
+	Comment_count  int             `json:"comment_count"`
+	Description    string          `json:"description"`
+	Created_at     string          `json:"created_at"`
+	// A string on newer versions of the site, an object with the "username" before
+	Submitter_user json.RawMessage `json:"submitter_user"`
+}
+
+/*
+getLobstersStories gets the stories of a Lobsters listing.
+
+-----------------------------------------------------------
+
+– Params:
+  - listing – "" or "newest" for the newest stories, "hottest" for the front page or "t/<tag>" for a tag
+
+– Returns:
+  - the stories
+  - an error if the listing is invalid or if the request failed, nil otherwise
+*/
+func getLobstersStories(listing string) ([]_AggregatorStory, error) {
+	var path string = ""
+	switch {
+		case "" == listing || "newest" == listing: {
+			path = "newest"
+		}
+		case "hottest" == listing: {
+			path = "hottest"
+		}
+		case strings.HasPrefix(listing, "t/") && len(listing) > len("t/"): {
+			path = "t/" + url.PathEscape(listing[len("t/"):])
+		}
+		default: {
+			return nil, errors.New("invalid Lobsters listing: " + listing)
+		}
+	}
+
+	var lobstersStories []_LobstersStory = nil
+	if err := getJson("https://lobste.rs/" + path + ".json", _USER_AGENT, &lobstersStories); nil != err {
+		return nil, err
+	}
+
+	var stories []_AggregatorStory = nil
+	for _, lobstersStory := range lobstersStories {
+		stories = append(stories, lobstersStoryToStory(lobstersStory))
+	}
+
+	return stories, nil
+}
+
+/*
+getLobstersStoriesById gets Lobsters stories by their IDs (one request each, up to _AGGREGATOR_WATCH_MAX_FETCHES).
+
+-----------------------------------------------------------
+
+– Params:
+  - ids – the IDs of the stories
+
+– Returns:
+  - the stories found
+  - the errors of the stories that couldn't be got, nil if there were none
+*/
+func getLobstersStoriesById(ids []string) ([]_AggregatorStory, error) {
+	var stories []_AggregatorStory = nil
+	var errs []error = nil
+	for i, id := range ids {
+		if i >= _AGGREGATOR_WATCH_MAX_FETCHES {
+			break
+		}
+
+		var lobstersStory _LobstersStory
+		var story_url string = "https://lobste.rs/s/" + url.PathEscape(id) + ".json"
+		if err := getJson(story_url, _USER_AGENT, &lobstersStory); nil != err {
+			errs = append(errs, errors.New("story " + id + ": " + err.Error()))
+
+			continue
+		}
+		stories = append(stories, lobstersStoryToStory(lobstersStory))
+	}
+
+	return stories, errors.Join(errs...)
+}
+
+/*
+lobstersStoryToStory converts a Lobsters story to an aggregator story.
+
+-----------------------------------------------------------
+
+– Params:
+  - lobstersStory – the Lobsters story
+
+– Returns:
+  - the aggregator story
+*/
+func lobstersStoryToStory(lobstersStory _LobstersStory) _AggregatorStory {
+	var author string = ""
+	if err := json.Unmarshal(lobstersStory.Submitter_user, &author); nil != err {
+		var submitter struct {
+			Username string `json:"username"`
+		}
+		_ = json.Unmarshal(lobstersStory.Submitter_user, &submitter)
+		author = submitter.Username
+	}
+	published, _ := time.Parse(time.RFC3339, lobstersStory.Created_at)
+
+	return _AggregatorStory{
+		id:             lobstersStory.Short_id,
+		title:          lobstersStory.Title,
+		url:            lobstersStory.Url,
+		discussion_url: lobstersStory.Comments_url,
+		author:         author,
+		score:          lobstersStory.Score,
+		comments:       lobstersStory.Comment_count,
+		text:           lobstersStory.Description,
+		published:      published,
+	}
+}
+
+/*
+readWatchedStories reads the watched stories of a feed.
+
+-----------------------------------------------------------
+
+– Params:
+  - feed_num – the number of the feed
+
+– Returns:
+  - the watched stories (empty if there are none or if an error occurs)
+*/
+func readWatchedStories(feed_num int) []_WatchedStory {
+	var watched_stories map[string][]_WatchedStory = readAllWatchedStories()
+
+	return watched_stories[strconv.Itoa(feed_num)]
+}
+
+/*
+writeWatchedStories writes the watched stories of a feed.
+
+-----------------------------------------------------------
+
+– Params:
+  - feed_num – the number of the feed
+  - watchedStories – the watched stories
+*/
+func writeWatchedStories(feed_num int, watchedStories []_WatchedStory) {
+	var watched_stories map[string][]_WatchedStory = readAllWatchedStories()
+	if 0 == len(watchedStories) {
+		delete(watched_stories, strconv.Itoa(feed_num))
+	} else {
+		watched_stories[strconv.Itoa(feed_num)] = watchedStories
+	}
+
+	watched_stories_json, err := json.Marshal(watched_stories)
+	if nil != err {
+		fmt.Println("Error writing the watched stories: " + err.Error())
+
+		return
+	}
+	getWatchedStoriesPath().WriteTextFile(string(watched_stories_json))
+}
+
+/*
+readAllWatchedStories reads the watched stories of all feeds.
+
+-----------------------------------------------------------
+
+– Returns:
+  - the watched stories mapped by feed number (empty if there are none or if an error occurs)
+*/
+func readAllWatchedStories() map[string][]_WatchedStory {
+	var watched_stories map[string][]_WatchedStory = make(map[string][]_WatchedStory)
+
+	var p_watched_stories_json *string = getWatchedStoriesPath().ReadTextFile()
+	if nil == p_watched_stories_json {
+		return watched_stories
+	}
+	if err := json.Unmarshal([]byte(*p_watched_stories_json), &watched_stories); nil != err || nil == watched_stories {
+		fmt.Println("Error reading the watched stories")
+
+		return make(map[string][]_WatchedStory)
+	}
+
+	return watched_stories
+}
+
+/*
+getWatchedStoriesPath gets the path of the watched stories file.
+
+-----------------------------------------------------------
+
+– Returns:
+  - the path of the file
+*/
+func getWatchedStoriesPath() Utils.GPath {
+	return moduleInfo_GL.ModDirsInfo.UserData.Add2("watched_stories.json")
+}
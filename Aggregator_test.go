@@ -0,0 +1,145 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+)
+
+func TestIsAggregatorStoryWanted(t *testing.T) {
+	var tests = []struct {
+		name         string
+		min_points   int
+		min_comments int
+		keywords     []string
+		story        _AggregatorStory
+		expected     bool
+	}{
+		{"no filters", 0, 0, nil, _AggregatorStory{}, true},
+		{"points reached", 100, 0, nil, _AggregatorStory{score: 100}, true},
+		{"points not reached", 100, 0, nil, _AggregatorStory{score: 99, comments: 500}, false},
+		{"points and comments", 100, 10, nil, _AggregatorStory{score: 150, comments: 9}, false},
+		{"keyword", 0, 0, []string{"Go"}, _AggregatorStory{title: "Why go 2 is late"}, true},
+		{"only keywords", 0, 0, []string{"rust"}, _AggregatorStory{title: "Go", score: 1000}, false},
+		{"keyword below points", 100, 0, []string{"go"}, _AggregatorStory{title: "Go 2", score: 1}, true},
+		{"points without keyword", 100, 0, []string{"rust"}, _AggregatorStory{title: "Go", score: 100}, true},
+		{"empty keyword", 0, 0, []string{""}, _AggregatorStory{title: "Go"}, false},
+	}
+	for _, test := range tests {
+		var feedInfo _FeedInfo = _FeedInfo{
+			Aggregator_min_points:   test.min_points,
+			Aggregator_min_comments: test.min_comments,
+			Aggregator_keywords:     test.keywords,
+		}
+		if isAggregatorStoryWanted(feedInfo, test.story) != test.expected {
+			t.Errorf("%s: isAggregatorStoryWanted() != %v", test.name, test.expected)
+		}
+	}
+}
+
+func TestGetWatchedStories(t *testing.T) {
+	var recent time.Time = time.Now().Add(-time.Hour)
+	var old time.Time = time.Now().Add(-_AGGREGATOR_WATCH_MAX_AGE - time.Hour)
+	var feedInfo _FeedInfo = _FeedInfo{
+		Aggregator_min_points: 100,
+	}
+	var stories []_AggregatorStory = []_AggregatorStory{
+		{id: "below", score: 10, published: recent},
+		{id: "above", score: 100, published: recent},
+		{id: "below old", score: 10, published: old},
+		{id: "crossed", score: 200, published: recent},
+	}
+	var watchedStories []_WatchedStory = []_WatchedStory{
+		{Id: "crossed", Published: recent.Unix()},
+		// Not got again (an error or the maximum fetches).
+		{Id: "not got", Published: recent.Unix()},
+		{Id: "not got old", Published: old.Unix()},
+	}
+
+	// The crossed one is still watched until it's notified.
+	var expected []_WatchedStory = []_WatchedStory{
+		{Id: "below", Published: recent.Unix()},
+		{Id: "crossed", Published: recent.Unix()},
+		{Id: "not got", Published: recent.Unix()},
+	}
+	var watchedStories_new []_WatchedStory = getWatchedStories(feedInfo, stories, watchedStories)
+	if !reflect.DeepEqual(expected, watchedStories_new) {
+		t.Errorf("getWatchedStories() = %v, expected %v", watchedStories_new, expected)
+	}
+
+	// Only keywords - nothing to watch.
+	feedInfo = _FeedInfo{
+		Aggregator_keywords: []string{"go"},
+	}
+	if watchedStories_new = getWatchedStories(feedInfo, stories, watchedStories); nil != watchedStories_new {
+		t.Errorf("getWatchedStories() with only keywords = %v", watchedStories_new)
+	}
+}
+
+func TestAggregatorTreatment(t *testing.T) {
+	var feedInfo _FeedInfo = _FeedInfo{
+		Aggregator_min_points: 100,
+	}
+	var parsed_feed *gofeed.Feed = &gofeed.Feed{
+		Title: "Hacker News (front)",
+		Items: []*gofeed.Item{
+			aggregatorStoryToItem(_AggregatorStory{
+				id:             "1",
+				title:          "Above",
+				url:            "https://example.com/above",
+				discussion_url: "https://news.ycombinator.com/item?id=1",
+				score:          150,
+				published:      time.Now(),
+			}),
+			aggregatorStoryToItem(_AggregatorStory{
+				id:             "2",
+				title:          "Below",
+				discussion_url: "https://news.ycombinator.com/item?id=2",
+				score:          10,
+				published:      time.Now(),
+			}),
+			// Without the extension (like from an older cache) - taken as 0 points, not a panic.
+			{Title: "No extension", Link: "https://example.com/no-ext"},
+		},
+	}
+
+	email_info, newsInfo := aggregatorTreatment(feedInfo, parsed_feed, 0, false)
+	if "https://example.com/above" != newsInfo.url {
+		t.Errorf("above: got URL %q", newsInfo.url)
+	}
+	if "Hacker News: Above (150 pontos)" != email_info.Subject {
+		t.Errorf("above: got subject %q", email_info.Subject)
+	}
+	for _, item_num := range []int{1, 2} {
+		if _, newsInfo = aggregatorTreatment(feedInfo, parsed_feed, item_num, false); "" != newsInfo.url {
+			t.Errorf("%s: got URL %q, expected none", parsed_feed.Items[item_num].Title, newsInfo.url)
+		}
+	}
+	if "1" != getAggregatorExtValue(parsed_feed.Items[0], "id") ||
+				"" != getAggregatorExtValue(parsed_feed.Items[2], "id") {
+		t.Error("wrong getAggregatorExtValue() values")
+	}
+}
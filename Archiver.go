@@ -0,0 +1,498 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"Utils"
+)
+
+// Formats of the archive.
+const (
+	_ARCHIVE_FORMAT_MARKDOWN string = "markdown"
+	_ARCHIVE_FORMAT_HTML     string = "html"
+)
+
+// _ARCHIVE_IMAGE_MAX_SIZE is the maximum size of an archived thumbnail.
+const _ARCHIVE_IMAGE_MAX_SIZE int64 = 10 * 1000 * 1000
+
+/*
+archiveNotifiedItem queues a notified item to be archived, if the archive is enabled. The item is only written by
+processArchiveQueue(), so that the notifications don't wait for the downloads of the archive. The queue is kept on a
+file, so that no item is lost if the module stops before archiving it.
+
+-----------------------------------------------------------
+
+– Params:
+  - notifiedItem – the notified item
+*/
+func archiveNotifiedItem(notifiedItem _NotifiedItem) {
+	var modUserInfo _ModUserInfo
+	if !moduleInfo_GL.GetModUserInfo(&modUserInfo) || "" == modUserInfo.Archive_dir {
+		return
+	}
+
+	notified_items, ok := readArchiveQueue()
+	if !ok {
+		// Don't overwrite a queue that couldn't be read.
+		fmt.Println("Error queuing an item to be archived: " + notifiedItem.Url)
+
+		return
+	}
+	writeArchiveQueue(append(notified_items, notifiedItem))
+}
+
+/*
+processArchiveQueue writes the queued items to the archive and then updates the indexes of their feeds (once per feed).
+The items are only taken off the queue after being written - the ones that failed are tried again on the next call.
+*/
+func processArchiveQueue() {
+	notified_items, ok := readArchiveQueue()
+	if !ok || 0 == len(notified_items) {
+		return
+	}
+
+	var modUserInfo _ModUserInfo
+	if !moduleInfo_GL.GetModUserInfo(&modUserInfo) || "" == modUserInfo.Archive_dir {
+		return
+	}
+	writeArchiveQueue(writeArchive(modUserInfo, notified_items))
+}
+
+/*
+readArchiveQueue reads the archive queue file.
+
+-----------------------------------------------------------
+
+– Returns:
+  - the queued items from the oldest to the newest (empty if there are none or if an error occurs)
+  - true if the queue was read (or there's no file yet), false if the file couldn't be read
+*/
+func readArchiveQueue() ([]_NotifiedItem, bool) {
+	var notified_items []_NotifiedItem = nil
+
+	var p_queue_json *string = getArchiveQueuePath().ReadTextFile()
+	if nil == p_queue_json {
+		return nil, !getArchiveQueuePath().Exists()
+	}
+	if err := json.Unmarshal([]byte(*p_queue_json), &notified_items); nil != err {
+		fmt.Println("Error reading the archive queue: " + err.Error())
+
+		return nil, false
+	}
+
+	return notified_items, true
+}
+
+/*
+writeArchiveQueue writes the archive queue file.
+
+-----------------------------------------------------------
+
+– Params:
+  - notified_items – the queued items
+*/
+func writeArchiveQueue(notified_items []_NotifiedItem) {
+	if nil == notified_items {
+		notified_items = []_NotifiedItem{}
+	}
+	queue_json, err := json.Marshal(notified_items)
+	if nil != err {
+		fmt.Println("Error writing the archive queue: " + err.Error())
+
+		return
+	}
+	getArchiveQueuePath().WriteTextFile(string(queue_json))
+}
+
+/*
+getArchiveQueuePath gets the path of the archive queue file.
+
+-----------------------------------------------------------
+
+– Returns:
+  - the path of the file
+*/
+func getArchiveQueuePath() Utils.GPath {
+	return moduleInfo_GL.ModDirsInfo.UserData.Add2("archive_queue.json")
+}
+
+/*
+writeArchive writes items to the archive: a file per item in <Archive_dir>/<feed_num>/<year>/<month>/, plus an index
+per feed.
+
+-----------------------------------------------------------
+
+– Params:
+  - modUserInfo – the user settings (with the archive ones)
+  - notified_items – the notified items
+
+– Returns:
+  - the items that couldn't be written
+*/
+func writeArchive(modUserInfo _ModUserInfo, notified_items []_NotifiedItem) []_NotifiedItem {
+	var format string = strings.ToLower(modUserInfo.Archive_format)
+	var extension string = ".md"
+	if _ARCHIVE_FORMAT_HTML == format {
+		extension = ".html"
+	} else {
+		format = _ARCHIVE_FORMAT_MARKDOWN
+	}
+
+	// Feed directory --> title of the feed (the latest one)
+	var feeds_dirs map[string]string = make(map[string]string)
+	var notified_items_failed []_NotifiedItem = nil
+	for _, notifiedItem := range notified_items {
+		var feed_dir string = filepath.Join(modUserInfo.Archive_dir, strconv.Itoa(notifiedItem.Feed_num))
+		if archiveItem(modUserInfo, notifiedItem, feed_dir, format, extension) {
+			feeds_dirs[feed_dir] = notifiedItem.Feed_title
+		} else {
+			notified_items_failed = append(notified_items_failed, notifiedItem)
+		}
+	}
+
+	for feed_dir, feed_title := range feeds_dirs {
+		writeArchiveIndex(feed_dir, feed_title, format, extension)
+	}
+
+	return notified_items_failed
+}
+
+/*
+archiveItem writes a notified item to the archive (without updating the index).
+
+-----------------------------------------------------------
+
+– Params:
+  - modUserInfo – the user settings (with the archive ones)
+  - notifiedItem – the notified item
+  - feed_dir – the directory of the item's feed on the archive
+  - format – one of the _ARCHIVE_FORMAT_ constants
+  - extension – the extension of the items' files
+
+– Returns:
+  - true if the item was written, false otherwise
+*/
+func archiveItem(modUserInfo _ModUserInfo, notifiedItem _NotifiedItem, feed_dir string, format string,
+			extension string) bool {
+	var date time.Time = getItemDate(notifiedItem).Local()
+	var item_dir string = filepath.Join(feed_dir, date.Format("2006"), date.Format("01"))
+	if err := os.MkdirAll(item_dir, 0o755); nil != err {
+		fmt.Println("Error creating the archive directory: " + err.Error())
+
+		return false
+	}
+
+	var base_name string = getArchiveBaseName(item_dir, date.Format("2006-01-02") + " - " +
+		sanitizeFileName(notifiedItem.Title), extension)
+
+	var image_file string = ""
+	if modUserInfo.Archive_images && "" != notifiedItem.Image {
+		image_file = archiveImage(notifiedItem.Image, filepath.Join(item_dir, base_name))
+	}
+
+	var content string = notifiedItem.Description
+	var content_html bool = _TYPE_1_YOUTUBE != notifiedItem.Category
+	if modUserInfo.Archive_full_content && _TYPE_1_YOUTUBE != notifiedItem.Category {
+		if full_content := getFullContent(notifiedItem.Url); "" != full_content {
+			content, _ = sanitizeHtml(full_content, notifiedItem.Url, 0)
+		}
+	}
+
+	var file_contents string = ""
+	if _ARCHIVE_FORMAT_HTML == format {
+		file_contents = getArchiveHtml(notifiedItem, content, content_html, image_file)
+	} else {
+		file_contents = getArchiveMarkdown(notifiedItem, content, content_html, image_file)
+	}
+	var file_path string = filepath.Join(item_dir, base_name + extension)
+	if err := os.WriteFile(file_path, []byte(file_contents), 0o644); nil != err {
+		fmt.Println("Error writing to the archive: " + err.Error())
+
+		return false
+	}
+
+	return true
+}
+
+/*
+getArchiveBaseName gets a file name (without extension) that is not used yet in a directory.
+
+-----------------------------------------------------------
+
+– Params:
+  - dir – the directory
+  - base_name – the wanted name
+  - extension – the extension of the file
+
+– Returns:
+  - the wanted name or, if it's used, the name with " (2)", " (3)", ... appended
+*/
+func getArchiveBaseName(dir string, base_name string, extension string) string {
+	var base_name_free string = base_name
+	for i := 2; ; i++ {
+		if _, err := os.Stat(filepath.Join(dir, base_name_free + extension)); os.IsNotExist(err) {
+			return base_name_free
+		}
+		base_name_free = base_name + " (" + strconv.Itoa(i) + ")"
+	}
+}
+
+/*
+archiveImage downloads an item's image to the archive.
+
+-----------------------------------------------------------
+
+– Params:
+  - image_url – the URL of the image
+  - file_path_no_ext – the path of the image's file without the extension
+
+– Returns:
+  - the name of the image's file or "" if it couldn't be downloaded
+*/
+func archiveImage(image_url string, file_path_no_ext string) string {
+	var client http.Client = http.Client{
+		Timeout: 60 * time.Second,
+	}
+	response, err := client.Get(image_url)
+	if nil != err {
+		return ""
+	}
+	defer response.Body.Close()
+	if http.StatusOK != response.StatusCode {
+		return ""
+	}
+
+	var extension string = ""
+	if parsed_url, err := url.Parse(image_url); nil == err {
+		extension = path.Ext(parsed_url.Path)
+	}
+	if "" == extension || len(extension) > 5 {
+		extension = ".jpg"
+		if extensions, err := mime.ExtensionsByType(response.Header.Get("Content-Type")); nil == err &&
+					len(extensions) > 0 {
+			extension = extensions[0]
+		}
+	}
+
+	var file_path string = file_path_no_ext + sanitizeFileName(extension)
+	file, err := os.Create(file_path)
+	if nil != err {
+		return ""
+	}
+	_, err = io.Copy(file, io.LimitReader(response.Body, _ARCHIVE_IMAGE_MAX_SIZE))
+	if err_close := file.Close(); nil == err {
+		err = err_close
+	}
+	if nil != err {
+		_ = os.Remove(file_path)
+
+		return ""
+	}
+
+	return filepath.Base(file_path)
+}
+
+/*
+getArchiveMarkdown gets the Markdown of an archived item: YAML front-matter with its information, then its content
+(Markdown allows HTML, so HTML content is kept as it is).
+
+-----------------------------------------------------------
+
+– Params:
+  - notifiedItem – the notified item
+  - content – the content of the item
+  - content_html – whether the content is HTML or plain text
+  - image_file – the name of the image's file ("" if there's none)
+
+– Returns:
+  - the Markdown
+*/
+func getArchiveMarkdown(notifiedItem _NotifiedItem, content string, content_html bool, image_file string) string {
+	var builder strings.Builder
+	builder.WriteString("---\n")
+	var writeField func(name string, value any) = func(name string, value any) {
+		// JSON strings and arrays are valid YAML.
+		value_json, _ := json.Marshal(value)
+		builder.WriteString(name + ": " + string(value_json) + "\n")
+	}
+	writeField("title", notifiedItem.Title)
+	writeField("url", notifiedItem.Url)
+	writeField("feed", notifiedItem.Feed_title)
+	writeField("feed_num", notifiedItem.Feed_num)
+	if "" != notifiedItem.Author {
+		writeField("author", notifiedItem.Author)
+	}
+	if 0 != notifiedItem.Published {
+		writeField("published", time.Unix(notifiedItem.Published, 0).Format(time.RFC3339))
+	}
+	writeField("notified", time.Unix(notifiedItem.Notified, 0).Format(time.RFC3339))
+	if 0 != len(notifiedItem.Tags) {
+		writeField("tags", notifiedItem.Tags)
+	}
+	if "" != notifiedItem.Image {
+		writeField("image", notifiedItem.Image)
+	}
+	builder.WriteString("---\n\n")
+
+	builder.WriteString("# [" + escapeMarkdown(notifiedItem.Title) + "](<" + notifiedItem.Url + ">)\n\n")
+	if "" != image_file {
+		builder.WriteString("![](<" + image_file + ">)\n\n")
+	}
+	if content_html {
+		builder.WriteString(content + "\n")
+	} else {
+		builder.WriteString(escapeMarkdown(content) + "\n")
+	}
+
+	return builder.String()
+}
+
+/*
+getArchiveHtml gets the standalone HTML of an archived item.
+
+-----------------------------------------------------------
+
+– Params:
+  - notifiedItem – the notified item
+  - content – the content of the item
+  - content_html – whether the content is HTML or plain text
+  - image_file – the name of the image's file ("" if there's none)
+
+– Returns:
+  - the HTML
+*/
+func getArchiveHtml(notifiedItem _NotifiedItem, content string, content_html bool, image_file string) string {
+	var details []string = []string{html.EscapeString(notifiedItem.Feed_title)}
+	if "" != notifiedItem.Author {
+		details = append(details, html.EscapeString(notifiedItem.Author))
+	}
+	details = append(details, getItemDate(notifiedItem).Local().Format("2006-01-02 15:04"))
+
+	var builder strings.Builder
+	builder.WriteString("<!DOCTYPE html>\n<html>\n<head><meta charset=\"UTF-8\"><title>")
+	builder.WriteString(html.EscapeString(notifiedItem.Title))
+	builder.WriteString("</title></head>\n<body style=\"font-family: Roboto, Arial, sans-serif; max-width: 800px; " +
+		"margin: auto;\">\n<h2><a href=\"")
+	builder.WriteString(html.EscapeString(notifiedItem.Url))
+	builder.WriteString("\">")
+	builder.WriteString(html.EscapeString(notifiedItem.Title))
+	builder.WriteString("</a></h2>\n<p>")
+	builder.WriteString(strings.Join(details, " • "))
+	builder.WriteString("</p>\n")
+	if "" != image_file {
+		builder.WriteString("<img src=\"" + html.EscapeString(image_file) + "\" style=\"max-width: 100%;\">\n")
+	}
+	if content_html {
+		builder.WriteString("<div>" + content + "</div>\n")
+	} else {
+		builder.WriteString("<p>" + strings.ReplaceAll(html.EscapeString(content), "\n", "<br>\n") + "</p>\n")
+	}
+	builder.WriteString("</body>\n</html>\n")
+
+	return builder.String()
+}
+
+/*
+writeArchiveIndex writes the index of a feed's archive, listing all its archived items from the newest to the oldest.
+
+-----------------------------------------------------------
+
+– Params:
+  - feed_dir – the directory of the feed on the archive
+  - feed_title – the title of the feed
+  - format – one of the _ARCHIVE_FORMAT_ constants
+  - extension – the extension of the items' files
+*/
+func writeArchiveIndex(feed_dir string, feed_title string, format string, extension string) {
+	var files []string = nil
+	_ = filepath.Walk(feed_dir, func(file_path string, file_info os.FileInfo, err error) error {
+		if nil == err && !file_info.IsDir() && feed_dir != filepath.Dir(file_path) &&
+					strings.HasSuffix(file_path, extension) {
+			relative_path, err := filepath.Rel(feed_dir, file_path)
+			if nil == err {
+				files = append(files, filepath.ToSlash(relative_path))
+			}
+		}
+
+		return nil
+	})
+	// The paths begin with the date, so this sorts them by date.
+	sort.Sort(sort.Reverse(sort.StringSlice(files)))
+
+	var builder strings.Builder
+	if _ARCHIVE_FORMAT_HTML == format {
+		builder.WriteString("<!DOCTYPE html>\n<html>\n<head><meta charset=\"UTF-8\"><title>")
+		builder.WriteString(html.EscapeString(feed_title))
+		builder.WriteString("</title></head>\n<body style=\"font-family: Roboto, Arial, sans-serif;\">\n<h2>")
+		builder.WriteString(html.EscapeString(feed_title))
+		builder.WriteString("</h2>\n<ul>\n")
+		for _, file := range files {
+			builder.WriteString("<li><a href=\"" + html.EscapeString(file) + "\">" +
+				html.EscapeString(strings.TrimSuffix(path.Base(file), extension)) + "</a></li>\n")
+		}
+		builder.WriteString("</ul>\n</body>\n</html>\n")
+	} else {
+		builder.WriteString("# " + escapeMarkdown(feed_title) + "\n\n")
+		for _, file := range files {
+			builder.WriteString("- [" + escapeMarkdown(strings.TrimSuffix(path.Base(file), extension)) + "](<" + file +
+				">)\n")
+		}
+	}
+
+	if err := os.WriteFile(filepath.Join(feed_dir, "index" + extension), []byte(builder.String()), 0o644); nil != err {
+		fmt.Println("Error writing the archive index: " + err.Error())
+	}
+}
+
+/*
+escapeMarkdown escapes the characters with meaning in Markdown.
+
+-----------------------------------------------------------
+
+– Params:
+  - text – the text
+
+– Returns:
+  - the escaped text
+*/
+func escapeMarkdown(text string) string {
+	var replacer *strings.Replacer = strings.NewReplacer(
+		"\\", "\\\\", "*", "\\*", "_", "\\_", "`", "\\`", "[", "\\[", "]", "\\]", "<", "&lt;", ">", "&gt;", "#", "\\#",
+	)
+
+	return replacer.Replace(text)
+}
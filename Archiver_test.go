@@ -0,0 +1,98 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteArchive(t *testing.T) {
+	var date time.Time = time.Date(2023, 11, 20, 12, 0, 0, 0, time.Local)
+	var notified_items []_NotifiedItem = []_NotifiedItem{
+		{Feed_num: 3, Feed_title: "Old title", Title: "First", Url: "https://example.com/1",
+			Description: "<p>One</p>", Published: date.Unix(), Notified: date.Unix()},
+		{Feed_num: 3, Feed_title: "Blog: news", Title: "First", Url: "https://example.com/1b",
+			Description: "<p>One again</p>", Published: date.Unix(), Notified: date.Unix()},
+		{Feed_num: 3, Feed_title: "Blog: news", Title: "Second [2/2]", Url: "https://example.com/2",
+			Author: "Someone", Tags: []string{"news"}, Published: date.AddDate(0, 1, 0).Unix(), Notified: date.Unix()},
+		{Feed_num: 4, Feed_title: "Channel", Category: _TYPE_1_YOUTUBE, Title: "Video", Url: "https://example.com/v",
+			Description: "a < b", Notified: date.Unix()},
+	}
+
+	var archive_dir string = t.TempDir()
+	var notified_items_failed []_NotifiedItem = writeArchive(_ModUserInfo{Archive_dir: archive_dir}, notified_items)
+	if nil != notified_items_failed {
+		t.Errorf("writeArchive() failed on %v", notified_items_failed)
+	}
+
+	var expected_files map[string][]string = map[string][]string{
+		"3/2023/11/2023-11-20 - First.md":     {`title: "First"`, `url: "https://example.com/1"`, "<p>One</p>"},
+		"3/2023/11/2023-11-20 - First (2).md": {`feed: "Blog: news"`, "<p>One again</p>"},
+		"3/2023/12/2023-12-20 - Second [2_2].md": {`author: "Someone"`, `tags: ["news"]`,
+			`# [Second \[2/2\]](<https://example.com/2>)`},
+		"4/2023/11/2023-11-20 - Video.md": {"a &lt; b"},
+		// Newest first, with the latest title of the feed.
+		"3/index.md": {"# Blog: news\n\n- [2023-12-20 - Second \\[2\\_2\\]](<2023/12/2023-12-20 - Second [2_2].md>)\n" +
+			"- [2023-11-20 - First](<2023/11/2023-11-20 - First.md>)\n" +
+			"- [2023-11-20 - First (2)](<2023/11/2023-11-20 - First (2).md>)\n"},
+		"4/index.md": {"# Channel"},
+	}
+	for file, contents := range expected_files {
+		file_contents, err := os.ReadFile(filepath.Join(archive_dir, filepath.FromSlash(file)))
+		if nil != err {
+			t.Errorf("%s: %v", file, err)
+
+			continue
+		}
+		for _, content := range contents {
+			if !strings.Contains(string(file_contents), content) {
+				t.Errorf("%s: %q not in:\n%s", file, content, file_contents)
+			}
+		}
+	}
+
+	// HTML, added to the same feed.
+	writeArchive(_ModUserInfo{Archive_dir: archive_dir, Archive_format: "HTML"}, notified_items[3:])
+	file_contents, err := os.ReadFile(filepath.Join(archive_dir, "4", "2023", "11", "2023-11-20 - Video.html"))
+	if nil != err || !strings.Contains(string(file_contents), "<title>Video</title>") ||
+				!strings.Contains(string(file_contents), "<p>a &lt; b</p>") {
+		t.Errorf("wrong HTML file (%v):\n%s", err, file_contents)
+	}
+	file_contents, err = os.ReadFile(filepath.Join(archive_dir, "4", "index.html"))
+	if nil != err || !strings.Contains(string(file_contents), `<a href="2023/11/2023-11-20 - Video.html">`) {
+		t.Errorf("wrong HTML index (%v):\n%s", err, file_contents)
+	}
+
+	// The items that can't be written are given back, to stay queued.
+	var file_path string = filepath.Join(archive_dir, "file")
+	if err = os.WriteFile(file_path, nil, 0o644); nil != err {
+		t.Fatal(err)
+	}
+	notified_items_failed = writeArchive(_ModUserInfo{Archive_dir: file_path}, notified_items[3:])
+	if 1 != len(notified_items_failed) || "https://example.com/v" != notified_items_failed[0].Url {
+		t.Errorf("writeArchive() into a file gave back %v", notified_items_failed)
+	}
+}
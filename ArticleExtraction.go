@@ -0,0 +1,230 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package main
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	nethtml "golang.org/x/net/html"
+)
+
+// _FULL_CONTENT_MAX_CHARS_DEF is the default maximum number of text characters of an extracted article.
+const _FULL_CONTENT_MAX_CHARS_DEF int = 20000
+
+// _ARTICLE_MIN_TEXT_LEN is the minimum number of text characters for an extraction to be considered an article.
+const _ARTICLE_MIN_TEXT_LEN int = 250
+
+// Elements that are never part of the article.
+const _ARTICLE_UNWANTED_ELEMENTS string = "script, style, noscript, iframe, form, nav, header, footer, aside, " +
+	"button, input, select, textarea, svg, canvas, object, embed"
+
+// Class and ID names that hint if an element is the article or not.
+var (
+	articlePositiveRegex_GL *regexp.Regexp = regexp.MustCompile(`(?i)article|body|content|entry|main|page|post|story|text`)
+	articleNegativeRegex_GL *regexp.Regexp = regexp.MustCompile(`(?i)ad-|ads|banner|breadcrumb|comment|combx|disqus|` +
+		`footer|menu|meta|nav|popup|promo|related|share|sidebar|social|sponsor|subscribe|widget`)
+)
+
+/*
+getFullContent gets the main article body of an item's page. The result is cached.
+
+The HTML is not sanitized nor truncated - that's for sanitizeHtml().
+
+-----------------------------------------------------------
+
+– Params:
+  - item_url – the URL of the item's page
+
+– Returns:
+  - the HTML of the article or "" if it couldn't be got or extracted
+*/
+func getFullContent(item_url string) string {
+	var article_html string = ""
+	if scrapeCacheGet(_CACHE_KIND_ARTICLE, item_url, &article_html) {
+		return article_html
+	}
+
+	var p_page_html *string = getPageHtmlCached(item_url)
+	if nil == p_page_html {
+		return ""
+	}
+
+	article_html = extractArticle(*p_page_html)
+	// Also cached if the extraction failed, to not download the page again.
+	scrapeCacheSet(_CACHE_KIND_ARTICLE, item_url, article_html)
+
+	return article_html
+}
+
+/*
+extractArticle extracts the main article body of a page, readability-style: the paragraphs give points to their parent
+(and half to their grandparent) based on their text, the class and ID names of the candidates add or remove points, and
+the score is reduced by the link density. The best candidate is the article.
+
+-----------------------------------------------------------
+
+– Params:
+  - page_html – the HTML of the page
+
+– Returns:
+  - the HTML of the article or "" if no article was found
+*/
+func extractArticle(page_html string) string {
+	document, err := goquery.NewDocumentFromReader(strings.NewReader(page_html))
+	if nil != err {
+		return ""
+	}
+	document.Find(_ARTICLE_UNWANTED_ELEMENTS).Remove()
+
+	var scores map[*nethtml.Node]float64 = make(map[*nethtml.Node]float64)
+	var candidates []*goquery.Selection = nil
+	var addScore func(candidate *goquery.Selection, score float64) = func(candidate *goquery.Selection,
+				score float64) {
+		if 0 == candidate.Length() || candidate.Is("body, html") {
+			return
+		}
+		var node *nethtml.Node = candidate.Get(0)
+		if _, ok := scores[node]; !ok {
+			scores[node] = getClassWeight(candidate)
+			candidates = append(candidates, candidate)
+		}
+		scores[node] += score
+	}
+
+	document.Find("p, pre, td").Each(func(_ int, paragraph *goquery.Selection) {
+		var text string = strings.TrimSpace(paragraph.Text())
+		if len(text) < 25 {
+			return
+		}
+
+		var score float64 = 1 + float64(strings.Count(text, ",")) + float64(len(text)/100)
+		if score > 4 {
+			score = 4
+		}
+		addScore(paragraph.Parent(), score)
+		addScore(paragraph.Parent().Parent(), score/2)
+	})
+
+	var best *goquery.Selection = nil
+	var best_score float64 = 0
+	for _, candidate := range candidates {
+		var score float64 = scores[candidate.Get(0)] * (1 - getLinkDensity(candidate))
+		if nil == best || score > best_score {
+			best = candidate
+			best_score = score
+		}
+	}
+	if nil == best {
+		// No paragraphs - maybe the article is marked as one.
+		best = document.Find("article").First()
+		if 0 == best.Length() {
+			return ""
+		}
+	}
+	if len(strings.TrimSpace(best.Text())) < _ARTICLE_MIN_TEXT_LEN {
+		return ""
+	}
+
+	cleanArticle(best)
+
+	article_html, err := best.Html()
+	if nil != err {
+		return ""
+	}
+
+	return strings.TrimSpace(article_html)
+}
+
+/*
+getClassWeight gets the initial score of an element from its class and ID names.
+
+-----------------------------------------------------------
+
+– Params:
+  - element – the element
+
+– Returns:
+  - the score
+*/
+func getClassWeight(element *goquery.Selection) float64 {
+	var weight float64 = 0
+	for _, attr := range []string{"class", "id"} {
+		value, ok := element.Attr(attr)
+		if !ok || "" == value {
+			continue
+		}
+		if articleNegativeRegex_GL.MatchString(value) {
+			weight -= 25
+		}
+		if articlePositiveRegex_GL.MatchString(value) {
+			weight += 25
+		}
+	}
+	if element.Is("article, main") {
+		weight += 25
+	}
+
+	return weight
+}
+
+/*
+getLinkDensity gets the fraction of the text of an element that is inside links.
+
+-----------------------------------------------------------
+
+– Params:
+  - element – the element
+
+– Returns:
+  - the link density, from 0 to 1
+*/
+func getLinkDensity(element *goquery.Selection) float64 {
+	var text_len int = len(element.Text())
+	if 0 == text_len {
+		return 0
+	}
+
+	var links_len int = 0
+	element.Find("a").Each(func(_ int, link *goquery.Selection) {
+		links_len += len(link.Text())
+	})
+
+	return float64(links_len) / float64(text_len)
+}
+
+/*
+cleanArticle removes the parts of the extracted article with too many links or that don't look like article parts.
+
+-----------------------------------------------------------
+
+– Params:
+  - article – the article element
+*/
+func cleanArticle(article *goquery.Selection) {
+	article.Find("div, section, ul, ol, table").Each(func(_ int, element *goquery.Selection) {
+		if getLinkDensity(element) > 0.5 || getClassWeight(element) < 0 {
+			element.Remove()
+		}
+	})
+}
@@ -0,0 +1,91 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// getTestParagraphs gets n paragraphs of article-like text.
+func getTestParagraphs(n int, word string) string {
+	var paragraphs string = ""
+	for i := 0; i < n; i++ {
+		paragraphs += "<p>This is a paragraph about " + word + ", with enough text, commas, and words to count as " +
+			"part of an article body.</p>"
+	}
+
+	return paragraphs
+}
+
+func TestExtractArticle(t *testing.T) {
+	var tests = []struct {
+		name        string
+		page_html   string
+		contains    []string
+		not_contain []string
+	}{
+		{
+			name: "article among navigation and comments",
+			page_html: `<html><body><nav><a href="/">Home</a><a href="/about">About</a></nav>` +
+				`<div class="sidebar"><p>Subscribe to our newsletter, now, today, for free, please.</p></div>` +
+				`<div class="post-content">` + getTestParagraphs(4, "cats") + `<script>track()</script></div>` +
+				`<div class="comments">` + getTestParagraphs(1, "comments") + `</div>` +
+				`<footer>Copyright, all rights reserved, forever and ever.</footer></body></html>`,
+			contains:    []string{"about cats"},
+			not_contain: []string{"Home", "newsletter", "track()", "about comments", "Copyright"},
+		},
+		{
+			name: "link lists lose to text",
+			page_html: `<html><body><div id="main">` + getTestParagraphs(3, "dogs") + `</div>` +
+				`<div><p><a href="/1">A link to another article, with a long title, and more</a></p>` +
+				`<p><a href="/2">Another link to another article, with a long title, and more</a></p></div>` +
+				`</body></html>`,
+			contains:    []string{"about dogs"},
+			not_contain: []string{"Another link"},
+		},
+		{
+			name:      "too short",
+			page_html: `<html><body><div><p>Only one short paragraph, not an article.</p></div></body></html>`,
+		},
+		{
+			name:      "no paragraphs",
+			page_html: `<html><body><div>Nothing here</div></body></html>`,
+		},
+	}
+	for _, test := range tests {
+		var article string = extractArticle(test.page_html)
+		if nil == test.contains && "" != article {
+			t.Errorf("%s: expected no article, got %q", test.name, article)
+		}
+		for _, text := range test.contains {
+			if !strings.Contains(article, text) {
+				t.Errorf("%s: %q not in the article: %q", test.name, text, article)
+			}
+		}
+		for _, text := range test.not_contain {
+			if strings.Contains(article, text) {
+				t.Errorf("%s: %q in the article: %q", test.name, text, article)
+			}
+		}
+	}
+}
@@ -0,0 +1,279 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+	ext "github.com/mmcdole/gofeed/extensions"
+)
+
+// _CATCH_UP_MAX_PAGES is the maximum number of pages of a General feed got when catching up.
+const _CATCH_UP_MAX_PAGES int = 10
+
+/*
+isFeedGapPossible checks if items may have been missed since the last check: if no item of the feed was already
+notified, or if even the oldest item is newer than the last check.
+
+-----------------------------------------------------------
+
+– Params:
+  - parsed_feed – the parsed feed
+  - newsInfo_list – the list of notified news
+  - last_check – when the feed was last checked (zero if unknown)
+
+– Returns:
+  - true if items may have been missed, false otherwise
+*/
+func isFeedGapPossible(parsed_feed *gofeed.Feed, newsInfo_list []_NewsInfo, last_check time.Time) bool {
+	if 0 == len(parsed_feed.Items) {
+		return false
+	}
+
+	var overlap bool = false
+	var oldest_published time.Time = time.Time{}
+	for _, item := range parsed_feed.Items {
+		if isNewsNotified(newsInfo_list, item.Title, item.Link) {
+			overlap = true
+		}
+		if nil != item.PublishedParsed && (oldest_published.IsZero() || item.PublishedParsed.Before(oldest_published)) {
+			oldest_published = *item.PublishedParsed
+		}
+	}
+
+	if !overlap {
+		return true
+	}
+
+	return !last_check.IsZero() && !oldest_published.IsZero() && oldest_published.After(last_check)
+}
+
+/*
+getCatchUpItems gets the items that were missed because they already left the feed, by paging the YouTube
+channel/playlist or the General feed (with "page=N") until an already notified item is found.
+
+-----------------------------------------------------------
+
+– Params:
+  - feedInfo – the information of the feed (with the final URL)
+  - feedType – the type of the feed
+  - parsed_feed – the parsed feed
+  - newsInfo_list – the list of notified news
+  - max_items – the maximum number of items to get
+
+– Returns:
+  - the missed items, from the newest to the oldest (ready to be appended to the feed items)
+*/
+func getCatchUpItems(feedInfo _FeedInfo, feedType _FeedType, parsed_feed *gofeed.Feed, newsInfo_list []_NewsInfo,
+			max_items int) []*gofeed.Item {
+	switch feedType.type_1 {
+		case _TYPE_1_YOUTUBE: {
+			return getYTCatchUpItems(feedType, parsed_feed, newsInfo_list, max_items)
+		}
+		case _TYPE_1_GENERAL, _TYPE_1_PODCAST, _TYPE_1_GITHUB, _TYPE_1_STACKEXCHANGE: {
+			return getPagedCatchUpItems(feedInfo, parsed_feed, newsInfo_list, max_items)
+		}
+	}
+
+	return nil
+}
+
+/*
+getYTCatchUpItems is getCatchUpItems() for YouTube feeds: the uploads playlist of the channel (or the feed's playlist) is
+got and its videos are turned into feed items like the ones of the YouTube feed.
+*/
+func getYTCatchUpItems(feedType _FeedType, parsed_feed *gofeed.Feed, newsInfo_list []_NewsInfo,
+			max_items int) []*gofeed.Item {
+	var channel_id string = parsed_feed.Items[0].Extensions["yt"]["channelId"][0].Value
+	var playlist_id string = ""
+	if _TYPE_2_YT_CHANNEL == feedType.type_2 {
+		if strings.HasPrefix(channel_id, "UC") {
+			// "UU" is the prefix of the playlist with all the uploads of a channel.
+			playlist_id = "UU" + channel_id[len("UC"):]
+		}
+	} else if isYTPlaylistFeed(feedType) {
+		playlist_id = parsed_feed.Extensions["yt"]["playlistId"][0].Value
+	}
+	if "" == playlist_id {
+		return nil
+	}
+
+	// The uploads are newest first, so the playlist is only got until where it was left.
+	var videos_info []_VideoInfo = getPlaylistVideos(playlist_id, func(videoInfo _VideoInfo) bool {
+		return isNewsNotified(newsInfo_list, videoInfo.Title, "https://www.youtube.com/watch?v=" + videoInfo.Id)
+	})
+	var oldest_item *gofeed.Item = parsed_feed.Items[len(parsed_feed.Items)-1]
+
+	var items []*gofeed.Item = nil
+	for _, videoInfo := range videos_info {
+		if len(items) >= max_items {
+			break
+		}
+
+		var video_url string = "https://www.youtube.com/watch?v=" + videoInfo.Id
+		if isItemInFeed(parsed_feed, video_url) {
+			continue
+		}
+
+		items = append(items, &gofeed.Item{
+			Title:       videoInfo.Title,
+			Link:        video_url,
+			// Not the real date (unknown) - the oldest of the feed, so that the feed keeps its order (scrapingNeeded()).
+			Published:   oldest_item.Published,
+			Extensions: ext.Extensions{
+				"yt": {
+					"videoId":   {{Value: videoInfo.Id}},
+					"channelId": {{Value: channel_id}},
+				},
+				"media": {
+					"group": {{
+						Children: map[string][]ext.Extension{
+							"thumbnail":   {{Attrs: map[string]string{"url": videoInfo.Image}}},
+							"description": {{Value: ""}},
+						},
+					}},
+				},
+			},
+		})
+	}
+
+	return items
+}
+
+/*
+getPagedCatchUpItems is getCatchUpItems() for General feeds: the next pages of the feed are got with "page=N" (which
+works on many sites, like StackExchange and WordPress) - stopping if a page brings nothing new.
+*/
+func getPagedCatchUpItems(feedInfo _FeedInfo, parsed_feed *gofeed.Feed, newsInfo_list []_NewsInfo,
+			max_items int) []*gofeed.Item {
+	feed_url, err := url.Parse(feedInfo.Feed_url)
+	if nil != err {
+		return nil
+	}
+
+	var items []*gofeed.Item = nil
+	for page_num := 2; page_num <= _CATCH_UP_MAX_PAGES; page_num++ {
+		var query url.Values = feed_url.Query()
+		query.Set("page", strconv.Itoa(page_num))
+		feed_url.RawQuery = query.Encode()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+		page_feed, err := gofeed.NewParser().ParseURLWithContext(feed_url.String(), ctx)
+		cancel()
+		if nil != err {
+			fmt.Println("Error parsing feed page " + strconv.Itoa(page_num) + ": " + err.Error())
+
+			break
+		}
+
+		var page_new_items int = 0
+		var found_notified bool = false
+		for _, item := range page_feed.Items {
+			if isNewsNotified(newsInfo_list, item.Title, item.Link) {
+				found_notified = true
+
+				break
+			}
+			if isItemInFeed(parsed_feed, item.Link) || containsItem(items, item.Link) {
+				continue
+			}
+			if len(items) >= max_items {
+				return items
+			}
+			items = append(items, item)
+			page_new_items++
+		}
+		if found_notified || 0 == page_new_items {
+			// Either found where it was left or the site ignores the page parameter.
+			break
+		}
+	}
+
+	return items
+}
+
+/*
+isNewsNotified checks if a news was already notified - same as !isNewNews() but without printing anything.
+
+-----------------------------------------------------------
+
+– Params:
+  - newsInfo_list – the list of notified news
+  - title – the title of the news
+  - url – the URL of the news
+
+– Returns:
+  - true if the news was already notified, false otherwise
+*/
+func isNewsNotified(newsInfo_list []_NewsInfo, title string, url string) bool {
+	for _, newsInfo := range newsInfo_list {
+		if newsInfo.url == url && newsInfo.title == title {
+			return true
+		}
+	}
+
+	return false
+}
+
+/*
+isItemInFeed checks if an item with the given link is in the feed.
+
+-----------------------------------------------------------
+
+– Params:
+  - parsed_feed – the parsed feed
+  - link – the link of the item
+
+– Returns:
+  - true if it is, false otherwise
+*/
+func isItemInFeed(parsed_feed *gofeed.Feed, link string) bool {
+	return containsItem(parsed_feed.Items, link)
+}
+
+/*
+containsItem checks if an item with the given link is in a list of items.
+
+-----------------------------------------------------------
+
+– Params:
+  - items – the list of items
+  - link – the link of the item
+
+– Returns:
+  - true if it is, false otherwise
+*/
+func containsItem(items []*gofeed.Item, link string) bool {
+	for _, item := range items {
+		if item.Link == link {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,69 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mmcdole/gofeed"
+	ext "github.com/mmcdole/gofeed/extensions"
+)
+
+func TestGetYTCatchUpItemsStopsAtNotified(t *testing.T) {
+	var browse_requests int = 0
+	var server *httptest.Server = newTestPlaylistServer(t, &browse_requests)
+	defer server.Close()
+	setTestYTBaseUrl(t, server.URL)
+
+	// The feed only has the newest video (vid1) and vid4 was the last one notified.
+	var parsed_feed *gofeed.Feed = &gofeed.Feed{
+		Items: []*gofeed.Item{{
+			Title:      "Title vid1",
+			Link:       "https://www.youtube.com/watch?v=vid1",
+			Published:  "2023-11-20T10:00:00+00:00",
+			Extensions: ext.Extensions{"yt": {"channelId": {{Value: "UCtest"}}}},
+		}},
+		Extensions: ext.Extensions{"yt": {"playlistId": {{Value: "PLtest"}}}},
+	}
+	var newsInfo_list []_NewsInfo = []_NewsInfo{
+		{url: "https://www.youtube.com/watch?v=vid4", title: "Title vid4"},
+	}
+	var feedType _FeedType = _FeedType{type_1: _TYPE_1_YOUTUBE, type_2: _TYPE_2_YT_PLAYLIST}
+
+	var items []*gofeed.Item = getYTCatchUpItems(feedType, parsed_feed, newsInfo_list, 10)
+	if 2 != len(items) || "https://www.youtube.com/watch?v=vid2" != items[0].Link ||
+				"https://www.youtube.com/watch?v=vid3" != items[1].Link {
+		t.Fatalf("expected vid2 and vid3, got %d items", len(items))
+	}
+	if "vid3" != items[1].Extensions["yt"]["videoId"][0].Value || parsed_feed.Items[0].Published != items[1].Published {
+		t.Errorf("wrong item: %+v", items[1])
+	}
+	// vid4 is on the first continuation, so the second one must not be got.
+	if 1 != browse_requests {
+		t.Errorf("got %d continuations, expected 1", browse_requests)
+	}
+
+	if items = getYTCatchUpItems(feedType, parsed_feed, newsInfo_list, 1); 1 != len(items) {
+		t.Errorf("got %d items with a maximum of 1", len(items))
+	}
+}
@@ -0,0 +1,432 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"Utils"
+)
+
+// _SERVE_WEBSUB_UPDATE_S is every how many seconds the "serve" command updates the WebSub subscriptions.
+const _SERVE_WEBSUB_UPDATE_S int = 10 * 60
+
+// _Command is a command that can be given to the module on the command line, to be run instead of the normal loop.
+type _Command struct {
+	// usage is the usage of the command (without the command name)
+	usage string
+	// description is what the command does
+	description string
+	// run runs the command with the given arguments and returns false if they're wrong
+	run func(args []string) bool
+	// unlocked is true if the module is not locked while the command runs (it locks it only when needed)
+	unlocked bool
+}
+
+// commands_GL has the available commands mapped by name.
+var commands_GL map[string]_Command = map[string]_Command{
+	"check": {
+		usage:       "[--tag <tag>] [feed_num...]",
+		description: "checks the given feeds (or all, or the ones with the tag) once",
+		run:         cmdCheck,
+	},
+	"reset-feed": {
+		usage:       "<feed_num>",
+		description: "forgets all about the feed and checks it again as a new feed (applying its Initial_sync policy)",
+		run:         cmdResetFeed,
+	},
+	"outbox": {
+		usage:       "[pending|sent|dead]",
+		description: "lists the notifications in the outbox (all or only the ones with a delivery with the status)",
+		run:         cmdOutbox,
+	},
+	"outbox-retry": {
+		usage:       "[id]",
+		description: "retries the dead-lettered deliveries of the outbox entry (or of all entries)",
+		run:         cmdOutboxRetry,
+	},
+	"outbox-replay": {
+		usage:       "<id>",
+		description: "delivers the outbox entry again to all its destinations",
+		run:         cmdOutboxReplay,
+	},
+	"search": {
+		usage:       "[--limit <n>] <query...>",
+		description: "searches the notified items: words, \"phrases\", feed:<num|title>, tag:<tag>, since:<date> and " +
+			"until:<date> (dates as YYYY-MM-DD or YYYY-MM)",
+		run:         cmdSearch,
+	},
+	"websub": {
+		usage:       "",
+		description: "lists the WebSub subscriptions of the feeds (whose new items are pushed by their hubs)",
+		run:         cmdWebSub,
+	},
+	"serve": {
+		usage:       "",
+		description: "runs the HTTP server on Http_server_addr (status, output feeds, search and WebSub callbacks) " +
+			"until the module is stopped",
+		run:         cmdServe,
+		unlocked:    true,
+	},
+}
+
+/*
+runCommand runs a command given on the command line, with the module locked (see lockModule()) - so it waits for the
+cycle running on another process, if any - unless it's one of the _Command.unlocked ones.
+
+-----------------------------------------------------------
+
+– Params:
+  - args – the command line arguments (without the program name)
+*/
+func runCommand(args []string) {
+	command, ok := commands_GL[args[0]]
+	if !ok {
+		printCommandsUsage()
+
+		return
+	}
+
+	if command.unlocked {
+		if !command.run(args[1:]) {
+			printCommandsUsage()
+		}
+
+		return
+	}
+
+	if !lockModule() {
+		return
+	}
+	defer unlockModule()
+
+	if !command.run(args[1:]) {
+		printCommandsUsage()
+
+		return
+	}
+	processArchiveQueue()
+	endScrapeCacheCycle()
+}
+
+/*
+printCommandsUsage prints the usage of all the commands.
+*/
+func printCommandsUsage() {
+	fmt.Println("Available commands:")
+	for name, command := range commands_GL {
+		fmt.Println("  " + name + " " + command.usage + " – " + command.description)
+	}
+}
+
+/*
+getFeedsByNum gets the information of the feeds with the given numbers.
+
+-----------------------------------------------------------
+
+– Params:
+  - feeds_nums – the numbers of the feeds as strings (if empty, all the feeds are returned)
+
+– Returns:
+  - the information of the feeds or nil if any of the numbers is invalid or if an error occurs
+*/
+func getFeedsByNum(feeds_nums []string) []_FeedInfo {
+	var feedsInfo []_FeedInfo = getFeedsInfo()
+	if nil == feedsInfo || 0 == len(feeds_nums) {
+		return feedsInfo
+	}
+
+	var feedsInfo_ret []_FeedInfo = nil
+	for _, feed_num_str := range feeds_nums {
+		feed_num, err := strconv.Atoi(feed_num_str)
+		if nil != err {
+			fmt.Println("Invalid feed number: " + feed_num_str)
+
+			return nil
+		}
+
+		var found bool = false
+		for _, feedInfo := range feedsInfo {
+			if feedInfo.Feed_num == feed_num {
+				feedsInfo_ret = append(feedsInfo_ret, feedInfo)
+				found = true
+
+				break
+			}
+		}
+		if !found {
+			fmt.Println("Feed not found: " + feed_num_str)
+
+			return nil
+		}
+	}
+
+	return feedsInfo_ret
+}
+
+/*
+getFeedsByTag filters feeds by tag.
+
+-----------------------------------------------------------
+
+– Params:
+  - feedsInfo – the information of the feeds
+  - tag – the tag
+
+– Returns:
+  - the information of the feeds with the tag
+*/
+func getFeedsByTag(feedsInfo []_FeedInfo, tag string) []_FeedInfo {
+	var feedsInfo_ret []_FeedInfo = nil
+	for _, feedInfo := range feedsInfo {
+		if feedHasTag(feedInfo, tag) {
+			feedsInfo_ret = append(feedsInfo_ret, feedInfo)
+		}
+	}
+
+	return feedsInfo_ret
+}
+
+func cmdCheck(args []string) bool {
+	var tag string = ""
+	if len(args) > 0 && "--tag" == args[0] {
+		if len(args) < 2 {
+			return false
+		}
+		tag = args[1]
+		args = args[2:]
+	}
+
+	var feedsInfo []_FeedInfo = getFeedsByNum(args)
+	if nil == feedsInfo {
+		return false
+	}
+	if "" != tag {
+		feedsInfo = getFeedsByTag(feedsInfo, tag)
+		if 0 == len(feedsInfo) {
+			fmt.Println("No feeds with the tag: " + tag)
+
+			return true
+		}
+	}
+
+	for _, feedInfo := range feedsInfo {
+		checkFeed(feedInfo, nil)
+	}
+	processPodcastDownloads()
+
+	return true
+}
+
+func cmdResetFeed(args []string) bool {
+	if 1 != len(args) {
+		return false
+	}
+	var feedsInfo []_FeedInfo = getFeedsByNum(args)
+	if nil == feedsInfo {
+		return false
+	}
+
+	resetFeed(feedsInfo[0].Feed_num)
+	fmt.Println("Feed reset: " + args[0])
+	checkFeed(feedsInfo[0], nil)
+
+	return true
+}
+
+func cmdOutbox(args []string) bool {
+	if len(args) > 1 {
+		return false
+	}
+	var status string = ""
+	if 1 == len(args) {
+		status = args[0]
+		if _DELIVERY_PENDING != status && _DELIVERY_SENT != status && _DELIVERY_DEAD != status {
+			return false
+		}
+	}
+
+	for _, outboxEntry := range readOutbox() {
+		var wanted bool = "" == status
+		for _, delivery := range outboxEntry.Deliveries {
+			if delivery.Status == status {
+				wanted = true
+
+				break
+			}
+		}
+		if !wanted {
+			continue
+		}
+
+		fmt.Println(outboxEntry.Id + " – " + time.Unix(outboxEntry.Created, 0).Format(Utils.DATE_TIME_FORMAT) + " – " +
+			outboxEntry.Subject)
+		for _, delivery := range outboxEntry.Deliveries {
+			var line string = "    " + delivery.Destination + ": " + delivery.Status
+			if 0 != delivery.Attempts {
+				line += " (" + strconv.Itoa(delivery.Attempts) + " attempts - " + delivery.Last_error + ")"
+			}
+			fmt.Println(line)
+		}
+	}
+
+	return true
+}
+
+func cmdOutboxRetry(args []string) bool {
+	if len(args) > 1 {
+		return false
+	}
+
+	var ids []string = args
+	if 0 == len(ids) {
+		for _, outboxEntry := range readOutbox() {
+			for _, delivery := range outboxEntry.Deliveries {
+				if _DELIVERY_DEAD == delivery.Status {
+					ids = append(ids, outboxEntry.Id)
+
+					break
+				}
+			}
+		}
+	}
+
+	for _, id := range ids {
+		if !replayOutboxEntry(id, true) {
+			fmt.Println("Outbox entry not found: " + id)
+
+			return true
+		}
+		fmt.Println("Outbox entry retried: " + id)
+	}
+
+	return true
+}
+
+func cmdOutboxReplay(args []string) bool {
+	if 1 != len(args) {
+		return false
+	}
+
+	if !replayOutboxEntry(args[0], false) {
+		fmt.Println("Outbox entry not found: " + args[0])
+
+		return true
+	}
+	fmt.Println("Outbox entry replayed: " + args[0])
+
+	return true
+}
+
+func cmdSearch(args []string) bool {
+	var limit int = _SEARCH_MAX_RESULTS_DEF
+	if len(args) > 0 && "--limit" == args[0] {
+		if len(args) < 2 {
+			return false
+		}
+		var err error
+		limit, err = strconv.Atoi(args[1])
+		if nil != err || limit < 0 {
+			return false
+		}
+		args = args[2:]
+	}
+	if 0 == len(args) {
+		return false
+	}
+
+	var searchResults []_SearchResult = searchNotifiedItems(strings.Join(args, " "), limit)
+	if 0 == len(searchResults) {
+		fmt.Println("No items found")
+
+		return true
+	}
+	for _, searchResult := range searchResults {
+		var notifiedItem _NotifiedItem = searchResult.notifiedItem
+		fmt.Println(getItemDate(notifiedItem).Local().Format(Utils.DATE_TIME_FORMAT) + " – " + notifiedItem.Feed_title +
+			" – " + notifiedItem.Title)
+		fmt.Println("    " + notifiedItem.Url)
+	}
+
+	return true
+}
+
+func cmdWebSub(args []string) bool {
+	if 0 != len(args) {
+		return false
+	}
+
+	var subscriptions map[int]_WebSubSubscription = getWebSubSubscriptions()
+	if 0 == len(subscriptions) {
+		fmt.Println("No WebSub subscriptions")
+
+		return true
+	}
+	var feeds_nums []int = nil
+	for feed_num := range subscriptions {
+		feeds_nums = append(feeds_nums, feed_num)
+	}
+	sort.Ints(feeds_nums)
+
+	for _, feed_num := range feeds_nums {
+		var subscription _WebSubSubscription = subscriptions[feed_num]
+		var line string = strconv.Itoa(feed_num) + " – " + subscription.Status
+		if 0 != subscription.Lease_end {
+			line += " (lease until " + time.Unix(subscription.Lease_end, 0).Format(Utils.DATE_TIME_FORMAT) + ")"
+		}
+		fmt.Println(line)
+		if "" != subscription.Hub {
+			fmt.Println("    " + subscription.Topic + " on " + subscription.Hub)
+		}
+	}
+
+	return true
+}
+
+func cmdServe(args []string) bool {
+	if 0 != len(args) {
+		return false
+	}
+
+	var modUserInfo _ModUserInfo
+	if !moduleInfo_GL.GetModUserInfo(&modUserInfo) || "" == modUserInfo.Http_server_addr {
+		fmt.Println("Http_server_addr is not set")
+
+		return true
+	}
+	startStatusServer(modUserInfo.Http_server_addr)
+
+	// Only this process gets the WebSub subscriptions verified (on its callbacks), so it's the one that keeps them.
+	for {
+		if feedsInfo := getFeedsInfo(); nil != feedsInfo {
+			updateWebSubSubscriptions(feedsInfo)
+		}
+
+		if moduleInfo_GL.LoopSleep(_SERVE_WEBSUB_UPDATE_S) {
+			return true
+		}
+	}
+}
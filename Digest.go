@@ -0,0 +1,86 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package main
+
+import (
+	"html"
+	"strings"
+)
+
+// _DigestItem is a news to list on a digest email.
+type _DigestItem struct {
+	// group is the group the news is listed under (see getFeedGroup())
+	group string
+	// newsInfo is the news
+	newsInfo _NewsInfo
+}
+
+/*
+getDigestHtml gets the HTML of an email listing many news at once (there's no email model for this, so it's simple).
+
+If the news are from more than one group, they're listed grouped, with the groups in the order they first appear.
+
+-----------------------------------------------------------
+
+– Params:
+  - title – the title to put on top of the list
+  - digest_items – the news to list
+
+– Returns:
+  - the HTML of the email
+*/
+func getDigestHtml(title string, digest_items []_DigestItem) string {
+	var groups []string = nil
+	var groups_items map[string][]_NewsInfo = make(map[string][]_NewsInfo)
+	for _, digestItem := range digest_items {
+		if _, ok := groups_items[digestItem.group]; !ok {
+			groups = append(groups, digestItem.group)
+		}
+		groups_items[digestItem.group] = append(groups_items[digestItem.group], digestItem.newsInfo)
+	}
+
+	var html_builder strings.Builder
+	html_builder.WriteString("<!DOCTYPE html>\n<html>\n<head><meta charset=\"UTF-8\"><title>")
+	html_builder.WriteString(html.EscapeString(title))
+	html_builder.WriteString("</title></head>\n<body style=\"font-family: Roboto, Arial, sans-serif;\">\n<h3>")
+	html_builder.WriteString(html.EscapeString(title))
+	html_builder.WriteString("</h3>\n")
+	for _, group := range groups {
+		if len(groups) > 1 {
+			html_builder.WriteString("<h4>")
+			html_builder.WriteString(html.EscapeString(group))
+			html_builder.WriteString("</h4>\n")
+		}
+		html_builder.WriteString("<ul>\n")
+		for _, newsInfo := range groups_items[group] {
+			html_builder.WriteString("<li><a href=\"")
+			html_builder.WriteString(html.EscapeString(newsInfo.url))
+			html_builder.WriteString("\">")
+			html_builder.WriteString(html.EscapeString(newsInfo.title))
+			html_builder.WriteString("</a></li>\n")
+		}
+		html_builder.WriteString("</ul>\n")
+	}
+	html_builder.WriteString("</body>\n</html>\n")
+
+	return html_builder.String()
+}
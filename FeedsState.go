@@ -0,0 +1,108 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"Utils"
+)
+
+// _FeedState is the state of a feed besides its notified news. It's exported to JSON, so the fields are exported.
+type _FeedState struct {
+	// Last_check is when the feed was last checked successfully in Unix seconds (0 if never)
+	Last_check int64
+}
+
+/*
+getFeedState gets the state of a feed.
+
+-----------------------------------------------------------
+
+– Params:
+  - feed_num – the number of the feed
+
+– Returns:
+  - the state of the feed (all fields empty if there's none)
+*/
+func getFeedState(feed_num int) _FeedState {
+	return readFeedsState()[feed_num]
+}
+
+/*
+setFeedState sets the state of a feed.
+
+-----------------------------------------------------------
+
+– Params:
+  - feed_num – the number of the feed
+  - feedState – the state of the feed
+*/
+func setFeedState(feed_num int, feedState _FeedState) {
+	var feeds_state map[int]_FeedState = readFeedsState()
+	feeds_state[feed_num] = feedState
+
+	feeds_state_json, err := json.MarshalIndent(feeds_state, "", "\t")
+	if nil != err {
+		fmt.Println("Error writing the feeds state: " + err.Error())
+
+		return
+	}
+	getFeedsStatePath().WriteTextFile(string(feeds_state_json))
+}
+
+/*
+readFeedsState reads the state of all feeds from the feeds state file.
+
+-----------------------------------------------------------
+
+– Returns:
+  - the states mapped by feed number (empty if there are none or if an error occurs)
+*/
+func readFeedsState() map[int]_FeedState {
+	var feeds_state map[int]_FeedState = make(map[int]_FeedState)
+
+	var p_feeds_state_json *string = getFeedsStatePath().ReadTextFile()
+	if nil == p_feeds_state_json {
+		return feeds_state
+	}
+	if err := json.Unmarshal([]byte(*p_feeds_state_json), &feeds_state); nil != err {
+		fmt.Println("Error reading the feeds state: " + err.Error())
+
+		return make(map[int]_FeedState)
+	}
+
+	return feeds_state
+}
+
+/*
+getFeedsStatePath gets the path of the feeds state file.
+
+-----------------------------------------------------------
+
+– Returns:
+  - the path of the file
+*/
+func getFeedsStatePath() Utils.GPath {
+	return moduleInfo_GL.ModDirsInfo.UserData.Add2("feeds_state.json")
+}
@@ -22,6 +22,7 @@
 package main
 
 import (
+	"html"
 	"time"
 
 	"github.com/mmcdole/gofeed"
@@ -29,52 +30,100 @@ import (
 	"Utils"
 )
 
+// _DESCRIPTION_MAX_CHARS is the maximum number of text characters of an item's description put in the email.
+const _DESCRIPTION_MAX_CHARS int = 20000
+
 /*
 generalTreatment does the general treatment of an RSS feed item.
 
 -----------------------------------------------------------
 
 – Params:
+  - feedInfo – the information of the feed
   - parsed_feed – the parsed feed
   - item_num – the number of the item to get
   - title_url_only – whether to only get the title and URL of the item through _NewsInfo (can be used for optimization)
+  - getDescriptionHtml – the function that gets the HTML put on the email from the sanitized description, for the
+    feed types that add their own information to it, or nil to put the description as it is
 
 – Returns:
   - the email info (without the Mail_to field) or all fields empty if title_url_only is true
   - the news info
  */
-func generalTreatment(parsed_feed *gofeed.Feed, item_num int, title_url_only bool, custom_msg_subject string) (
-					Utils.EmailInfo, _NewsInfo) {
+func generalTreatment(feedInfo _FeedInfo, parsed_feed *gofeed.Feed, item_num int, title_url_only bool,
+					getDescriptionHtml func(description string) string) (Utils.EmailInfo, _NewsInfo) {
 	var feed_item *gofeed.Item = parsed_feed.Items[item_num]
 
+	// Not all feeds have authors on the items (podcasts usually only have the iTunes one).
+	var author string = ""
+	if len(feed_item.Authors) > 0 {
+		author = feed_item.Authors[0].Name
+	} else if nil != feed_item.ITunesExt {
+		author = feed_item.ITunesExt.Author
+	}
+
 	var things_replace = map[string]string{
 		Utils.MODEL_RSS_ENTRY_TITLE_EMAIL:       feed_item.Title,
-		Utils.MODEL_RSS_ENTRY_AUTHOR_EMAIL:      feed_item.Authors[0].Name,
+		Utils.MODEL_RSS_ENTRY_AUTHOR_EMAIL:      author,
 		Utils.MODEL_RSS_ENTRY_DESCRIPTION_EMAIL: feed_item.Description,
 		Utils.MODEL_RSS_ENTRY_URL_EMAIL:         feed_item.Link,
 		Utils.MODEL_RSS_ENTRY_PUB_DATE_EMAIL:    feed_item.Published,
 		Utils.MODEL_RSS_ENTRY_UPD_DATE_EMAIL:    feed_item.Updated,
 	}
 	var newsInfo _NewsInfo = _NewsInfo{
-		title: things_replace[Utils.MODEL_RSS_ENTRY_TITLE_EMAIL],
-		url:   things_replace[Utils.MODEL_RSS_ENTRY_URL_EMAIL],
+		title:  things_replace[Utils.MODEL_RSS_ENTRY_TITLE_EMAIL],
+		url:    things_replace[Utils.MODEL_RSS_ENTRY_URL_EMAIL],
+		author: things_replace[Utils.MODEL_RSS_ENTRY_AUTHOR_EMAIL],
+	}
+	if nil != feed_item.Image {
+		newsInfo.image = feed_item.Image.URL
+	}
+	if nil != feed_item.PublishedParsed {
+		newsInfo.published = *feed_item.PublishedParsed
 	}
 
 	if title_url_only {
 		return Utils.EmailInfo{}, newsInfo
 	}
 
+	newsInfo.description, _ = sanitizeHtml(feed_item.Description, feed_item.Link, 0)
+
 	if things_replace[Utils.MODEL_RSS_ENTRY_UPD_DATE_EMAIL] != "" {
 		if things_replace[Utils.MODEL_RSS_ENTRY_UPD_DATE_EMAIL] == things_replace[Utils.MODEL_RSS_ENTRY_PUB_DATE_EMAIL] {
 			things_replace[Utils.MODEL_RSS_ENTRY_UPD_DATE_EMAIL] = "[new]"
 		}
 	}
 
+	var full_content string = ""
+	if feedInfo.Full_content && "" != feed_item.Link {
+		// The description is only a teaser on many feeds - the whole article is put instead, if it's found.
+		full_content = getFullContent(feed_item.Link)
+	}
+	// The feed's HTML goes into the email, so it's sanitized first (the description already was, above).
+	var description string = ""
+	var truncated bool = false
+	if "" != full_content {
+		var max_chars int = feedInfo.Full_content_max_chars
+		if max_chars <= 0 {
+			max_chars = _FULL_CONTENT_MAX_CHARS_DEF
+		}
+		description, truncated = sanitizeHtml(full_content, feed_item.Link, max_chars)
+	} else {
+		description, truncated = truncateHtml(newsInfo.description, _DESCRIPTION_MAX_CHARS)
+	}
+	if truncated {
+		description += "\n<p><a href=\"" + html.EscapeString(feed_item.Link) + "\">Continuar a ler</a></p>"
+	}
+	if nil != getDescriptionHtml {
+		description = getDescriptionHtml(description)
+	}
+	things_replace[Utils.MODEL_RSS_ENTRY_DESCRIPTION_EMAIL] = description
+
 	things_replace[Utils.MODEL_RSS_ENTRY_PUB_DATE_EMAIL] = convertDate(things_replace[Utils.MODEL_RSS_ENTRY_PUB_DATE_EMAIL])
 	things_replace[Utils.MODEL_RSS_ENTRY_UPD_DATE_EMAIL] = convertDate(things_replace[Utils.MODEL_RSS_ENTRY_UPD_DATE_EMAIL])
 
 	var email_info Utils.EmailInfo = Utils.GetModelFileEMAIL(Utils.MODEL_FILE_RSS, things_replace)
-	email_info.Subject = custom_msg_subject
+	email_info.Subject = feedInfo.Custom_msg_subject
 
 	return email_info, newsInfo
 }
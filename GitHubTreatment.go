@@ -0,0 +1,420 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package main
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/mmcdole/gofeed"
+
+	"Utils"
+)
+
+// Kinds of changes of a version relative to the previous one.
+const (
+	_GH_CHANGE_MAJOR string = "major"
+	_GH_CHANGE_MINOR string = "minor"
+	_GH_CHANGE_PATCH string = "patch"
+)
+
+// semVerRegex_GL matches a semantic version inside a tag name (like "v1.2.3", "release-1.2" or "1.2.3-rc.1").
+var semVerRegex_GL *regexp.Regexp = regexp.MustCompile(`(\d+)\.(\d+)(?:\.(\d+))?(?:-([0-9A-Za-z.-]+))?`)
+
+// preReleaseRegex_GL matches the words of pre-release tag names not following semver (like "2.0beta1").
+var preReleaseRegex_GL *regexp.Regexp = regexp.MustCompile(
+	`(?i)(alpha|beta|rc|pre|preview|dev|nightly|snapshot|canary)`)
+
+// _GitHubVersion is the version of a GitHub release or tag.
+type _GitHubVersion struct {
+	// tag is the name of the tag
+	tag string
+	// major, minor and patch are the numbers of the version (valid only if semver is true)
+	major int
+	minor int
+	patch int
+	// semver is true if the tag has a semantic version
+	semver bool
+	// prerelease is true if the version is a pre-release
+	prerelease bool
+	// change is the kind of change from the previous stable version (one of the _GH_CHANGE_ constants - empty if
+	// unknown)
+	change string
+}
+
+/*
+getGitHubFeedUrl gets the URL of the Atom feed of a GitHub feed.
+
+-----------------------------------------------------------
+
+– Params:
+  - feedInfo – the information of the feed, with the repository ("owner/repo" or its URL) on Feed_url
+  - feedType – the type of the feed
+
+– Returns:
+  - the URL or "" if the repository or the feed type are invalid
+*/
+func getGitHubFeedUrl(feedInfo _FeedInfo, feedType _FeedType) string {
+	var repo string = getGitHubRepo(feedInfo.Feed_url)
+	if 2 != len(strings.Split(repo, "/")) {
+		return ""
+	}
+
+	switch feedType.type_2 {
+		case "", _TYPE_2_GH_RELEASES: {
+			return "https://github.com/" + repo + "/releases.atom"
+		}
+		case _TYPE_2_GH_TAGS: {
+			return "https://github.com/" + repo + "/tags.atom"
+		}
+		case _TYPE_2_GH_COMMITS: {
+			if "" == feedInfo.Github_branch {
+				// The default branch
+				return "https://github.com/" + repo + "/commits.atom"
+			}
+
+			return "https://github.com/" + repo + "/commits/" + url.PathEscape(feedInfo.Github_branch) + ".atom"
+		}
+	}
+
+	return ""
+}
+
+/*
+getGitHubRepo gets the "owner/repo" of a GitHub repository.
+
+-----------------------------------------------------------
+
+– Params:
+  - repo – the repository, as "owner/repo" or its URL
+
+– Returns:
+  - the "owner/repo"
+*/
+func getGitHubRepo(repo string) string {
+	repo = strings.TrimSpace(repo)
+	repo = strings.TrimPrefix(repo, "https://")
+	repo = strings.TrimPrefix(repo, "http://")
+	repo = strings.TrimPrefix(repo, "www.")
+	repo = strings.TrimPrefix(repo, "github.com/")
+	repo = strings.TrimSuffix(strings.Trim(repo, "/"), ".git")
+
+	return repo
+}
+
+/*
+gitHubTreatment does the treatment of a GitHub feed item: the general treatment plus the version information and the
+subject of the repository's releases, tags and commits. Pre-releases (unless wanted) and versions with changes smaller
+than Github_min_change are not notified.
+
+-----------------------------------------------------------
+
+– Params:
+  - feedInfo – the information of the feed
+  - feedType – the type of the feed
+  - parsed_feed – the parsed feed
+  - item_num – the number of the item to get
+  - title_url_only – whether to only get the title and URL of the item through _NewsInfo (can be used for optimization)
+
+– Returns:
+  - the email info (without the Mail_to field) or all fields empty if title_url_only is true or if the item is not to
+    be notified
+  - the news info
+*/
+func gitHubTreatment(feedInfo _FeedInfo, feedType _FeedType, parsed_feed *gofeed.Feed, item_num int,
+			title_url_only bool) (Utils.EmailInfo, _NewsInfo) {
+	var feed_item *gofeed.Item = parsed_feed.Items[item_num]
+
+	// The commits have no release information.
+	var getDescriptionHtml func(description string) string = nil
+	if _TYPE_2_GH_COMMITS != feedType.type_2 {
+		getDescriptionHtml = func(description string) string {
+			return getGitHubHtml(parsed_feed, item_num) + description
+		}
+	}
+	email_info, newsInfo := generalTreatment(feedInfo, parsed_feed, item_num, title_url_only, getDescriptionHtml)
+	if title_url_only {
+		return email_info, newsInfo
+	}
+
+	var repo_name string = getGitHubRepo(feedInfo.Feed_url)
+	repo_name = repo_name[strings.LastIndex(repo_name, "/")+1:]
+
+	if _TYPE_2_GH_COMMITS == feedType.type_2 {
+		if "" == email_info.Subject {
+			var branch string = ""
+			if "" != feedInfo.Github_branch {
+				branch = " (" + feedInfo.Github_branch + ")"
+			}
+			email_info.Subject = repo_name + branch + ": " + feed_item.Title
+		}
+
+		return email_info, newsInfo
+	}
+
+	var gitHubVersion _GitHubVersion = getGitHubVersion(parsed_feed, item_num)
+	if gitHubVersion.prerelease && !feedInfo.Github_prereleases {
+		fmt.Println("GitHub pre-release ignored: " + gitHubVersion.tag)
+
+		return Utils.EmailInfo{}, newsInfo
+	}
+	if !isGitHubChangeWanted(gitHubVersion, feedInfo.Github_min_change) {
+		fmt.Println("GitHub " + gitHubVersion.change + " version ignored: " + gitHubVersion.tag)
+
+		return Utils.EmailInfo{}, newsInfo
+	}
+
+	if "" == email_info.Subject {
+		var version string = gitHubVersion.tag
+		if "" == version {
+			version = feed_item.Title
+		}
+		if _TYPE_2_GH_TAGS == feedType.type_2 {
+			email_info.Subject = repo_name + ": nova tag " + version
+		} else {
+			email_info.Subject = repo_name + " " + version + " lançada"
+		}
+		if gitHubVersion.prerelease {
+			email_info.Subject += " (pré-lançamento)"
+		} else if "" != gitHubVersion.change {
+			email_info.Subject += " (" + gitHubVersion.change + ")"
+		}
+	}
+
+	return email_info, newsInfo
+}
+
+/*
+getGitHubHtml gets the HTML with the version information of a GitHub release or tag to put on top of its description.
+
+-----------------------------------------------------------
+
+– Params:
+  - parsed_feed – the parsed feed
+  - item_num – the number of the item
+
+– Returns:
+  - the HTML or "" if there's no information
+*/
+func getGitHubHtml(parsed_feed *gofeed.Feed, item_num int) string {
+	var gitHubVersion _GitHubVersion = getGitHubVersion(parsed_feed, item_num)
+	if "" == gitHubVersion.tag {
+		return ""
+	}
+
+	var infos []string = []string{"Versão <b>" + html.EscapeString(gitHubVersion.tag) + "</b>"}
+	if gitHubVersion.prerelease {
+		infos = append(infos, "pré-lançamento")
+	}
+	if "" != gitHubVersion.change {
+		infos = append(infos, "atualização " + gitHubVersion.change)
+	}
+	if previous := getGitHubPreviousVersion(parsed_feed, item_num); "" != previous.tag {
+		infos = append(infos, "anterior: " + html.EscapeString(previous.tag))
+	}
+
+	return "<p>" + strings.Join(infos, " • ") + "</p>\n"
+}
+
+/*
+getGitHubVersion gets the version of a GitHub release or tag, with the kind of change from the previous stable version
+on the feed.
+
+-----------------------------------------------------------
+
+– Params:
+  - parsed_feed – the parsed feed
+  - item_num – the number of the item
+
+– Returns:
+  - the version (with an empty tag if the item is not a release nor a tag)
+*/
+func getGitHubVersion(parsed_feed *gofeed.Feed, item_num int) _GitHubVersion {
+	var gitHubVersion _GitHubVersion = parseGitHubTag(getGitHubItemTag(parsed_feed.Items[item_num]))
+	if !gitHubVersion.semver || gitHubVersion.prerelease {
+		return gitHubVersion
+	}
+
+	var previous _GitHubVersion = getGitHubPreviousVersion(parsed_feed, item_num)
+	if previous.semver {
+		if gitHubVersion.major != previous.major {
+			gitHubVersion.change = _GH_CHANGE_MAJOR
+		} else if gitHubVersion.minor != previous.minor {
+			gitHubVersion.change = _GH_CHANGE_MINOR
+		} else {
+			gitHubVersion.change = _GH_CHANGE_PATCH
+		}
+	}
+
+	return gitHubVersion
+}
+
+/*
+getGitHubPreviousVersion gets the previous stable version of a GitHub release or tag: the highest older one on the feed
+with a semantic version lower than the item's.
+
+-----------------------------------------------------------
+
+– Params:
+  - parsed_feed – the parsed feed
+  - item_num – the number of the item
+
+– Returns:
+  - the previous version (with an empty tag if there's none on the feed)
+*/
+func getGitHubPreviousVersion(parsed_feed *gofeed.Feed, item_num int) _GitHubVersion {
+	var gitHubVersion _GitHubVersion = parseGitHubTag(getGitHubItemTag(parsed_feed.Items[item_num]))
+	if !gitHubVersion.semver {
+		return _GitHubVersion{}
+	}
+
+	// The feed is from the newest to the oldest, but the versions of different branches may be mixed (like a 1.x fix
+	// released after a 2.0), so the previous version is the highest older one lower than the item's - not just the
+	// first lower one, which may be from an older branch.
+	var previous _GitHubVersion = _GitHubVersion{}
+	for _, feed_item := range parsed_feed.Items[item_num+1:] {
+		var older _GitHubVersion = parseGitHubTag(getGitHubItemTag(feed_item))
+		if older.semver && !older.prerelease && compareGitHubVersions(older, gitHubVersion) < 0 &&
+					(!previous.semver || compareGitHubVersions(older, previous) > 0) {
+			previous = older
+		}
+	}
+
+	return previous
+}
+
+/*
+getGitHubItemTag gets the tag name of a GitHub release or tag item, from its link (".../releases/tag/<tag>").
+
+-----------------------------------------------------------
+
+– Params:
+  - feed_item – the item
+
+– Returns:
+  - the tag name or "" if the item is not a release nor a tag
+*/
+func getGitHubItemTag(feed_item *gofeed.Item) string {
+	var idx int = strings.Index(feed_item.Link, "/releases/tag/")
+	if idx < 0 {
+		return ""
+	}
+
+	tag, err := url.PathUnescape(feed_item.Link[idx+len("/releases/tag/"):])
+	if nil != err {
+		return ""
+	}
+
+	return tag
+}
+
+/*
+parseGitHubTag parses the version of a tag name.
+
+-----------------------------------------------------------
+
+– Params:
+  - tag – the tag name
+
+– Returns:
+  - the version, without the change
+*/
+func parseGitHubTag(tag string) _GitHubVersion {
+	var gitHubVersion _GitHubVersion = _GitHubVersion{
+		tag: tag,
+	}
+
+	var matches []string = semVerRegex_GL.FindStringSubmatch(tag)
+	if nil == matches {
+		gitHubVersion.prerelease = preReleaseRegex_GL.MatchString(tag)
+
+		return gitHubVersion
+	}
+	gitHubVersion.semver = true
+	gitHubVersion.major, _ = strconv.Atoi(matches[1])
+	gitHubVersion.minor, _ = strconv.Atoi(matches[2])
+	gitHubVersion.patch, _ = strconv.Atoi(matches[3]) // 0 if there's no patch number
+	// Only what comes after the version is checked for pre-release words, as the names before may have them too
+	// (like "prettier@3.0.0").
+	var after_version string = tag[strings.Index(tag, matches[0])+len(matches[0]):]
+	gitHubVersion.prerelease = "" != matches[4] || preReleaseRegex_GL.MatchString(after_version)
+
+	return gitHubVersion
+}
+
+/*
+compareGitHubVersions compares two semantic versions (without the pre-release part).
+
+-----------------------------------------------------------
+
+– Params:
+  - version1 – the first version
+  - version2 – the second version
+
+– Returns:
+  - a negative number if version1 is lower, 0 if they're equal, a positive number if version1 is higher
+*/
+func compareGitHubVersions(version1 _GitHubVersion, version2 _GitHubVersion) int {
+	if version1.major != version2.major {
+		return version1.major - version2.major
+	}
+	if version1.minor != version2.minor {
+		return version1.minor - version2.minor
+	}
+
+	return version1.patch - version2.patch
+}
+
+/*
+isGitHubChangeWanted checks if the change of a version is at least the minimum wanted.
+
+-----------------------------------------------------------
+
+– Params:
+  - gitHubVersion – the version
+  - min_change – the minimum change (one of the _GH_CHANGE_ constants - empty for all)
+
+– Returns:
+  - true if the change is wanted or unknown, false otherwise
+*/
+func isGitHubChangeWanted(gitHubVersion _GitHubVersion, min_change string) bool {
+	var changes_order []string = []string{_GH_CHANGE_PATCH, _GH_CHANGE_MINOR, _GH_CHANGE_MAJOR}
+	var getOrder func(change string) int = func(change string) int {
+		for i, change_order := range changes_order {
+			if strings.EqualFold(change_order, change) {
+				return i
+			}
+		}
+
+		return 0
+	}
+
+	if "" == gitHubVersion.change || "" == min_change {
+		return true
+	}
+
+	return getOrder(gitHubVersion.change) >= getOrder(min_change)
+}
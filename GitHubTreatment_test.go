@@ -0,0 +1,121 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package main
+
+import (
+	"testing"
+
+	"github.com/mmcdole/gofeed"
+)
+
+func TestParseGitHubTag(t *testing.T) {
+	var tests = []struct {
+		tag      string
+		expected _GitHubVersion
+	}{
+		{"v1.2.3", _GitHubVersion{major: 1, minor: 2, patch: 3, semver: true}},
+		{"release-1.2", _GitHubVersion{major: 1, minor: 2, semver: true}},
+		{"1.2.3-rc.1", _GitHubVersion{major: 1, minor: 2, patch: 3, semver: true, prerelease: true}},
+		{"v2.0beta1", _GitHubVersion{major: 2, semver: true, prerelease: true}},
+		{"prettier@3.0.0", _GitHubVersion{major: 3, semver: true}},
+		{"preview-1.0.0", _GitHubVersion{major: 1, semver: true}},
+		{"nightly", _GitHubVersion{prerelease: true}},
+		{"latest", _GitHubVersion{}},
+	}
+	for _, test := range tests {
+		test.expected.tag = test.tag
+		if gitHubVersion := parseGitHubTag(test.tag); gitHubVersion != test.expected {
+			t.Errorf("parseGitHubTag(%q) = %+v, expected %+v", test.tag, gitHubVersion, test.expected)
+		}
+	}
+}
+
+func TestGetGitHubVersion(t *testing.T) {
+	var tags []string = []string{"v2.1.0", "v1.4.2", "v2.0.1", "v2.0.0", "v2.0.0-rc.1", "v1.4.1", "v1.4.0"}
+	var parsed_feed *gofeed.Feed = &gofeed.Feed{}
+	for _, tag := range tags {
+		parsed_feed.Items = append(parsed_feed.Items, &gofeed.Item{
+			Link: "https://github.com/owner/repo/releases/tag/" + tag,
+		})
+	}
+
+	var tests = []struct {
+		item_num int
+		previous string
+		change   string
+	}{
+		{0, "v2.0.1", _GH_CHANGE_MINOR},
+		// A fix of an older branch released after a newer version.
+		{1, "v1.4.1", _GH_CHANGE_PATCH},
+		{2, "v2.0.0", _GH_CHANGE_PATCH},
+		// The pre-release is skipped as the previous version.
+		{3, "v1.4.1", _GH_CHANGE_MAJOR},
+		// Pre-releases have no change.
+		{4, "v1.4.1", ""},
+		{6, "", ""},
+	}
+	for _, test := range tests {
+		if previous := getGitHubPreviousVersion(parsed_feed, test.item_num); previous.tag != test.previous {
+			t.Errorf("getGitHubPreviousVersion(%s) = %q, expected %q", tags[test.item_num], previous.tag, test.previous)
+		}
+		if gitHubVersion := getGitHubVersion(parsed_feed, test.item_num); gitHubVersion.change != test.change {
+			t.Errorf("getGitHubVersion(%s).change = %q, expected %q", tags[test.item_num], gitHubVersion.change,
+				test.change)
+		}
+	}
+}
+
+func TestIsGitHubChangeWanted(t *testing.T) {
+	var tests = []struct {
+		change     string
+		min_change string
+		expected   bool
+	}{
+		{_GH_CHANGE_PATCH, "", true},
+		{"", _GH_CHANGE_MAJOR, true},
+		{_GH_CHANGE_PATCH, _GH_CHANGE_MINOR, false},
+		{_GH_CHANGE_MINOR, _GH_CHANGE_MINOR, true},
+		{_GH_CHANGE_MAJOR, "Minor", true},
+		{_GH_CHANGE_MINOR, _GH_CHANGE_MAJOR, false},
+	}
+	for _, test := range tests {
+		if isGitHubChangeWanted(_GitHubVersion{change: test.change}, test.min_change) != test.expected {
+			t.Errorf("isGitHubChangeWanted(%q, %q) != %v", test.change, test.min_change, test.expected)
+		}
+	}
+}
+
+func TestGetGitHubItemTag(t *testing.T) {
+	var tests = []struct {
+		link     string
+		expected string
+	}{
+		{"https://github.com/owner/repo/releases/tag/v1.0.0", "v1.0.0"},
+		{"https://github.com/owner/repo/releases/tag/prettier%403.0.0", "prettier@3.0.0"},
+		{"https://github.com/owner/repo/commit/abc", ""},
+	}
+	for _, test := range tests {
+		if tag := getGitHubItemTag(&gofeed.Item{Link: test.link}); tag != test.expected {
+			t.Errorf("getGitHubItemTag(%q) = %q, expected %q", test.link, tag, test.expected)
+		}
+	}
+}
@@ -0,0 +1,402 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package main
+
+import (
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	nethtml "golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+
+	"Utils"
+)
+
+// sanitizerAllowedElems_GL has the elements allowed in the sanitized HTML, mapped to their allowed attributes. The
+// elements not here are removed, but their contents are kept (except for the ones in sanitizerDroppedElems_GL).
+var sanitizerAllowedElems_GL map[string][]string = map[string][]string{
+	"a": {"href", "title"}, "img": {"src", "alt", "title", "width", "height"},
+	"p": nil, "br": nil, "hr": nil, "div": nil, "span": nil, "section": nil, "article": nil,
+	"h1": nil, "h2": nil, "h3": nil, "h4": nil, "h5": nil, "h6": nil,
+	"b": nil, "strong": nil, "i": nil, "em": nil, "u": nil, "s": nil, "strike": nil, "del": nil, "ins": nil,
+	"sub": nil, "sup": nil, "small": nil, "mark": nil, "abbr": {"title"}, "cite": nil, "q": nil,
+	"code": nil, "pre": nil, "kbd": nil, "samp": nil, "blockquote": nil,
+	"ul": nil, "ol": {"start"}, "li": nil, "dl": nil, "dt": nil, "dd": nil,
+	"table": nil, "thead": nil, "tbody": nil, "tfoot": nil, "tr": nil, "caption": nil,
+	"th": {"colspan", "rowspan"}, "td": {"colspan", "rowspan"},
+	"figure": nil, "figcaption": nil,
+}
+
+// sanitizerDroppedElems_GL has the elements removed together with their contents.
+var sanitizerDroppedElems_GL map[string]bool = map[string]bool{
+	"script": true, "style": true, "noscript": true, "iframe": true, "frame": true, "frameset": true,
+	"object": true, "embed": true, "applet": true, "link": true, "meta": true, "base": true, "template": true,
+	"form": true, "input": true, "button": true, "select": true, "textarea": true,
+	"svg": true, "math": true, "canvas": true, "audio": true, "video": true, "head": true, "title": true,
+}
+
+// trackingPixelRegex_GL matches the URLs of known tracking pixels (the 1x1 images are removed by their size too).
+var trackingPixelRegex_GL *regexp.Regexp = regexp.MustCompile(`(?i)feeds\.feedburner\.com/~r/|` +
+	`feedburner\.com/~ff/|stats\.wordpress\.com|pixel\.wp\.com|google-analytics\.com|doubleclick\.net|` +
+	`/pixel(\.gif|\.png)?([?/]|$)|/tracking/|/track/|/beacon|mailchimp\.com/track|list-manage\.com/track|` +
+	`facebook\.com/tr[?/]|quantserve\.com|scorecardresearch\.com`)
+
+/*
+sanitizeHtml sanitizes HTML from a feed to be put in an email: only allow-listed elements and attributes are kept, the
+tracking pixels are removed (as are the styles, so no remote fonts either), the relative URLs are made absolute and
+only web and email links are kept. It can also truncate the text, without ever cutting inside a tag.
+
+-----------------------------------------------------------
+
+– Params:
+  - html_str – the HTML
+  - base_url – the URL to make the relative URLs absolute against (the item's link)
+  - max_chars – the maximum number of text characters (0 for no maximum)
+
+– Returns:
+  - the sanitized HTML
+  - true if the text was truncated, false otherwise
+*/
+func sanitizeHtml(html_str string, base_url string, max_chars int) (string, bool) {
+	var context *nethtml.Node = &nethtml.Node{
+		Type:     nethtml.ElementNode,
+		Data:     "div",
+		DataAtom: atom.Div,
+	}
+	nodes, err := nethtml.ParseFragment(strings.NewReader(html_str), context)
+	if nil != err {
+		return "", false
+	}
+
+	var sanitizer _Sanitizer = _Sanitizer{
+		max_chars: max_chars,
+	}
+	sanitizer.base_url, _ = url.Parse(base_url)
+
+	var html_builder strings.Builder
+	for _, node := range nodes {
+		for _, node_sanitized := range sanitizer.sanitizeNode(node) {
+			if err = nethtml.Render(&html_builder, node_sanitized); nil != err {
+				return "", false
+			}
+		}
+		if sanitizer.truncated {
+			break
+		}
+	}
+
+	return html_builder.String(), sanitizer.truncated
+}
+
+/*
+truncateHtml truncates the text of HTML already sanitized by sanitizeHtml, without sanitizing it again and without ever
+cutting inside a tag.
+
+-----------------------------------------------------------
+
+– Params:
+  - html_str – the sanitized HTML
+  - max_chars – the maximum number of text characters (0 for no maximum)
+
+– Returns:
+  - the truncated HTML (the same if it's short enough)
+  - true if the text was truncated, false otherwise
+*/
+func truncateHtml(html_str string, max_chars int) (string, bool) {
+	if max_chars <= 0 || utf8.RuneCountInString(html_str) <= max_chars {
+		// The text can't be longer than the whole HTML.
+		return html_str, false
+	}
+
+	var context *nethtml.Node = &nethtml.Node{
+		Type:     nethtml.ElementNode,
+		Data:     "div",
+		DataAtom: atom.Div,
+	}
+	nodes, err := nethtml.ParseFragment(strings.NewReader(html_str), context)
+	if nil != err {
+		return html_str, false
+	}
+
+	var sanitizer _Sanitizer = _Sanitizer{
+		max_chars: max_chars,
+	}
+
+	var html_builder strings.Builder
+	for _, node := range nodes {
+		sanitizer.truncateNode(node)
+		if err = nethtml.Render(&html_builder, node); nil != err {
+			return html_str, false
+		}
+		if sanitizer.truncated {
+			break
+		}
+	}
+
+	return html_builder.String(), sanitizer.truncated
+}
+
+// _Sanitizer is the state of a sanitization.
+type _Sanitizer struct {
+	// base_url is the URL to make the relative URLs absolute against
+	base_url *url.URL
+	// max_chars is the maximum number of text characters (0 for no maximum)
+	max_chars int
+	// text_len is the number of text characters so far
+	text_len int
+	// truncated is true if the text was truncated
+	truncated bool
+}
+
+/*
+sanitizeNode sanitizes a node and its children.
+
+-----------------------------------------------------------
+
+– Params:
+  - node – the node
+
+– Returns:
+  - the sanitized nodes to put in place of the node (none if it's removed, its children if only the element is removed)
+*/
+func (sanitizer *_Sanitizer) sanitizeNode(node *nethtml.Node) []*nethtml.Node {
+	if sanitizer.truncated {
+		return nil
+	}
+
+	switch node.Type {
+		case nethtml.TextNode: {
+			return []*nethtml.Node{{
+				Type: nethtml.TextNode,
+				Data: sanitizer.truncateText(node.Data),
+			}}
+		}
+		case nethtml.ElementNode: {
+			// Handled below
+		}
+		default: {
+			// Comments, doctypes...
+			return nil
+		}
+	}
+
+	if sanitizerDroppedElems_GL[node.Data] {
+		return nil
+	}
+
+	var children []*nethtml.Node = nil
+	for child := node.FirstChild; nil != child; child = child.NextSibling {
+		children = append(children, sanitizer.sanitizeNode(child)...)
+		if sanitizer.truncated {
+			break
+		}
+	}
+
+	allowed_attrs, ok := sanitizerAllowedElems_GL[node.Data]
+	if !ok {
+		// Only the element is removed.
+		return children
+	}
+
+	var node_sanitized *nethtml.Node = &nethtml.Node{
+		Type:     nethtml.ElementNode,
+		Data:     node.Data,
+		DataAtom: node.DataAtom,
+	}
+	for _, attr := range node.Attr {
+		if "" != attr.Namespace || !Utils.ContainsSLICES(allowed_attrs, attr.Key) {
+			continue
+		}
+		if "href" == attr.Key || "src" == attr.Key {
+			var attr_url string = sanitizer.sanitizeUrl(attr.Val, "href" == attr.Key)
+			if "" == attr_url {
+				continue
+			}
+			attr.Val = attr_url
+		}
+		node_sanitized.Attr = append(node_sanitized.Attr, attr)
+	}
+
+	switch node.Data {
+		case "img": {
+			if isTrackingPixel(node_sanitized) {
+				return nil
+			}
+			node_sanitized.Attr = append(node_sanitized.Attr, nethtml.Attribute{
+				Key: "style",
+				Val: "max-width: 100%; height: auto;",
+			})
+		}
+		case "a": {
+			node_sanitized.Attr = append(node_sanitized.Attr, nethtml.Attribute{
+				Key: "rel",
+				Val: "noopener noreferrer nofollow",
+			})
+		}
+	}
+
+	for _, child := range children {
+		node_sanitized.AppendChild(child)
+	}
+
+	return []*nethtml.Node{node_sanitized}
+}
+
+/*
+truncateNode truncates the texts of a node and its children, removing the children after the truncation point.
+
+-----------------------------------------------------------
+
+– Params:
+  - node – the node
+*/
+func (sanitizer *_Sanitizer) truncateNode(node *nethtml.Node) {
+	if nethtml.TextNode == node.Type {
+		node.Data = sanitizer.truncateText(node.Data)
+
+		return
+	}
+
+	for child := node.FirstChild; nil != child; child = child.NextSibling {
+		sanitizer.truncateNode(child)
+		if sanitizer.truncated {
+			for nil != child.NextSibling {
+				node.RemoveChild(child.NextSibling)
+			}
+
+			break
+		}
+	}
+}
+
+/*
+sanitizeUrl sanitizes a URL of an attribute: makes it absolute and checks its scheme.
+
+-----------------------------------------------------------
+
+– Params:
+  - attr_url – the URL
+  - link – true if the URL is of a link (email links allowed), false if it's of an image
+
+– Returns:
+  - the sanitized URL or "" if it's not allowed
+*/
+func (sanitizer *_Sanitizer) sanitizeUrl(attr_url string, link bool) string {
+	parsed_url, err := url.Parse(strings.TrimSpace(attr_url))
+	if nil != err {
+		return ""
+	}
+	if nil != sanitizer.base_url {
+		parsed_url = sanitizer.base_url.ResolveReference(parsed_url)
+	}
+
+	switch strings.ToLower(parsed_url.Scheme) {
+		case "http", "https": {
+			return parsed_url.String()
+		}
+		case "mailto": {
+			if link {
+				return parsed_url.String()
+			}
+		}
+		case "": {
+			// Still relative (no base URL) - only in-page links make sense.
+			if link && strings.HasPrefix(attr_url, "#") {
+				return attr_url
+			}
+		}
+	}
+
+	return ""
+}
+
+/*
+truncateText counts the characters of a text and truncates it if the maximum is reached (at a word boundary, if
+possible).
+
+-----------------------------------------------------------
+
+– Params:
+  - text – the text
+
+– Returns:
+  - the text, truncated or not
+*/
+func (sanitizer *_Sanitizer) truncateText(text string) string {
+	if sanitizer.max_chars <= 0 {
+		return text
+	}
+
+	var text_len int = utf8.RuneCountInString(text)
+	if sanitizer.text_len+text_len <= sanitizer.max_chars {
+		sanitizer.text_len += text_len
+
+		return text
+	}
+
+	sanitizer.truncated = true
+	var runes []rune = []rune(text)[:sanitizer.max_chars-sanitizer.text_len]
+	sanitizer.text_len = sanitizer.max_chars
+
+	var idx_space int = strings.LastIndexFunc(string(runes), unicode.IsSpace)
+	if idx_space > 0 {
+		return strings.TrimRightFunc(string(runes)[:idx_space], unicode.IsSpace) + "…"
+	}
+
+	return string(runes) + "…"
+}
+
+/*
+isTrackingPixel checks if an image is a tracking pixel: if it has no source, is 1 pixel wide or high (or less) or comes
+from a known tracker.
+
+-----------------------------------------------------------
+
+– Params:
+  - img – the sanitized image element
+
+– Returns:
+  - true if the image is a tracking pixel, false otherwise
+*/
+func isTrackingPixel(img *nethtml.Node) bool {
+	var src string = ""
+	var small_dims int = 0
+	for _, attr := range img.Attr {
+		switch attr.Key {
+			case "src": {
+				src = attr.Val
+			}
+			case "width", "height": {
+				dim, err := strconv.Atoi(strings.TrimSuffix(strings.TrimSpace(attr.Val), "px"))
+				if nil == err && dim <= 1 {
+					small_dims++
+				}
+			}
+		}
+	}
+
+	return "" == src || small_dims > 0 || trackingPixelRegex_GL.MatchString(src)
+}
@@ -0,0 +1,91 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package main
+
+import (
+	"testing"
+)
+
+func TestSanitizeHtml(t *testing.T) {
+	var tests = []struct {
+		name      string
+		html      string
+		max_chars int
+		expected  string
+		truncated bool
+	}{
+		{"allowed elements", `<p>Hello <b>world</b><br/></p>`, 0, `<p>Hello <b>world</b><br/></p>`, false},
+		{"unknown element", `<p><font color="red">text</font></p>`, 0, `<p>text</p>`, false},
+		{"dropped elements", `<p>a</p><script>alert(1)</script><style>p {}</style><iframe src="x"></iframe>`, 0,
+			`<p>a</p>`, false},
+		{"attributes", `<p style="color: red" onclick="x()" class="c">a</p>`, 0, `<p>a</p>`, false},
+		{"relative link", `<a href="/page?a=1" onmouseover="x()">link</a>`, 0,
+			`<a href="https://example.com/page?a=1" rel="noopener noreferrer nofollow">link</a>`, false},
+		{"javascript link", `<a href="javascript:alert(1)">link</a>`, 0,
+			`<a rel="noopener noreferrer nofollow">link</a>`, false},
+		{"mailto link", `<a href="mailto:a@b.com">mail</a>`, 0,
+			`<a href="mailto:a@b.com" rel="noopener noreferrer nofollow">mail</a>`, false},
+		{"image", `<img src="img.png" alt="x">`, 0,
+			`<img src="https://example.com/posts/img.png" alt="x" style="max-width: 100%; height: auto;"/>`, false},
+		{"data image", `<img src="data:image/png;base64,AAAA">`, 0, ``, false},
+		{"tracking pixel by size", `<img src="https://example.com/a.gif" width="1" height="1">`, 0, ``, false},
+		{"tracking pixel by URL", `<img src="https://feeds.feedburner.com/~r/feed/~4/abc">`, 0, ``, false},
+		{"comment", `<p>a<!-- comment --></p>`, 0, `<p>a</p>`, false},
+		{"escaping", `<p>a &lt;b&gt; &amp; c</p>`, 0, `<p>a &lt;b&gt; &amp; c</p>`, false},
+		{"truncated at a word", `<p>one two three</p><p>four</p>`, 9, `<p>one two…</p>`, true},
+		{"truncated inside an element", `<p>one <b>two three</b> four</p>`, 10, `<p>one <b>two…</b></p>`, true},
+		{"not truncated", `<p>one two</p>`, 7, `<p>one two</p>`, false},
+	}
+	for _, test := range tests {
+		sanitized, truncated := sanitizeHtml(test.html, "https://example.com/posts/1", test.max_chars)
+		if test.expected != sanitized || test.truncated != truncated {
+			t.Errorf("%s: got %q (truncated: %t), expected %q (truncated: %t)", test.name, sanitized, truncated,
+				test.expected, test.truncated)
+		}
+	}
+}
+
+func TestTruncateHtml(t *testing.T) {
+	var tests = []struct {
+		name      string
+		html      string
+		max_chars int
+		expected  string
+		truncated bool
+	}{
+		{"no maximum", `<p>one two three</p>`, 0, `<p>one two three</p>`, false},
+		{"short HTML", `<p>one two</p>`, 20, `<p>one two</p>`, false},
+		{"not truncated", `<p>one two</p>`, 7, `<p>one two</p>`, false},
+		{"truncated at a word", `<p>one two three</p><p>four</p>`, 9, `<p>one two…</p>`, true},
+		{"truncated inside an element", `<p>one <b>two three</b> four</p>`, 10, `<p>one <b>two…</b></p>`, true},
+		{"attributes kept",
+			`<p><a href="https://example.com/" rel="noopener noreferrer nofollow">one two</a> three</p>`, 5,
+			`<p><a href="https://example.com/" rel="noopener noreferrer nofollow">one…</a></p>`, true},
+	}
+	for _, test := range tests {
+		truncated_html, truncated := truncateHtml(test.html, test.max_chars)
+		if test.expected != truncated_html || test.truncated != truncated {
+			t.Errorf("%s: got %q (truncated: %t), expected %q (truncated: %t)", test.name, truncated_html, truncated,
+				test.expected, test.truncated)
+		}
+	}
+}
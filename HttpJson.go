@@ -0,0 +1,88 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// _USER_AGENT is the User-Agent sent to the APIs of the sites.
+const _USER_AGENT string = "RssFeedNotifier/1.0 (V.I.S.O.R. module)"
+
+// _HttpStatusError is the error of a response that is not OK, with what's needed to handle it (like the Retry-After
+// header of rate limits).
+type _HttpStatusError struct {
+	// status_code is the status code of the response
+	status_code int
+	// header is the header of the response
+	header http.Header
+	// url is the requested URL
+	url string
+}
+
+func (err _HttpStatusError) Error() string {
+	return "HTTP error " + strconv.Itoa(err.status_code) + " on " + err.url
+}
+
+/*
+getJson gets JSON from a URL.
+
+-----------------------------------------------------------
+
+– Params:
+  - json_url – the URL
+  - user_agent – the User-Agent to send (usually _USER_AGENT)
+  - p_response – pointer to where to decode the JSON to
+
+– Returns:
+  - an error if the request failed or if the response is not OK (a _HttpStatusError), nil otherwise
+*/
+func getJson(json_url string, user_agent string, p_response any) error {
+	request, err := http.NewRequest(http.MethodGet, json_url, nil)
+	if nil != err {
+		return err
+	}
+	request.Header.Set("User-Agent", user_agent)
+	request.Header.Set("Accept", "application/json")
+
+	var client http.Client = http.Client{
+		Timeout: 60 * time.Second,
+	}
+	response, err := client.Do(request)
+	if nil != err {
+		return err
+	}
+	defer response.Body.Close()
+
+	if http.StatusOK != response.StatusCode {
+		return _HttpStatusError{
+			status_code: response.StatusCode,
+			header:      response.Header,
+			url:         json_url,
+		}
+	}
+
+	return json.NewDecoder(response.Body).Decode(p_response)
+}
@@ -0,0 +1,127 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+)
+
+// Policies for the first run of a feed (_FeedInfo.Initial_sync).
+const (
+	_INITIAL_SYNC_MARK_ALL_SEEN string = "mark-all-seen"     // Notify nothing (default)
+	_INITIAL_SYNC_LATEST_N      string = "notify-latest-N"   // Notify the latest Initial_sync_n items
+	_INITIAL_SYNC_SINCE_DATE    string = "notify-since-date" // Notify the items published since Initial_sync_since
+	_INITIAL_SYNC_ALL           string = "notify-all"        // Notify all the items
+)
+
+// _INITIAL_SYNC_DATE_FORMAT is the format of _FeedInfo.Initial_sync_since.
+const _INITIAL_SYNC_DATE_FORMAT string = "2006-01-02"
+
+/*
+getInitialSyncItems gets the items to notify on the first run of a feed, according to its Initial_sync policy.
+
+-----------------------------------------------------------
+
+– Params:
+  - feedInfo – the information of the feed
+  - feedType – the type of the feed
+  - parsed_feed – the parsed feed
+
+– Returns:
+  - the numbers of the items to notify (the others are only marked as seen)
+*/
+func getInitialSyncItems(feedInfo _FeedInfo, feedType _FeedType, parsed_feed *gofeed.Feed) map[int]bool {
+	var items_notify map[int]bool = make(map[int]bool)
+	var num_items int = len(parsed_feed.Items)
+
+	switch feedInfo.Initial_sync {
+		case "", _INITIAL_SYNC_MARK_ALL_SEEN: {
+			// Nothing to notify.
+		}
+		case _INITIAL_SYNC_ALL: {
+			for item_num := 0; item_num < num_items; item_num++ {
+				items_notify[item_num] = true
+			}
+		}
+		case _INITIAL_SYNC_LATEST_N: {
+			var items_nums []int = make([]int, num_items)
+			for item_num := range items_nums {
+				items_nums[item_num] = item_num
+			}
+			if isYTPlaylistFeed(feedType) && scrapingNeeded(parsed_feed) {
+				// The items are got backwards from the playlist (see ytPlaylistScraping()), so the last is the latest.
+				sort.Sort(sort.Reverse(sort.IntSlice(items_nums)))
+			} else {
+				// Newest first. Items without date go after all the dated ones, in the feed order (the stable sort
+				// keeps it).
+				sort.SliceStable(items_nums, func(i, j int) bool {
+					var date_i *time.Time = parsed_feed.Items[items_nums[i]].PublishedParsed
+					var date_j *time.Time = parsed_feed.Items[items_nums[j]].PublishedParsed
+					if nil == date_i || nil == date_j {
+						return nil != date_i && nil == date_j
+					}
+
+					return date_i.After(*date_j)
+				})
+			}
+			for i := 0; i < feedInfo.Initial_sync_n && i < num_items; i++ {
+				items_notify[items_nums[i]] = true
+			}
+		}
+		case _INITIAL_SYNC_SINCE_DATE: {
+			since, err := time.ParseInLocation(_INITIAL_SYNC_DATE_FORMAT, feedInfo.Initial_sync_since, time.Local)
+			if nil != err {
+				fmt.Println("Invalid Initial_sync_since: " + feedInfo.Initial_sync_since)
+
+				break
+			}
+			for item_num, item := range parsed_feed.Items {
+				if nil != item.PublishedParsed && !item.PublishedParsed.Before(since) {
+					items_notify[item_num] = true
+				}
+			}
+		}
+		default: {
+			fmt.Println("Unknown Initial_sync policy: " + feedInfo.Initial_sync)
+		}
+	}
+
+	return items_notify
+}
+
+/*
+resetFeed forgets everything about a feed, so that on its next check it's treated as a new feed (and its Initial_sync
+policy is applied again).
+
+-----------------------------------------------------------
+
+– Params:
+  - feed_num – the number of the feed
+*/
+func resetFeed(feed_num int) {
+	getNotifiedNewsPath(feed_num).WriteTextFile("")
+	setFeedState(feed_num, _FeedState{})
+}
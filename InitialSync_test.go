@@ -0,0 +1,98 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+)
+
+// getTestFeed gets a feed with items published on the given days of November 2023 (0 for no date), in that order.
+func getTestFeed(days []int) *gofeed.Feed {
+	var parsed_feed *gofeed.Feed = &gofeed.Feed{}
+	for _, day := range days {
+		var item *gofeed.Item = &gofeed.Item{}
+		if 0 != day {
+			var published time.Time = time.Date(2023, 11, day, 12, 0, 0, 0, time.Local)
+			item.PublishedParsed = &published
+			item.Published = published.Format(_YT_TIME_DATE_FORMAT)
+		}
+		parsed_feed.Items = append(parsed_feed.Items, item)
+	}
+
+	return parsed_feed
+}
+
+func TestGetInitialSyncItems(t *testing.T) {
+	var general _FeedType = _FeedType{type_1: _TYPE_1_GENERAL}
+	var tests = []struct {
+		feedInfo _FeedInfo
+		days     []int
+		expected []int
+	}{
+		{_FeedInfo{}, []int{3, 2, 1}, nil},
+		{_FeedInfo{Initial_sync: _INITIAL_SYNC_MARK_ALL_SEEN}, []int{3, 2, 1}, nil},
+		{_FeedInfo{Initial_sync: _INITIAL_SYNC_ALL}, []int{3, 2, 1}, []int{0, 1, 2}},
+		// The latest by date, whatever the feed order.
+		{_FeedInfo{Initial_sync: _INITIAL_SYNC_LATEST_N, Initial_sync_n: 2}, []int{1, 5, 3, 4}, []int{1, 3}},
+		{_FeedInfo{Initial_sync: _INITIAL_SYNC_LATEST_N, Initial_sync_n: 10}, []int{1, 2}, []int{0, 1}},
+		{_FeedInfo{Initial_sync: _INITIAL_SYNC_LATEST_N, Initial_sync_n: 1}, []int{0, 0}, []int{0}},
+		// Mixed dates - the undated ones only after all the dated ones, in the feed order.
+		{_FeedInfo{Initial_sync: _INITIAL_SYNC_LATEST_N, Initial_sync_n: 3}, []int{2, 0, 5, 0, 1, 4}, []int{0, 2, 5}},
+		{_FeedInfo{Initial_sync: _INITIAL_SYNC_LATEST_N, Initial_sync_n: 4}, []int{0, 2, 0, 3, 1}, []int{0, 1, 3, 4}},
+		{_FeedInfo{Initial_sync: _INITIAL_SYNC_SINCE_DATE, Initial_sync_since: "2023-11-03"}, []int{4, 3, 2, 0},
+			[]int{0, 1}},
+		{_FeedInfo{Initial_sync: _INITIAL_SYNC_SINCE_DATE, Initial_sync_since: "03/11/2023"}, []int{4, 3}, nil},
+		{_FeedInfo{Initial_sync: "unknown"}, []int{1}, nil},
+	}
+	for i, test := range tests {
+		var items_notify map[int]bool = getInitialSyncItems(test.feedInfo, general, getTestFeed(test.days))
+		var items_nums []int = nil
+		for item_num := range items_notify {
+			items_nums = append(items_nums, item_num)
+		}
+		sort.Ints(items_nums)
+		if fmt.Sprint(test.expected) != fmt.Sprint(items_nums) {
+			t.Errorf("test %d (%s): got %v, expected %v", i, test.feedInfo.Initial_sync, items_nums, test.expected)
+		}
+	}
+}
+
+func TestGetInitialSyncItemsAscendingPlaylist(t *testing.T) {
+	// A full YouTube playlist feed in ascending order - its items are got backwards (see ytPlaylistScraping()), so the
+	// latest are the last ones, whatever their dates.
+	var days []int = nil
+	for day := 1; day <= 15; day++ {
+		days = append(days, day)
+	}
+	var feedInfo _FeedInfo = _FeedInfo{Initial_sync: _INITIAL_SYNC_LATEST_N, Initial_sync_n: 2}
+	var feedType _FeedType = _FeedType{type_1: _TYPE_1_YOUTUBE, type_2: _TYPE_2_YT_PLAYLIST}
+
+	var items_notify map[int]bool = getInitialSyncItems(feedInfo, feedType, getTestFeed(days))
+	if 2 != len(items_notify) || !items_notify[14] || !items_notify[13] {
+		t.Errorf("got %v, expected the items 13 and 14", items_notify)
+	}
+}
@@ -0,0 +1,489 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+	ext "github.com/mmcdole/gofeed/extensions"
+
+	"Utils"
+)
+
+// _MASTODON_STATUSES_LIMIT is the number of statuses got on each check (the maximum the API allows).
+const _MASTODON_STATUSES_LIMIT int = 40
+
+// _MASTODON_TITLE_MAX_CHARS is the maximum number of characters of the statuses' text put on the items' titles.
+const _MASTODON_TITLE_MAX_CHARS int = 100
+
+// Kinds of Mastodon statuses.
+const (
+	_MASTODON_KIND_POST  string = "post"
+	_MASTODON_KIND_REPLY string = "reply"
+	_MASTODON_KIND_BOOST string = "boost"
+)
+
+// mastodonScheme_GL is the scheme of the URLs of the Mastodon servers (a variable only to be able to point them to
+// local stand-in servers).
+var mastodonScheme_GL string = "https://"
+
+// _MastodonAccount is a Mastodon account resolved through WebFinger. It's cached as JSON, so the fields are exported.
+type _MastodonAccount struct {
+	// Api_host is the host of the account's server (where its API is - may be different from the account's domain)
+	Api_host string
+	// Id is the ID of the account on its server
+	Id string
+}
+
+// _MastodonStatus is the part used of a status of the Mastodon API.
+type _MastodonStatus struct {
+	Id                string                   `json:"id"`
+	Created_at        string                   `json:"created_at"`
+	Url               string                   `json:"url"`
+	Uri               string                   `json:"uri"`
+	Content           string                   `json:"content"`
+	Spoiler_text      string                   `json:"spoiler_text"`
+	Sensitive         bool                     `json:"sensitive"`
+	In_reply_to_id    *string                  `json:"in_reply_to_id"`
+	Reblog            *_MastodonStatus         `json:"reblog"`
+	Account           _MastodonStatusAccount   `json:"account"`
+	Mentions          []_MastodonStatusAccount `json:"mentions"`
+	Media_attachments []struct {
+		Type        string `json:"type"`
+		Url         string `json:"url"`
+		Preview_url string `json:"preview_url"`
+		Description string `json:"description"`
+	} `json:"media_attachments"`
+}
+
+// _MastodonStatusAccount is the part used of an account of the Mastodon API.
+type _MastodonStatusAccount struct {
+	Acct         string `json:"acct"`
+	Display_name string `json:"display_name"`
+	Url          string `json:"url"`
+}
+
+/*
+getMastodonStatusesUrl gets the URL of the API with the statuses of a Mastodon account, resolving the account through
+WebFinger.
+
+-----------------------------------------------------------
+
+– Params:
+  - feedInfo – the information of the feed, with the account ("@user@instance") on Feed_url
+
+– Returns:
+  - the URL or "" if the account couldn't be resolved
+*/
+func getMastodonStatusesUrl(feedInfo _FeedInfo) string {
+	mastodonAccount, err := resolveMastodonAccount(feedInfo.Feed_url)
+	if nil != err {
+		fmt.Println("Error resolving the Mastodon account " + feedInfo.Feed_url + ": " + err.Error())
+
+		return ""
+	}
+
+	var params url.Values = url.Values{}
+	params.Set("limit", strconv.Itoa(_MASTODON_STATUSES_LIMIT))
+	if feedInfo.Mastodon_exclude_replies {
+		params.Set("exclude_replies", "true")
+	}
+	if feedInfo.Mastodon_exclude_boosts {
+		params.Set("exclude_reblogs", "true")
+	}
+
+	return mastodonScheme_GL + mastodonAccount.Api_host + "/api/v1/accounts/" + url.PathEscape(mastodonAccount.Id) +
+		"/statuses?" + params.Encode()
+}
+
+/*
+resolveMastodonAccount resolves a Mastodon account: finds its server through WebFinger and then its ID there. The
+result is kept on the scrape cache.
+
+-----------------------------------------------------------
+
+– Params:
+  - account – the account, as "@user@instance" or "user@instance"
+
+– Returns:
+  - the account
+  - an error if the account couldn't be resolved, nil otherwise
+*/
+func resolveMastodonAccount(account string) (_MastodonAccount, error) {
+	var acct string = strings.TrimPrefix(strings.TrimSpace(account), "@")
+	username, domain, found := strings.Cut(acct, "@")
+	if !found || "" == username || "" == domain {
+		return _MastodonAccount{}, errors.New("invalid account (must be @user@instance)")
+	}
+
+	var mastodonAccount _MastodonAccount
+	if scrapeCacheGet(_CACHE_KIND_MASTODON_ACCOUNT, acct, &mastodonAccount) {
+		return mastodonAccount, nil
+	}
+
+	var webFinger struct {
+		Subject string `json:"subject"`
+		Links   []struct {
+			Rel  string `json:"rel"`
+			Type string `json:"type"`
+			Href string `json:"href"`
+		} `json:"links"`
+	}
+	err := getJson(mastodonScheme_GL + domain + "/.well-known/webfinger?resource=" + url.QueryEscape("acct:" + acct),
+		_USER_AGENT, &webFinger)
+	if nil != err {
+		return _MastodonAccount{}, err
+	}
+	for _, link := range webFinger.Links {
+		if "self" == link.Rel && strings.HasPrefix(link.Type, "application/activity+json") {
+			if actor_url, err := url.Parse(link.Href); nil == err {
+				mastodonAccount.Api_host = actor_url.Host
+			}
+
+			break
+		}
+	}
+	if "" == mastodonAccount.Api_host {
+		return _MastodonAccount{}, errors.New("no ActivityPub actor on WebFinger")
+	}
+	// The username may be different on the subject (the canonical one).
+	if subject_acct, ok := strings.CutPrefix(webFinger.Subject, "acct:"); ok {
+		username, _, _ = strings.Cut(subject_acct, "@")
+	}
+
+	// The account is local on its server, so only the username is needed.
+	var lookupAccount struct {
+		Id string `json:"id"`
+	}
+	err = getJson(mastodonScheme_GL + mastodonAccount.Api_host + "/api/v1/accounts/lookup?acct=" +
+		url.QueryEscape(username), _USER_AGENT, &lookupAccount)
+	if nil != err {
+		return _MastodonAccount{}, err
+	}
+	if "" == lookupAccount.Id {
+		return _MastodonAccount{}, errors.New("account not found on " + mastodonAccount.Api_host)
+	}
+	mastodonAccount.Id = lookupAccount.Id
+
+	scrapeCacheSet(_CACHE_KIND_MASTODON_ACCOUNT, acct, mastodonAccount)
+
+	return mastodonAccount, nil
+}
+
+/*
+getMastodonFeed gets the statuses of a Mastodon account and converts them to a feed.
+
+The statuses are got from the API and not from the account's .rss: that one has the boosts, replies and content
+warnings flattened into the text and only some of the media. The Mastodon information of each status goes on the
+"mastodon" extension of its item.
+
+-----------------------------------------------------------
+
+– Params:
+  - statuses_url – the URL of the statuses (from getMastodonStatusesUrl())
+
+– Returns:
+  - the feed, with the statuses from the newest to the oldest
+  - an error if the request failed, nil otherwise
+*/
+func getMastodonFeed(statuses_url string) (*gofeed.Feed, error) {
+	var mastodonStatuses []_MastodonStatus = nil
+	if err := getJson(statuses_url, _USER_AGENT, &mastodonStatuses); nil != err {
+		return nil, err
+	}
+
+	var parsed_feed *gofeed.Feed = &gofeed.Feed{
+		Title:    statuses_url,
+		FeedType: "json",
+	}
+	if 0 != len(mastodonStatuses) {
+		// The account of the statuses is always the one of the feed (even on boosts).
+		var account _MastodonStatusAccount = mastodonStatuses[0].Account
+		parsed_feed.Title = getMastodonAccountName(account)
+		parsed_feed.Link = account.Url
+	}
+
+	for _, mastodonStatus := range mastodonStatuses {
+		parsed_feed.Items = append(parsed_feed.Items, mastodonStatusToItem(mastodonStatus))
+	}
+
+	return parsed_feed, nil
+}
+
+/*
+mastodonStatusToItem converts a Mastodon status to a feed item.
+
+-----------------------------------------------------------
+
+– Params:
+  - mastodonStatus – the status
+
+– Returns:
+  - the feed item
+*/
+func mastodonStatusToItem(mastodonStatus _MastodonStatus) *gofeed.Item {
+	var kind string = _MASTODON_KIND_POST
+	var reply_to string = ""
+	if nil != mastodonStatus.Reblog {
+		kind = _MASTODON_KIND_BOOST
+	} else if nil != mastodonStatus.In_reply_to_id {
+		kind = _MASTODON_KIND_REPLY
+		if 0 != len(mastodonStatus.Mentions) {
+			reply_to = "@" + mastodonStatus.Mentions[0].Acct
+		}
+	}
+
+	// On boosts, the content is all on the boosted status.
+	var content_status _MastodonStatus = mastodonStatus
+	if nil != mastodonStatus.Reblog {
+		content_status = *mastodonStatus.Reblog
+	}
+	var status_url string = content_status.Url
+	if "" == status_url {
+		status_url = content_status.Uri
+	}
+
+	var title string = htmlToText(content_status.Content)
+	if runes := []rune(title); len(runes) > _MASTODON_TITLE_MAX_CHARS {
+		title = string(runes[:_MASTODON_TITLE_MAX_CHARS]) + "…"
+	}
+	if "" == title {
+		title = "(" + strconv.Itoa(len(content_status.Media_attachments)) + " anexos)"
+	}
+	if "" != content_status.Spoiler_text {
+		// The content warning is what is shown before the content.
+		title = "CW: " + content_status.Spoiler_text
+	}
+
+	var feed_item *gofeed.Item = &gofeed.Item{
+		Title:       title,
+		Link:        status_url,
+		GUID:        mastodonStatus.Uri,
+		Description: content_status.Content,
+		Authors:     []*gofeed.Person{{Name: getMastodonAccountName(content_status.Account)}},
+		Published:   mastodonStatus.Created_at,
+		Extensions: ext.Extensions{
+			"mastodon": {
+				"kind":      {{Value: kind}},
+				"cw":        {{Value: content_status.Spoiler_text}},
+				"reply_to":  {{Value: reply_to}},
+				"sensitive": {{Value: strconv.FormatBool(content_status.Sensitive)}},
+			},
+		},
+	}
+	if published, err := time.Parse(time.RFC3339, mastodonStatus.Created_at); nil == err {
+		feed_item.PublishedParsed = &published
+	}
+	if kind == _MASTODON_KIND_BOOST {
+		feed_item.Extensions["mastodon"]["boosted"] = []ext.Extension{{Value: "@" + content_status.Account.Acct}}
+	}
+
+	for _, attachment := range content_status.Media_attachments {
+		feed_item.Extensions["mastodon"]["media"] = append(feed_item.Extensions["mastodon"]["media"], ext.Extension{
+			Attrs: map[string]string{
+				"type":        attachment.Type,
+				"url":         attachment.Url,
+				"preview_url": attachment.Preview_url,
+				"description": attachment.Description,
+			},
+		})
+		// Sensitive media and media behind a content warning are not shown (see getMastodonHtml()).
+		if nil == feed_item.Image && "image" == attachment.Type && !content_status.Sensitive &&
+					"" == content_status.Spoiler_text {
+			feed_item.Image = &gofeed.Image{URL: attachment.Preview_url}
+		}
+	}
+
+	return feed_item
+}
+
+/*
+getMastodonAccountName gets the name of a Mastodon account to show.
+
+-----------------------------------------------------------
+
+– Params:
+  - account – the account
+
+– Returns:
+  - the display name and the account, like "Name (@user@instance)", or only the account if there's no display name
+*/
+func getMastodonAccountName(account _MastodonStatusAccount) string {
+	if "" == account.Display_name {
+		return "@" + account.Acct
+	}
+
+	return account.Display_name + " (@" + account.Acct + ")"
+}
+
+/*
+mastodonTreatment does the treatment of a Mastodon feed item: the general treatment plus the subject, which never has
+the text of statuses with content warnings (only the warning).
+
+-----------------------------------------------------------
+
+– Params:
+  - feedInfo – the information of the feed
+  - parsed_feed – the parsed feed
+  - item_num – the number of the item to get
+  - title_url_only – whether to only get the title and URL of the item through _NewsInfo (can be used for optimization)
+
+– Returns:
+  - the email info (without the Mail_to field) or all fields empty if title_url_only is true
+  - the news info
+*/
+func mastodonTreatment(feedInfo _FeedInfo, parsed_feed *gofeed.Feed, item_num int, title_url_only bool) (
+			Utils.EmailInfo, _NewsInfo) {
+	var feed_item *gofeed.Item = parsed_feed.Items[item_num]
+
+	email_info, newsInfo := generalTreatment(feedInfo, parsed_feed, item_num, title_url_only,
+		func(description string) string {
+			return getMastodonHtml(feedInfo, feed_item, description)
+		})
+	if title_url_only || "" != email_info.Subject {
+		return email_info, newsInfo
+	}
+
+	var name string = parsed_feed.Title
+	if idx := strings.Index(name, " (@"); idx > 0 {
+		name = name[:idx]
+	}
+	switch getMastodonExtValue(feed_item, "kind") {
+		case _MASTODON_KIND_BOOST: {
+			email_info.Subject = name + " partilhou uma publicação de " + getMastodonExtValue(feed_item, "boosted")
+		}
+		case _MASTODON_KIND_REPLY: {
+			email_info.Subject = name + " respondeu"
+			if reply_to := getMastodonExtValue(feed_item, "reply_to"); "" != reply_to {
+				email_info.Subject += " a " + reply_to
+			}
+		}
+		default: {
+			email_info.Subject = name + " publicou"
+		}
+	}
+	// The title is the content warning, if there's one.
+	email_info.Subject += ": " + feed_item.Title
+
+	return email_info, newsInfo
+}
+
+/*
+getMastodonHtml gets the HTML of a Mastodon status for the email: its content with the content warning before and, if
+the feed wants it, the media attachments after. The images of sensitive statuses or with a content warning are only
+linked, not shown.
+
+-----------------------------------------------------------
+
+– Params:
+  - feedInfo – the information of the feed
+  - feed_item – the item of the status
+  - content – the (sanitized) content of the status
+
+– Returns:
+  - the HTML
+*/
+func getMastodonHtml(feedInfo _FeedInfo, feed_item *gofeed.Item, content string) string {
+	var html_builder strings.Builder
+
+	switch getMastodonExtValue(feed_item, "kind") {
+		case _MASTODON_KIND_BOOST: {
+			html_builder.WriteString("<p>🔁 Partilha de " +
+				html.EscapeString(getMastodonExtValue(feed_item, "boosted")) + "</p>\n")
+		}
+		case _MASTODON_KIND_REPLY: {
+			html_builder.WriteString("<p>↩️ Resposta")
+			if reply_to := getMastodonExtValue(feed_item, "reply_to"); "" != reply_to {
+				html_builder.WriteString(" a " + html.EscapeString(reply_to))
+			}
+			html_builder.WriteString("</p>\n")
+		}
+	}
+	if cw := getMastodonExtValue(feed_item, "cw"); "" != cw {
+		html_builder.WriteString("<p><b>⚠️ Aviso de conteúdo: " + html.EscapeString(cw) + "</b></p>\n<hr>\n")
+	}
+
+	html_builder.WriteString(content)
+
+	if feedInfo.Mastodon_media {
+		var hide_images bool = "true" == getMastodonExtValue(feed_item, "sensitive") ||
+			"" != getMastodonExtValue(feed_item, "cw")
+		for _, media := range feed_item.Extensions["mastodon"]["media"] {
+			var media_url string = html.EscapeString(media.Attrs["url"])
+			var description string = html.EscapeString(media.Attrs["description"])
+			switch media.Attrs["type"] {
+				case "image": {
+					if hide_images {
+						html_builder.WriteString("\n<p>🖼️ <a href=\"" + media_url + "\" title=\"" + description +
+							"\">Imagem (sensível)</a></p>")
+					} else {
+						var preview_url string = media.Attrs["preview_url"]
+						if "" == preview_url {
+							preview_url = media.Attrs["url"]
+						}
+						html_builder.WriteString("\n<p><a href=\"" + media_url + "\"><img src=\"" +
+							html.EscapeString(preview_url) + "\" alt=\"" + description + "\" title=\"" + description +
+							"\" style=\"max-width: 100%; height: auto;\"></a></p>")
+					}
+				}
+				case "video", "gifv": {
+					html_builder.WriteString("\n<p>🎞️ <a href=\"" + media_url + "\">Vídeo</a></p>")
+				}
+				case "audio": {
+					html_builder.WriteString("\n<p>🔊 <a href=\"" + media_url + "\">Áudio</a></p>")
+				}
+				default: {
+					html_builder.WriteString("\n<p>📎 <a href=\"" + media_url + "\">Anexo</a></p>")
+				}
+			}
+		}
+	}
+
+	return html_builder.String()
+}
+
+/*
+getMastodonExtValue gets a value of the "mastodon" extension of an item.
+
+-----------------------------------------------------------
+
+– Params:
+  - feed_item – the item
+  - name – the name of the value
+
+– Returns:
+  - the value or "" if there's none
+*/
+func getMastodonExtValue(feed_item *gofeed.Item, name string) string {
+	if values := feed_item.Extensions["mastodon"][name]; 0 != len(values) {
+		return values[0].Value
+	}
+
+	return ""
+}
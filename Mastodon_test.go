@@ -0,0 +1,241 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mmcdole/gofeed"
+)
+
+// setTestMastodonScheme makes the Mastodon servers be requested through plain HTTP (for local stand-ins) until the end
+// of the test.
+func setTestMastodonScheme(t *testing.T) {
+	var old_scheme string = mastodonScheme_GL
+	mastodonScheme_GL = "http://"
+	t.Cleanup(func() {
+		mastodonScheme_GL = old_scheme
+	})
+}
+
+func TestResolveMastodonAccount(t *testing.T) {
+	setTestMastodonScheme(t)
+
+	// The API is on another host than the account's domain, and the canonical username has other case.
+	var api_server *httptest.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if "/api/v1/accounts/lookup" != r.URL.Path || "Alice" != r.URL.Query().Get("acct") {
+			http.NotFound(w, r)
+
+			return
+		}
+		_, _ = fmt.Fprint(w, `{"id": "109", "username": "Alice"}`)
+	}))
+	defer api_server.Close()
+	var api_host string = strings.TrimPrefix(api_server.URL, "http://")
+
+	var domain_server *httptest.Server = nil
+	domain_server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var domain string = strings.TrimPrefix(domain_server.URL, "http://")
+		if "/.well-known/webfinger" != r.URL.Path || "acct:alice@" + domain != r.URL.Query().Get("resource") {
+			http.NotFound(w, r)
+
+			return
+		}
+		_, _ = fmt.Fprint(w, `{"subject": "acct:Alice@` + domain + `", "links": [` +
+			`{"rel": "http://webfinger.net/rel/profile-page", "type": "text/html", ` +
+			`"href": "https://example.com/@Alice"},` +
+			`{"rel": "self", "type": "application/activity+json", "href": "http://` + api_host + `/users/Alice"}]}`)
+	}))
+	defer domain_server.Close()
+	var domain string = strings.TrimPrefix(domain_server.URL, "http://")
+
+	mastodonAccount, err := resolveMastodonAccount(" @alice@" + domain)
+	if nil != err || api_host != mastodonAccount.Api_host || "109" != mastodonAccount.Id {
+		t.Errorf("resolveMastodonAccount() = %+v, %v", mastodonAccount, err)
+	}
+
+	var statuses_url string = getMastodonStatusesUrl(_FeedInfo{Feed_url: "alice@" + domain,
+		Mastodon_exclude_replies: true})
+	if "http://" + api_host + "/api/v1/accounts/109/statuses?exclude_replies=true&limit=40" != statuses_url {
+		t.Errorf("getMastodonStatusesUrl() = %q", statuses_url)
+	}
+
+	var accounts []string = []string{"alice", "@alice", "alice@", "@nobody@" + domain}
+	for _, account := range accounts {
+		if mastodonAccount, err = resolveMastodonAccount(account); nil == err {
+			t.Errorf("resolveMastodonAccount(%q) = %+v, expected an error", account, mastodonAccount)
+		}
+	}
+}
+
+func TestMastodonStatusToItem(t *testing.T) {
+	var author _MastodonStatusAccount = _MastodonStatusAccount{Acct: "alice@example.com", Display_name: "Alice"}
+	var reply_to_id string = "1"
+
+	var mastodonStatus _MastodonStatus = _MastodonStatus{
+		Uri:        "https://example.com/users/alice/statuses/2",
+		Url:        "https://example.com/@alice/2",
+		Created_at: "2023-11-20T10:00:00Z",
+		Content:    "<p>Hello <b>world</b></p>",
+		Account:    author,
+	}
+	mastodonStatus.Media_attachments = append(mastodonStatus.Media_attachments, struct {
+		Type        string `json:"type"`
+		Url         string `json:"url"`
+		Preview_url string `json:"preview_url"`
+		Description string `json:"description"`
+	}{Type: "image", Url: "https://example.com/big.png", Preview_url: "https://example.com/small.png"})
+
+	var feed_item *gofeed.Item = mastodonStatusToItem(mastodonStatus)
+	if "Hello world" != feed_item.Title || "https://example.com/@alice/2" != feed_item.Link ||
+				"Alice (@alice@example.com)" != feed_item.Authors[0].Name || nil == feed_item.PublishedParsed ||
+				_MASTODON_KIND_POST != getMastodonExtValue(feed_item, "kind") {
+		t.Errorf("wrong post: %+v", feed_item)
+	}
+	if nil == feed_item.Image || "https://example.com/small.png" != feed_item.Image.URL {
+		t.Errorf("wrong image: %+v", feed_item.Image)
+	}
+
+	// Sensitive media is not the image of the item.
+	mastodonStatus.Sensitive = true
+	if feed_item = mastodonStatusToItem(mastodonStatus); nil != feed_item.Image ||
+				"true" != getMastodonExtValue(feed_item, "sensitive") {
+		t.Errorf("sensitive media: image %+v", feed_item.Image)
+	}
+
+	// Content warning: it's the title, and the media is not the image either.
+	mastodonStatus.Sensitive = false
+	mastodonStatus.Spoiler_text = "Spoilers"
+	if feed_item = mastodonStatusToItem(mastodonStatus); "CW: Spoilers" != feed_item.Title || nil != feed_item.Image {
+		t.Errorf("content warning: title %q, image %+v", feed_item.Title, feed_item.Image)
+	}
+
+	// Reply - to the first mentioned account.
+	mastodonStatus.Spoiler_text = ""
+	mastodonStatus.In_reply_to_id = &reply_to_id
+	mastodonStatus.Mentions = []_MastodonStatusAccount{{Acct: "bob@example.org"}}
+	feed_item = mastodonStatusToItem(mastodonStatus)
+	if _MASTODON_KIND_REPLY != getMastodonExtValue(feed_item, "kind") ||
+				"@bob@example.org" != getMastodonExtValue(feed_item, "reply_to") {
+		t.Errorf("wrong reply: %v", feed_item.Extensions["mastodon"])
+	}
+
+	// Boost - the content is the boosted status's, and there's no content but the media.
+	var boostedStatus _MastodonStatus = mastodonStatus
+	boostedStatus.Account = _MastodonStatusAccount{Acct: "carol@example.net"}
+	boostedStatus.Content = ""
+	boostedStatus.Url = "https://example.net/@carol/3"
+	var boostStatus _MastodonStatus = _MastodonStatus{
+		Uri:        "https://example.com/users/alice/statuses/4/activity",
+		Created_at: "2023-11-20T11:00:00Z",
+		Account:    author,
+		Reblog:     &boostedStatus,
+	}
+	feed_item = mastodonStatusToItem(boostStatus)
+	if _MASTODON_KIND_BOOST != getMastodonExtValue(feed_item, "kind") ||
+				"@carol@example.net" != getMastodonExtValue(feed_item, "boosted") ||
+				"https://example.net/@carol/3" != feed_item.Link || "(1 anexos)" != feed_item.Title ||
+				"@carol@example.net" != feed_item.Authors[0].Name {
+		t.Errorf("wrong boost: %+v, %v", feed_item, feed_item.Extensions["mastodon"])
+	}
+}
+
+func TestGetMastodonHtml(t *testing.T) {
+	var mastodonStatus _MastodonStatus = _MastodonStatus{Content: "<p>Text</p>"}
+	mastodonStatus.Media_attachments = append(mastodonStatus.Media_attachments, struct {
+		Type        string `json:"type"`
+		Url         string `json:"url"`
+		Preview_url string `json:"preview_url"`
+		Description string `json:"description"`
+	}{Type: "image", Url: "https://example.com/big.png", Preview_url: "https://example.com/small.png",
+		Description: "A cat"})
+
+	var feedInfo _FeedInfo = _FeedInfo{Mastodon_media: true}
+	var mastodon_html string = getMastodonHtml(feedInfo, mastodonStatusToItem(mastodonStatus), "<p>Text</p>")
+	if !strings.HasPrefix(mastodon_html, "<p>Text</p>") ||
+				!strings.Contains(mastodon_html, `<img src="https://example.com/small.png" alt="A cat"`) {
+		t.Errorf("wrong HTML:\n%s", mastodon_html)
+	}
+	if mastodon_html = getMastodonHtml(_FeedInfo{}, mastodonStatusToItem(mastodonStatus), "<p>Text</p>");
+				"<p>Text</p>" != mastodon_html {
+		t.Errorf("media without Mastodon_media:\n%s", mastodon_html)
+	}
+
+	// Sensitive or behind a content warning: only linked.
+	mastodonStatus.Sensitive = true
+	var sensitive_html string = getMastodonHtml(feedInfo, mastodonStatusToItem(mastodonStatus), "<p>Text</p>")
+	mastodonStatus.Sensitive = false
+	mastodonStatus.Spoiler_text = "Spoilers"
+	var cw_html string = getMastodonHtml(feedInfo, mastodonStatusToItem(mastodonStatus), "<p>Text</p>")
+	for _, mastodon_html = range []string{sensitive_html, cw_html} {
+		if strings.Contains(mastodon_html, "<img") ||
+					!strings.Contains(mastodon_html, `<a href="https://example.com/big.png" title="A cat">`) {
+			t.Errorf("media shown:\n%s", mastodon_html)
+		}
+	}
+	if !strings.HasPrefix(cw_html, "<p><b>⚠️ Aviso de conteúdo: Spoilers</b></p>") {
+		t.Errorf("no content warning:\n%s", cw_html)
+	}
+}
+
+func TestMastodonTreatment(t *testing.T) {
+	var reply_to_id string = "1"
+	var author _MastodonStatusAccount = _MastodonStatusAccount{Acct: "alice@example.com", Display_name: "Alice"}
+	var boostedStatus _MastodonStatus = _MastodonStatus{Content: "<p>Boosted</p>",
+		Account: _MastodonStatusAccount{Acct: "carol@example.net"}}
+	var mastodonStatuses []_MastodonStatus = []_MastodonStatus{
+		{Content: "<p>Post</p>", Account: author},
+		{Content: "<p>Reply</p>", Account: author, In_reply_to_id: &reply_to_id,
+			Mentions: []_MastodonStatusAccount{{Acct: "bob@example.org"}}},
+		{Account: author, Reblog: &boostedStatus},
+		{Content: "<p>Secret</p>", Spoiler_text: "Spoilers", Account: author},
+	}
+	var parsed_feed *gofeed.Feed = &gofeed.Feed{Title: getMastodonAccountName(author)}
+	for _, mastodonStatus := range mastodonStatuses {
+		parsed_feed.Items = append(parsed_feed.Items, mastodonStatusToItem(mastodonStatus))
+	}
+
+	var expected_subjects []string = []string{
+		"Alice publicou: Post",
+		"Alice respondeu a @bob@example.org: Reply",
+		"Alice partilhou uma publicação de @carol@example.net: Boosted",
+		// Never the text behind the content warning.
+		"Alice publicou: CW: Spoilers",
+	}
+	var feedInfo _FeedInfo = _FeedInfo{Feed_type: _TYPE_1_MASTODON}
+	for item_num, expected_subject := range expected_subjects {
+		email_info, _ := mastodonTreatment(feedInfo, parsed_feed, item_num, false)
+		if expected_subject != email_info.Subject {
+			t.Errorf("item %d: subject %q, expected %q", item_num, email_info.Subject, expected_subject)
+		}
+	}
+
+	// A custom subject is kept.
+	feedInfo.Custom_msg_subject = "Custom"
+	if email_info, _ := mastodonTreatment(feedInfo, parsed_feed, 0, false); "Custom" != email_info.Subject {
+		t.Errorf("custom subject replaced by %q", email_info.Subject)
+	}
+}
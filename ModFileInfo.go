@@ -25,8 +25,60 @@ package main
 type _ModUserInfo struct {
 	// Mails_info is the information about the mails to send the feeds info to
 	Mails_to   []string
+	// Recipients is the information about the mails to send only some feeds' info to (by tag)
+	Recipients []_Recipient
 	// Feed_info is the information about the feeds
 	Feeds_info []_FeedInfo
+	// Yt_api_key is the YouTube Data API v3 key to get YouTube metadata with (optional - if empty or if the quota is
+	// exceeded, the pages are scraped instead)
+	Yt_api_key string
+	// Se_api_key is the StackExchange API key to get the questions' information and the comments with (optional - more
+	// quota than without it)
+	Se_api_key string
+	// Output_feeds_dir is the directory where to write the Atom, RSS and JSON feeds of the notified items (optional)
+	Output_feeds_dir string
+	// Output_feeds_max_items is the maximum number of items of each output feed (0 for the default)
+	Output_feeds_max_items int
+	// Http_server_addr is the address for the module's HTTP server to listen on, like ":8080" - it serves the output
+	// feeds and is run with the "serve" command (optional - no server if empty)
+	Http_server_addr string
+	// Archive_dir is the directory where to archive every notified item (optional - no archive if empty)
+	Archive_dir string
+	// Archive_format is the format of the archived items: "markdown" (the default) or "html"
+	Archive_format string
+	// Archive_full_content is whether to archive the extracted full content of the items instead of their description
+	// (not for YouTube items)
+	Archive_full_content bool
+	// Archive_images is whether to also archive the items' images (thumbnails)
+	Archive_images bool
+	// Websub_callback_url is the public URL of the HTTP server (like "https://example.com:8080") for the WebSub hubs to
+	// push the new items of the feeds to (optional - only polling if empty; needs the "serve" command running)
+	Websub_callback_url string
+}
+
+// _Recipient is an email to send notifications to.
+type _Recipient struct {
+	// Mail_to is the email
+	Mail_to string
+	// Tags is the list of tags of the feeds to notify (if empty, all feeds are notified)
+	Tags []string
+	// Time_zone is the IANA time zone of the quiet hours, like "Europe/Lisbon" (if empty, the local one)
+	Time_zone string
+	// Quiet_hours is the list of windows during which the notifications are held and delivered as a batch at the end
+	Quiet_hours []_QuietHours
+	// Max_per_hour is the maximum number of emails per hour - the rest are held and delivered as a batch later (0 for
+	// no limit)
+	Max_per_hour int
+}
+
+// _QuietHours is a window of time during which a recipient doesn't want to be notified.
+type _QuietHours struct {
+	// Days is the list of weekdays in which the window begins ("mon", "tue", ... - if empty, all days)
+	Days []string
+	// Start is the time of the day the window begins, in the "15:04" format
+	Start string
+	// End is the time of the day the window ends, in the "15:04" format (if before Start, it's on the next day)
+	End string
 }
 
 // _FeedInfo is the information about a feed.
@@ -39,4 +91,93 @@ type _FeedInfo struct {
 	Feed_type string
 	// Custom_msg_subject is the custom message subject
 	Custom_msg_subject string
+	// Tags is the list of tags (categories) of the feed, used to route the notifications, group digests and the status
+	// page, filter on the command line and generate output feeds per tag
+	Tags []string
+	// Burst_threshold is the number of news in one check above which they're all notified in one email instead of one
+	// email each (0 to never collapse them)
+	Burst_threshold int
+	// Max_per_hour is the maximum number of emails per hour about the feed - the rest of the news are postponed (0 for
+	// no limit)
+	Max_per_hour int
+	// Live_breaks_quiet is whether the notifications of live videos of the feed are sent even during quiet hours
+	Live_breaks_quiet bool
+	// Notify_upcoming is whether to also notify when a livestream or premiere is scheduled (YouTube channels only - the
+	// start of the event is always notified)
+	Notify_upcoming bool
+	// Yt_content is the list of the kinds of YouTube content to notify about (the _YT_CONTENT_ constants). If empty,
+	// all except Shorts are notified (Shorts only with the "+S" type)
+	Yt_content []string
+	// Min_duration_s is the minimum duration of YouTube videos to notify in seconds (0 for no minimum)
+	Min_duration_s int
+	// Max_duration_s is the maximum duration of YouTube videos to notify in seconds (0 for no maximum)
+	Max_duration_s int
+	// Catch_up_digest is whether to notify the items missed while the module was stopped all in one email, instead of
+	// one email each
+	Catch_up_digest bool
+	// Full_content is whether to download the page of each item and put its article in the email instead of the item's
+	// description (General feeds only)
+	Full_content bool
+	// Full_content_max_chars is the maximum number of text characters of the article (0 for the default)
+	Full_content_max_chars int
+	// Podcast_download is whether to download the episodes of the feed (Podcast feeds only)
+	Podcast_download bool
+	// Download_dir is the directory where to save the episodes
+	Download_dir string
+	// Download_filename is the template of the episodes' file names (see getEpisodeFileName() - if empty,
+	// _DOWNLOAD_FILENAME_DEF)
+	Download_filename string
+	// Download_keep_count is the number of latest episodes to keep (0 to keep all)
+	Download_keep_count int
+	// Download_keep_days is for how many days after being published the episodes are kept (0 to keep them forever)
+	Download_keep_days int
+	// Reddit_sort is the sorting of the Reddit listing ("new", "hot", "top", "rising" or, for searches, "relevance" -
+	// if empty, "new")
+	Reddit_sort string
+	// Reddit_min_score is the minimum score of the Reddit posts to notify (0 for no minimum). The posts below it are
+	// checked again while they're in the listing
+	Reddit_min_score int
+	// Reddit_flairs is the list of flairs of the Reddit posts to notify (not case-sensitive - if empty, all)
+	Reddit_flairs []string
+	// Github_branch is the branch of the GitHub commits to notify (if empty, the default branch)
+	Github_branch string
+	// Github_prereleases is whether to also notify the GitHub pre-releases
+	Github_prereleases bool
+	// Github_min_change is the minimum change of the GitHub versions to notify, relative to the previous version
+	// (_GH_CHANGE_MAJOR, _GH_CHANGE_MINOR or _GH_CHANGE_PATCH - if empty, all)
+	Github_min_change string
+	// Mastodon_exclude_replies is whether to not notify the replies of the Mastodon account
+	Mastodon_exclude_replies bool
+	// Mastodon_exclude_boosts is whether to not notify the boosts of the Mastodon account
+	Mastodon_exclude_boosts bool
+	// Mastodon_media is whether to put the media attachments of the Mastodon statuses in the emails
+	Mastodon_media bool
+	// Aggregator_min_points is the minimum points of the link aggregator stories to notify (0 for no minimum). The
+	// stories below it are watched and notified when they cross it
+	Aggregator_min_points int
+	// Aggregator_min_comments is the minimum number of comments of the link aggregator stories to notify (0 for no
+	// minimum) - watched like with Aggregator_min_points
+	Aggregator_min_comments int
+	// Aggregator_keywords is the list of keywords (not case-sensitive) of which any on a story's title makes it be
+	// notified regardless of the thresholds. If there are no thresholds, only these stories are notified
+	Aggregator_keywords []string
+	// Se_tags_include is the list of tags of which the StackExchange questions must have any to be notified (if empty,
+	// all)
+	Se_tags_include []string
+	// Se_tags_exclude is the list of tags of which the StackExchange questions must have none to be notified
+	Se_tags_exclude []string
+	// Se_min_score is the minimum score of the StackExchange questions to notify (0 for no minimum). The questions
+	// below it are checked again while they're in the feed
+	Se_min_score int
+	// Se_comments is whether to also notify the comments of a watched StackExchange question and of its answers
+	Se_comments bool
+	// Websub_hub is the WebSub hub to subscribe to instead of the one the feed advertises (optional)
+	Websub_hub string
+	// Initial_sync is what to notify on the first check of the feed (one of the _INITIAL_SYNC_ constants - if empty,
+	// _INITIAL_SYNC_MARK_ALL_SEEN)
+	Initial_sync string
+	// Initial_sync_n is the number of latest items to notify with _INITIAL_SYNC_LATEST_N
+	Initial_sync_n int
+	// Initial_sync_since is the date since which to notify items with _INITIAL_SYNC_SINCE_DATE (format: 2006-01-02)
+	Initial_sync_since string
 }
@@ -0,0 +1,93 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// _MODULE_LOCK_FILE is the file on the user data folder that is locked while the module uses the data folder.
+const _MODULE_LOCK_FILE string = "module.lock"
+
+// _MODULE_LOCK_WAIT is for how long lockModule() waits for another process to unlock the module.
+const _MODULE_LOCK_WAIT time.Duration = 10 * time.Minute
+
+// moduleMutex_GL is locked together with the lock file, for the goroutines of the same process (like the feeds pushed
+// by the WebSub hubs) to also wait for each other.
+var moduleMutex_GL sync.Mutex
+
+// moduleLockFile_GL is the locked file while the module is locked. Must be used with moduleMutex_GL locked.
+var moduleLockFile_GL *os.File = nil
+
+/*
+lockModule locks the user data folder of the module, so that only one cycle, command or pushed feed check uses it at a
+time (on this process or on others). If it's locked, it waits for it to be unlocked. Must be followed by
+unlockModule().
+
+-----------------------------------------------------------
+
+– Returns:
+  - true if the lock was got, false if it was still locked after _MODULE_LOCK_WAIT (or if an error occurred)
+*/
+func lockModule() bool {
+	moduleMutex_GL.Lock()
+
+	var lock_path string = moduleInfo_GL.ModDirsInfo.UserData.Add2(_MODULE_LOCK_FILE).GPathToStringConversion()
+	var deadline time.Time = time.Now().Add(_MODULE_LOCK_WAIT)
+	var waiting bool = false
+	for {
+		file, err := lockFile(lock_path)
+		if nil == err {
+			moduleLockFile_GL = file
+
+			// The files may have been changed by another process while the module wasn't locked.
+			forgetSearchIndex()
+
+			return true
+		}
+		if time.Now().After(deadline) {
+			fmt.Println("Error locking the module: " + err.Error())
+			moduleMutex_GL.Unlock()
+
+			return false
+		}
+		if !waiting {
+			waiting = true
+			fmt.Println("The module is locked by another process - waiting...")
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+/*
+unlockModule unlocks the module locked by lockModule().
+*/
+func unlockModule() {
+	if nil != moduleLockFile_GL {
+		_ = moduleLockFile_GL.Close()
+		moduleLockFile_GL = nil
+	}
+	moduleMutex_GL.Unlock()
+}
@@ -0,0 +1,56 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+//go:build !windows
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+/*
+lockFile opens a file, creating it if it doesn't exist, and locks it exclusively (flock). The lock lasts until the file
+is closed.
+
+-----------------------------------------------------------
+
+– Params:
+  - path – the path of the file
+
+– Returns:
+  - the locked file
+  - the error if the file couldn't be opened or locked (like if another process has it locked)
+*/
+func lockFile(path string) (*os.File, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if nil != err {
+		return nil, err
+	}
+	if err = syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); nil != err {
+		_ = file.Close()
+
+		return nil, err
+	}
+
+	return file, nil
+}
@@ -0,0 +1,48 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestLockFile(t *testing.T) {
+	var path string = filepath.Join(t.TempDir(), _MODULE_LOCK_FILE)
+
+	file, err := lockFile(path)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if other_file, err := lockFile(path); nil == err {
+		_ = other_file.Close()
+		t.Fatal("the file was locked twice")
+	}
+
+	// Released when closed.
+	_ = file.Close()
+	file, err = lockFile(path)
+	if nil != err {
+		t.Fatal("the file couldn't be locked again after being closed: " + err.Error())
+	}
+	_ = file.Close()
+}
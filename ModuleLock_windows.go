@@ -0,0 +1,53 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+/*
+lockFile opens a file exclusively (no sharing), creating it if it doesn't exist. The lock lasts until the file is closed.
+
+-----------------------------------------------------------
+
+– Params:
+  - path – the path of the file
+
+– Returns:
+  - the locked file
+  - the error if the file couldn't be opened (like if another process has it open)
+*/
+func lockFile(path string) (*os.File, error) {
+	path_utf16, err := syscall.UTF16PtrFromString(path)
+	if nil != err {
+		return nil, err
+	}
+	handle, err := syscall.CreateFile(path_utf16, syscall.GENERIC_READ|syscall.GENERIC_WRITE, 0, nil,
+		syscall.OPEN_ALWAYS, syscall.FILE_ATTRIBUTE_NORMAL, 0)
+	if nil != err {
+		return nil, err
+	}
+
+	return os.NewFile(uintptr(handle), path), nil
+}
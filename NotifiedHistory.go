@@ -0,0 +1,174 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"Utils"
+)
+
+// _MAX_NOTIFIED_ITEMS_STORED is the maximum number of notified items kept in the history of each feed.
+const _MAX_NOTIFIED_ITEMS_STORED int = 500
+
+// _NotifiedItem is an item that was notified, as kept in the history. It's exported to JSON, so the fields are
+// exported.
+type _NotifiedItem struct {
+	// Feed_num is the number of the feed the item came from
+	Feed_num int
+	// Feed_title is the title of the feed the item came from
+	Feed_title string
+	// Category is the category of the feed (its _FeedType.type_1)
+	Category string
+	// Tags are the tags of the feed
+	Tags []string
+	// Title is the title of the item
+	Title string
+	// Url is the URL of the item
+	Url string
+	// Author is the author of the item
+	Author string
+	// Description is the description of the item (may be HTML)
+	Description string
+	// Image is the URL of the image of the item
+	Image string
+	// Published is when the item was published in Unix seconds (0 if unknown)
+	Published int64
+	// Notified is when the item was notified in Unix seconds
+	Notified int64
+}
+
+/*
+recordNotifiedItem adds a notified item to the history of its feed and to the search index (and to the archive, if
+it's enabled). If it's a podcast episode and the feed wants it, its download is queued too.
+
+-----------------------------------------------------------
+
+– Params:
+  - feedInfo – the information of the feed
+  - feedType – the type of the feed
+  - feed_title – the title of the feed
+  - newsInfo – the news info of the item
+*/
+func recordNotifiedItem(feedInfo _FeedInfo, feedType _FeedType, feed_title string, newsInfo _NewsInfo) {
+	var notifiedItem _NotifiedItem = _NotifiedItem{
+		Feed_num:    feedInfo.Feed_num,
+		Feed_title:  feed_title,
+		Category:    feedType.type_1,
+		Tags:        getFeedTags(feedInfo),
+		Title:       newsInfo.title,
+		Url:         newsInfo.url,
+		Author:      newsInfo.author,
+		Description: newsInfo.description,
+		Image:       newsInfo.image,
+		Notified:    time.Now().Unix(),
+	}
+	if !newsInfo.published.IsZero() {
+		notifiedItem.Published = newsInfo.published.Unix()
+	}
+
+	var notified_items []_NotifiedItem = append(readNotifiedItems(feedInfo.Feed_num), notifiedItem)
+	if len(notified_items) > _MAX_NOTIFIED_ITEMS_STORED {
+		notified_items = notified_items[len(notified_items)-_MAX_NOTIFIED_ITEMS_STORED:]
+	}
+
+	notified_items_json, err := json.Marshal(notified_items)
+	if nil != err {
+		fmt.Println("Error writing the notified items: " + err.Error())
+
+		return
+	}
+	getNotifiedItemsPath(feedInfo.Feed_num).WriteTextFile(string(notified_items_json))
+
+	addToSearchIndex(notifiedItem)
+	archiveNotifiedItem(notifiedItem)
+	if feedInfo.Podcast_download && nil != newsInfo.episode {
+		queuePodcastDownload(feedInfo, feed_title, newsInfo.episode)
+	}
+}
+
+/*
+readNotifiedItems reads the history of notified items of a feed.
+
+-----------------------------------------------------------
+
+– Params:
+  - feed_num – the number of the feed
+
+– Returns:
+  - the notified items from the oldest to the newest (empty if there are none or if an error occurs)
+*/
+func readNotifiedItems(feed_num int) []_NotifiedItem {
+	var notified_items []_NotifiedItem = nil
+
+	var p_notified_items_json *string = getNotifiedItemsPath(feed_num).ReadTextFile()
+	if nil == p_notified_items_json {
+		return nil
+	}
+	if err := json.Unmarshal([]byte(*p_notified_items_json), &notified_items); nil != err {
+		fmt.Println("Error reading the notified items: " + err.Error())
+
+		return nil
+	}
+
+	return notified_items
+}
+
+/*
+readAllNotifiedItems reads the history of notified items of all the configured feeds.
+
+-----------------------------------------------------------
+
+– Returns:
+  - the notified items from the newest to the oldest
+*/
+func readAllNotifiedItems() []_NotifiedItem {
+	var notified_items []_NotifiedItem = nil
+	for _, feedInfo := range getFeedsInfo() {
+		notified_items = append(notified_items, readNotifiedItems(feedInfo.Feed_num)...)
+	}
+
+	sort.SliceStable(notified_items, func(i, j int) bool {
+		return notified_items[i].Notified > notified_items[j].Notified
+	})
+
+	return notified_items
+}
+
+/*
+getNotifiedItemsPath gets the path of the file with the history of notified items of a feed.
+
+-----------------------------------------------------------
+
+– Params:
+  - feed_num – the number of the feed
+
+– Returns:
+  - the path of the file
+*/
+func getNotifiedItemsPath(feed_num int) Utils.GPath {
+	return moduleInfo_GL.ModDirsInfo.UserData.Add2("notified_items/", strconv.Itoa(feed_num)+".json")
+}
@@ -0,0 +1,430 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"Utils"
+)
+
+// Delivery statuses of the outbox entries.
+const (
+	_DELIVERY_PENDING string = "pending"
+	_DELIVERY_SENT    string = "sent"
+	_DELIVERY_DEAD    string = "dead"
+)
+
+// _OUTBOX_MAX_ATTEMPTS is the number of failed delivery attempts after which a delivery is dead-lettered.
+const _OUTBOX_MAX_ATTEMPTS int = 8
+// _OUTBOX_BACKOFF_BASE is the delay before the first retry (doubled on each attempt).
+const _OUTBOX_BACKOFF_BASE time.Duration = 1 * time.Minute
+// _OUTBOX_BACKOFF_MAX is the maximum delay between retries.
+const _OUTBOX_BACKOFF_MAX time.Duration = 6 * time.Hour
+// _OUTBOX_KEEP_FINISHED is for how long the finished entries (all deliveries sent or dead) are kept (to be inspected,
+// retried or replayed).
+const _OUTBOX_KEEP_FINISHED time.Duration = 7 * 24 * time.Hour
+// _OUTBOX_MAX_FINISHED is the maximum number of finished entries kept - the oldest are removed first.
+const _OUTBOX_MAX_FINISHED int = 500
+
+// _OutboxBackend is a way of delivering notifications.
+type _OutboxBackend interface {
+	// deliver delivers a notification (with the given HTML) to an address of the backend
+	deliver(outboxEntry _OutboxEntry, html string, address string) error
+}
+
+// outboxBackends_GL has the backends mapped by the prefix of their destinations ("<prefix>:<address>").
+var outboxBackends_GL map[string]_OutboxBackend = map[string]_OutboxBackend{
+	"email": _EmailBackend{},
+}
+
+// _OutboxEntry is a rendered notification waiting to be (or already) delivered. It's exported to JSON, so the fields
+// are exported. Its HTML is stored apart (see getOutboxHtmlPath()), so that the outbox file stays small - it's written
+// on every status change.
+type _OutboxEntry struct {
+	// Id is the ID of the entry
+	Id string
+	// Created is when the entry was created in Unix seconds
+	Created int64
+	// Sender is the name of the sender
+	Sender string
+	// Subject is the subject of the notification
+	Subject string
+	// Deliveries are the deliveries of the notification, one per destination
+	Deliveries []_Delivery
+}
+
+// _Delivery is the delivery status of a notification to one destination.
+type _Delivery struct {
+	// Destination is where to deliver the notification to, as "<backend>:<address>" (like "email:a@b.com")
+	Destination string
+	// Status is one of the _DELIVERY_ constants
+	Status string
+	// Attempts is the number of failed attempts
+	Attempts int
+	// Next_attempt is when to attempt again in Unix seconds
+	Next_attempt int64
+	// Last_error is the error of the last failed attempt
+	Last_error string
+}
+
+// _EmailBackend delivers the notifications by email, through the Email Sender module's queue.
+type _EmailBackend struct{}
+
+func (_EmailBackend) deliver(outboxEntry _OutboxEntry, html string, address string) error {
+	return sendEmail(address, outboxEntry.Sender, outboxEntry.Subject, html)
+}
+
+/*
+addToOutbox stores a rendered notification in the outbox and tries to deliver it right away.
+
+-----------------------------------------------------------
+
+– Params:
+  - sender_name – the name of the sender
+  - subject – the subject of the notification
+  - html – the HTML of the notification
+  - destinations – the destinations, as "<backend>:<address>"
+
+– Returns:
+  - true if the notification was stored (delivered or not), false otherwise
+*/
+func addToOutbox(sender_name string, subject string, html string, destinations []string) bool {
+	if 0 == len(destinations) {
+		return true
+	}
+
+	var now time.Time = time.Now()
+	var outboxEntry _OutboxEntry = _OutboxEntry{
+		Id:      strconv.FormatInt(now.UnixNano(), 36),
+		Created: now.Unix(),
+		Sender:  sender_name,
+		Subject: subject,
+	}
+	for _, destination := range destinations {
+		outboxEntry.Deliveries = append(outboxEntry.Deliveries, _Delivery{
+			Destination:  destination,
+			Status:       _DELIVERY_PENDING,
+			Next_attempt: now.Unix(),
+		})
+	}
+
+	var outbox []_OutboxEntry = readOutbox()
+	if nil == outbox && getOutboxPath().Exists() {
+		// Don't overwrite an outbox that couldn't be read.
+		return false
+	}
+	if !getOutboxHtmlPath(outboxEntry.Id).WriteTextFile(html) {
+		return false
+	}
+	if !writeOutbox(append(outbox, outboxEntry)) {
+		_ = os.Remove(getOutboxHtmlPath(outboxEntry.Id).GPathToStringConversion())
+
+		return false
+	}
+
+	processOutbox()
+
+	return true
+}
+
+/*
+processOutbox attempts the pending deliveries that are due, dead-letters the ones that failed too many times and removes
+the old finished entries (see pruneOutbox()).
+*/
+func processOutbox() {
+	var outbox []_OutboxEntry = readOutbox()
+	if 0 == len(outbox) {
+		return
+	}
+
+	var now time.Time = time.Now()
+	var changed bool = false
+	for _, outboxEntry := range outbox {
+		var html string = ""
+		for i := range outboxEntry.Deliveries {
+			var delivery *_Delivery = &outboxEntry.Deliveries[i]
+			if _DELIVERY_PENDING == delivery.Status && delivery.Next_attempt <= now.Unix() {
+				if "" == html {
+					html = readOutboxHtml(outboxEntry.Id)
+				}
+				attemptDelivery(outboxEntry, html, delivery, now)
+				changed = true
+			}
+		}
+	}
+
+	outbox, removed_ids := pruneOutbox(outbox, now)
+	if !changed && 0 == len(removed_ids) {
+		return
+	}
+	if !writeOutbox(outbox) {
+		fmt.Println("Error writing the outbox")
+
+		return
+	}
+	// Only after the outbox was written, so that no kept entry loses its HTML.
+	for _, id := range removed_ids {
+		_ = os.Remove(getOutboxHtmlPath(id).GPathToStringConversion())
+	}
+}
+
+/*
+pruneOutbox removes the finished entries (all deliveries sent or dead) that are older than _OUTBOX_KEEP_FINISHED or
+that go over _OUTBOX_MAX_FINISHED. The entries with pending deliveries are always kept.
+
+-----------------------------------------------------------
+
+– Params:
+  - outbox – the outbox entries from the oldest to the newest
+  - now – the current time
+
+– Returns:
+  - the kept entries
+  - the IDs of the removed entries
+*/
+func pruneOutbox(outbox []_OutboxEntry, now time.Time) ([]_OutboxEntry, []string) {
+	var finished []bool = make([]bool, len(outbox))
+	var num_finished int = 0
+	for i, outboxEntry := range outbox {
+		finished[i] = true
+		for _, delivery := range outboxEntry.Deliveries {
+			if _DELIVERY_PENDING == delivery.Status {
+				finished[i] = false
+
+				break
+			}
+		}
+		if finished[i] {
+			num_finished++
+		}
+	}
+
+	var outbox_kept []_OutboxEntry = nil
+	var removed_ids []string = nil
+	for i, outboxEntry := range outbox {
+		if finished[i] && (num_finished > _OUTBOX_MAX_FINISHED ||
+					now.Sub(time.Unix(outboxEntry.Created, 0)) > _OUTBOX_KEEP_FINISHED) {
+			removed_ids = append(removed_ids, outboxEntry.Id)
+			num_finished--
+
+			continue
+		}
+		outbox_kept = append(outbox_kept, outboxEntry)
+	}
+
+	return outbox_kept, removed_ids
+}
+
+/*
+attemptDelivery attempts a delivery and updates its status. Failed deliveries are retried with exponential backoff, so
+that a backend that's down (like a full email queue) isn't dead-lettered in a few cycles.
+
+-----------------------------------------------------------
+
+– Params:
+  - outboxEntry – the entry of the delivery
+  - html – the HTML of the entry
+  - delivery – the delivery
+  - now – the current time
+*/
+func attemptDelivery(outboxEntry _OutboxEntry, html string, delivery *_Delivery, now time.Time) {
+	var err error = nil
+
+	backend_name, address, _ := strings.Cut(delivery.Destination, ":")
+	backend, ok := outboxBackends_GL[backend_name]
+	if !ok {
+		err = fmt.Errorf("unknown backend: %s", backend_name)
+	} else if "" == html {
+		err = fmt.Errorf("the HTML of the entry is missing")
+	} else {
+		err = backend.deliver(outboxEntry, html, address)
+	}
+
+	if nil == err {
+		delivery.Status = _DELIVERY_SENT
+		delivery.Last_error = ""
+
+		return
+	}
+
+	delivery.Attempts++
+	delivery.Last_error = err.Error()
+	fmt.Println("Error delivering \"" + outboxEntry.Subject + "\" to " + delivery.Destination + ": " + err.Error())
+	if delivery.Attempts >= _OUTBOX_MAX_ATTEMPTS {
+		delivery.Status = _DELIVERY_DEAD
+		fmt.Println("Delivery dead-lettered after " + strconv.Itoa(delivery.Attempts) + " attempts")
+
+		return
+	}
+
+	var delay time.Duration = _OUTBOX_BACKOFF_BASE << (delivery.Attempts - 1)
+	if delay > _OUTBOX_BACKOFF_MAX || delay <= 0 {
+		delay = _OUTBOX_BACKOFF_MAX
+	}
+	delivery.Next_attempt = now.Add(delay).Unix()
+}
+
+/*
+replayOutboxEntry puts the deliveries of an outbox entry as pending again (all of them or only the dead ones) and
+processes the outbox.
+
+-----------------------------------------------------------
+
+– Params:
+  - id – the ID of the entry
+  - dead_only – whether to replay only the dead deliveries
+
+– Returns:
+  - true if the entry was found, false otherwise
+*/
+func replayOutboxEntry(id string, dead_only bool) bool {
+	var outbox []_OutboxEntry = readOutbox()
+	var found bool = false
+	for i := range outbox {
+		if outbox[i].Id != id {
+			continue
+		}
+		found = true
+
+		for j := range outbox[i].Deliveries {
+			var delivery *_Delivery = &outbox[i].Deliveries[j]
+			if dead_only && _DELIVERY_DEAD != delivery.Status {
+				continue
+			}
+			delivery.Status = _DELIVERY_PENDING
+			delivery.Attempts = 0
+			delivery.Next_attempt = 0
+		}
+	}
+	if !found {
+		return false
+	}
+
+	if !writeOutbox(outbox) {
+		fmt.Println("Error writing the outbox")
+	}
+	processOutbox()
+
+	return true
+}
+
+/*
+readOutbox reads the outbox file.
+
+-----------------------------------------------------------
+
+– Returns:
+  - the outbox entries from the oldest to the newest (nil if there are none or if an error occurs)
+*/
+func readOutbox() []_OutboxEntry {
+	var outbox []_OutboxEntry = nil
+
+	var p_outbox_json *string = getOutboxPath().ReadTextFile()
+	if nil == p_outbox_json {
+		return nil
+	}
+	if err := json.Unmarshal([]byte(*p_outbox_json), &outbox); nil != err {
+		fmt.Println("Error reading the outbox: " + err.Error())
+
+		return nil
+	}
+
+	return outbox
+}
+
+/*
+writeOutbox writes the outbox file.
+
+-----------------------------------------------------------
+
+– Params:
+  - outbox – the outbox entries
+
+– Returns:
+  - true if the file was written, false otherwise
+*/
+func writeOutbox(outbox []_OutboxEntry) bool {
+	if nil == outbox {
+		outbox = []_OutboxEntry{}
+	}
+	outbox_json, err := json.Marshal(outbox)
+	if nil != err {
+		fmt.Println("Error writing the outbox: " + err.Error())
+
+		return false
+	}
+
+	return getOutboxPath().WriteTextFile(string(outbox_json))
+}
+
+/*
+readOutboxHtml reads the HTML of an outbox entry.
+
+-----------------------------------------------------------
+
+– Params:
+  - id – the ID of the entry
+
+– Returns:
+  - the HTML or "" if it couldn't be read
+*/
+func readOutboxHtml(id string) string {
+	var p_html *string = getOutboxHtmlPath(id).ReadTextFile()
+	if nil == p_html {
+		return ""
+	}
+
+	return *p_html
+}
+
+/*
+getOutboxHtmlPath gets the path of the file with the HTML of an outbox entry.
+
+-----------------------------------------------------------
+
+– Params:
+  - id – the ID of the entry
+
+– Returns:
+  - the path of the file
+*/
+func getOutboxHtmlPath(id string) Utils.GPath {
+	return moduleInfo_GL.ModDirsInfo.UserData.Add2("outbox/", id + ".html")
+}
+
+/*
+getOutboxPath gets the path of the outbox file.
+
+-----------------------------------------------------------
+
+– Returns:
+  - the path of the file
+*/
+func getOutboxPath() Utils.GPath {
+	return moduleInfo_GL.ModDirsInfo.UserData.Add2("outbox.json")
+}
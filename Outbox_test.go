@@ -0,0 +1,134 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package main
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// _TestBackend is an outbox backend that fails while failing is true and remembers what it delivered.
+type _TestBackend struct {
+	p_failing   *bool
+	p_delivered *[]string
+}
+
+func (backend _TestBackend) deliver(outboxEntry _OutboxEntry, html string, address string) error {
+	if *backend.p_failing {
+		return errors.New("backend down")
+	}
+	*backend.p_delivered = append(*backend.p_delivered, address + " " + outboxEntry.Subject + " " + html)
+
+	return nil
+}
+
+func TestAttemptDelivery(t *testing.T) {
+	var failing bool = true
+	var delivered []string = nil
+	outboxBackends_GL["test"] = _TestBackend{p_failing: &failing, p_delivered: &delivered}
+	defer delete(outboxBackends_GL, "test")
+
+	var outboxEntry _OutboxEntry = _OutboxEntry{Id: "1", Subject: "Subject"}
+	var delivery _Delivery = _Delivery{Destination: "test:someone", Status: _DELIVERY_PENDING}
+	var now time.Time = time.Unix(1700000000, 0)
+
+	// The retries are more and more apart.
+	var expected_delays []time.Duration = []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute}
+	for i, expected_delay := range expected_delays {
+		attemptDelivery(outboxEntry, "<p>Hi</p>", &delivery, now)
+		if _DELIVERY_PENDING != delivery.Status || i + 1 != delivery.Attempts || "backend down" != delivery.Last_error {
+			t.Fatalf("attempt %d: wrong delivery: %+v", i + 1, delivery)
+		}
+		if now.Add(expected_delay).Unix() != delivery.Next_attempt {
+			t.Errorf("attempt %d: next attempt in %ds, expected %v", i + 1, delivery.Next_attempt - now.Unix(),
+				expected_delay)
+		}
+	}
+	delivery.Attempts = 6
+	attemptDelivery(outboxEntry, "<p>Hi</p>", &delivery, now)
+	if now.Add(64 * time.Minute).Unix() != delivery.Next_attempt {
+		t.Errorf("attempt 7: next attempt in %ds, expected 64m", delivery.Next_attempt - now.Unix())
+	}
+
+	// Dead-lettered on the last attempt.
+	attemptDelivery(outboxEntry, "<p>Hi</p>", &delivery, now)
+	if _DELIVERY_DEAD != delivery.Status || _OUTBOX_MAX_ATTEMPTS != delivery.Attempts {
+		t.Errorf("expected a dead delivery after %d attempts: %+v", _OUTBOX_MAX_ATTEMPTS, delivery)
+	}
+
+	failing = false
+	delivery = _Delivery{Destination: "test:someone", Status: _DELIVERY_PENDING, Last_error: "old"}
+	attemptDelivery(outboxEntry, "<p>Hi</p>", &delivery, now)
+	if _DELIVERY_SENT != delivery.Status || "" != delivery.Last_error || 1 != len(delivered) ||
+				"someone Subject <p>Hi</p>" != delivered[0] {
+		t.Errorf("wrong successful delivery: %+v, %v", delivery, delivered)
+	}
+
+	// Without the HTML (lost file) or with an unknown backend, it's a failure.
+	var tests = []struct {
+		destination string
+		html        string
+	}{
+		{"test:someone", ""},
+		{"unknown:someone", "<p>Hi</p>"},
+	}
+	for _, test := range tests {
+		delivery = _Delivery{Destination: test.destination, Status: _DELIVERY_PENDING}
+		attemptDelivery(outboxEntry, test.html, &delivery, now)
+		if _DELIVERY_PENDING != delivery.Status || 1 != delivery.Attempts {
+			t.Errorf("%s with %q: expected a failed attempt, got %+v", test.destination, test.html, delivery)
+		}
+	}
+}
+
+func TestPruneOutbox(t *testing.T) {
+	var now time.Time = time.Unix(1700000000, 0)
+	var old int64 = now.Add(-_OUTBOX_KEEP_FINISHED - time.Hour).Unix()
+
+	var outbox []_OutboxEntry = []_OutboxEntry{
+		{Id: "old-sent", Created: old, Deliveries: []_Delivery{{Status: _DELIVERY_SENT}}},
+		{Id: "old-dead", Created: old, Deliveries: []_Delivery{{Status: _DELIVERY_SENT}, {Status: _DELIVERY_DEAD}}},
+		{Id: "old-pending", Created: old,
+			Deliveries: []_Delivery{{Status: _DELIVERY_DEAD}, {Status: _DELIVERY_PENDING}}},
+		{Id: "new-sent", Created: now.Unix(), Deliveries: []_Delivery{{Status: _DELIVERY_SENT}}},
+	}
+	outbox_kept, removed_ids := pruneOutbox(outbox, now)
+	if !reflect.DeepEqual([]string{"old-sent", "old-dead"}, removed_ids) || 2 != len(outbox_kept) ||
+				"old-pending" != outbox_kept[0].Id || "new-sent" != outbox_kept[1].Id {
+		t.Errorf("pruneOutbox() removed %v and kept %v", removed_ids, outbox_kept)
+	}
+
+	// Over the limit, the oldest finished entries are removed first - never the pending ones.
+	outbox = []_OutboxEntry{{Id: "pending", Created: now.Unix(), Deliveries: []_Delivery{{Status: _DELIVERY_PENDING}}}}
+	for i := 0; i < _OUTBOX_MAX_FINISHED + 2; i++ {
+		outbox = append(outbox, _OutboxEntry{Id: strconv.Itoa(i), Created: now.Unix(),
+			Deliveries: []_Delivery{{Status: _DELIVERY_SENT}}})
+	}
+	outbox_kept, removed_ids = pruneOutbox(outbox, now)
+	if !reflect.DeepEqual([]string{"0", "1"}, removed_ids) || _OUTBOX_MAX_FINISHED + 1 != len(outbox_kept) ||
+				"pending" != outbox_kept[0].Id || "2" != outbox_kept[1].Id {
+		t.Errorf("pruneOutbox() over the limit removed %v and kept %d entries", removed_ids, len(outbox_kept))
+	}
+}
@@ -0,0 +1,412 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Formats of the output feeds, which are also the extensions of their files.
+const (
+	_OUTPUT_FORMAT_ATOM string = "atom"
+	_OUTPUT_FORMAT_RSS  string = "rss"
+	_OUTPUT_FORMAT_JSON string = "json"
+)
+
+var outputFormats_GL []string = []string{_OUTPUT_FORMAT_ATOM, _OUTPUT_FORMAT_RSS, _OUTPUT_FORMAT_JSON}
+
+// _OUTPUT_MAX_ITEMS_DEF is the default maximum number of items of each output feed.
+const _OUTPUT_MAX_ITEMS_DEF int = 50
+
+// _OutputFeed is a feed generated from the notified items.
+type _OutputFeed struct {
+	// name is the name of the feed, used for its file and URL ("all", "category-<category>", "tag-<tag>" or
+	// "feed-<feed_num>")
+	name string
+	// title is the title of the feed
+	title string
+	// items are the items of the feed, from the newest to the oldest
+	items []_NotifiedItem
+}
+
+/*
+getOutputFeeds generates the output feeds from the notified items history (see buildOutputFeeds()).
+
+-----------------------------------------------------------
+
+– Params:
+  - max_items – the maximum number of items of each feed
+
+– Returns:
+  - the output feeds
+*/
+func getOutputFeeds(max_items int) []_OutputFeed {
+	return buildOutputFeeds(readAllNotifiedItems(), max_items)
+}
+
+/*
+buildOutputFeeds generates the output feeds from notified items: one with all the items, one per category, one per tag
+and one per feed. The items are deduplicated by URL (the same video may come from a channel and a playlist, for
+example).
+
+-----------------------------------------------------------
+
+– Params:
+  - notified_items – the notified items, from the newest to the oldest
+  - max_items – the maximum number of items of each feed
+
+– Returns:
+  - the output feeds
+*/
+func buildOutputFeeds(notified_items []_NotifiedItem, max_items int) []_OutputFeed {
+	var outputFeeds []_OutputFeed = []_OutputFeed{{
+		name:  "all",
+		title: "RSS Feed Notifier",
+	}}
+	var feeds_idxs map[string]int = map[string]int{"all": 0}
+	var urls_added map[string]map[string]bool = make(map[string]map[string]bool)
+
+	for _, notifiedItem := range notified_items {
+		// The names are used for the files, so the categories and tags must not have "/" or "..", for example.
+		var names_titles [][2]string = [][2]string{
+			{"all", ""},
+			{"category-" + sanitizeFileName(strings.ToLower(notifiedItem.Category)),
+				"RSS Feed Notifier – " + notifiedItem.Category},
+			{"feed-" + strconv.Itoa(notifiedItem.Feed_num), notifiedItem.Feed_title},
+		}
+		for _, tag := range notifiedItem.Tags {
+			names_titles = append(names_titles, [2]string{"tag-" + sanitizeFileName(tag), "RSS Feed Notifier – " + tag})
+		}
+		for _, name_title := range names_titles {
+			var name string = name_title[0]
+			idx, ok := feeds_idxs[name]
+			if !ok {
+				idx = len(outputFeeds)
+				feeds_idxs[name] = idx
+				outputFeeds = append(outputFeeds, _OutputFeed{
+					name:  name,
+					title: name_title[1],
+				})
+			}
+			if nil == urls_added[name] {
+				urls_added[name] = make(map[string]bool)
+			}
+			if urls_added[name][notifiedItem.Url] || len(outputFeeds[idx].items) >= max_items {
+				continue
+			}
+			urls_added[name][notifiedItem.Url] = true
+			outputFeeds[idx].items = append(outputFeeds[idx].items, notifiedItem)
+		}
+	}
+
+	return outputFeeds
+}
+
+/*
+renderOutputFeed renders an output feed in the given format.
+
+-----------------------------------------------------------
+
+– Params:
+  - outputFeed – the output feed
+  - format – one of the _OUTPUT_FORMAT_ constants
+  - self_url – the URL where the feed is (can be empty)
+
+– Returns:
+  - the rendered feed
+  - the MIME type of the rendered feed
+*/
+func renderOutputFeed(outputFeed _OutputFeed, format string, self_url string) (string, string) {
+	switch format {
+		case _OUTPUT_FORMAT_ATOM: {
+			return renderAtom(outputFeed, self_url), "application/atom+xml; charset=utf-8"
+		}
+		case _OUTPUT_FORMAT_RSS: {
+			return renderRss(outputFeed, self_url), "application/rss+xml; charset=utf-8"
+		}
+		case _OUTPUT_FORMAT_JSON: {
+			return renderJsonFeed(outputFeed, self_url), "application/feed+json; charset=utf-8"
+		}
+	}
+
+	return "", ""
+}
+
+/*
+writeOutputFeeds writes all the output feeds in all formats to a directory.
+
+-----------------------------------------------------------
+
+– Params:
+  - dir – the directory
+  - max_items – the maximum number of items of each feed
+*/
+func writeOutputFeeds(dir string, max_items int) {
+	if err := os.MkdirAll(dir, 0o755); nil != err {
+		fmt.Println("Error creating the output feeds directory: " + err.Error())
+
+		return
+	}
+
+	for _, outputFeed := range getOutputFeeds(max_items) {
+		for _, format := range outputFormats_GL {
+			rendered, _ := renderOutputFeed(outputFeed, format, "")
+			var file_path string = filepath.Join(dir, outputFeed.name+"."+format)
+			if err := os.WriteFile(file_path, []byte(rendered), 0o644); nil != err {
+				fmt.Println("Error writing an output feed: " + err.Error())
+			}
+		}
+	}
+}
+
+/*
+getItemDate gets the date to show for an item: the publishing date or, if unknown, the notification date.
+
+-----------------------------------------------------------
+
+– Params:
+  - notifiedItem – the item
+
+– Returns:
+  - the date
+*/
+func getItemDate(notifiedItem _NotifiedItem) time.Time {
+	if 0 != notifiedItem.Published {
+		return time.Unix(notifiedItem.Published, 0).UTC()
+	}
+
+	return time.Unix(notifiedItem.Notified, 0).UTC()
+}
+
+/*
+getItemCategories gets the categories to put on an item: the title of its feed and the feed's tags.
+
+-----------------------------------------------------------
+
+– Params:
+  - notifiedItem – the item
+
+– Returns:
+  - the categories
+*/
+func getItemCategories(notifiedItem _NotifiedItem) []string {
+	return append([]string{notifiedItem.Feed_title}, notifiedItem.Tags...)
+}
+
+/*
+getOutputFeedUpdated gets the last update date of an output feed.
+
+-----------------------------------------------------------
+
+– Params:
+  - outputFeed – the output feed
+
+– Returns:
+  - the date of the newest item or now if there are no items
+*/
+func getOutputFeedUpdated(outputFeed _OutputFeed) time.Time {
+	if 0 == len(outputFeed.items) {
+		return time.Now().UTC()
+	}
+
+	return getItemDate(outputFeed.items[0])
+}
+
+type _AtomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type _AtomText struct {
+	Type  string `xml:"type,attr,omitempty"`
+	Value string `xml:",chardata"`
+}
+
+type _AtomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type _AtomEntry struct {
+	Title      string          `xml:"title"`
+	Id         string          `xml:"id"`
+	Link       _AtomLink       `xml:"link"`
+	Updated    string          `xml:"updated"`
+	Published  string          `xml:"published,omitempty"`
+	Author     string          `xml:"author>name,omitempty"`
+	Summary    *_AtomText      `xml:"summary,omitempty"`
+	Categories []_AtomCategory `xml:"category"`
+}
+
+type _AtomFeed struct {
+	XMLName xml.Name     `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string       `xml:"title"`
+	Id      string       `xml:"id"`
+	Updated string       `xml:"updated"`
+	Links   []_AtomLink  `xml:"link"`
+	Entries []_AtomEntry `xml:"entry"`
+}
+
+func renderAtom(outputFeed _OutputFeed, self_url string) string {
+	var atomFeed _AtomFeed = _AtomFeed{
+		Title:   outputFeed.title,
+		Id:      "urn:rssfeednotifier:" + outputFeed.name,
+		Updated: getOutputFeedUpdated(outputFeed).Format(time.RFC3339),
+	}
+	if "" != self_url {
+		atomFeed.Links = append(atomFeed.Links, _AtomLink{Href: self_url, Rel: "self"})
+	}
+	for _, notifiedItem := range outputFeed.items {
+		var atomEntry _AtomEntry = _AtomEntry{
+			Title:   notifiedItem.Title,
+			Id:      notifiedItem.Url,
+			Link:    _AtomLink{Href: notifiedItem.Url, Rel: "alternate"},
+			Updated: getItemDate(notifiedItem).Format(time.RFC3339),
+			Author:  notifiedItem.Author,
+		}
+		if 0 != notifiedItem.Published {
+			atomEntry.Published = atomEntry.Updated
+		}
+		if "" != notifiedItem.Description {
+			atomEntry.Summary = &_AtomText{Type: "html", Value: notifiedItem.Description}
+		}
+		for _, category := range getItemCategories(notifiedItem) {
+			atomEntry.Categories = append(atomEntry.Categories, _AtomCategory{Term: category})
+		}
+		atomFeed.Entries = append(atomFeed.Entries, atomEntry)
+	}
+
+	rendered, err := xml.MarshalIndent(atomFeed, "", "\t")
+	if nil != err {
+		return ""
+	}
+
+	return xml.Header + string(rendered)
+}
+
+type _RssItem struct {
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	Guid        string   `xml:"guid"`
+	PubDate     string   `xml:"pubDate"`
+	Author      string   `xml:"http://purl.org/dc/elements/1.1/ creator,omitempty"`
+	Description string   `xml:"description,omitempty"`
+	Categories  []string `xml:"category"`
+}
+
+type _RssFeed struct {
+	XMLName xml.Name `xml:"rss"`
+	Version string   `xml:"version,attr"`
+	Channel struct {
+		Title         string     `xml:"title"`
+		Link          string     `xml:"link"`
+		Description   string     `xml:"description"`
+		LastBuildDate string     `xml:"lastBuildDate"`
+		Items         []_RssItem `xml:"item"`
+	} `xml:"channel"`
+}
+
+func renderRss(outputFeed _OutputFeed, self_url string) string {
+	var rssFeed _RssFeed = _RssFeed{
+		Version: "2.0",
+	}
+	rssFeed.Channel.Title = outputFeed.title
+	rssFeed.Channel.Link = self_url
+	rssFeed.Channel.Description = outputFeed.title
+	rssFeed.Channel.LastBuildDate = getOutputFeedUpdated(outputFeed).Format(time.RFC1123Z)
+	for _, notifiedItem := range outputFeed.items {
+		rssFeed.Channel.Items = append(rssFeed.Channel.Items, _RssItem{
+			Title:       notifiedItem.Title,
+			Link:        notifiedItem.Url,
+			Guid:        notifiedItem.Url,
+			PubDate:     getItemDate(notifiedItem).Format(time.RFC1123Z),
+			Author:      notifiedItem.Author,
+			Description: notifiedItem.Description,
+			Categories:  getItemCategories(notifiedItem),
+		})
+	}
+
+	rendered, err := xml.MarshalIndent(rssFeed, "", "\t")
+	if nil != err {
+		return ""
+	}
+
+	return xml.Header + string(rendered)
+}
+
+func renderJsonFeed(outputFeed _OutputFeed, self_url string) string {
+	type jsonFeedAuthor struct {
+		Name string `json:"name"`
+	}
+	type jsonFeedItem struct {
+		Id            string           `json:"id"`
+		Url           string           `json:"url"`
+		Title         string           `json:"title"`
+		ContentHtml   string           `json:"content_html,omitempty"`
+		Image         string           `json:"image,omitempty"`
+		DatePublished string           `json:"date_published"`
+		Authors       []jsonFeedAuthor `json:"authors,omitempty"`
+		Tags          []string         `json:"tags,omitempty"`
+	}
+	type jsonFeed struct {
+		Version string         `json:"version"`
+		Title   string         `json:"title"`
+		FeedUrl string         `json:"feed_url,omitempty"`
+		Items   []jsonFeedItem `json:"items"`
+	}
+
+	var feed jsonFeed = jsonFeed{
+		Version: "https://jsonfeed.org/version/1.1",
+		Title:   outputFeed.title,
+		FeedUrl: self_url,
+		Items:   make([]jsonFeedItem, 0, len(outputFeed.items)),
+	}
+	for _, notifiedItem := range outputFeed.items {
+		var item jsonFeedItem = jsonFeedItem{
+			Id:            notifiedItem.Url,
+			Url:           notifiedItem.Url,
+			Title:         notifiedItem.Title,
+			ContentHtml:   notifiedItem.Description,
+			Image:         notifiedItem.Image,
+			DatePublished: getItemDate(notifiedItem).Format(time.RFC3339),
+			Tags:          getItemCategories(notifiedItem),
+		}
+		if "" != notifiedItem.Author {
+			item.Authors = []jsonFeedAuthor{{Name: notifiedItem.Author}}
+		}
+		feed.Items = append(feed.Items, item)
+	}
+
+	rendered, err := json.MarshalIndent(feed, "", "\t")
+	if nil != err {
+		return ""
+	}
+
+	return string(rendered)
+}
@@ -0,0 +1,162 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+)
+
+func TestRenderOutputFeed(t *testing.T) {
+	var outputFeed _OutputFeed = _OutputFeed{
+		name:  "tag-news",
+		title: "RSS Feed Notifier – news",
+		items: []_NotifiedItem{
+			{
+				Feed_num:    3,
+				Feed_title:  "A & B",
+				Tags:        []string{"news"},
+				Title:       "First <item>",
+				Url:         "https://example.com/1?a=1&b=2",
+				Author:      "Someone",
+				Description: "<p>Hello &amp; bye</p>",
+				Image:       "https://example.com/1.jpg",
+				Published:   time.Date(2023, 11, 20, 10, 0, 0, 0, time.UTC).Unix(),
+				Notified:    time.Date(2023, 11, 20, 11, 0, 0, 0, time.UTC).Unix(),
+			},
+			{
+				Feed_num:   3,
+				Feed_title: "A & B",
+				Title:      "Second",
+				Url:        "https://example.com/2",
+				Notified:   time.Date(2023, 11, 19, 9, 0, 0, 0, time.UTC).Unix(),
+			},
+		},
+	}
+
+	var tests = []struct {
+		format    string
+		mime_type string
+		feed_type string
+	}{
+		{_OUTPUT_FORMAT_ATOM, "application/atom+xml; charset=utf-8", "atom"},
+		{_OUTPUT_FORMAT_RSS, "application/rss+xml; charset=utf-8", "rss"},
+		{_OUTPUT_FORMAT_JSON, "application/feed+json; charset=utf-8", "json"},
+	}
+	for _, test := range tests {
+		rendered, mime_type := renderOutputFeed(outputFeed, test.format, "https://example.com/feeds/tag-news")
+		if test.mime_type != mime_type {
+			t.Errorf("%s: got MIME type %q", test.format, mime_type)
+		}
+
+		// What's rendered must be read back the same by a feed parser.
+		parsed_feed, err := gofeed.NewParser().ParseString(rendered)
+		if nil != err {
+			t.Errorf("%s: the rendered feed doesn't parse: %v\n%s", test.format, err, rendered)
+
+			continue
+		}
+		if test.feed_type != parsed_feed.FeedType || outputFeed.title != parsed_feed.Title ||
+					2 != len(parsed_feed.Items) {
+			t.Errorf("%s: got type %q, title %q and %d items", test.format, parsed_feed.FeedType, parsed_feed.Title,
+				len(parsed_feed.Items))
+
+			continue
+		}
+
+		var first_item *gofeed.Item = parsed_feed.Items[0]
+		if "First <item>" != first_item.Title || "https://example.com/1?a=1&b=2" != first_item.Link ||
+					"https://example.com/1?a=1&b=2" != first_item.GUID {
+			t.Errorf("%s: wrong first item: %q, %q, %q", test.format, first_item.Title, first_item.Link,
+				first_item.GUID)
+		}
+		var description string = first_item.Description + first_item.Content
+		if !strings.Contains(description, "<p>Hello &amp; bye</p>") {
+			t.Errorf("%s: wrong description: %q", test.format, description)
+		}
+		if nil == first_item.PublishedParsed ||
+					!first_item.PublishedParsed.Equal(time.Unix(outputFeed.items[0].Published, 0)) {
+			t.Errorf("%s: wrong date: %v", test.format, first_item.PublishedParsed)
+		}
+		if 2 != len(first_item.Categories) || "A & B" != first_item.Categories[0] ||
+					"news" != first_item.Categories[1] {
+			t.Errorf("%s: wrong categories: %v", test.format, first_item.Categories)
+		}
+
+		// Without a publishing date, the notification one is used.
+		var second_item *gofeed.Item = parsed_feed.Items[1]
+		var second_date *time.Time = second_item.PublishedParsed
+		if nil == second_date {
+			second_date = second_item.UpdatedParsed
+		}
+		if nil == second_date || !second_date.Equal(time.Unix(outputFeed.items[1].Notified, 0)) {
+			t.Errorf("%s: wrong date of the item without one: %v", test.format, second_date)
+		}
+	}
+
+	if rendered, _ := renderOutputFeed(outputFeed, "xml", ""); "" != rendered {
+		t.Error("expected nothing for an unknown format")
+	}
+}
+
+func TestBuildOutputFeeds(t *testing.T) {
+	var notified_items []_NotifiedItem = []_NotifiedItem{
+		{Feed_num: 1, Category: "YouTube", Tags: []string{"../../etc", "music"}, Url: "https://example.com/1"},
+		{Feed_num: 2, Category: "A/B", Tags: []string{"music"}, Url: "https://example.com/2"},
+		// The same item from another feed.
+		{Feed_num: 3, Category: "YouTube", Url: "https://example.com/1"},
+	}
+
+	var names_items map[string]int = make(map[string]int)
+	for _, outputFeed := range buildOutputFeeds(notified_items, 10) {
+		if strings.ContainsAny(outputFeed.name, "/\\") || strings.HasPrefix(outputFeed.name, ".") {
+			t.Errorf("unsafe output feed name: %q", outputFeed.name)
+		}
+		names_items[outputFeed.name] = len(outputFeed.items)
+	}
+
+	var expected map[string]int = map[string]int{
+		"all":              2,
+		"category-youtube": 1,
+		"category-a_b":     1,
+		"tag-_.._etc":      1,
+		"tag-music":        2,
+		"feed-1":           1,
+		"feed-2":           1,
+		"feed-3":           1,
+	}
+	if len(names_items) != len(expected) {
+		t.Errorf("output feeds = %v, expected %v", names_items, expected)
+	}
+	for name, items := range expected {
+		if names_items[name] != items {
+			t.Errorf("output feed %q has %d items, expected %d", name, names_items[name], items)
+		}
+	}
+
+	if outputFeeds := buildOutputFeeds(notified_items, 1); 1 != len(outputFeeds[0].items) {
+		t.Errorf("max_items not applied: %d items", len(outputFeeds[0].items))
+	}
+}
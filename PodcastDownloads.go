@@ -0,0 +1,653 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package main
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+	ext "github.com/mmcdole/gofeed/extensions"
+
+	"Utils"
+)
+
+// Statuses of the podcast downloads.
+const (
+	_DOWNLOAD_PENDING string = "pending"
+	_DOWNLOAD_DONE    string = "done"
+	_DOWNLOAD_FAILED  string = "failed"
+	_DOWNLOAD_DELETED string = "deleted"
+)
+
+// _DOWNLOAD_MAX_ATTEMPTS is the number of failed attempts after which a download is given up.
+const _DOWNLOAD_MAX_ATTEMPTS int = 5
+// _DOWNLOAD_TIMEOUT is the maximum time of one download attempt (it's resumed on the next attempt).
+const _DOWNLOAD_TIMEOUT time.Duration = 1 * time.Hour
+// _DOWNLOAD_FILENAME_DEF is the default template of the names of the downloaded episodes.
+const _DOWNLOAD_FILENAME_DEF string = "{feed}/{date} - {title}.{ext}"
+
+// _PodcastDownload is a download of a podcast episode. It's exported to JSON, so the fields are exported.
+type _PodcastDownload struct {
+	// Feed_num is the number of the feed of the episode
+	Feed_num int
+	// Title is the title of the episode
+	Title string
+	// Published is when the episode was published in Unix seconds (0 if unknown)
+	Published int64
+	// Enclosure_url is the URL of the episode's file
+	Enclosure_url string
+	// Length is the size of the file announced by the feed (0 if unknown - and it's often wrong)
+	Length int64
+	// Hash_algo is the algorithm of Hash ("md5", "sha-1" or "sha-256")
+	Hash_algo string
+	// Hash is the checksum of the file announced by the feed (Media RSS), in hexadecimal ("" if there's none)
+	Hash string
+	// File_path is where the file is saved
+	File_path string
+	// Status is one of the _DOWNLOAD_ constants
+	Status string
+	// Attempts is the number of failed attempts
+	Attempts int
+	// Last_error is the error of the last failed attempt
+	Last_error string
+	// Sha256 is the SHA-256 of the downloaded file, in hexadecimal
+	Sha256 string
+}
+
+// podcastDownloadsMutex_GL protects the podcast downloads file (the downloads run on their own goroutine).
+var podcastDownloadsMutex_GL sync.Mutex
+// podcastDownloading_GL is true while the downloads are being processed.
+var podcastDownloading_GL atomic.Bool
+
+/*
+queuePodcastDownload queues the download of an episode (if it's not queued or downloaded already).
+
+-----------------------------------------------------------
+
+– Params:
+  - feedInfo – the information of the feed
+  - feed_title – the title of the feed
+  - feed_item – the item of the episode
+*/
+func queuePodcastDownload(feedInfo _FeedInfo, feed_title string, feed_item *gofeed.Item) {
+	var enclosure *gofeed.Enclosure = getEpisodeEnclosure(feed_item)
+	if nil == enclosure || "" == enclosure.URL {
+		return
+	}
+	if "" == feedInfo.Download_dir {
+		fmt.Println("No Download_dir for the podcast feed " + strconv.Itoa(feedInfo.Feed_num))
+
+		return
+	}
+
+	podcastDownloadsMutex_GL.Lock()
+	defer podcastDownloadsMutex_GL.Unlock()
+
+	var downloads map[string]_PodcastDownload = readPodcastDownloads()
+	if _, ok := downloads[enclosure.URL]; ok {
+		return
+	}
+
+	var download _PodcastDownload = _PodcastDownload{
+		Feed_num:      feedInfo.Feed_num,
+		Title:         feed_item.Title,
+		Enclosure_url: enclosure.URL,
+		Status:        _DOWNLOAD_PENDING,
+	}
+	if nil != feed_item.PublishedParsed {
+		download.Published = feed_item.PublishedParsed.Unix()
+	}
+	download.Length, _ = strconv.ParseInt(enclosure.Length, 10, 64)
+	download.Hash_algo, download.Hash = getEpisodeHash(feed_item)
+	download.File_path = getEpisodeFreePath(filepath.Join(feedInfo.Download_dir, getEpisodeFileName(feedInfo,
+		feed_title, feed_item, enclosure)), downloads)
+
+	downloads[enclosure.URL] = download
+	writePodcastDownloads(downloads)
+	fmt.Println("Episode download queued: " + download.File_path)
+}
+
+/*
+processPodcastDownloads downloads the pending episodes and applies the retention of the feeds. If it's already running,
+it returns right away.
+*/
+func processPodcastDownloads() {
+	if !podcastDownloading_GL.CompareAndSwap(false, true) {
+		return
+	}
+	defer podcastDownloading_GL.Store(false)
+
+	podcastDownloadsMutex_GL.Lock()
+	var downloads map[string]_PodcastDownload = readPodcastDownloads()
+	podcastDownloadsMutex_GL.Unlock()
+
+	for enclosure_url, download := range downloads {
+		if _DOWNLOAD_PENDING != download.Status {
+			continue
+		}
+
+		fmt.Println("Downloading episode: " + download.Title)
+		var sha256_hex string = ""
+		var err error = downloadEpisode(download, &sha256_hex)
+
+		podcastDownloadsMutex_GL.Lock()
+		var downloads_now map[string]_PodcastDownload = readPodcastDownloads()
+		if nil == err {
+			download.Status = _DOWNLOAD_DONE
+			download.Sha256 = sha256_hex
+			download.Last_error = ""
+		} else {
+			fmt.Println("Error downloading episode " + download.Title + ": " + err.Error())
+			download.Attempts++
+			download.Last_error = err.Error()
+			if download.Attempts >= _DOWNLOAD_MAX_ATTEMPTS {
+				download.Status = _DOWNLOAD_FAILED
+			}
+		}
+		downloads_now[enclosure_url] = download
+		writePodcastDownloads(downloads_now)
+		podcastDownloadsMutex_GL.Unlock()
+	}
+
+	applyPodcastRetention()
+}
+
+/*
+downloadEpisode downloads an episode, resuming a previous partial download if there's one, and verifies it.
+
+-----------------------------------------------------------
+
+– Params:
+  - download – the download
+  - p_sha256_hex – pointer to where to put the SHA-256 of the file
+
+– Returns:
+  - the error, if any
+*/
+func downloadEpisode(download _PodcastDownload, p_sha256_hex *string) error {
+	var part_path string = download.File_path + ".part"
+	if err := os.MkdirAll(filepath.Dir(part_path), 0o755); nil != err {
+		return err
+	}
+
+	var offset int64 = 0
+	if file_info, err := os.Stat(part_path); nil == err {
+		offset = file_info.Size()
+	}
+
+	request, err := http.NewRequest(http.MethodGet, download.Enclosure_url, nil)
+	if nil != err {
+		return err
+	}
+	if offset > 0 {
+		request.Header.Set("Range", "bytes=" + strconv.FormatInt(offset, 10) + "-")
+	}
+	var client http.Client = http.Client{
+		Timeout: _DOWNLOAD_TIMEOUT,
+	}
+	response, err := client.Do(request)
+	if nil != err {
+		return err
+	}
+	defer response.Body.Close()
+
+	var open_flags int = os.O_CREATE | os.O_WRONLY
+	var write_body bool = true
+	switch response.StatusCode {
+		case http.StatusOK: {
+			// Whole file (the server doesn't support ranges or there was no partial download)
+			open_flags |= os.O_TRUNC
+		}
+		case http.StatusPartialContent: {
+			// Appending a range not starting where the partial download ends would corrupt the file.
+			if getContentRangeStart(response.Header.Get("Content-Range")) != offset {
+				if 0 == offset {
+					return errors.New("unexpected Content-Range " + response.Header.Get("Content-Range"))
+				}
+				fmt.Println("Wrong range to resume the episode - downloading it again: " + download.Title)
+				_ = response.Body.Close()
+				if err = os.Remove(part_path); nil != err {
+					return err
+				}
+
+				return downloadEpisode(download, p_sha256_hex)
+			}
+			open_flags |= os.O_APPEND
+		}
+		case http.StatusRequestedRangeNotSatisfiable: {
+			// The partial download is already the whole file.
+			write_body = false
+		}
+		default: {
+			return errors.New("HTTP status " + response.Status)
+		}
+	}
+	if write_body {
+		file, err := os.OpenFile(part_path, open_flags, 0o644)
+		if nil != err {
+			return err
+		}
+		_, err = io.Copy(file, response.Body)
+		if err_close := file.Close(); nil == err {
+			err = err_close
+		}
+		if nil != err {
+			return err
+		}
+	}
+
+	if err = verifyEpisode(download, part_path, p_sha256_hex); nil != err {
+		// A corrupted file would be corrupted again if resumed.
+		_ = os.Remove(part_path)
+
+		return err
+	}
+
+	return os.Rename(part_path, download.File_path)
+}
+
+/*
+getContentRangeStart gets where the range of a Content-Range header starts.
+
+-----------------------------------------------------------
+
+– Params:
+  - content_range – the header, like "bytes 1234-9999/10000"
+
+– Returns:
+  - the position of the first byte of the range or -1 if the header is invalid
+*/
+func getContentRangeStart(content_range string) int64 {
+	range_str, ok := strings.CutPrefix(strings.TrimSpace(content_range), "bytes ")
+	if !ok {
+		return -1
+	}
+	start_str, _, ok := strings.Cut(range_str, "-")
+	if !ok {
+		return -1
+	}
+	start, err := strconv.ParseInt(strings.TrimSpace(start_str), 10, 64)
+	if nil != err || start < 0 {
+		return -1
+	}
+
+	return start
+}
+
+/*
+verifyEpisode verifies a downloaded episode with the checksum announced by the feed (if there's one) and computes its
+SHA-256. The size announced by the feed is only checked for a warning, since it's often wrong.
+
+-----------------------------------------------------------
+
+– Params:
+  - download – the download
+  - file_path – the path of the downloaded file
+  - p_sha256_hex – pointer to where to put the SHA-256 of the file
+
+– Returns:
+  - the error if the verification failed or if the file couldn't be read
+*/
+func verifyEpisode(download _PodcastDownload, file_path string, p_sha256_hex *string) error {
+	file, err := os.Open(file_path)
+	if nil != err {
+		return err
+	}
+	defer file.Close()
+
+	var sha256_hash hash.Hash = sha256.New()
+	var writers []io.Writer = []io.Writer{sha256_hash}
+	var feed_hash hash.Hash = nil
+	switch strings.ToLower(download.Hash_algo) {
+		case "md5": {
+			feed_hash = md5.New()
+		}
+		case "sha-1", "sha1": {
+			feed_hash = sha1.New()
+		}
+		case "sha-256", "sha256": {
+			feed_hash = sha256.New()
+		}
+	}
+	if nil != feed_hash && "" != download.Hash {
+		writers = append(writers, feed_hash)
+	}
+
+	size, err := io.Copy(io.MultiWriter(writers...), file)
+	if nil != err {
+		return err
+	}
+
+	if download.Length > 0 && size != download.Length {
+		fmt.Println("Warning: size of " + download.Title + " is " + strconv.FormatInt(size, 10) + " bytes and the " +
+			"feed says " + strconv.FormatInt(download.Length, 10))
+	}
+	if nil != feed_hash && "" != download.Hash {
+		if !strings.EqualFold(hex.EncodeToString(feed_hash.Sum(nil)), download.Hash) {
+			return errors.New("checksum mismatch (" + download.Hash_algo + ")")
+		}
+	}
+	*p_sha256_hex = hex.EncodeToString(sha256_hash.Sum(nil))
+
+	return nil
+}
+
+/*
+applyPodcastRetention deletes the downloaded episodes over the Download_keep_count or older than the Download_keep_days
+of their feeds.
+*/
+func applyPodcastRetention() {
+	var feedsInfo []_FeedInfo = getFeedsInfo()
+
+	podcastDownloadsMutex_GL.Lock()
+	defer podcastDownloadsMutex_GL.Unlock()
+
+	var downloads map[string]_PodcastDownload = readPodcastDownloads()
+	var modified bool = false
+	for _, feedInfo := range feedsInfo {
+		if !feedInfo.Podcast_download || (feedInfo.Download_keep_count <= 0 && feedInfo.Download_keep_days <= 0) {
+			continue
+		}
+
+		var enclosure_urls []string = nil
+		for enclosure_url, download := range downloads {
+			if download.Feed_num == feedInfo.Feed_num && _DOWNLOAD_DONE == download.Status {
+				enclosure_urls = append(enclosure_urls, enclosure_url)
+			}
+		}
+		// Newest first
+		sort.Slice(enclosure_urls, func(i, j int) bool {
+			return downloads[enclosure_urls[i]].Published > downloads[enclosure_urls[j]].Published
+		})
+
+		var oldest_kept int64 = 0
+		if feedInfo.Download_keep_days > 0 {
+			oldest_kept = time.Now().AddDate(0, 0, -feedInfo.Download_keep_days).Unix()
+		}
+		for i, enclosure_url := range enclosure_urls {
+			var download _PodcastDownload = downloads[enclosure_url]
+			var over_count bool = feedInfo.Download_keep_count > 0 && i >= feedInfo.Download_keep_count
+			var too_old bool = 0 != oldest_kept && 0 != download.Published && download.Published < oldest_kept
+			if !over_count && !too_old {
+				continue
+			}
+
+			if err := os.Remove(download.File_path); nil != err && !os.IsNotExist(err) {
+				fmt.Println("Error deleting episode " + download.File_path + ": " + err.Error())
+
+				continue
+			}
+			fmt.Println("Episode deleted (retention): " + download.File_path)
+			download.Status = _DOWNLOAD_DELETED
+			downloads[enclosure_url] = download
+			modified = true
+		}
+	}
+
+	if modified {
+		writePodcastDownloads(downloads)
+	}
+}
+
+/*
+getEpisodeHash gets the checksum of an episode's file announced by the feed, from Media RSS's media:hash (on the item
+or on its media:content).
+
+-----------------------------------------------------------
+
+– Params:
+  - feed_item – the item of the episode
+
+– Returns:
+  - the algorithm ("md5" by default, like Media RSS says)
+  - the checksum or "" if there's none
+*/
+func getEpisodeHash(feed_item *gofeed.Item) (string, string) {
+	var media_ext map[string][]ext.Extension = feed_item.Extensions["media"]
+	if nil == media_ext {
+		return "", ""
+	}
+
+	var hashes []ext.Extension = media_ext["hash"]
+	for _, content := range media_ext["content"] {
+		hashes = append(hashes, content.Children["hash"]...)
+	}
+	for _, hash_ext := range hashes {
+		if "" == strings.TrimSpace(hash_ext.Value) {
+			continue
+		}
+		var algo string = hash_ext.Attrs["algo"]
+		if "" == algo {
+			algo = "md5"
+		}
+
+		return algo, strings.TrimSpace(hash_ext.Value)
+	}
+
+	return "", ""
+}
+
+/*
+getEpisodeFileName gets the name of an episode's file from the feed's Download_filename template.
+
+The template may have "/" for directories and these placeholders: {feed} (the title of the feed), {feed_num}, {title},
+{date} (publishing date as 2006-01-02), {season}, {episode} and {ext}.
+
+-----------------------------------------------------------
+
+– Params:
+  - feedInfo – the information of the feed
+  - feed_title – the title of the feed
+  - feed_item – the item of the episode
+  - enclosure – the enclosure of the episode
+
+– Returns:
+  - the relative path of the file
+*/
+func getEpisodeFileName(feedInfo _FeedInfo, feed_title string, feed_item *gofeed.Item,
+			enclosure *gofeed.Enclosure) string {
+	var file_name_template string = feedInfo.Download_filename
+	if "" == file_name_template {
+		file_name_template = _DOWNLOAD_FILENAME_DEF
+	}
+
+	var date string = "0000-00-00"
+	if nil != feed_item.PublishedParsed {
+		date = feed_item.PublishedParsed.Format("2006-01-02")
+	}
+	var season string = ""
+	var episode string = ""
+	if nil != feed_item.ITunesExt {
+		season = feed_item.ITunesExt.Season
+		episode = feed_item.ITunesExt.Episode
+	}
+
+	var replacer *strings.Replacer = strings.NewReplacer(
+		"{feed}", sanitizeFileName(feed_title),
+		"{feed_num}", strconv.Itoa(feedInfo.Feed_num),
+		"{title}", sanitizeFileName(feed_item.Title),
+		"{date}", date,
+		"{season}", sanitizeFileName(season),
+		"{episode}", sanitizeFileName(episode),
+		"{ext}", getEnclosureExtension(enclosure),
+	)
+
+	// Clean() also removes any ".." that could take the file out of the directory.
+	return filepath.Clean("/" + replacer.Replace(file_name_template))[1:]
+}
+
+/*
+getEpisodeFreePath gets a path for an episode's file that is not used yet, neither by a file nor by another download
+(episodes with the same title and date would get the same path).
+
+-----------------------------------------------------------
+
+– Params:
+  - file_path – the wanted path
+  - downloads – the downloads mapped by enclosure URL
+
+– Returns:
+  - the wanted path or, if it's used, the path with " (2)", " (3)", ... appended to the name
+*/
+func getEpisodeFreePath(file_path string, downloads map[string]_PodcastDownload) string {
+	var used_paths map[string]bool = make(map[string]bool)
+	for _, download := range downloads {
+		used_paths[download.File_path] = true
+	}
+
+	var extension string = filepath.Ext(file_path)
+	var file_path_free string = file_path
+	for i := 2; ; i++ {
+		if !used_paths[file_path_free] {
+			if _, err := os.Stat(file_path_free); os.IsNotExist(err) {
+				return file_path_free
+			}
+		}
+		file_path_free = strings.TrimSuffix(file_path, extension) + " (" + strconv.Itoa(i) + ")" + extension
+	}
+}
+
+/*
+getEnclosureExtension gets the file extension of an enclosure, from its URL or from its MIME type.
+
+-----------------------------------------------------------
+
+– Params:
+  - enclosure – the enclosure
+
+– Returns:
+  - the extension without the dot ("bin" if it's unknown)
+*/
+func getEnclosureExtension(enclosure *gofeed.Enclosure) string {
+	if parsed_url, err := url.Parse(enclosure.URL); nil == err {
+		var extension string = strings.TrimPrefix(path.Ext(parsed_url.Path), ".")
+		if "" != extension && len(extension) <= 5 {
+			return sanitizeFileName(extension)
+		}
+	}
+	if extensions, err := mime.ExtensionsByType(enclosure.Type); nil == err && len(extensions) > 0 {
+		return strings.TrimPrefix(extensions[0], ".")
+	}
+
+	return "bin"
+}
+
+/*
+sanitizeFileName makes a string safe to be used as (part of) a file name.
+
+-----------------------------------------------------------
+
+– Params:
+  - name – the string
+
+– Returns:
+  - the string without the characters not allowed in file names, and with at most 150 characters
+*/
+func sanitizeFileName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r < 32 || strings.ContainsRune(`/\:*?"<>|`, r) {
+			return '_'
+		}
+
+		return r
+	}, name)
+	name = strings.Trim(strings.TrimSpace(name), ".")
+	if runes := []rune(name); len(runes) > 150 {
+		name = strings.TrimSpace(string(runes[:150]))
+	}
+
+	return name
+}
+
+/*
+readPodcastDownloads reads the podcast downloads file.
+
+-----------------------------------------------------------
+
+– Returns:
+  - the downloads mapped by enclosure URL (empty if there are none or if an error occurs)
+*/
+func readPodcastDownloads() map[string]_PodcastDownload {
+	var downloads map[string]_PodcastDownload = make(map[string]_PodcastDownload)
+
+	var p_downloads_json *string = getPodcastDownloadsPath().ReadTextFile()
+	if nil == p_downloads_json {
+		return downloads
+	}
+	if err := json.Unmarshal([]byte(*p_downloads_json), &downloads); nil != err {
+		fmt.Println("Error reading podcast downloads: " + err.Error())
+
+		return make(map[string]_PodcastDownload)
+	}
+
+	return downloads
+}
+
+/*
+writePodcastDownloads writes the podcast downloads file.
+
+-----------------------------------------------------------
+
+– Params:
+  - downloads – the downloads mapped by enclosure URL
+*/
+func writePodcastDownloads(downloads map[string]_PodcastDownload) {
+	downloads_json, err := json.Marshal(downloads)
+	if nil != err {
+		fmt.Println("Error writing podcast downloads: " + err.Error())
+
+		return
+	}
+	getPodcastDownloadsPath().WriteTextFile(string(downloads_json))
+}
+
+/*
+getPodcastDownloadsPath gets the path of the podcast downloads file.
+
+-----------------------------------------------------------
+
+– Returns:
+  - the path of the file
+*/
+func getPodcastDownloadsPath() Utils.GPath {
+	return moduleInfo_GL.ModDirsInfo.UserData.Add2("podcast_downloads.json")
+}
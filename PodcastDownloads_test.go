@@ -0,0 +1,216 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+	ext "github.com/mmcdole/gofeed/extensions"
+)
+
+func TestGetEpisodeFileName(t *testing.T) {
+	var published time.Time = time.Date(2023, 11, 20, 10, 0, 0, 0, time.UTC)
+	var feed_item *gofeed.Item = &gofeed.Item{
+		Title:           `Episode 5: "Why?" / Part 1`,
+		PublishedParsed: &published,
+		ITunesExt:       &ext.ITunesItemExtension{Season: "2", Episode: "5"},
+	}
+	var enclosure *gofeed.Enclosure = &gofeed.Enclosure{URL: "https://example.com/ep5.mp3?token=x", Type: "audio/mpeg"}
+
+	var tests = []struct {
+		template  string
+		feed_item *gofeed.Item
+		enclosure *gofeed.Enclosure
+		expected  string
+	}{
+		{"", feed_item, enclosure, filepath.Join("My_ Podcast", `2023-11-20 - Episode 5_ _Why__ _ Part 1.mp3`)},
+		{"{feed_num}/S{season}E{episode}.{ext}", feed_item, enclosure, filepath.Join("7", "S2E5.mp3")},
+		{"../../{title}.{ext}", feed_item, enclosure, `Episode 5_ _Why__ _ Part 1.mp3`},
+		{"{date}.{ext}", &gofeed.Item{Title: "x"}, &gofeed.Enclosure{URL: "https://example.com/download"},
+			"0000-00-00.bin"},
+		{"{title}.{ext}", &gofeed.Item{Title: "x"}, &gofeed.Enclosure{URL: "https://example.com/a.verylongext"},
+			"x.bin"},
+	}
+	for _, test := range tests {
+		var feedInfo _FeedInfo = _FeedInfo{Feed_num: 7, Download_filename: test.template}
+		var file_name string = getEpisodeFileName(feedInfo, "My: Podcast", test.feed_item, test.enclosure)
+		if test.expected != file_name {
+			t.Errorf("template %q: got %q, expected %q", test.template, file_name, test.expected)
+		}
+	}
+}
+
+func TestDownloadEpisode(t *testing.T) {
+	var content []byte = bytes.Repeat([]byte("0123456789"), 1000)
+	var server *httptest.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Supports ranges (206) and answers 416 for a range after the end.
+		http.ServeContent(w, r, "episode.mp3", time.Time{}, bytes.NewReader(content))
+	}))
+	defer server.Close()
+
+	var sha256_sum [32]byte = sha256.Sum256(content)
+	var content_sha256 string = hex.EncodeToString(sha256_sum[:])
+	var tests = []struct {
+		name      string
+		partial   []byte
+		hash      string
+		error_exp bool
+	}{
+		{"new download", nil, content_sha256, false},
+		{"resumed download", content[:1234], content_sha256, false},
+		{"already complete", content, content_sha256, false},
+		{"corrupted", []byte("xyz"), content_sha256, true},
+	}
+	for _, test := range tests {
+		var download _PodcastDownload = _PodcastDownload{
+			Title:         test.name,
+			Enclosure_url: server.URL + "/episode.mp3",
+			Hash_algo:     "sha-256",
+			Hash:          test.hash,
+			File_path:     filepath.Join(t.TempDir(), "podcast", "episode.mp3"),
+		}
+		if nil != test.partial {
+			if err := os.MkdirAll(filepath.Dir(download.File_path), 0o755); nil != err {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(download.File_path + ".part", test.partial, 0o644); nil != err {
+				t.Fatal(err)
+			}
+		}
+
+		var sha256_hex string = ""
+		var err error = downloadEpisode(download, &sha256_hex)
+		if test.error_exp {
+			if nil == err {
+				t.Errorf("%s: expected an error", test.name)
+			}
+			if _, err = os.Stat(download.File_path + ".part"); !os.IsNotExist(err) {
+				t.Errorf("%s: the corrupted partial file was kept", test.name)
+			}
+
+			continue
+		}
+		if nil != err {
+			t.Errorf("%s: %v", test.name, err)
+
+			continue
+		}
+		file_content, err := os.ReadFile(download.File_path)
+		if nil != err || !bytes.Equal(content, file_content) || content_sha256 != sha256_hex {
+			t.Errorf("%s: wrong file (%d bytes, %v) or SHA-256 %q", test.name, len(file_content), err, sha256_hex)
+		}
+	}
+}
+
+func TestDownloadEpisodeWrongRange(t *testing.T) {
+	var content []byte = bytes.Repeat([]byte("0123456789"), 1000)
+	var requests int = 0
+	var server *httptest.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if "" == r.Header.Get("Range") {
+			_, _ = w.Write(content)
+
+			return
+		}
+		// Always from byte 1000, whatever the requested range.
+		w.Header().Set("Content-Range", "bytes 1000-9999/10000")
+		w.WriteHeader(http.StatusPartialContent)
+		_, _ = w.Write(content[1000:])
+	}))
+	defer server.Close()
+
+	var download _PodcastDownload = _PodcastDownload{
+		Title:         "wrong range",
+		Enclosure_url: server.URL + "/episode.mp3",
+		File_path:     filepath.Join(t.TempDir(), "episode.mp3"),
+	}
+	if err := os.WriteFile(download.File_path + ".part", content[:1234], 0o644); nil != err {
+		t.Fatal(err)
+	}
+
+	var sha256_hex string = ""
+	if err := downloadEpisode(download, &sha256_hex); nil != err {
+		t.Fatal(err)
+	}
+	file_content, err := os.ReadFile(download.File_path)
+	if nil != err || !bytes.Equal(content, file_content) {
+		t.Errorf("wrong file (%d bytes, %v)", len(file_content), err)
+	}
+	if 2 != requests {
+		t.Errorf("got %d requests, expected 2", requests)
+	}
+}
+
+func TestGetContentRangeStart(t *testing.T) {
+	var tests = []struct {
+		content_range string
+		expected      int64
+	}{
+		{"bytes 1234-9999/10000", 1234},
+		{"bytes 0-9999/*", 0},
+		{"bytes */10000", -1},
+		{"items 1-2/3", -1},
+		{"", -1},
+	}
+	for _, test := range tests {
+		if start := getContentRangeStart(test.content_range); test.expected != start {
+			t.Errorf("%q: got %d, expected %d", test.content_range, start, test.expected)
+		}
+	}
+}
+
+func TestGetEpisodeFreePath(t *testing.T) {
+	var dir string = t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "on disk.mp3"), nil, 0o644); nil != err {
+		t.Fatal(err)
+	}
+	var downloads map[string]_PodcastDownload = map[string]_PodcastDownload{
+		"https://example.com/1.mp3": {File_path: filepath.Join(dir, "queued.mp3")},
+		"https://example.com/2.mp3": {File_path: filepath.Join(dir, "queued (2).mp3")},
+		"https://example.com/3.mp3": {File_path: filepath.Join(dir, "on disk (2).mp3")},
+	}
+
+	var tests = []struct {
+		file_name string
+		expected  string
+	}{
+		{"free.mp3", "free.mp3"},
+		{"queued.mp3", "queued (3).mp3"},
+		{"on disk.mp3", "on disk (3).mp3"},
+		{"no extension", "no extension"},
+	}
+	for _, test := range tests {
+		var file_path string = getEpisodeFreePath(filepath.Join(dir, test.file_name), downloads)
+		if filepath.Join(dir, test.expected) != file_path {
+			t.Errorf("%q: got %q, expected %q", test.file_name, file_path, filepath.Join(dir, test.expected))
+		}
+	}
+}
@@ -0,0 +1,201 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package main
+
+import (
+	"html"
+	"strconv"
+	"strings"
+
+	"github.com/mmcdole/gofeed"
+	ext "github.com/mmcdole/gofeed/extensions"
+
+	"Utils"
+)
+
+/*
+podcastTreatment does the treatment of a podcast feed item: the general treatment plus the episode information (the
+enclosure and the iTunes data). The episode is put on the news info, to be downloaded once notified, if the feed wants
+it (see recordNotifiedItem()).
+
+-----------------------------------------------------------
+
+– Params:
+  - feedInfo – the information of the feed
+  - parsed_feed – the parsed feed
+  - item_num – the number of the item to get
+  - title_url_only – whether to only get the title and URL of the item through _NewsInfo (can be used for optimization)
+
+– Returns:
+  - the email info (without the Mail_to field) or all fields empty if title_url_only is true
+  - the news info
+*/
+func podcastTreatment(feedInfo _FeedInfo, parsed_feed *gofeed.Feed, item_num int, title_url_only bool) (
+			Utils.EmailInfo, _NewsInfo) {
+	var feed_item *gofeed.Item = parsed_feed.Items[item_num]
+
+	email_info, newsInfo := generalTreatment(feedInfo, parsed_feed, item_num, title_url_only,
+		func(description string) string {
+			return getEpisodeHtml(feed_item) + description
+		})
+	if "" == newsInfo.url {
+		// Some episodes only have the enclosure.
+		if enclosure := getEpisodeEnclosure(feed_item); nil != enclosure {
+			newsInfo.url = enclosure.URL
+		}
+	}
+	if "" == newsInfo.image && nil != feed_item.ITunesExt && "" != feed_item.ITunesExt.Image {
+		newsInfo.image = feed_item.ITunesExt.Image
+	}
+	if title_url_only {
+		return email_info, newsInfo
+	}
+
+	if "" == email_info.Subject {
+		email_info.Subject = "Novo episódio de " + parsed_feed.Title
+	}
+	newsInfo.episode = feed_item
+
+	return email_info, newsInfo
+}
+
+/*
+getEpisodeEnclosure gets the audio/video enclosure of an episode.
+
+-----------------------------------------------------------
+
+– Params:
+  - feed_item – the item of the episode
+
+– Returns:
+  - the first audio or video enclosure, or the first enclosure if none is audio nor video, or nil if there are none
+*/
+func getEpisodeEnclosure(feed_item *gofeed.Item) *gofeed.Enclosure {
+	for _, enclosure := range feed_item.Enclosures {
+		if strings.HasPrefix(enclosure.Type, "audio/") || strings.HasPrefix(enclosure.Type, "video/") {
+			return enclosure
+		}
+	}
+	if len(feed_item.Enclosures) > 0 {
+		return feed_item.Enclosures[0]
+	}
+
+	return nil
+}
+
+/*
+getEpisodeHtml gets the HTML with the information of a podcast episode to put on top of its description.
+
+-----------------------------------------------------------
+
+– Params:
+  - feed_item – the item of the episode
+
+– Returns:
+  - the HTML or "" if there's no information
+*/
+func getEpisodeHtml(feed_item *gofeed.Item) string {
+	var infos []string = nil
+
+	if nil != feed_item.ITunesExt {
+		var itunes_ext *ext.ITunesItemExtension = feed_item.ITunesExt
+		if "" != itunes_ext.Season {
+			infos = append(infos, "Temporada " + html.EscapeString(itunes_ext.Season))
+		}
+		if "" != itunes_ext.Episode {
+			infos = append(infos, "Episódio " + html.EscapeString(itunes_ext.Episode))
+		}
+		if "" != itunes_ext.EpisodeType && "full" != itunes_ext.EpisodeType {
+			infos = append(infos, html.EscapeString(itunes_ext.EpisodeType))
+		}
+		if "" != itunes_ext.Duration {
+			infos = append(infos, "Duração: " + html.EscapeString(getEpisodeDuration(itunes_ext.Duration)))
+		}
+	}
+
+	var enclosure *gofeed.Enclosure = getEpisodeEnclosure(feed_item)
+	if nil != enclosure {
+		var enclosure_info string = "<a href=\"" + html.EscapeString(enclosure.URL) + "\">Ficheiro</a>"
+		var details []string = nil
+		if "" != enclosure.Type {
+			details = append(details, html.EscapeString(enclosure.Type))
+		}
+		if size, err := strconv.ParseInt(enclosure.Length, 10, 64); nil == err && size > 0 {
+			details = append(details, bytesToStr(size))
+		}
+		if 0 != len(details) {
+			enclosure_info += " (" + strings.Join(details, ", ") + ")"
+		}
+		infos = append(infos, enclosure_info)
+	}
+
+	if 0 == len(infos) {
+		return ""
+	}
+
+	return "<p>" + strings.Join(infos, " • ") + "</p>\n"
+}
+
+/*
+getEpisodeDuration gets the duration of an episode in the SecondsToTimeStr() format.
+
+-----------------------------------------------------------
+
+– Params:
+  - itunes_duration – the iTunes duration (in seconds or already in the "HH:MM:SS" or "MM:SS" formats)
+
+– Returns:
+  - the duration
+*/
+func getEpisodeDuration(itunes_duration string) string {
+	if _, err := strconv.Atoi(itunes_duration); nil == err {
+		return SecondsToTimeStr(itunes_duration)
+	}
+
+	return itunes_duration
+}
+
+/*
+bytesToStr converts a number of bytes to a human-readable string.
+
+-----------------------------------------------------------
+
+– Params:
+  - bytes – the number of bytes
+
+– Returns:
+  - the string, like "12.3 MB"
+*/
+func bytesToStr(bytes int64) string {
+	var units []string = []string{"B", "KB", "MB", "GB", "TB"}
+	var size float64 = float64(bytes)
+	var unit int = 0
+	for size >= 1000 && unit < len(units)-1 {
+		size /= 1000
+		unit++
+	}
+	if 0 == unit {
+		return strconv.FormatInt(bytes, 10) + " B"
+	}
+
+	return strconv.FormatFloat(size, 'f', 1, 64) + " " + units[unit]
+}
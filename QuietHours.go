@@ -0,0 +1,357 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"Utils"
+)
+
+// weekdays_GL has the weekday names accepted on _QuietHours.Days, in the order of time.Weekday.
+var weekdays_GL []string = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// _HeldEmail is an email held because its recipient was in quiet hours or over its rate limit. It's exported to JSON,
+// so the fields are exported.
+type _HeldEmail struct {
+	// Mail_to is the recipient
+	Mail_to string
+	// Sender is the name of the sender
+	Sender string
+	// Subject is the subject of the email
+	Subject string
+	// Html is the HTML of the email
+	Html string
+	// Title is the title of the news of the email
+	Title string
+	// Url is the URL of the news of the email (empty if the email is not about one news, like a digest)
+	Url string
+	// Group is the group of the feed of the news (see getFeedGroup())
+	Group string
+	// Held is when the email was held in Unix seconds
+	Held int64
+}
+
+/*
+isInQuietHours checks if a recipient is in any of its quiet hours windows.
+
+-----------------------------------------------------------
+
+– Params:
+  - recipient – the recipient
+  - now – the time to check
+
+– Returns:
+  - true if the recipient is in quiet hours, false otherwise
+*/
+func isInQuietHours(recipient _Recipient, now time.Time) bool {
+	now = now.In(getRecipientLocation(recipient))
+	var minutes int = now.Hour()*60 + now.Minute()
+	var weekday time.Weekday = now.Weekday()
+	var weekday_before time.Weekday = (weekday + 6) % 7
+
+	for _, quietHours := range recipient.Quiet_hours {
+		start, ok1 := clockToMinutes(quietHours.Start)
+		end, ok2 := clockToMinutes(quietHours.End)
+		if !ok1 || !ok2 {
+			fmt.Println("Invalid quiet hours of " + recipient.Mail_to + ": " + quietHours.Start + "-" + quietHours.End)
+
+			continue
+		}
+
+		if start <= end {
+			if isQuietDay(quietHours, weekday) && minutes >= start && minutes < end {
+				return true
+			}
+		} else {
+			// The window goes through midnight - the days are the ones where it begins.
+			if isQuietDay(quietHours, weekday) && minutes >= start {
+				return true
+			}
+			if isQuietDay(quietHours, weekday_before) && minutes < end {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+/*
+getRecipientLocation gets the time zone of a recipient.
+
+-----------------------------------------------------------
+
+– Params:
+  - recipient – the recipient
+
+– Returns:
+  - the time zone of the recipient or the local one if it's not set or is invalid
+*/
+func getRecipientLocation(recipient _Recipient) *time.Location {
+	if "" == recipient.Time_zone {
+		return time.Local
+	}
+
+	location, err := time.LoadLocation(recipient.Time_zone)
+	if nil != err {
+		fmt.Println("Invalid time zone of " + recipient.Mail_to + ": " + recipient.Time_zone)
+
+		return time.Local
+	}
+
+	return location
+}
+
+/*
+isQuietDay checks if a quiet hours window applies to a weekday.
+
+-----------------------------------------------------------
+
+– Params:
+  - quietHours – the quiet hours window
+  - weekday – the weekday
+
+– Returns:
+  - true if the window has no days or if it has the weekday, false otherwise
+*/
+func isQuietDay(quietHours _QuietHours, weekday time.Weekday) bool {
+	if 0 == len(quietHours.Days) {
+		return true
+	}
+	for _, day := range quietHours.Days {
+		if strings.EqualFold(day, weekdays_GL[weekday]) {
+			return true
+		}
+	}
+
+	return false
+}
+
+/*
+clockToMinutes converts a time of the day in the "15:04" format to minutes since midnight. "24:00" is accepted too, for
+windows that end at midnight.
+
+-----------------------------------------------------------
+
+– Params:
+  - clock – the time of the day
+
+– Returns:
+  - the minutes since midnight
+  - true if the time is valid, false otherwise
+*/
+func clockToMinutes(clock string) (int, bool) {
+	var clock_split []string = strings.Split(strings.TrimSpace(clock), ":")
+	if 2 != len(clock_split) {
+		return 0, false
+	}
+	hours, err1 := strconv.Atoi(clock_split[0])
+	minutes, err2 := strconv.Atoi(clock_split[1])
+	if nil != err1 || nil != err2 || hours < 0 || minutes < 0 || minutes > 59 {
+		return 0, false
+	}
+	if hours > 23 && !(24 == hours && 0 == minutes) {
+		return 0, false
+	}
+
+	return hours*60 + minutes, true
+}
+
+/*
+holdEmail holds an email until its recipient can receive it.
+
+-----------------------------------------------------------
+
+– Params:
+  - heldEmail – the email
+
+– Returns:
+  - true if the email was held, false otherwise (the held emails file couldn't be read or written)
+*/
+func holdEmail(heldEmail _HeldEmail) bool {
+	heldEmails, ok := readHeldEmails()
+	if !ok {
+		// Don't overwrite held emails that couldn't be read.
+		return false
+	}
+	heldEmail.Held = time.Now().Unix()
+
+	return writeHeldEmails(append(heldEmails, heldEmail))
+}
+
+/*
+deliverHeldEmails delivers the held emails of the recipients that are no longer in quiet hours nor over their rate
+limit. The emails about news are delivered as a batch, in one digest email - the others are delivered as they were.
+*/
+func deliverHeldEmails() {
+	heldEmails, ok := readHeldEmails()
+	if !ok || 0 == len(heldEmails) {
+		return
+	}
+
+	var modUserInfo _ModUserInfo
+	if !moduleInfo_GL.GetModUserInfo(&modUserInfo) {
+		return
+	}
+
+	var now time.Time = time.Now()
+	var still_held map[string]bool = make(map[string]bool)
+	for _, recipient := range modUserInfo.Recipients {
+		if isInQuietHours(recipient, now) ||
+					!rateLimitAllows(getMailRateKey(recipient.Mail_to), recipient.Max_per_hour) {
+			still_held[strings.ToLower(recipient.Mail_to)] = true
+		}
+	}
+
+	var heldEmails_kept []_HeldEmail = nil
+	var mails_to []string = nil
+	var digest_items map[string][]_DigestItem = make(map[string][]_DigestItem)
+	var news_emails map[string][]_HeldEmail = make(map[string][]_HeldEmail)
+	for _, heldEmail := range heldEmails {
+		var mail_to string = strings.ToLower(heldEmail.Mail_to)
+		if still_held[mail_to] {
+			heldEmails_kept = append(heldEmails_kept, heldEmail)
+
+			continue
+		}
+
+		if "" == heldEmail.Url {
+			fmt.Println("Queuing held email: " + heldEmail.Subject)
+			if !queueEmail(heldEmail.Mail_to, heldEmail.Sender, heldEmail.Subject, heldEmail.Html) {
+				heldEmails_kept = append(heldEmails_kept, heldEmail)
+
+				continue
+			}
+			registerRateLimitSend(getMailRateKey(heldEmail.Mail_to))
+
+			continue
+		}
+
+		if _, ok := digest_items[mail_to]; !ok {
+			mails_to = append(mails_to, heldEmail.Mail_to)
+		}
+		digest_items[mail_to] = append(digest_items[mail_to], _DigestItem{
+			group: heldEmail.Group,
+			newsInfo: _NewsInfo{
+				url:   heldEmail.Url,
+				title: heldEmail.Title,
+			},
+		})
+		news_emails[mail_to] = append(news_emails[mail_to], heldEmail)
+	}
+
+	for _, mail_to := range mails_to {
+		var mail_to_lower string = strings.ToLower(mail_to)
+		if 1 == len(news_emails[mail_to_lower]) {
+			var heldEmail _HeldEmail = news_emails[mail_to_lower][0]
+			fmt.Println("Queuing held email: " + heldEmail.Subject)
+			if !queueEmail(heldEmail.Mail_to, heldEmail.Sender, heldEmail.Subject, heldEmail.Html) {
+				heldEmails_kept = append(heldEmails_kept, heldEmail)
+
+				continue
+			}
+			registerRateLimitSend(getMailRateKey(heldEmail.Mail_to))
+
+			continue
+		}
+
+		var subject string = strconv.Itoa(len(digest_items[mail_to_lower])) + " notificações em espera"
+		fmt.Println("Queuing held emails digest: " + subject)
+		if !queueEmail(mail_to, "RSS Feed Notifier", subject, getDigestHtml(subject, digest_items[mail_to_lower])) {
+			heldEmails_kept = append(heldEmails_kept, news_emails[mail_to_lower]...)
+
+			continue
+		}
+		registerRateLimitSend(getMailRateKey(mail_to))
+	}
+
+	if !writeHeldEmails(heldEmails_kept) {
+		fmt.Println("Error writing the held emails - the delivered ones may be delivered again")
+	}
+}
+
+/*
+readHeldEmails reads the held emails from the held emails file.
+
+-----------------------------------------------------------
+
+– Returns:
+  - the held emails from the oldest to the newest (empty if there are none or if an error occurs)
+  - true if the held emails were read (or there's no file yet), false if the file couldn't be read
+*/
+func readHeldEmails() ([]_HeldEmail, bool) {
+	var heldEmails []_HeldEmail = nil
+
+	var p_held_emails_json *string = getHeldEmailsPath().ReadTextFile()
+	if nil == p_held_emails_json {
+		return nil, !getHeldEmailsPath().Exists()
+	}
+	if err := json.Unmarshal([]byte(*p_held_emails_json), &heldEmails); nil != err {
+		fmt.Println("Error reading held emails: " + err.Error())
+
+		return nil, false
+	}
+
+	return heldEmails, true
+}
+
+/*
+writeHeldEmails writes the held emails to the held emails file.
+
+-----------------------------------------------------------
+
+– Params:
+  - heldEmails – the held emails
+
+– Returns:
+  - true if the held emails were written, false otherwise
+*/
+func writeHeldEmails(heldEmails []_HeldEmail) bool {
+	if nil == heldEmails {
+		heldEmails = []_HeldEmail{}
+	}
+	held_emails_json, err := json.Marshal(heldEmails)
+	if nil != err {
+		fmt.Println("Error writing held emails: " + err.Error())
+
+		return false
+	}
+
+	return getHeldEmailsPath().WriteTextFile(string(held_emails_json))
+}
+
+/*
+getHeldEmailsPath gets the path of the held emails file.
+
+-----------------------------------------------------------
+
+– Returns:
+  - the path of the file
+*/
+func getHeldEmailsPath() Utils.GPath {
+	return moduleInfo_GL.ModDirsInfo.UserData.Add2("held_emails.json")
+}
@@ -0,0 +1,106 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package main
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func TestClockToMinutes(t *testing.T) {
+	var tests = []struct {
+		clock    string
+		expected int
+		ok       bool
+	}{
+		{"00:00", 0, true},
+		{"07:30", 450, true},
+		{" 23:59 ", 1439, true},
+		{"24:00", 1440, true},
+		{"24:01", 0, false},
+		{"24:59", 0, false},
+		{"25:00", 0, false},
+		{"12:60", 0, false},
+		{"-1:00", 0, false},
+		{"12", 0, false},
+		{"12:00:00", 0, false},
+		{"ab:cd", 0, false},
+		{"", 0, false},
+	}
+	for _, test := range tests {
+		minutes, ok := clockToMinutes(test.clock)
+		if test.ok != ok || test.expected != minutes {
+			t.Errorf("clockToMinutes(%q) = %d, %t, expected %d, %t", test.clock, minutes, ok, test.expected, test.ok)
+		}
+	}
+}
+
+func TestIsInQuietHours(t *testing.T) {
+	var night _QuietHours = _QuietHours{Start: "22:00", End: "07:00"}
+	var weekend_night _QuietHours = _QuietHours{Days: []string{"Fri", "sat"}, Start: "23:00", End: "09:00"}
+	var lunch _QuietHours = _QuietHours{Days: []string{"mon"}, Start: "12:00", End: "24:00"}
+
+	// 2023-11-20 is a Monday.
+	var tests = []struct {
+		quietHours []_QuietHours
+		now        time.Time
+		expected   bool
+	}{
+		{nil, time.Date(2023, 11, 20, 23, 0, 0, 0, time.UTC), false},
+		// Through midnight, every day.
+		{[]_QuietHours{night}, time.Date(2023, 11, 20, 21, 59, 0, 0, time.UTC), false},
+		{[]_QuietHours{night}, time.Date(2023, 11, 20, 22, 0, 0, 0, time.UTC), true},
+		{[]_QuietHours{night}, time.Date(2023, 11, 21, 6, 59, 0, 0, time.UTC), true},
+		{[]_QuietHours{night}, time.Date(2023, 11, 21, 7, 0, 0, 0, time.UTC), false},
+		// Through midnight, only beginning on Friday and Saturday.
+		{[]_QuietHours{weekend_night}, time.Date(2023, 11, 24, 23, 30, 0, 0, time.UTC), true},
+		{[]_QuietHours{weekend_night}, time.Date(2023, 11, 26, 8, 0, 0, 0, time.UTC), true},
+		{[]_QuietHours{weekend_night}, time.Date(2023, 11, 26, 23, 30, 0, 0, time.UTC), false},
+		{[]_QuietHours{weekend_night}, time.Date(2023, 11, 27, 8, 0, 0, 0, time.UTC), false},
+		// Until the end of the day.
+		{[]_QuietHours{lunch}, time.Date(2023, 11, 20, 23, 59, 0, 0, time.UTC), true},
+		{[]_QuietHours{lunch}, time.Date(2023, 11, 21, 0, 0, 0, 0, time.UTC), false},
+		{[]_QuietHours{night, lunch}, time.Date(2023, 11, 20, 13, 0, 0, 0, time.UTC), true},
+		// Invalid windows are ignored.
+		{[]_QuietHours{{Start: "24:30", End: "07:00"}}, time.Date(2023, 11, 20, 3, 0, 0, 0, time.UTC), false},
+	}
+	for i, test := range tests {
+		var recipient _Recipient = _Recipient{
+			Mail_to:     "someone@example.com",
+			Time_zone:   "UTC",
+			Quiet_hours: test.quietHours,
+		}
+		if in_quiet_hours := isInQuietHours(recipient, test.now); test.expected != in_quiet_hours {
+			t.Errorf("test %d: isInQuietHours(%v) = %t, expected %t", i, test.now, in_quiet_hours, test.expected)
+		}
+	}
+
+	// The time zone of the recipient is used: 23:00 UTC is 08:00 in Tokyo.
+	var recipient _Recipient = _Recipient{
+		Time_zone:   "Asia/Tokyo",
+		Quiet_hours: []_QuietHours{night},
+	}
+	if isInQuietHours(recipient, time.Date(2023, 11, 20, 23, 0, 0, 0, time.UTC)) {
+		t.Error("the time zone of the recipient was not used")
+	}
+}
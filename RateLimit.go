@@ -0,0 +1,147 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"Utils"
+)
+
+// _RATE_LIMIT_WINDOW is the window of the rate limits ("max N per hour").
+const _RATE_LIMIT_WINDOW time.Duration = 1 * time.Hour
+
+/*
+rateLimitAllows checks if one more notification can be sent without going over a rate limit.
+
+-----------------------------------------------------------
+
+– Params:
+  - key – the key of the rate limit (getFeedRateKey() or getMailRateKey())
+  - max_per_hour – the maximum number of notifications per hour (0 for no limit)
+
+– Returns:
+  - true if the notification can be sent, false otherwise
+*/
+func rateLimitAllows(key string, max_per_hour int) bool {
+	if max_per_hour <= 0 {
+		return true
+	}
+
+	return len(readRateLimits()[key]) < max_per_hour
+}
+
+/*
+registerRateLimitSend registers that a notification was sent, for the rate limits.
+
+-----------------------------------------------------------
+
+– Params:
+  - key – the key of the rate limit (getFeedRateKey() or getMailRateKey())
+*/
+func registerRateLimitSend(key string) {
+	var rate_limits map[string][]int64 = readRateLimits()
+	rate_limits[key] = append(rate_limits[key], time.Now().Unix())
+	writeRateLimits(rate_limits)
+}
+
+func getFeedRateKey(feed_num int) string {
+	return "feed-" + strconv.Itoa(feed_num)
+}
+
+func getMailRateKey(mail_to string) string {
+	return "mail-" + strings.ToLower(mail_to)
+}
+
+/*
+readRateLimits reads the sending times of the notifications from the rate limits file.
+
+-----------------------------------------------------------
+
+– Returns:
+  - the sending times in Unix seconds mapped by rate limit key, only the ones inside the window (empty if there are
+    none or if an error occurs)
+*/
+func readRateLimits() map[string][]int64 {
+	var rate_limits map[string][]int64 = make(map[string][]int64)
+
+	var p_rate_limits_json *string = getRateLimitsPath().ReadTextFile()
+	if nil == p_rate_limits_json {
+		return rate_limits
+	}
+	if err := json.Unmarshal([]byte(*p_rate_limits_json), &rate_limits); nil != err {
+		fmt.Println("Error reading rate limits: " + err.Error())
+
+		return make(map[string][]int64)
+	}
+
+	var window_begin int64 = time.Now().Add(-_RATE_LIMIT_WINDOW).Unix()
+	for key, send_times := range rate_limits {
+		var send_times_kept []int64 = nil
+		for _, send_time := range send_times {
+			if send_time > window_begin {
+				send_times_kept = append(send_times_kept, send_time)
+			}
+		}
+		if 0 == len(send_times_kept) {
+			delete(rate_limits, key)
+		} else {
+			rate_limits[key] = send_times_kept
+		}
+	}
+
+	return rate_limits
+}
+
+/*
+writeRateLimits writes the sending times of the notifications to the rate limits file.
+
+-----------------------------------------------------------
+
+– Params:
+  - rate_limits – the sending times mapped by rate limit key
+*/
+func writeRateLimits(rate_limits map[string][]int64) {
+	rate_limits_json, err := json.Marshal(rate_limits)
+	if nil != err {
+		fmt.Println("Error writing rate limits: " + err.Error())
+
+		return
+	}
+	getRateLimitsPath().WriteTextFile(string(rate_limits_json))
+}
+
+/*
+getRateLimitsPath gets the path of the rate limits file.
+
+-----------------------------------------------------------
+
+– Returns:
+  - the path of the file
+*/
+func getRateLimitsPath() Utils.GPath {
+	return moduleInfo_GL.ModDirsInfo.UserData.Add2("rate_limits.json")
+}
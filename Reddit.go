@@ -0,0 +1,443 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+	ext "github.com/mmcdole/gofeed/extensions"
+
+	"Utils"
+)
+
+// _REDDIT_USER_AGENT is the User-Agent sent to Reddit. It rate-limits the default ones of HTTP libraries much more.
+const _REDDIT_USER_AGENT string = "server:RssFeedNotifier:v1.0 (V.I.S.O.R. module)"
+
+// _REDDIT_LISTING_LIMIT is the number of posts got from each listing (the maximum Reddit allows is 100).
+const _REDDIT_LISTING_LIMIT int = 50
+
+// _REDDIT_COLOR is the color of the Reddit emails.
+const _REDDIT_COLOR string = "#FF4500"
+
+// redditRateLimitedUntil_GL is until when Reddit is not to be requested because it said the rate limit was exceeded.
+var redditRateLimitedUntil_GL time.Time = time.Time{}
+
+// redditBaseUrl_GL is the base of the URLs of the Reddit JSON listings (a variable only to be able to point it to a
+// local stand-in server).
+var redditBaseUrl_GL string = "https://www.reddit.com"
+
+var errRedditRateLimited error = errors.New("Reddit rate limit exceeded")
+
+// The placeholders of the Reddit email model.
+const (
+	_MODEL_REDDIT_HTML_TITLE    string = "3234_REDDIT_HTML_TITLE"
+	_MODEL_REDDIT_COLOR         string = "3234_REDDIT_COLOR"
+	_MODEL_REDDIT_SUBREDDIT     string = "3234_REDDIT_SUBREDDIT"
+	_MODEL_REDDIT_AUTHOR        string = "3234_REDDIT_AUTHOR"
+	_MODEL_REDDIT_FLAIR         string = "3234_REDDIT_FLAIR"
+	_MODEL_REDDIT_POST_TITLE    string = "3234_REDDIT_POST_TITLE"
+	_MODEL_REDDIT_POST_URL      string = "3234_REDDIT_POST_URL"
+	_MODEL_REDDIT_POST_DATE     string = "3234_REDDIT_POST_DATE"
+	_MODEL_REDDIT_SCORE         string = "3234_REDDIT_SCORE"
+	_MODEL_REDDIT_NUM_COMMENTS  string = "3234_REDDIT_NUM_COMMENTS"
+	_MODEL_REDDIT_THUMBNAIL     string = "3234_REDDIT_THUMBNAIL"
+	_MODEL_REDDIT_LINK          string = "3234_REDDIT_LINK"
+	_MODEL_REDDIT_SELF_TEXT     string = "3234_REDDIT_SELF_TEXT"
+)
+
+// _REDDIT_EMAIL_MODEL is the email model of the Reddit posts. The values of the placeholders go in as they are, so the
+// texts must be escaped before.
+const _REDDIT_EMAIL_MODEL string = `<!DOCTYPE html>
+<html>
+<head><meta charset="UTF-8"><title>3234_REDDIT_HTML_TITLE</title></head>
+<body style="font-family: Roboto, Arial, sans-serif; max-width: 700px;">
+<div style="border-left: 4px solid 3234_REDDIT_COLOR; padding-left: 12px;">
+<p style="color: #555555; font-size: 13px;"><b>3234_REDDIT_SUBREDDIT</b> • 3234_REDDIT_AUTHOR • 3234_REDDIT_POST_DATE3234_REDDIT_FLAIR</p>
+<h3 style="margin: 8px 0;"><a href="3234_REDDIT_POST_URL" style="color: #1A1A1B; text-decoration: none;">3234_REDDIT_POST_TITLE</a></h3>
+<p style="color: 3234_REDDIT_COLOR; font-size: 13px;"><b>▲ 3234_REDDIT_SCORE pontos</b> • 3234_REDDIT_NUM_COMMENTS comentários</p>
+3234_REDDIT_THUMBNAIL3234_REDDIT_LINK3234_REDDIT_SELF_TEXT
+<p><a href="3234_REDDIT_POST_URL" style="background-color: 3234_REDDIT_COLOR; color: #FFFFFF; padding: 8px 16px; border-radius: 16px; text-decoration: none;">Ver no Reddit</a></p>
+</div>
+</body>
+</html>
+`
+
+// _RedditListing is the part used of a listing of the Reddit JSON API.
+type _RedditListing struct {
+	Data struct {
+		Children []struct {
+			Kind string        `json:"kind"`
+			Data _RedditPost `json:"data"`
+		} `json:"children"`
+	} `json:"data"`
+}
+
+// _RedditPost is the part used of a post of the Reddit JSON API.
+type _RedditPost struct {
+	Title                 string  `json:"title"`
+	Author                string  `json:"author"`
+	Permalink             string  `json:"permalink"`
+	Url                   string  `json:"url"`
+	Created_utc           float64 `json:"created_utc"`
+	Score                 int     `json:"score"`
+	Num_comments          int     `json:"num_comments"`
+	Link_flair_text       string  `json:"link_flair_text"`
+	Subreddit_name_prefix string  `json:"subreddit_name_prefixed"`
+	Thumbnail             string  `json:"thumbnail"`
+	Selftext_html         string  `json:"selftext_html"`
+	Is_self               bool    `json:"is_self"`
+	Over_18               bool    `json:"over_18"`
+	Preview               struct {
+		Images []struct {
+			Source struct {
+				Url string `json:"url"`
+			} `json:"source"`
+		} `json:"images"`
+	} `json:"preview"`
+}
+
+/*
+getRedditListingUrl gets the URL of the Reddit JSON listing of a Reddit feed.
+
+-----------------------------------------------------------
+
+– Params:
+  - feedInfo – the information of the feed, with the subreddit(s), the user or the search query on Feed_url
+  - feedType – the type of the feed
+
+– Returns:
+  - the URL or "" if the feed type is invalid
+*/
+func getRedditListingUrl(feedInfo _FeedInfo, feedType _FeedType) string {
+	var sort string = strings.ToLower(feedInfo.Reddit_sort)
+	if "" == sort {
+		sort = "new"
+	}
+
+	var params url.Values = url.Values{}
+	params.Set("limit", strconv.Itoa(_REDDIT_LISTING_LIMIT))
+	// Without this, the texts come HTML-escaped (even the HTML ones, escaped twice).
+	params.Set("raw_json", "1")
+	if "top" == sort {
+		params.Set("t", "day")
+	}
+
+	var feed_url string = strings.Trim(strings.TrimSpace(feedInfo.Feed_url), "/")
+	switch feedType.type_2 {
+		case _TYPE_2_REDDIT_SUB: {
+			feed_url = strings.TrimPrefix(feed_url, "r/")
+
+			return redditBaseUrl_GL + "/r/" + url.PathEscape(feed_url) + "/" + url.PathEscape(sort) + ".json?" +
+				params.Encode()
+		}
+		case _TYPE_2_REDDIT_USER: {
+			feed_url = strings.TrimPrefix(strings.TrimPrefix(feed_url, "u/"), "user/")
+			params.Set("sort", sort)
+
+			return redditBaseUrl_GL + "/user/" + url.PathEscape(feed_url) + "/submitted.json?" + params.Encode()
+		}
+		case _TYPE_2_REDDIT_SEARCH: {
+			params.Set("q", feedInfo.Feed_url)
+			params.Set("sort", sort)
+
+			return redditBaseUrl_GL + "/search.json?" + params.Encode()
+		}
+	}
+
+	return ""
+}
+
+/*
+getRedditFeed gets a Reddit listing and converts it to a feed.
+
+The listing is got from the JSON API and not from the .rss version: that one has no score nor flair, the authors come
+as "/u/name" and the post text comes inside an HTML table with the thumbnail and the links. The Reddit information of
+each post goes on the "reddit" extension of its item.
+
+-----------------------------------------------------------
+
+– Params:
+  - listing_url – the URL of the listing (from getRedditListingUrl())
+  - feedType – the type of the feed
+
+– Returns:
+  - the feed, with the posts from the newest to the oldest
+  - errRedditRateLimited if Reddit is rate limiting the requests, other error if the request failed, nil otherwise
+*/
+func getRedditFeed(listing_url string, feedType _FeedType) (*gofeed.Feed, error) {
+	if time.Now().Before(redditRateLimitedUntil_GL) {
+		return nil, errRedditRateLimited
+	}
+
+	var redditListing _RedditListing
+	var err error = getJson(listing_url, _REDDIT_USER_AGENT, &redditListing)
+	var statusError _HttpStatusError
+	if errors.As(err, &statusError) && http.StatusTooManyRequests == statusError.status_code {
+		var pause time.Duration = 10 * time.Minute
+		if retry_after, err := strconv.Atoi(statusError.header.Get("Retry-After")); nil == err && retry_after > 0 {
+			pause = time.Duration(retry_after) * time.Second
+		}
+		redditRateLimitedUntil_GL = time.Now().Add(pause)
+
+		return nil, errRedditRateLimited
+	}
+	if nil != err {
+		return nil, err
+	}
+
+	parsed_url, err := url.Parse(listing_url)
+	if nil != err {
+		return nil, err
+	}
+	var parsed_feed *gofeed.Feed = &gofeed.Feed{
+		Link:     strings.TrimSuffix(strings.Split(listing_url, "?")[0], ".json"),
+		FeedType: "json",
+	}
+	switch feedType.type_2 {
+		case _TYPE_2_REDDIT_SEARCH: {
+			parsed_feed.Title = "Reddit: " + parsed_url.Query().Get("q")
+		}
+		case _TYPE_2_REDDIT_USER: {
+			// "/user/<name>/submitted.json"
+			parsed_feed.Title = "u/" + strings.Split(strings.TrimPrefix(parsed_url.Path, "/user/"), "/")[0]
+		}
+		default: {
+			// "/r/<subreddit>/<sort>.json"
+			parsed_feed.Title = "r/" + strings.Split(strings.TrimPrefix(parsed_url.Path, "/r/"), "/")[0]
+		}
+	}
+
+	for _, child := range redditListing.Data.Children {
+		// Only posts ("t3") - user listings could have comments too.
+		if "t3" != child.Kind {
+			continue
+		}
+		parsed_feed.Items = append(parsed_feed.Items, redditPostToItem(child.Data))
+	}
+
+	return parsed_feed, nil
+}
+
+/*
+redditPostToItem converts a Reddit post to a feed item.
+
+-----------------------------------------------------------
+
+– Params:
+  - redditPost – the post
+
+– Returns:
+  - the feed item
+*/
+func redditPostToItem(redditPost _RedditPost) *gofeed.Item {
+	var published time.Time = time.Unix(int64(redditPost.Created_utc), 0)
+
+	var feed_item *gofeed.Item = &gofeed.Item{
+		Title:           redditPost.Title,
+		Link:            "https://www.reddit.com" + redditPost.Permalink,
+		Description:     redditPost.Selftext_html,
+		Authors:         []*gofeed.Person{{Name: "u/" + redditPost.Author}},
+		Published:       published.Format(time.RFC3339),
+		PublishedParsed: &published,
+		Extensions: ext.Extensions{
+			"reddit": {
+				"score":        {{Value: strconv.Itoa(redditPost.Score)}},
+				"num_comments": {{Value: strconv.Itoa(redditPost.Num_comments)}},
+				"flair":        {{Value: redditPost.Link_flair_text}},
+				"subreddit":    {{Value: redditPost.Subreddit_name_prefix}},
+				"nsfw":         {{Value: strconv.FormatBool(redditPost.Over_18)}},
+			},
+		},
+	}
+	if !redditPost.Is_self {
+		feed_item.Extensions["reddit"]["link"] = []ext.Extension{{Value: redditPost.Url}}
+	}
+
+	// The thumbnail may also be "self", "default", "nsfw", etc.
+	var image string = ""
+	if 0 != len(redditPost.Preview.Images) {
+		image = redditPost.Preview.Images[0].Source.Url
+	} else if strings.HasPrefix(redditPost.Thumbnail, "http") {
+		image = redditPost.Thumbnail
+	}
+	if "" != image {
+		feed_item.Image = &gofeed.Image{URL: image}
+	}
+
+	return feed_item
+}
+
+/*
+redditTreatment does the treatment of a Reddit feed item.
+
+-----------------------------------------------------------
+
+– Params:
+  - feedInfo – the information of the feed
+  - feedType – the type of the feed
+  - parsed_feed – the parsed feed
+  - item_num – the number of the item to get
+  - title_url_only – whether to only get the title and URL of the item through _NewsInfo (can be used for optimization)
+
+– Returns:
+  - the email info (without the Mail_to field) or all fields empty if title_url_only is true or if the post's flair is
+    not wanted
+  - the news info, or all fields empty if the post's score is below the minimum (so that it's checked again later)
+*/
+func redditTreatment(feedInfo _FeedInfo, feedType _FeedType, parsed_feed *gofeed.Feed, item_num int,
+			title_url_only bool) (Utils.EmailInfo, _NewsInfo) {
+	var feed_item *gofeed.Item = parsed_feed.Items[item_num]
+	var reddit_ext map[string][]ext.Extension = feed_item.Extensions["reddit"]
+	var getExtValue func(name string) string = func(name string) string {
+		if values := reddit_ext[name]; 0 != len(values) {
+			return values[0].Value
+		}
+
+		return ""
+	}
+
+	var newsInfo _NewsInfo = _NewsInfo{
+		title:  feed_item.Title,
+		url:    feed_item.Link,
+		author: feed_item.Authors[0].Name,
+	}
+	if nil != feed_item.Image {
+		newsInfo.image = feed_item.Image.URL
+	}
+	if nil != feed_item.PublishedParsed {
+		newsInfo.published = *feed_item.PublishedParsed
+	}
+
+	if title_url_only {
+		return Utils.EmailInfo{}, newsInfo
+	}
+
+	score, _ := strconv.Atoi(getExtValue("score"))
+	if score < feedInfo.Reddit_min_score {
+		fmt.Println("Reddit post below the minimum score (" + strconv.Itoa(score) + "): " + feed_item.Title)
+
+		return Utils.EmailInfo{}, _NewsInfo{}
+	}
+	var flair string = getExtValue("flair")
+	if 0 != len(feedInfo.Reddit_flairs) {
+		var flair_wanted bool = false
+		for _, wanted_flair := range feedInfo.Reddit_flairs {
+			if strings.EqualFold(strings.TrimSpace(wanted_flair), strings.TrimSpace(flair)) {
+				flair_wanted = true
+
+				break
+			}
+		}
+		if !flair_wanted {
+			fmt.Println("Reddit post flair not wanted (" + flair + "): " + feed_item.Title)
+
+			return Utils.EmailInfo{}, newsInfo
+		}
+	}
+
+	var self_text string = ""
+	if "" != feed_item.Description {
+		self_text, _ = sanitizeHtml(feed_item.Description, feed_item.Link, _DESCRIPTION_MAX_CHARS)
+		newsInfo.description = self_text
+	}
+
+	var subreddit string = getExtValue("subreddit")
+	var msg_subject string = feedInfo.Custom_msg_subject
+	if "" == msg_subject {
+		if _TYPE_2_REDDIT_USER == feedType.type_2 {
+			msg_subject = newsInfo.author + " publicou em " + subreddit + ": " + feed_item.Title
+		} else {
+			msg_subject = "Nova publicação em " + subreddit + ": " + feed_item.Title
+		}
+	}
+
+	var things_replace map[string]string = map[string]string{
+		_MODEL_REDDIT_HTML_TITLE:   html.EscapeString(msg_subject),
+		_MODEL_REDDIT_COLOR:        _REDDIT_COLOR,
+		_MODEL_REDDIT_SUBREDDIT:    html.EscapeString(subreddit),
+		_MODEL_REDDIT_AUTHOR:       html.EscapeString(newsInfo.author),
+		_MODEL_REDDIT_FLAIR:        "",
+		_MODEL_REDDIT_POST_TITLE:   html.EscapeString(feed_item.Title),
+		_MODEL_REDDIT_POST_URL:     html.EscapeString(feed_item.Link),
+		_MODEL_REDDIT_POST_DATE:    newsInfo.published.Local().Format(Utils.DATE_TIME_FORMAT),
+		_MODEL_REDDIT_SCORE:        strconv.Itoa(score),
+		_MODEL_REDDIT_NUM_COMMENTS: getExtValue("num_comments"),
+		_MODEL_REDDIT_THUMBNAIL:    "",
+		_MODEL_REDDIT_LINK:         "",
+		_MODEL_REDDIT_SELF_TEXT:    "",
+	}
+	if "" != flair {
+		things_replace[_MODEL_REDDIT_FLAIR] = " • <span style=\"background-color: #EDEFF1; padding: 1px 6px; " +
+			"border-radius: 8px;\">" + html.EscapeString(flair) + "</span>"
+	}
+	if "true" == getExtValue("nsfw") {
+		things_replace[_MODEL_REDDIT_FLAIR] += " • <b style=\"color: #FF0000;\">NSFW</b>"
+	}
+	// NSFW thumbnails are not put in the email.
+	if "" != newsInfo.image && "true" != getExtValue("nsfw") {
+		things_replace[_MODEL_REDDIT_THUMBNAIL] = "<p><img src=\"" + html.EscapeString(newsInfo.image) +
+			"\" style=\"max-width: 100%; height: auto;\"></p>\n"
+	}
+	if link := getExtValue("link"); "" != link {
+		things_replace[_MODEL_REDDIT_LINK] = "<p>🔗 <a href=\"" + html.EscapeString(link) + "\">" +
+			html.EscapeString(link) + "</a></p>\n"
+	}
+	if "" != self_text {
+		things_replace[_MODEL_REDDIT_SELF_TEXT] = "<div>" + self_text + "</div>\n"
+	}
+
+	var email_info Utils.EmailInfo = getRedditEmailInfo(things_replace)
+	email_info.Sender = parsed_feed.Title
+	email_info.Subject = msg_subject
+
+	return email_info, newsInfo
+}
+
+/*
+getRedditEmailInfo fills the Reddit email model.
+
+-----------------------------------------------------------
+
+– Params:
+  - things_replace – the values of the _MODEL_REDDIT_ placeholders
+
+– Returns:
+  - the email info with the HTML
+*/
+func getRedditEmailInfo(things_replace map[string]string) Utils.EmailInfo {
+	var replacements []string = nil
+	for placeholder, value := range things_replace {
+		replacements = append(replacements, placeholder, value)
+	}
+
+	return Utils.EmailInfo{
+		Html: strings.NewReplacer(replacements...).Replace(_REDDIT_EMAIL_MODEL),
+	}
+}
@@ -0,0 +1,206 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+	ext "github.com/mmcdole/gofeed/extensions"
+)
+
+// setTestRedditBaseUrl points the Reddit listings to a stand-in server until the end of the test.
+func setTestRedditBaseUrl(t *testing.T, base_url string) {
+	var old_base_url string = redditBaseUrl_GL
+	redditBaseUrl_GL = base_url
+	t.Cleanup(func() {
+		redditBaseUrl_GL = old_base_url
+		redditRateLimitedUntil_GL = time.Time{}
+	})
+}
+
+func TestGetRedditListingUrl(t *testing.T) {
+	setTestRedditBaseUrl(t, "https://reddit.test")
+
+	var tests = []struct {
+		feed_type string
+		feedInfo  _FeedInfo
+		expected  string
+	}{
+		{"Reddit SUB", _FeedInfo{Feed_url: "/r/golang+rust/"},
+			"https://reddit.test/r/golang+rust/new.json?limit=50&raw_json=1"},
+		{"Reddit SUB", _FeedInfo{Feed_url: "golang", Reddit_sort: "Top"},
+			"https://reddit.test/r/golang/top.json?limit=50&raw_json=1&t=day"},
+		{"Reddit USER", _FeedInfo{Feed_url: "u/someone", Reddit_sort: "hot"},
+			"https://reddit.test/user/someone/submitted.json?limit=50&raw_json=1&sort=hot"},
+		{"Reddit SEARCH", _FeedInfo{Feed_url: "tesla coil", Reddit_sort: "relevance"},
+			"https://reddit.test/search.json?limit=50&q=tesla+coil&raw_json=1&sort=relevance"},
+		{"Reddit OTHER", _FeedInfo{Feed_url: "golang"}, ""},
+	}
+	for _, test := range tests {
+		var listing_url string = getRedditListingUrl(test.feedInfo, getFeedType(test.feed_type))
+		if test.expected != listing_url {
+			t.Errorf("getRedditListingUrl(%s, %q) = %q, expected %q", test.feed_type, test.feedInfo.Feed_url,
+				listing_url, test.expected)
+		}
+	}
+}
+
+func TestRedditPostToItem(t *testing.T) {
+	var redditPost _RedditPost = _RedditPost{
+		Title:                 "Self post",
+		Author:                "someone",
+		Permalink:             "/r/golang/comments/1/self_post/",
+		Url:                   "https://www.reddit.com/r/golang/comments/1/self_post/",
+		Created_utc:           1700000000,
+		Score:                 42,
+		Link_flair_text:       "Discussion",
+		Subreddit_name_prefix: "r/golang",
+		Thumbnail:             "self",
+		Selftext_html:         "<p>Text</p>",
+		Is_self:               true,
+	}
+	var feed_item *gofeed.Item = redditPostToItem(redditPost)
+	if "https://www.reddit.com/r/golang/comments/1/self_post/" != feed_item.Link ||
+				"u/someone" != feed_item.Authors[0].Name || 1700000000 != feed_item.PublishedParsed.Unix() ||
+				"<p>Text</p>" != feed_item.Description {
+		t.Errorf("wrong item: %+v", feed_item)
+	}
+	if nil != feed_item.Image {
+		t.Errorf("a \"self\" thumbnail gave the image %q", feed_item.Image.URL)
+	}
+	if _, ok := feed_item.Extensions["reddit"]["link"]; ok {
+		t.Error("a self post has a link")
+	}
+	if "42" != feed_item.Extensions["reddit"]["score"][0].Value ||
+				"Discussion" != feed_item.Extensions["reddit"]["flair"][0].Value {
+		t.Errorf("wrong extension: %v", feed_item.Extensions["reddit"])
+	}
+
+	redditPost.Thumbnail = "default"
+	if feed_item = redditPostToItem(redditPost); nil != feed_item.Image {
+		t.Errorf("a \"default\" thumbnail gave the image %q", feed_item.Image.URL)
+	}
+
+	// Link post - the preview is preferred to the thumbnail.
+	redditPost.Is_self = false
+	redditPost.Url = "https://example.com/article"
+	redditPost.Thumbnail = "https://b.thumbs.redditmedia.com/small.jpg"
+	if feed_item = redditPostToItem(redditPost); nil == feed_item.Image ||
+				"https://b.thumbs.redditmedia.com/small.jpg" != feed_item.Image.URL {
+		t.Errorf("the thumbnail was not used: %+v", feed_item.Image)
+	}
+	var preview_json string = `{"preview": {"images": [{"source": {"url": "https://preview.redd.it/big.jpg"}}]}}`
+	if err := json.Unmarshal([]byte(preview_json), &redditPost); nil != err {
+		t.Fatal(err)
+	}
+	feed_item = redditPostToItem(redditPost)
+	if nil == feed_item.Image || "https://preview.redd.it/big.jpg" != feed_item.Image.URL {
+		t.Errorf("the preview was not used: %+v", feed_item.Image)
+	}
+	var links []ext.Extension = feed_item.Extensions["reddit"]["link"]
+	if 1 != len(links) || "https://example.com/article" != links[0].Value {
+		t.Errorf("wrong link: %v", links)
+	}
+}
+
+// _TEST_REDDIT_LISTING is a listing with a post, a comment (ignored) and a link post.
+const _TEST_REDDIT_LISTING string = `{"kind": "Listing", "data": {"children": [
+	{"kind": "t3", "data": {"title": "Low", "author": "a", "permalink": "/r/golang/comments/1/low/", "score": 1,
+		"created_utc": 1700000000, "subreddit_name_prefixed": "r/golang", "is_self": true, "thumbnail": "self",
+		"link_flair_text": "Discussion", "selftext_html": "<p>Some text</p>"}},
+	{"kind": "t1", "data": {"author": "b", "permalink": "/r/golang/comments/1/low/c1/"}},
+	{"kind": "t3", "data": {"title": "High", "author": "c", "permalink": "/r/golang/comments/2/high/", "score": 500,
+		"created_utc": 1700000100, "subreddit_name_prefixed": "r/golang", "is_self": false,
+		"url": "https://example.com/article", "thumbnail": "default", "link_flair_text": "News"}}
+]}}`
+
+func TestRedditTreatment(t *testing.T) {
+	var requests int = 0
+	var server *httptest.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if _REDDIT_USER_AGENT != r.Header.Get("User-Agent") {
+			t.Errorf("wrong User-Agent: %q", r.Header.Get("User-Agent"))
+		}
+		if "/r/golang/new.json" != r.URL.Path {
+			w.Header().Set("Retry-After", "60")
+			w.WriteHeader(http.StatusTooManyRequests)
+
+			return
+		}
+		_, _ = w.Write([]byte(_TEST_REDDIT_LISTING))
+	}))
+	defer server.Close()
+	setTestRedditBaseUrl(t, server.URL)
+
+	var feedType _FeedType = getFeedType("Reddit SUB")
+	parsed_feed, err := getRedditFeed(getRedditListingUrl(_FeedInfo{Feed_url: "golang"}, feedType), feedType)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if "r/golang" != parsed_feed.Title || 2 != len(parsed_feed.Items) || "Low" != parsed_feed.Items[0].Title ||
+				"High" != parsed_feed.Items[1].Title {
+		t.Fatalf("wrong feed: %+v", parsed_feed)
+	}
+
+	// Below the minimum score: an empty news info, so that it's checked again while it's in the listing.
+	emailInfo, newsInfo := redditTreatment(_FeedInfo{Reddit_min_score: 10}, feedType, parsed_feed, 0, false)
+	if "" != emailInfo.Html || "" != newsInfo.url {
+		t.Errorf("post below the minimum score: %+v, %+v", emailInfo, newsInfo)
+	}
+
+	// Unwanted flair: ignored for good.
+	emailInfo, newsInfo = redditTreatment(_FeedInfo{Reddit_flairs: []string{" news "}}, feedType, parsed_feed, 0, false)
+	if "" != emailInfo.Html || "https://www.reddit.com/r/golang/comments/1/low/" != newsInfo.url {
+		t.Errorf("post with an unwanted flair: %+v, %+v", emailInfo, newsInfo)
+	}
+
+	emailInfo, newsInfo = redditTreatment(_FeedInfo{Reddit_min_score: 10, Reddit_flairs: []string{"news"}}, feedType,
+		parsed_feed, 1, false)
+	if "Nova publicação em r/golang: High" != emailInfo.Subject || "r/golang" != emailInfo.Sender ||
+				!strings.Contains(emailInfo.Html, `<a href="https://example.com/article">`) ||
+				!strings.Contains(emailInfo.Html, "▲ 500 pontos") || "u/c" != newsInfo.author {
+		t.Errorf("wanted post: %+v, %+v", emailInfo, newsInfo)
+	}
+
+	emailInfo, newsInfo = redditTreatment(_FeedInfo{}, feedType, parsed_feed, 0, false)
+	if !strings.Contains(emailInfo.Html, "<p>Some text</p>") || !strings.Contains(newsInfo.description, "Some text") {
+		t.Errorf("self post: %+v, %+v", emailInfo, newsInfo)
+	}
+
+	// Rate limited: no more requests until the pause ends.
+	feedType = getFeedType("Reddit USER")
+	if _, err = getRedditFeed(getRedditListingUrl(_FeedInfo{Feed_url: "someone"}, feedType), feedType);
+				errRedditRateLimited != err {
+		t.Errorf("expected errRedditRateLimited, got %v", err)
+	}
+	var requests_before int = requests
+	if _, err = getRedditFeed(getRedditListingUrl(_FeedInfo{Feed_url: "golang"}, feedType), feedType);
+				errRedditRateLimited != err || requests_before != requests {
+		t.Errorf("expected no request while rate limited, got %v after %d requests", err, requests - requests_before)
+	}
+}
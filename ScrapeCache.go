@@ -0,0 +1,317 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"Utils"
+)
+
+// _CACHE_PAGES_MAX_BYTES is the maximum total size of the pages kept by getPageHtmlCached() during a cycle - the oldest
+// ones are forgotten first.
+const _CACHE_PAGES_MAX_BYTES int = 32 << 20
+
+// Kinds of data stored in the scrape cache.
+const (
+	_CACHE_KIND_CHANNEL_IMAGE    string = "channel_image"    // Channel image URLs mapped by channel code
+	_CACHE_KIND_PLAYLIST         string = "playlist"         // Crawled playlists mapped by playlist ID
+	_CACHE_KIND_VIDEO_INFO       string = "video_info"       // Video page information mapped by video ID
+	_CACHE_KIND_ARTICLE          string = "article"          // Extracted article HTML mapped by page URL
+	_CACHE_KIND_MASTODON_ACCOUNT string = "mastodon_account" // Mastodon accounts resolved through WebFinger by account
+	_CACHE_KIND_SE_QUESTION      string = "se_question"      // StackExchange questions mapped by "<site>/<question ID>"
+	_CACHE_KIND_SHORT_VERDICT    string = "short_verdict"    // Whether videos are Shorts mapped by video ID
+)
+
+// _CacheKindInfo is the configuration of a kind of cached data.
+type _CacheKindInfo struct {
+	// ttl is how long the data is valid (0 means only during the current cycle)
+	ttl time.Duration
+	// max_entries is the maximum number of entries kept - the oldest ones are removed first
+	max_entries int
+	// persist is whether the data is saved to disk
+	persist bool
+}
+
+var cacheKindsInfo_GL map[string]_CacheKindInfo = map[string]_CacheKindInfo{
+	_CACHE_KIND_CHANNEL_IMAGE:    {ttl: 7 * 24 * time.Hour, max_entries: 1000, persist: true},
+	// Ascending playlists are only known to have new videos by crawling them, so this delays their notifications.
+	_CACHE_KIND_PLAYLIST:         {ttl: 10 * time.Minute, max_entries: 100, persist: true},
+	// Only finished videos are stored (their information doesn't change anymore).
+	_CACHE_KIND_VIDEO_INFO:       {ttl: 30 * 24 * time.Hour, max_entries: 5000, persist: true},
+	// Items are only notified once, so this is mostly for replays and for the feeds sharing items.
+	_CACHE_KIND_ARTICLE:          {ttl: 7 * 24 * time.Hour, max_entries: 300, persist: true},
+	_CACHE_KIND_MASTODON_ACCOUNT: {ttl: 7 * 24 * time.Hour, max_entries: 200, persist: true},
+	// The scores and answers change, so they're only kept for all the questions of a feed to be got at once.
+	_CACHE_KIND_SE_QUESTION:      {ttl: 0, max_entries: 500, persist: false},
+	// A video doesn't stop being a Short (or start being one), so the verdicts are kept for long.
+	_CACHE_KIND_SHORT_VERDICT:    {ttl: 365 * 24 * time.Hour, max_entries: 2000, persist: true},
+}
+
+// _CacheEntry is an entry of the scrape cache. It's exported to JSON, so the fields are exported.
+type _CacheEntry struct {
+	// Value is the data, in JSON
+	Value json.RawMessage
+	// Stored is when the entry was stored in Unix seconds
+	Stored int64
+}
+
+// _ScrapeCache is the cache of scraped pages and data got from them, mapped by kind and then by key.
+type _ScrapeCache struct {
+	mutex    sync.Mutex
+	loaded   bool
+	modified bool
+	entries  map[string]map[string]_CacheEntry
+	// pages is the raw HTML of the pages got during the current cycle, mapped by URL (not in entries, as they're big
+	// and only kept during the cycle)
+	pages       map[string]string
+	// pages_urls is the URLs of pages in the order they were got
+	pages_urls  []string
+	// pages_bytes is the total size of pages
+	pages_bytes int
+}
+
+var scrapeCache_GL _ScrapeCache = _ScrapeCache{}
+
+/*
+scrapeCacheGet gets data from the scrape cache.
+
+-----------------------------------------------------------
+
+– Params:
+  - kind – one of the _CACHE_KIND_ constants
+  - key – the key of the data
+  - p_value – pointer to where to decode the data to
+
+– Returns:
+  - true if the data was found and is still valid, false otherwise
+*/
+func scrapeCacheGet(kind string, key string, p_value any) bool {
+	scrapeCache_GL.mutex.Lock()
+	defer scrapeCache_GL.mutex.Unlock()
+
+	loadScrapeCache()
+
+	entry, ok := scrapeCache_GL.entries[kind][key]
+	if !ok {
+		return false
+	}
+	var ttl time.Duration = cacheKindsInfo_GL[kind].ttl
+	if 0 != ttl && time.Since(time.Unix(entry.Stored, 0)) > ttl {
+		delete(scrapeCache_GL.entries[kind], key)
+		scrapeCache_GL.modified = true
+
+		return false
+	}
+
+	return nil == json.Unmarshal(entry.Value, p_value)
+}
+
+/*
+scrapeCacheSet stores data on the scrape cache.
+
+-----------------------------------------------------------
+
+– Params:
+  - kind – one of the _CACHE_KIND_ constants
+  - key – the key of the data
+  - value – the data (must be encodable to JSON)
+*/
+func scrapeCacheSet(kind string, key string, value any) {
+	value_json, err := json.Marshal(value)
+	if nil != err {
+		return
+	}
+
+	scrapeCache_GL.mutex.Lock()
+	defer scrapeCache_GL.mutex.Unlock()
+
+	loadScrapeCache()
+
+	var kind_entries map[string]_CacheEntry = scrapeCache_GL.entries[kind]
+	if nil == kind_entries {
+		kind_entries = make(map[string]_CacheEntry)
+		scrapeCache_GL.entries[kind] = kind_entries
+	}
+	kind_entries[key] = _CacheEntry{
+		Value:  value_json,
+		Stored: time.Now().Unix(),
+	}
+	limitCacheEntries(kind_entries, cacheKindsInfo_GL[kind].max_entries)
+	scrapeCache_GL.modified = true
+}
+
+/*
+getPageHtmlCached gets the HTML of a page, but only once per cycle - the next times it's got from the scrape cache.
+
+-----------------------------------------------------------
+
+– Params:
+  - page_url – the URL of the page
+
+– Returns:
+  - the HTML of the page or nil if an error occurs
+*/
+func getPageHtmlCached(page_url string) *string {
+	scrapeCache_GL.mutex.Lock()
+	page_html, ok := scrapeCache_GL.pages[page_url]
+	scrapeCache_GL.mutex.Unlock()
+	if ok {
+		return &page_html
+	}
+
+	var p_page_html *string = Utils.GetPageHtmlTIMEDATE(page_url)
+	if nil == p_page_html || len(*p_page_html) > _CACHE_PAGES_MAX_BYTES {
+		return p_page_html
+	}
+
+	scrapeCache_GL.mutex.Lock()
+	defer scrapeCache_GL.mutex.Unlock()
+
+	if nil == scrapeCache_GL.pages {
+		scrapeCache_GL.pages = make(map[string]string)
+	}
+	if _, ok = scrapeCache_GL.pages[page_url]; !ok {
+		scrapeCache_GL.pages[page_url] = *p_page_html
+		scrapeCache_GL.pages_urls = append(scrapeCache_GL.pages_urls, page_url)
+		scrapeCache_GL.pages_bytes += len(*p_page_html)
+	}
+	for scrapeCache_GL.pages_bytes > _CACHE_PAGES_MAX_BYTES {
+		var oldest_url string = scrapeCache_GL.pages_urls[0]
+		scrapeCache_GL.pages_urls = scrapeCache_GL.pages_urls[1:]
+		scrapeCache_GL.pages_bytes -= len(scrapeCache_GL.pages[oldest_url])
+		delete(scrapeCache_GL.pages, oldest_url)
+	}
+
+	return p_page_html
+}
+
+/*
+endScrapeCacheCycle must be called at the end of each cycle: it forgets the data only valid during the cycle and saves
+the rest to disk.
+*/
+func endScrapeCacheCycle() {
+	scrapeCache_GL.mutex.Lock()
+	defer scrapeCache_GL.mutex.Unlock()
+
+	loadScrapeCache()
+
+	scrapeCache_GL.pages = nil
+	scrapeCache_GL.pages_urls = nil
+	scrapeCache_GL.pages_bytes = 0
+
+	var now time.Time = time.Now()
+	var entries_to_save map[string]map[string]_CacheEntry = make(map[string]map[string]_CacheEntry)
+	for kind, kind_entries := range scrapeCache_GL.entries {
+		var kindInfo _CacheKindInfo = cacheKindsInfo_GL[kind]
+		if 0 == kindInfo.ttl {
+			delete(scrapeCache_GL.entries, kind)
+
+			continue
+		}
+		for key, entry := range kind_entries {
+			if now.Sub(time.Unix(entry.Stored, 0)) > kindInfo.ttl {
+				delete(kind_entries, key)
+				scrapeCache_GL.modified = true
+			}
+		}
+		if kindInfo.persist {
+			entries_to_save[kind] = kind_entries
+		}
+	}
+
+	if !scrapeCache_GL.modified {
+		return
+	}
+
+	cache_json, err := json.Marshal(entries_to_save)
+	if nil != err {
+		fmt.Println("Error saving the scrape cache: " + err.Error())
+
+		return
+	}
+	getScrapeCachePath().WriteTextFile(string(cache_json))
+	scrapeCache_GL.modified = false
+}
+
+/*
+loadScrapeCache loads the scrape cache from disk if it wasn't loaded yet. The mutex must be locked by the caller.
+*/
+func loadScrapeCache() {
+	if scrapeCache_GL.loaded {
+		return
+	}
+	scrapeCache_GL.loaded = true
+	scrapeCache_GL.entries = make(map[string]map[string]_CacheEntry)
+
+	var p_cache_json *string = getScrapeCachePath().ReadTextFile()
+	if nil == p_cache_json {
+		return
+	}
+	if err := json.Unmarshal([]byte(*p_cache_json), &scrapeCache_GL.entries); nil != err {
+		fmt.Println("Error loading the scrape cache: " + err.Error())
+
+		scrapeCache_GL.entries = make(map[string]map[string]_CacheEntry)
+	}
+}
+
+/*
+limitCacheEntries removes the oldest entries of a kind until there are only max_entries left.
+
+-----------------------------------------------------------
+
+– Params:
+  - kind_entries – the entries of the kind
+  - max_entries – the maximum number of entries
+*/
+func limitCacheEntries(kind_entries map[string]_CacheEntry, max_entries int) {
+	if len(kind_entries) <= max_entries {
+		return
+	}
+
+	var keys []string = make([]string, 0, len(kind_entries))
+	for key := range kind_entries {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return kind_entries[keys[i]].Stored < kind_entries[keys[j]].Stored
+	})
+	for _, key := range keys[:len(keys)-max_entries] {
+		delete(kind_entries, key)
+	}
+}
+
+/*
+getScrapeCachePath gets the path of the scrape cache file.
+
+-----------------------------------------------------------
+
+– Returns:
+  - the path of the file
+*/
+func getScrapeCachePath() Utils.GPath {
+	return moduleInfo_GL.ModDirsInfo.Temp.Add2("scrape_cache.json")
+}
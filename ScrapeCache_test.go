@@ -0,0 +1,146 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func setTestCacheKind(t *testing.T, kind string, kindInfo _CacheKindInfo) {
+	cacheKindsInfo_GL[kind] = kindInfo
+	t.Cleanup(func() {
+		delete(cacheKindsInfo_GL, kind)
+		scrapeCache_GL.mutex.Lock()
+		delete(scrapeCache_GL.entries, kind)
+		scrapeCache_GL.mutex.Unlock()
+	})
+}
+
+func TestScrapeCacheTtl(t *testing.T) {
+	setTestCacheKind(t, "test_ttl", _CacheKindInfo{ttl: time.Hour, max_entries: 10})
+
+	scrapeCacheSet("test_ttl", "fresh", "value")
+	scrapeCache_GL.mutex.Lock()
+	scrapeCache_GL.entries["test_ttl"]["expired"] = _CacheEntry{
+		Value:  json.RawMessage(`"value"`),
+		Stored: time.Now().Add(-2 * time.Hour).Unix(),
+	}
+	scrapeCache_GL.mutex.Unlock()
+
+	var value string = ""
+	if !scrapeCacheGet("test_ttl", "fresh", &value) || "value" != value {
+		t.Errorf("fresh entry: got %q, expected \"value\"", value)
+	}
+	if scrapeCacheGet("test_ttl", "expired", &value) {
+		t.Error("expired entry: expected not found")
+	}
+	if _, ok := scrapeCache_GL.entries["test_ttl"]["expired"]; ok {
+		t.Error("expired entry: expected it removed")
+	}
+	if scrapeCacheGet("test_ttl", "missing", &value) {
+		t.Error("missing entry: expected not found")
+	}
+}
+
+func TestLimitCacheEntries(t *testing.T) {
+	var now int64 = time.Now().Unix()
+	var kind_entries map[string]_CacheEntry = map[string]_CacheEntry{
+		"newest": {Stored: now},
+		"oldest": {Stored: now - 30},
+		"middle": {Stored: now - 20},
+		"older":  {Stored: now - 25},
+	}
+
+	limitCacheEntries(kind_entries, 4)
+	if 4 != len(kind_entries) {
+		t.Errorf("under the maximum: got %d entries, expected 4", len(kind_entries))
+	}
+
+	limitCacheEntries(kind_entries, 2)
+	if 2 != len(kind_entries) {
+		t.Errorf("over the maximum: got %d entries, expected 2", len(kind_entries))
+	}
+	for _, key := range []string{"newest", "middle"} {
+		if _, ok := kind_entries[key]; !ok {
+			t.Errorf("over the maximum: expected %q kept", key)
+		}
+	}
+
+	// Through scrapeCacheSet.
+	setTestCacheKind(t, "test_max", _CacheKindInfo{ttl: time.Hour, max_entries: 2})
+	for _, key := range []string{"a", "b", "c"} {
+		scrapeCacheSet("test_max", key, key)
+	}
+	if 2 != len(scrapeCache_GL.entries["test_max"]) {
+		t.Errorf("scrapeCacheSet: got %d entries, expected 2", len(scrapeCache_GL.entries["test_max"]))
+	}
+}
+
+func TestGetPageHtmlCachedMaxBytes(t *testing.T) {
+	var page_requests map[string]int = make(map[string]int)
+	var server *httptest.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page_requests[r.URL.Path]++
+		var size int = 12 << 20
+		if "/huge" == r.URL.Path {
+			size = _CACHE_PAGES_MAX_BYTES + 1
+		}
+		_, _ = w.Write([]byte(strings.Repeat("a", size)))
+	}))
+	defer server.Close()
+	t.Cleanup(func() {
+		scrapeCache_GL.mutex.Lock()
+		scrapeCache_GL.pages = nil
+		scrapeCache_GL.pages_urls = nil
+		scrapeCache_GL.pages_bytes = 0
+		scrapeCache_GL.mutex.Unlock()
+	})
+
+	// A page bigger than the maximum is never kept.
+	for i := 0; i < 2; i++ {
+		if p_page_html := getPageHtmlCached(server.URL + "/huge"); nil == p_page_html {
+			t.Fatal("huge page: got nil")
+		}
+	}
+	if 2 != page_requests["/huge"] {
+		t.Errorf("huge page: got %d requests, expected 2", page_requests["/huge"])
+	}
+
+	// 3 pages of 12 MB don't fit in 32 MB, so the 1st is forgotten.
+	for _, path := range []string{"/page1", "/page2", "/page3", "/page2", "/page3", "/page1"} {
+		if p_page_html := getPageHtmlCached(server.URL + path); nil == p_page_html {
+			t.Fatalf("%s: got nil", path)
+		}
+	}
+	for path, expected := range map[string]int{"/page1": 2, "/page2": 1, "/page3": 1} {
+		if expected != page_requests[path] {
+			t.Errorf("%s: got %d requests, expected %d", path, page_requests[path], expected)
+		}
+	}
+	if scrapeCache_GL.pages_bytes > _CACHE_PAGES_MAX_BYTES {
+		t.Errorf("got %d bytes kept, expected at most %d", scrapeCache_GL.pages_bytes, _CACHE_PAGES_MAX_BYTES)
+	}
+}
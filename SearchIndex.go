@@ -0,0 +1,686 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	nethtml "golang.org/x/net/html"
+
+	"Utils"
+)
+
+// _SEARCH_MAX_RESULTS_DEF is the default maximum number of search results.
+const _SEARCH_MAX_RESULTS_DEF int = 20
+
+// _SearchIndex is the full-text search index over the notified items.
+type _SearchIndex struct {
+	// items are the indexed items, with the description in plain text, from the oldest to the newest
+	items []_NotifiedItem
+	// postings maps each word to the indexes on items of the items that have it
+	postings map[string][]int
+	// indexed has the feed numbers and URLs of the indexed items (to not index the same item twice)
+	indexed map[string]bool
+}
+
+// _SearchQuery is a parsed search query.
+type _SearchQuery struct {
+	// words are the words that must all be in the item
+	words []string
+	// phrases are the phrases (as words) that must all be in the item, in one of its fields
+	phrases [][]string
+	// feeds are the feeds to search in (numbers or parts of the titles - any of them), from "feed:"
+	feeds []string
+	// tags are the tags of the feeds to search in (any of them), from "tag:"
+	tags []string
+	// since is the minimum date of the items (zero for none), from "since:"
+	since time.Time
+	// until is the date the items must be before (zero for none), from "until:"
+	until time.Time
+}
+
+// _SearchResult is an item found by a search.
+type _SearchResult struct {
+	// notifiedItem is the item
+	notifiedItem _NotifiedItem
+	// score is how relevant the item is to the query
+	score int
+}
+
+// searchIndex_GL is the search index, loaded on the first use. Must be used with searchIndexMutex_GL locked.
+var searchIndex_GL *_SearchIndex = nil
+var searchIndexMutex_GL sync.Mutex
+
+// searchAccentsReplacer_GL removes the accents of the letters, so that searching works with and without them.
+var searchAccentsReplacer_GL *strings.Replacer = strings.NewReplacer(
+	"á", "a", "à", "a", "â", "a", "ã", "a", "ä", "a", "é", "e", "è", "e", "ê", "e", "ë", "e", "í", "i", "ì", "i",
+	"î", "i", "ï", "i", "ó", "o", "ò", "o", "ô", "o", "õ", "o", "ö", "o", "ú", "u", "ù", "u", "û", "u", "ü", "u",
+	"ç", "c", "ñ", "n",
+)
+
+/*
+addToSearchIndex adds a notified item to the search index (if it's not there yet).
+
+-----------------------------------------------------------
+
+– Params:
+  - notifiedItem – the notified item
+*/
+func addToSearchIndex(notifiedItem _NotifiedItem) {
+	searchIndexMutex_GL.Lock()
+	defer searchIndexMutex_GL.Unlock()
+
+	var searchIndex *_SearchIndex = getSearchIndex()
+	if searchIndex.indexed[getSearchIndexKey(notifiedItem)] {
+		return
+	}
+	var searchableItem _NotifiedItem = toSearchableItem(notifiedItem)
+	searchIndex.add(searchableItem)
+
+	appendToSearchIndex(searchableItem)
+}
+
+/*
+searchNotifiedItems searches the notified items.
+
+-----------------------------------------------------------
+
+– Params:
+  - query – the search query: words, "phrases" between quotes, feed:<number or title>, tag:<tag>, since:<date> and
+    until:<date> (dates as 2006-01-02 or 2006-01)
+  - max_results – the maximum number of results (0 for no maximum)
+
+– Returns:
+  - the items found, from the most to the least relevant (and from the newest to the oldest when equal)
+*/
+func searchNotifiedItems(query string, max_results int) []_SearchResult {
+	var searchQuery _SearchQuery = parseSearchQuery(query)
+
+	searchIndexMutex_GL.Lock()
+	defer searchIndexMutex_GL.Unlock()
+
+	var searchIndex *_SearchIndex = getSearchIndex()
+
+	var all_words []string = searchQuery.words
+	for _, phrase := range searchQuery.phrases {
+		all_words = append(all_words, phrase...)
+	}
+
+	// The candidates are the items with all the words (or all the items if there are no words).
+	var candidates []int = nil
+	if 0 == len(all_words) {
+		for i := range searchIndex.items {
+			candidates = append(candidates, i)
+		}
+	} else {
+		var counts map[int]int = make(map[int]int)
+		var unique_words map[string]bool = make(map[string]bool)
+		for _, word := range all_words {
+			if unique_words[word] {
+				continue
+			}
+			unique_words[word] = true
+			for _, item_idx := range searchIndex.postings[word] {
+				counts[item_idx]++
+			}
+		}
+		for item_idx, count := range counts {
+			if count == len(unique_words) {
+				candidates = append(candidates, item_idx)
+			}
+		}
+	}
+
+	var searchResults []_SearchResult = nil
+	for _, item_idx := range candidates {
+		var notifiedItem _NotifiedItem = searchIndex.items[item_idx]
+		if !searchQuery.matchesFilters(notifiedItem) {
+			continue
+		}
+
+		var fields_words [][]string = [][]string{
+			tokenizeSearchText(notifiedItem.Title),
+			tokenizeSearchText(notifiedItem.Description),
+			tokenizeSearchText(notifiedItem.Author),
+			tokenizeSearchText(notifiedItem.Feed_title),
+		}
+		var score int = 0
+		var phrases_found bool = true
+		for _, phrase := range searchQuery.phrases {
+			var phrase_found bool = false
+			for field_idx, field_words := range fields_words {
+				if containsPhrase(field_words, phrase) {
+					phrase_found = true
+					// A phrase in the title counts more.
+					if 0 == field_idx {
+						score += 5
+					} else {
+						score += 2
+					}
+				}
+			}
+			if !phrase_found {
+				phrases_found = false
+
+				break
+			}
+		}
+		if !phrases_found {
+			continue
+		}
+		for _, word := range all_words {
+			for field_idx, field_words := range fields_words {
+				for _, field_word := range field_words {
+					if field_word == word {
+						if 0 == field_idx {
+							score += 3
+						} else {
+							score++
+						}
+					}
+				}
+			}
+		}
+
+		searchResults = append(searchResults, _SearchResult{
+			notifiedItem: notifiedItem,
+			score:        score,
+		})
+	}
+
+	sort.SliceStable(searchResults, func(i, j int) bool {
+		if searchResults[i].score != searchResults[j].score {
+			return searchResults[i].score > searchResults[j].score
+		}
+
+		return getItemDate(searchResults[i].notifiedItem).After(getItemDate(searchResults[j].notifiedItem))
+	})
+	if max_results > 0 && len(searchResults) > max_results {
+		searchResults = searchResults[:max_results]
+	}
+
+	return searchResults
+}
+
+/*
+parseSearchQuery parses a search query (see searchNotifiedItems()).
+
+-----------------------------------------------------------
+
+– Params:
+  - query – the search query
+
+– Returns:
+  - the parsed query
+*/
+func parseSearchQuery(query string) _SearchQuery {
+	var searchQuery _SearchQuery
+
+	// Split on the spaces outside quotes, keeping the quotes.
+	var parts []string = nil
+	var part strings.Builder
+	var in_quotes bool = false
+	for _, char := range query {
+		if '"' == char {
+			in_quotes = !in_quotes
+		}
+		if unicode.IsSpace(char) && !in_quotes {
+			parts = append(parts, part.String())
+			part.Reset()
+
+			continue
+		}
+		part.WriteRune(char)
+	}
+	parts = append(parts, part.String())
+
+	for _, part := range parts {
+		if "" == part {
+			continue
+		}
+
+		if strings.HasPrefix(part, "\"") {
+			var phrase []string = tokenizeSearchText(strings.Trim(part, "\""))
+			if len(phrase) > 1 {
+				searchQuery.phrases = append(searchQuery.phrases, phrase)
+			} else {
+				searchQuery.words = append(searchQuery.words, phrase...)
+			}
+
+			continue
+		}
+
+		filter, value, is_filter := strings.Cut(part, ":")
+		value = strings.Trim(value, "\"")
+		if is_filter && "" != value {
+			switch strings.ToLower(filter) {
+				case "feed": {
+					searchQuery.feeds = append(searchQuery.feeds, value)
+
+					continue
+				}
+				case "tag": {
+					searchQuery.tags = append(searchQuery.tags, normalizeTag(value))
+
+					continue
+				}
+				case "since", "until": {
+					since, until, ok := parseSearchDate(value)
+					if !ok {
+						fmt.Println("Invalid search date: " + value)
+
+						continue
+					}
+					if "since" == strings.ToLower(filter) {
+						searchQuery.since = since
+					} else {
+						searchQuery.until = until
+					}
+
+					continue
+				}
+			}
+		}
+
+		searchQuery.words = append(searchQuery.words, tokenizeSearchText(part)...)
+	}
+
+	return searchQuery
+}
+
+/*
+parseSearchDate parses a date of a search query, which can be a day or a month.
+
+-----------------------------------------------------------
+
+– Params:
+  - date – the date, as 2006-01-02 or 2006-01
+
+– Returns:
+  - the beginning of the day or month
+  - the end of the day or month (the beginning of the next one)
+  - true if the date is valid, false otherwise
+*/
+func parseSearchDate(date string) (time.Time, time.Time, bool) {
+	if day, err := time.ParseInLocation("2006-01-02", date, time.Local); nil == err {
+		return day, day.AddDate(0, 0, 1), true
+	}
+	if month, err := time.ParseInLocation("2006-01", date, time.Local); nil == err {
+		return month, month.AddDate(0, 1, 0), true
+	}
+
+	return time.Time{}, time.Time{}, false
+}
+
+/*
+matchesFilters checks if an item matches the feed, tag and date filters of the query.
+
+-----------------------------------------------------------
+
+– Params:
+  - notifiedItem – the item
+
+– Returns:
+  - true if the item matches all the filters, false otherwise
+*/
+func (searchQuery _SearchQuery) matchesFilters(notifiedItem _NotifiedItem) bool {
+	if 0 != len(searchQuery.feeds) {
+		var feed_matches bool = false
+		for _, feed := range searchQuery.feeds {
+			if strconv.Itoa(notifiedItem.Feed_num) == feed ||
+						strings.Contains(strings.ToLower(notifiedItem.Feed_title), strings.ToLower(feed)) {
+				feed_matches = true
+
+				break
+			}
+		}
+		if !feed_matches {
+			return false
+		}
+	}
+
+	if 0 != len(searchQuery.tags) {
+		var tag_matches bool = false
+		for _, tag := range searchQuery.tags {
+			if containsTag(notifiedItem.Tags, tag) {
+				tag_matches = true
+
+				break
+			}
+		}
+		if !tag_matches {
+			return false
+		}
+	}
+
+	var date time.Time = getItemDate(notifiedItem)
+	if !searchQuery.since.IsZero() && date.Before(searchQuery.since) {
+		return false
+	}
+	if !searchQuery.until.IsZero() && !date.Before(searchQuery.until) {
+		return false
+	}
+
+	return true
+}
+
+/*
+forgetSearchIndex forgets the loaded search index, so that it's read again from the file on the next use (for when
+another process may have changed it).
+*/
+func forgetSearchIndex() {
+	searchIndexMutex_GL.Lock()
+	defer searchIndexMutex_GL.Unlock()
+
+	searchIndex_GL = nil
+}
+
+/*
+getSearchIndex gets the search index, loading it if it wasn't yet. The first time ever, it's built from the notified
+items history. Must be called with searchIndexMutex_GL locked.
+
+-----------------------------------------------------------
+
+– Returns:
+  - the search index
+*/
+func getSearchIndex() *_SearchIndex {
+	if nil != searchIndex_GL {
+		return searchIndex_GL
+	}
+
+	searchIndex_GL = newSearchIndex()
+
+	var p_search_index_jsonl *string = getSearchIndexPath().ReadTextFile()
+	if nil != p_search_index_jsonl {
+		for _, notifiedItem := range parseSearchIndexLines(*p_search_index_jsonl) {
+			if !searchIndex_GL.indexed[getSearchIndexKey(notifiedItem)] {
+				searchIndex_GL.add(notifiedItem)
+			}
+		}
+
+		return searchIndex_GL
+	}
+
+	// readAllNotifiedItems() returns them from the newest to the oldest.
+	var notified_items []_NotifiedItem = readAllNotifiedItems()
+	for i := len(notified_items) - 1; i >= 0; i-- {
+		if !searchIndex_GL.indexed[getSearchIndexKey(notified_items[i])] {
+			searchIndex_GL.add(toSearchableItem(notified_items[i]))
+		}
+	}
+	writeSearchIndex(searchIndex_GL.items)
+
+	return searchIndex_GL
+}
+
+/*
+newSearchIndex creates an empty search index.
+
+-----------------------------------------------------------
+
+– Returns:
+  - the search index
+*/
+func newSearchIndex() *_SearchIndex {
+	return &_SearchIndex{
+		postings: make(map[string][]int),
+		indexed:  make(map[string]bool),
+	}
+}
+
+/*
+add adds an item to the index (without writing it to the file).
+
+-----------------------------------------------------------
+
+– Params:
+  - notifiedItem – the item, with the description in plain text
+*/
+func (searchIndex *_SearchIndex) add(notifiedItem _NotifiedItem) {
+	var item_idx int = len(searchIndex.items)
+	searchIndex.items = append(searchIndex.items, notifiedItem)
+	searchIndex.indexed[getSearchIndexKey(notifiedItem)] = true
+
+	var words []string = tokenizeSearchText(notifiedItem.Title + " " + notifiedItem.Description + " " +
+		notifiedItem.Author + " " + notifiedItem.Feed_title)
+	var added map[string]bool = make(map[string]bool)
+	for _, word := range words {
+		if !added[word] {
+			added[word] = true
+			searchIndex.postings[word] = append(searchIndex.postings[word], item_idx)
+		}
+	}
+}
+
+/*
+toSearchableItem converts the description of a notified item to plain text, to be indexed.
+
+-----------------------------------------------------------
+
+– Params:
+  - notifiedItem – the notified item
+
+– Returns:
+  - the item with the description in plain text
+*/
+func toSearchableItem(notifiedItem _NotifiedItem) _NotifiedItem {
+	// The YouTube descriptions are already plain text.
+	if _TYPE_1_YOUTUBE != notifiedItem.Category {
+		notifiedItem.Description = htmlToText(notifiedItem.Description)
+	}
+
+	return notifiedItem
+}
+
+/*
+htmlToText gets the text of HTML.
+
+-----------------------------------------------------------
+
+– Params:
+  - html_str – the HTML
+
+– Returns:
+  - the text, with the texts of the different elements separated by spaces
+*/
+func htmlToText(html_str string) string {
+	var texts []string = nil
+	var tokenizer *nethtml.Tokenizer = nethtml.NewTokenizer(strings.NewReader(html_str))
+	for {
+		switch tokenizer.Next() {
+			case nethtml.ErrorToken: {
+				return strings.Join(texts, " ")
+			}
+			case nethtml.TextToken: {
+				if text := strings.TrimSpace(string(tokenizer.Text())); "" != text {
+					texts = append(texts, text)
+				}
+			}
+		}
+	}
+}
+
+/*
+tokenizeSearchText splits text in words to index or search: in lower case, without accents and without punctuation.
+
+-----------------------------------------------------------
+
+– Params:
+  - text – the text
+
+– Returns:
+  - the words, in order
+*/
+func tokenizeSearchText(text string) []string {
+	return strings.FieldsFunc(searchAccentsReplacer_GL.Replace(strings.ToLower(text)), func(char rune) bool {
+		return !unicode.IsLetter(char) && !unicode.IsNumber(char)
+	})
+}
+
+/*
+containsPhrase checks if words have a phrase.
+
+-----------------------------------------------------------
+
+– Params:
+  - words – the words
+  - phrase – the words of the phrase
+
+– Returns:
+  - true if the phrase words are in the words, in order and one after the other, false otherwise
+*/
+func containsPhrase(words []string, phrase []string) bool {
+	for i := 0; i+len(phrase) <= len(words); i++ {
+		var found bool = true
+		for j, phrase_word := range phrase {
+			if words[i+j] != phrase_word {
+				found = false
+
+				break
+			}
+		}
+		if found {
+			return true
+		}
+	}
+
+	return false
+}
+
+/*
+getSearchIndexKey gets the key of an item on _SearchIndex.indexed.
+
+-----------------------------------------------------------
+
+– Params:
+  - notifiedItem – the item
+
+– Returns:
+  - the key
+*/
+func getSearchIndexKey(notifiedItem _NotifiedItem) string {
+	return strconv.Itoa(notifiedItem.Feed_num) + " " + notifiedItem.Url
+}
+
+/*
+writeSearchIndex writes all the indexed items to the search index file, replacing it.
+
+-----------------------------------------------------------
+
+– Params:
+  - notified_items – the indexed items
+*/
+func writeSearchIndex(notified_items []_NotifiedItem) {
+	var builder strings.Builder
+	for _, notifiedItem := range notified_items {
+		item_json, err := json.Marshal(notifiedItem)
+		if nil != err {
+			fmt.Println("Error writing the search index: " + err.Error())
+
+			return
+		}
+		builder.Write(item_json)
+		builder.WriteString("\n")
+	}
+	getSearchIndexPath().WriteTextFile(builder.String())
+}
+
+/*
+appendToSearchIndex appends an indexed item to the search index file, without rewriting the rest of the file.
+
+-----------------------------------------------------------
+
+– Params:
+  - notifiedItem – the indexed item
+*/
+func appendToSearchIndex(notifiedItem _NotifiedItem) {
+	item_json, err := json.Marshal(notifiedItem)
+	if nil != err {
+		fmt.Println("Error writing the search index: " + err.Error())
+
+		return
+	}
+
+	var open_flags int = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	file, err := os.OpenFile(getSearchIndexPath().GPathToStringConversion(), open_flags, 0o644)
+	if nil != err {
+		fmt.Println("Error writing the search index: " + err.Error())
+
+		return
+	}
+	defer file.Close()
+
+	if _, err = file.Write(append(item_json, '\n')); nil != err {
+		fmt.Println("Error writing the search index: " + err.Error())
+	}
+}
+
+/*
+parseSearchIndexLines parses the contents of the search index file - one item in JSON per line.
+
+-----------------------------------------------------------
+
+– Params:
+  - search_index_jsonl – the contents of the file
+
+– Returns:
+  - the indexed items, in the order of the file (an invalid line, like one cut by a crash, is skipped)
+*/
+func parseSearchIndexLines(search_index_jsonl string) []_NotifiedItem {
+	var notified_items []_NotifiedItem = nil
+	for _, line := range strings.Split(search_index_jsonl, "\n") {
+		if "" == strings.TrimSpace(line) {
+			continue
+		}
+
+		var notifiedItem _NotifiedItem
+		if err := json.Unmarshal([]byte(line), &notifiedItem); nil != err {
+			fmt.Println("Error reading a search index entry: " + err.Error())
+
+			continue
+		}
+		notified_items = append(notified_items, notifiedItem)
+	}
+
+	return notified_items
+}
+
+/*
+getSearchIndexPath gets the path of the search index file.
+
+-----------------------------------------------------------
+
+– Returns:
+  - the path of the file
+*/
+func getSearchIndexPath() Utils.GPath {
+	return moduleInfo_GL.ModDirsInfo.UserData.Add2("search_index.jsonl")
+}
@@ -0,0 +1,144 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseSearchQuery(t *testing.T) {
+	var tests = []struct {
+		name     string
+		query    string
+		expected _SearchQuery
+	}{
+		{"words", "Tesla  Coil", _SearchQuery{words: []string{"tesla", "coil"}}},
+		{"accents and punctuation", "Ação, já!", _SearchQuery{words: []string{"acao", "ja"}}},
+		{"phrase", `"tesla coil" arc`, _SearchQuery{words: []string{"arc"}, phrases: [][]string{{"tesla", "coil"}}}},
+		{"one word phrase", `"tesla"`, _SearchQuery{words: []string{"tesla"}}},
+		{"feed and tag", `feed:3 feed:"Electro BOOM" tag:YouTube`, _SearchQuery{
+			feeds: []string{"3", "Electro BOOM"},
+			tags:  []string{"youtube"},
+		}},
+		{"unknown filter", "foo:bar", _SearchQuery{words: []string{"foo", "bar"}}},
+		{"empty filter", "feed:", _SearchQuery{words: []string{"feed"}}},
+		{"invalid date", "since:yesterday", _SearchQuery{}},
+		{"day", "since:2023-03-15 until:2023-03-15", _SearchQuery{
+			since: time.Date(2023, 3, 15, 0, 0, 0, 0, time.Local),
+			until: time.Date(2023, 3, 16, 0, 0, 0, 0, time.Local),
+		}},
+		{"month", "since:2023-12 until:2023-12", _SearchQuery{
+			since: time.Date(2023, 12, 1, 0, 0, 0, 0, time.Local),
+			until: time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local),
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var searchQuery _SearchQuery = parseSearchQuery(test.query)
+			if !reflect.DeepEqual(test.expected.words, searchQuery.words) ||
+						!reflect.DeepEqual(test.expected.phrases, searchQuery.phrases) ||
+						!reflect.DeepEqual(test.expected.feeds, searchQuery.feeds) ||
+						!reflect.DeepEqual(test.expected.tags, searchQuery.tags) ||
+						!test.expected.since.Equal(searchQuery.since) || !test.expected.until.Equal(searchQuery.until) {
+				t.Errorf("parseSearchQuery(%q) = %+v, expected %+v", test.query, searchQuery, test.expected)
+			}
+		})
+	}
+}
+
+func TestContainsPhrase(t *testing.T) {
+	var words []string = []string{"a", "big", "tesla", "coil", "arc"}
+	var tests = []struct {
+		phrase   []string
+		expected bool
+	}{
+		{[]string{"tesla", "coil"}, true},
+		{[]string{"a", "big"}, true},
+		{[]string{"coil", "arc"}, true},
+		{[]string{"coil", "tesla"}, false},
+		{[]string{"big", "coil"}, false},
+		{[]string{"arc", "again"}, false},
+		{[]string{"a", "big", "tesla", "coil", "arc", "again"}, false},
+	}
+	for _, test := range tests {
+		if containsPhrase(words, test.phrase) != test.expected {
+			t.Errorf("containsPhrase(%v, %v) != %v", words, test.phrase, test.expected)
+		}
+	}
+}
+
+func TestParseSearchIndexLines(t *testing.T) {
+	var search_index_jsonl string = `{"Feed_num":1,"Url":"https://a"}` + "\n\n" +
+		`{"Feed_num":2,"Url":"https://b"}` + "\n" + `{"Feed_num":3,"Ur`
+
+	var notified_items []_NotifiedItem = parseSearchIndexLines(search_index_jsonl)
+	if 2 != len(notified_items) || "https://a" != notified_items[0].Url || 2 != notified_items[1].Feed_num {
+		t.Errorf("parseSearchIndexLines() = %+v", notified_items)
+	}
+}
+
+func TestSearchNotifiedItems(t *testing.T) {
+	var old_searchIndex *_SearchIndex = searchIndex_GL
+	t.Cleanup(func() {
+		searchIndex_GL = old_searchIndex
+	})
+
+	searchIndex_GL = newSearchIndex()
+	for _, notifiedItem := range []_NotifiedItem{
+		{Feed_num: 1, Feed_title: "ElectroBOOM", Tags: []string{"youtube"}, Title: "Tesla coil",
+			Url: "https://a", Published: time.Date(2023, 3, 10, 12, 0, 0, 0, time.UTC).Unix()},
+		{Feed_num: 2, Feed_title: "Blog", Title: "A coil of tesla wire", Url: "https://b",
+			Published: time.Date(2023, 4, 10, 12, 0, 0, 0, time.UTC).Unix()},
+		{Feed_num: 2, Feed_title: "Blog", Title: "Other", Description: "About a tesla coil", Url: "https://c",
+			Published: time.Date(2023, 5, 10, 12, 0, 0, 0, time.UTC).Unix()},
+	} {
+		searchIndex_GL.add(notifiedItem)
+	}
+
+	var getUrls = func(searchResults []_SearchResult) []string {
+		var urls []string = nil
+		for _, searchResult := range searchResults {
+			urls = append(urls, searchResult.notifiedItem.Url)
+		}
+
+		return urls
+	}
+
+	var tests = []struct {
+		query    string
+		expected []string
+	}{
+		{"tesla coil", []string{"https://b", "https://a", "https://c"}},
+		{`"tesla coil"`, []string{"https://a", "https://c"}},
+		{"tesla feed:blog", []string{"https://b", "https://c"}},
+		{"tesla tag:youtube", []string{"https://a"}},
+		{"coil since:2023-04 until:2023-04", []string{"https://b"}},
+		{"nothing", nil},
+	}
+	for _, test := range tests {
+		if urls := getUrls(searchNotifiedItems(test.query, 0)); !reflect.DeepEqual(test.expected, urls) {
+			t.Errorf("searchNotifiedItems(%q) = %v, expected %v", test.query, urls, test.expected)
+		}
+	}
+}
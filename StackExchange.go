@@ -0,0 +1,582 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+	ext "github.com/mmcdole/gofeed/extensions"
+
+	"Utils"
+)
+
+// Kinds of StackExchange posts.
+const (
+	_SE_KIND_QUESTION string = "question"
+	_SE_KIND_ANSWER   string = "answer"
+	_SE_KIND_COMMENT  string = "comment"
+)
+
+// seApiBackoffUntil_GL is until when the StackExchange API is not to be used (because it asked to back off or because
+// the quota was exceeded).
+var seApiBackoffUntil_GL time.Time = time.Time{}
+
+// seQuestionIdRegex_GL gets the question ID from a question link ("/questions/<id>/..." or "/q/<id>").
+var seQuestionIdRegex_GL *regexp.Regexp = regexp.MustCompile(`/(?:questions|q)/(\d+)`)
+
+// seAnswerIdRegex_GL gets the answer ID from an answer link ("/questions/<id>/<title>/<answer id>#<answer id>").
+var seAnswerIdRegex_GL *regexp.Regexp = regexp.MustCompile(`/questions/\d+/[^/]*/(\d+)`)
+
+// _SEQuestion is the part used of a question of the StackExchange API. It's cached as JSON, so the fields are exported.
+type _SEQuestion struct {
+	Question_id        int      `json:"question_id"`
+	Score              int      `json:"score"`
+	Answer_count       int      `json:"answer_count"`
+	Accepted_answer_id int      `json:"accepted_answer_id"`
+	View_count         int      `json:"view_count"`
+	Tags               []string `json:"tags"`
+}
+
+// _SEComment is the part used of a comment of the StackExchange API.
+type _SEComment struct {
+	Comment_id    int    `json:"comment_id"`
+	Post_id       int    `json:"post_id"`
+	Creation_date int64  `json:"creation_date"`
+	Body          string `json:"body"`
+	Owner         struct {
+		Display_name string `json:"display_name"`
+	} `json:"owner"`
+}
+
+/*
+getStackExchangeSite splits the Feed_url of a StackExchange feed in the site and the rest.
+
+-----------------------------------------------------------
+
+– Params:
+  - feed_url – the Feed_url, like "stackoverflow.com", "stackoverflow.com/go" or a URL of the site
+
+– Returns:
+  - the site (like "stackoverflow.com")
+  - the rest, without the slash (like "go")
+*/
+func getStackExchangeSite(feed_url string) (string, string) {
+	feed_url = strings.TrimSpace(feed_url)
+	feed_url = strings.TrimPrefix(feed_url, "https://")
+	feed_url = strings.TrimPrefix(feed_url, "http://")
+	site, rest, _ := strings.Cut(strings.Trim(feed_url, "/"), "/")
+
+	return site, rest
+}
+
+/*
+getStackExchangeFeedUrl gets the URL of the feed of a StackExchange feed.
+
+-----------------------------------------------------------
+
+– Params:
+  - feedInfo – the information of the feed, with the site (and the tag or the question) on Feed_url
+  - feedType – the type of the feed
+
+– Returns:
+  - the URL or "" if the Feed_url or the feed type are invalid
+*/
+func getStackExchangeFeedUrl(feedInfo _FeedInfo, feedType _FeedType) string {
+	site, rest := getStackExchangeSite(feedInfo.Feed_url)
+	if "" == site {
+		return ""
+	}
+
+	switch feedType.type_2 {
+		case "", _TYPE_2_SE_SITE: {
+			return "https://" + site + "/feeds"
+		}
+		case _TYPE_2_SE_TAG: {
+			rest = strings.TrimPrefix(strings.TrimPrefix(rest, "questions/"), "tagged/")
+			if "" == rest {
+				return ""
+			}
+
+			return "https://" + site + "/feeds/tag/" + url.PathEscape(rest)
+		}
+		case _TYPE_2_SE_QUESTION: {
+			// The question ID or a question URL
+			if matches := seQuestionIdRegex_GL.FindStringSubmatch("/" + rest); nil != matches {
+				rest = matches[1]
+			}
+			if _, err := strconv.Atoi(rest); nil != err {
+				return ""
+			}
+
+			return "https://" + site + "/feeds/question/" + rest
+		}
+	}
+
+	return ""
+}
+
+/*
+getStackExchangeFeed gets a StackExchange feed. On question feeds (the question and its answers), the comments of the
+question and of the answers are added if the feed wants them (from the StackExchange API - they're not on the feed).
+
+-----------------------------------------------------------
+
+– Params:
+  - feedInfo – the information of the feed (with the final URL)
+  - feedType – the type of the feed
+
+– Returns:
+  - the parsed feed
+  - an error if the feed couldn't be got or parsed
+*/
+func getStackExchangeFeed(feedInfo _FeedInfo, feedType _FeedType) (*gofeed.Feed, error) {
+	var parser *gofeed.Parser = gofeed.NewParser()
+	parser.UserAgent = _USER_AGENT
+	parsed_feed, err := parser.ParseURL(feedInfo.Feed_url)
+	if nil != err {
+		return nil, err
+	}
+	if _TYPE_2_SE_QUESTION != feedType.type_2 || !feedInfo.Se_comments {
+		return parsed_feed, nil
+	}
+
+	var site string = strings.Split(strings.TrimPrefix(feedInfo.Feed_url, "https://"), "/")[0]
+	var post_ids []string = []string{feedInfo.Feed_url[strings.LastIndex(feedInfo.Feed_url, "/")+1:]}
+	for _, feed_item := range parsed_feed.Items {
+		if matches := seAnswerIdRegex_GL.FindStringSubmatch(feed_item.Link); nil != matches {
+			post_ids = append(post_ids, matches[1])
+		}
+	}
+
+	var response struct {
+		Items []_SEComment `json:"items"`
+	}
+	var params url.Values = url.Values{}
+	params.Set("sort", "creation")
+	params.Set("order", "desc")
+	params.Set("pagesize", "100")
+	params.Set("filter", "withbody")
+	if err = stackExchangeApi(site, "posts/" + strings.Join(post_ids, ";") + "/comments", params,
+				&response); nil != err {
+		// The feed is still notified, only without the comments.
+		fmt.Println("Error getting the StackExchange comments: " + err.Error())
+
+		return parsed_feed, nil
+	}
+
+	for _, seComment := range response.Items {
+		var published time.Time = time.Unix(seComment.Creation_date, 0)
+		var author string = html.UnescapeString(seComment.Owner.Display_name)
+		parsed_feed.Items = append(parsed_feed.Items, &gofeed.Item{
+			Title:           "Comentário de " + author,
+			Link:            "https://" + site + "/posts/comments/" + strconv.Itoa(seComment.Comment_id),
+			Description:     seComment.Body,
+			Authors:         []*gofeed.Person{{Name: author}},
+			Published:       published.Format(time.RFC3339),
+			PublishedParsed: &published,
+			Extensions: ext.Extensions{
+				"stackexchange": {
+					"kind": {{Value: _SE_KIND_COMMENT}},
+				},
+			},
+		})
+	}
+
+	return parsed_feed, nil
+}
+
+/*
+stackExchangeTreatment does the treatment of a StackExchange feed item: the general treatment plus the score, answers
+and tags of the questions - only notifying the ones with the wanted tags and score - and the kind of post on question
+feeds (the new answers and comments).
+
+-----------------------------------------------------------
+
+– Params:
+  - feedInfo – the information of the feed
+  - feedType – the type of the feed
+  - parsed_feed – the parsed feed
+  - item_num – the number of the item to get
+  - title_url_only – whether to only get the title and URL of the item through _NewsInfo (can be used for optimization)
+
+– Returns:
+  - the email info (without the Mail_to field) or all fields empty if title_url_only is true or if the question's tags
+    are not wanted
+  - the news info, or all fields empty if the question's score is below the minimum (so that it's checked again later)
+*/
+func stackExchangeTreatment(feedInfo _FeedInfo, feedType _FeedType, parsed_feed *gofeed.Feed, item_num int,
+			title_url_only bool) (Utils.EmailInfo, _NewsInfo) {
+	var feed_item *gofeed.Item = parsed_feed.Items[item_num]
+
+	if title_url_only {
+		return generalTreatment(feedInfo, parsed_feed, item_num, true, nil)
+	}
+
+	var kind string = getStackExchangeKind(feed_item)
+	if _SE_KIND_QUESTION == kind && _TYPE_2_SE_QUESTION != feedType.type_2 {
+		// Got before the general treatment, which uses it too (through getStackExchangeHtml()).
+		var seQuestion _SEQuestion = getSEQuestion(parsed_feed, item_num)
+		if seQuestion.Score < feedInfo.Se_min_score {
+			fmt.Println("StackExchange question below the minimum score (" + strconv.Itoa(seQuestion.Score) + "): " +
+				feed_item.Title)
+
+			return Utils.EmailInfo{}, _NewsInfo{}
+		}
+		if !isSETagsWanted(feedInfo, seQuestion.Tags) {
+			fmt.Println("StackExchange question tags not wanted: " + feed_item.Title)
+
+			return generalTreatment(feedInfo, parsed_feed, item_num, true, nil)
+		}
+	}
+
+	email_info, newsInfo := generalTreatment(feedInfo, parsed_feed, item_num, false, func(description string) string {
+		return getStackExchangeHtml(feedType, parsed_feed, item_num) + description
+	})
+	if "" == email_info.Subject {
+		switch kind {
+			case _SE_KIND_ANSWER: {
+				email_info.Subject = "Nova resposta a " + getSEQuestionTitle(parsed_feed)
+			}
+			case _SE_KIND_COMMENT: {
+				email_info.Subject = "Novo comentário em " + getSEQuestionTitle(parsed_feed)
+			}
+			default: {
+				email_info.Subject = "Nova pergunta em " + strings.TrimSpace(getSESiteTitle(parsed_feed)) + ": " +
+					feed_item.Title
+			}
+		}
+	}
+
+	return email_info, newsInfo
+}
+
+/*
+getStackExchangeHtml gets the HTML with the information of a StackExchange post to put on top of its description.
+
+-----------------------------------------------------------
+
+– Params:
+  - feedType – the type of the feed
+  - parsed_feed – the parsed feed
+  - item_num – the number of the item
+
+– Returns:
+  - the HTML or "" if there's no information
+*/
+func getStackExchangeHtml(feedType _FeedType, parsed_feed *gofeed.Feed, item_num int) string {
+	var feed_item *gofeed.Item = parsed_feed.Items[item_num]
+
+	switch getStackExchangeKind(feed_item) {
+		case _SE_KIND_ANSWER: {
+			return "<p>💬 Resposta a <b>" + html.EscapeString(getSEQuestionTitle(parsed_feed)) + "</b></p>\n"
+		}
+		case _SE_KIND_COMMENT: {
+			return "<p>🗨️ Comentário em <b>" + html.EscapeString(getSEQuestionTitle(parsed_feed)) + "</b></p>\n"
+		}
+	}
+	if _TYPE_2_SE_QUESTION == feedType.type_2 {
+		return ""
+	}
+
+	var seQuestion _SEQuestion = getSEQuestion(parsed_feed, item_num)
+	var infos []string = []string{"▲ " + strconv.Itoa(seQuestion.Score) + " pontos"}
+	if seQuestion.Answer_count >= 0 {
+		var answers string = strconv.Itoa(seQuestion.Answer_count) + " respostas"
+		if 0 != seQuestion.Accepted_answer_id {
+			answers += " (✔ aceite)"
+		}
+		infos = append(infos, answers)
+	}
+	if 0 != seQuestion.View_count {
+		infos = append(infos, strconv.Itoa(seQuestion.View_count) + " visualizações")
+	}
+	var tags []string = nil
+	for _, tag := range seQuestion.Tags {
+		tags = append(tags, "<span style=\"background-color: #E1ECF4; color: #39739D; padding: 1px 6px; " +
+			"border-radius: 4px;\">" + html.EscapeString(tag) + "</span>")
+	}
+	if 0 != len(tags) {
+		infos = append(infos, strings.Join(tags, " "))
+	}
+
+	return "<p>" + strings.Join(infos, " • ") + "</p>\n"
+}
+
+/*
+getSEQuestion gets the information of a question of a feed: from the StackExchange API (for all the questions of the
+feed at once, kept for the cycle) or, if it fails, from the feed (without the answers).
+
+-----------------------------------------------------------
+
+– Params:
+  - parsed_feed – the parsed feed
+  - item_num – the number of the item of the question
+
+– Returns:
+  - the information of the question (with Answer_count -1 if unknown)
+*/
+func getSEQuestion(parsed_feed *gofeed.Feed, item_num int) _SEQuestion {
+	var feed_item *gofeed.Item = parsed_feed.Items[item_num]
+	var site string = getSEItemSite(feed_item)
+	var question_id string = getSEQuestionId(feed_item.Link)
+
+	var seQuestion _SEQuestion
+	if "" != question_id && !scrapeCacheGet(_CACHE_KIND_SE_QUESTION, site + "/" + question_id, &seQuestion) {
+		// All the questions of the feed are got at once, as the feed is checked for all of them.
+		var question_ids []string = nil
+		for _, item := range parsed_feed.Items {
+			var item_question_id string = getSEQuestionId(item.Link)
+			if "" != item_question_id && !scrapeCacheGet(_CACHE_KIND_SE_QUESTION, site + "/" + item_question_id,
+						&_SEQuestion{}) && len(question_ids) < 100 {
+				question_ids = append(question_ids, item_question_id)
+			}
+		}
+
+		var response struct {
+			Items []_SEQuestion `json:"items"`
+		}
+		var params url.Values = url.Values{}
+		params.Set("pagesize", "100")
+		var path string = "questions/" + strings.Join(question_ids, ";")
+		if err := stackExchangeApi(site, path, params, &response); nil != err {
+			fmt.Println("Error getting the StackExchange questions: " + err.Error())
+		}
+		for _, question := range response.Items {
+			scrapeCacheSet(_CACHE_KIND_SE_QUESTION, site + "/" + strconv.Itoa(question.Question_id), question)
+		}
+	}
+	if "" != question_id && scrapeCacheGet(_CACHE_KIND_SE_QUESTION, site + "/" + question_id, &seQuestion) {
+		return seQuestion
+	}
+
+	// The feed has the score ("re:rank") and the tags (the categories).
+	seQuestion = _SEQuestion{
+		Answer_count: -1,
+		Tags:         feed_item.Categories,
+	}
+	if ranks := feed_item.Extensions["re"]["rank"]; 0 != len(ranks) {
+		seQuestion.Score, _ = strconv.Atoi(ranks[0].Value)
+	}
+
+	return seQuestion
+}
+
+/*
+stackExchangeApi makes a request to the StackExchange API, with the key of the module user info if there's one (more
+quota).
+
+-----------------------------------------------------------
+
+– Params:
+  - site – the site (like "stackoverflow.com")
+  - path – the path of the request (like "questions/1;2")
+  - params – the parameters of the request
+  - p_response – pointer to where to decode the JSON response to
+
+– Returns:
+  - an error if the API is not to be used now or if the request failed, nil otherwise
+*/
+func stackExchangeApi(site string, path string, params url.Values, p_response any) error {
+	if time.Now().Before(seApiBackoffUntil_GL) {
+		return errors.New("StackExchange API backing off")
+	}
+
+	var modUserInfo _ModUserInfo
+	if moduleInfo_GL.GetModUserInfo(&modUserInfo) && "" != modUserInfo.Se_api_key {
+		params.Set("key", modUserInfo.Se_api_key)
+	}
+	params.Set("site", site)
+
+	var response_json json.RawMessage = nil
+	var api_url string = "https://api.stackexchange.com/2.3/" + path + "?" + params.Encode()
+	if err := getJson(api_url, _USER_AGENT, &response_json); nil != err {
+		// Errors are usually the quota or throttling - stop for a while.
+		seApiBackoffUntil_GL = time.Now().Add(10 * time.Minute)
+
+		return err
+	}
+
+	var response struct {
+		Backoff         int `json:"backoff"`
+		Quota_remaining int `json:"quota_remaining"`
+	}
+	if err := json.Unmarshal(response_json, &response); nil != err {
+		return err
+	}
+	if response.Backoff > 0 {
+		seApiBackoffUntil_GL = time.Now().Add(time.Duration(response.Backoff) * time.Second)
+	} else if 0 == response.Quota_remaining {
+		seApiBackoffUntil_GL = time.Now().Add(1 * time.Hour)
+	}
+
+	return json.Unmarshal(response_json, p_response)
+}
+
+/*
+isSETagsWanted checks if the tags of a question are wanted by a feed: with any of Se_tags_include (if there are any) and
+none of Se_tags_exclude.
+
+-----------------------------------------------------------
+
+– Params:
+  - feedInfo – the information of the feed
+  - tags – the tags of the question
+
+– Returns:
+  - true if the tags are wanted, false otherwise
+*/
+func isSETagsWanted(feedInfo _FeedInfo, tags []string) bool {
+	var hasTag func(tag string) bool = func(tag string) bool {
+		for _, question_tag := range tags {
+			if strings.EqualFold(question_tag, strings.TrimSpace(tag)) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	for _, tag := range feedInfo.Se_tags_exclude {
+		if hasTag(tag) {
+			return false
+		}
+	}
+	if 0 == len(feedInfo.Se_tags_include) {
+		return true
+	}
+	for _, tag := range feedInfo.Se_tags_include {
+		if hasTag(tag) {
+			return true
+		}
+	}
+
+	return false
+}
+
+/*
+getStackExchangeKind gets the kind of post of a StackExchange feed item.
+
+-----------------------------------------------------------
+
+– Params:
+  - feed_item – the item
+
+– Returns:
+  - one of the _SE_KIND_ constants
+*/
+func getStackExchangeKind(feed_item *gofeed.Item) string {
+	if kinds := feed_item.Extensions["stackexchange"]["kind"]; 0 != len(kinds) {
+		return kinds[0].Value
+	}
+	if seAnswerIdRegex_GL.MatchString(feed_item.Link) {
+		return _SE_KIND_ANSWER
+	}
+
+	return _SE_KIND_QUESTION
+}
+
+/*
+getSEQuestionId gets the question ID from a question link.
+
+-----------------------------------------------------------
+
+– Params:
+  - link – the link
+
+– Returns:
+  - the question ID or "" if it's not a question link
+*/
+func getSEQuestionId(link string) string {
+	if matches := seQuestionIdRegex_GL.FindStringSubmatch(link); nil != matches {
+		return matches[1]
+	}
+
+	return ""
+}
+
+/*
+getSEItemSite gets the site of a StackExchange feed item.
+
+-----------------------------------------------------------
+
+– Params:
+  - feed_item – the item
+
+– Returns:
+  - the site (like "stackoverflow.com")
+*/
+func getSEItemSite(feed_item *gofeed.Item) string {
+	site, _ := getStackExchangeSite(feed_item.Link)
+
+	return site
+}
+
+/*
+getSEQuestionTitle gets the title of the question of a question feed.
+
+-----------------------------------------------------------
+
+– Params:
+  - parsed_feed – the parsed feed
+
+– Returns:
+  - the title of the question
+*/
+func getSEQuestionTitle(parsed_feed *gofeed.Feed) string {
+	// The title of the feed is "<question title> - <site title>".
+	var idx int = strings.LastIndex(parsed_feed.Title, " - ")
+	if idx < 0 {
+		return parsed_feed.Title
+	}
+
+	return parsed_feed.Title[:idx]
+}
+
+/*
+getSESiteTitle gets the title of the site of a site or tag feed.
+
+-----------------------------------------------------------
+
+– Params:
+  - parsed_feed – the parsed feed
+
+– Returns:
+  - the title of the site
+*/
+func getSESiteTitle(parsed_feed *gofeed.Feed) string {
+	// The title of the feed is "<tag> - <site title>" or "Recent Questions - <site title>" (or only the site title).
+	var idx int = strings.LastIndex(parsed_feed.Title, " - ")
+	if idx < 0 {
+		return parsed_feed.Title
+	}
+
+	return parsed_feed.Title[idx+len(" - "):]
+}
@@ -0,0 +1,125 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package main
+
+import (
+	"testing"
+
+	"github.com/mmcdole/gofeed"
+	ext "github.com/mmcdole/gofeed/extensions"
+)
+
+func TestIsSETagsWanted(t *testing.T) {
+	var tests = []struct {
+		name     string
+		include  []string
+		exclude  []string
+		tags     []string
+		expected bool
+	}{
+		{"no filters", nil, nil, []string{"go"}, true},
+		{"no tags", []string{"go"}, nil, nil, false},
+		{"included", []string{"rust", "go"}, nil, []string{"go", "generics"}, true},
+		{"not included", []string{"rust"}, nil, []string{"go"}, false},
+		{"excluded", nil, []string{"homework"}, []string{"go", "homework"}, false},
+		{"excluded wins", []string{"go"}, []string{"homework"}, []string{"go", "homework"}, false},
+		{"case and spaces", []string{" Go "}, nil, []string{"go"}, true},
+	}
+	for _, test := range tests {
+		var feedInfo _FeedInfo = _FeedInfo{
+			Se_tags_include: test.include,
+			Se_tags_exclude: test.exclude,
+		}
+		if isSETagsWanted(feedInfo, test.tags) != test.expected {
+			t.Errorf("%s: isSETagsWanted(%v) != %v", test.name, test.tags, test.expected)
+		}
+	}
+}
+
+func TestGetStackExchangeFeedUrl(t *testing.T) {
+	var tests = []struct {
+		feed_url string
+		type_2   string
+		expected string
+	}{
+		{"stackoverflow.com", "", "https://stackoverflow.com/feeds"},
+		{"https://superuser.com/", _TYPE_2_SE_SITE, "https://superuser.com/feeds"},
+		{"stackoverflow.com/go", _TYPE_2_SE_TAG, "https://stackoverflow.com/feeds/tag/go"},
+		{"https://stackoverflow.com/questions/tagged/c++", _TYPE_2_SE_TAG, "https://stackoverflow.com/feeds/tag/c++"},
+		{"stackoverflow.com", _TYPE_2_SE_TAG, ""},
+		{"stackoverflow.com/12345", _TYPE_2_SE_QUESTION, "https://stackoverflow.com/feeds/question/12345"},
+		{"https://stackoverflow.com/questions/12345/some-title", _TYPE_2_SE_QUESTION,
+			"https://stackoverflow.com/feeds/question/12345"},
+		{"stackoverflow.com/q/12345", _TYPE_2_SE_QUESTION, "https://stackoverflow.com/feeds/question/12345"},
+		{"stackoverflow.com/abc", _TYPE_2_SE_QUESTION, ""},
+		{"", "", ""},
+	}
+	for _, test := range tests {
+		var feedInfo _FeedInfo = _FeedInfo{
+			Feed_url: test.feed_url,
+		}
+		var feed_url string = getStackExchangeFeedUrl(feedInfo, _FeedType{type_2: test.type_2})
+		if feed_url != test.expected {
+			t.Errorf("getStackExchangeFeedUrl(%q, %q) = %q, expected %q", test.feed_url, test.type_2, feed_url,
+				test.expected)
+		}
+	}
+}
+
+func TestGetStackExchangeKind(t *testing.T) {
+	var tests = []struct {
+		feed_item *gofeed.Item
+		expected  string
+	}{
+		{&gofeed.Item{Link: "https://stackoverflow.com/questions/12345/some-title"}, _SE_KIND_QUESTION},
+		{&gofeed.Item{Link: "https://stackoverflow.com/questions/12345/some-title/67890#67890"}, _SE_KIND_ANSWER},
+		{&gofeed.Item{
+			Link: "https://stackoverflow.com/questions/12345/some-title#comment1",
+			Extensions: ext.Extensions{
+				"stackexchange": {"kind": {{Value: _SE_KIND_COMMENT}}},
+			},
+		}, _SE_KIND_COMMENT},
+	}
+	for _, test := range tests {
+		if kind := getStackExchangeKind(test.feed_item); kind != test.expected {
+			t.Errorf("getStackExchangeKind(%q) = %q, expected %q", test.feed_item.Link, kind, test.expected)
+		}
+	}
+}
+
+func TestGetSETitles(t *testing.T) {
+	var parsed_feed *gofeed.Feed = &gofeed.Feed{Title: "How to do a - b in Go? - Stack Overflow"}
+	if title := getSEQuestionTitle(parsed_feed); "How to do a - b in Go?" != title {
+		t.Errorf("getSEQuestionTitle() = %q", title)
+	}
+	if title := getSESiteTitle(parsed_feed); "Stack Overflow" != title {
+		t.Errorf("getSESiteTitle() = %q", title)
+	}
+
+	parsed_feed.Title = "Super User"
+	if title := getSEQuestionTitle(parsed_feed); "Super User" != title {
+		t.Errorf("getSEQuestionTitle() without a site = %q", title)
+	}
+	if title := getSESiteTitle(parsed_feed); "Super User" != title {
+		t.Errorf("getSESiteTitle() without a site = %q", title)
+	}
+}
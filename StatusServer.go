@@ -0,0 +1,290 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"Utils"
+)
+
+// statusServerStarted_GL is true if the status server was already started.
+var statusServerStarted_GL bool = false
+
+/*
+startStatusServer starts the module's HTTP server (only once), which serves a status page on /status (the feeds
+grouped by tag), the output feeds on /feeds/<name>.<atom|rss|json>, the search of the notified items on /search and the
+callbacks of the WebSub hubs on /websub/callback/<feed num>.
+
+-----------------------------------------------------------
+
+– Params:
+  - addr – the address to listen on (like ":8080")
+*/
+func startStatusServer(addr string) {
+	if statusServerStarted_GL {
+		return
+	}
+	statusServerStarted_GL = true
+
+	var mux *http.ServeMux = http.NewServeMux()
+	mux.HandleFunc("/status", withModuleLocked(handleStatus))
+	mux.HandleFunc("/feeds/", withModuleLocked(handleOutputFeed))
+	mux.HandleFunc("/search", withModuleLocked(handleSearch))
+	// Not locked - the WebSub subscriptions are only changed by this process (and the pushed feeds are checked with the
+	// module locked).
+	mux.HandleFunc("/websub/callback/", handleWebSubCallback)
+
+	go func() {
+		fmt.Println("Status server listening on " + addr)
+		if err := http.ListenAndServe(addr, mux); nil != err {
+			fmt.Println("Status server error: " + err.Error())
+		}
+	}()
+}
+
+/*
+withModuleLocked makes a handler run with the module locked, so that the files it reads aren't being written by a cycle
+or a command meanwhile.
+
+-----------------------------------------------------------
+
+– Params:
+  - handler – the handler
+
+– Returns:
+  - the handler with the module locked
+*/
+func withModuleLocked(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !lockModule() {
+			http.Error(w, "The module is busy", http.StatusServiceUnavailable)
+
+			return
+		}
+		defer unlockModule()
+
+		handler(w, r)
+	}
+}
+
+/*
+handleStatus serves the status page: the feeds grouped by their first tag, with when each was last checked and how many
+items of it were notified.
+*/
+func handleStatus(w http.ResponseWriter, r *http.Request) {
+	var feedsInfo []_FeedInfo = getFeedsInfo()
+
+	var groups []string = append(getAllTags(feedsInfo), _NO_TAG_GROUP)
+	var groups_feeds map[string][]_FeedInfo = make(map[string][]_FeedInfo)
+	for _, feedInfo := range feedsInfo {
+		var group string = getFeedGroup(feedInfo)
+		groups_feeds[group] = append(groups_feeds[group], feedInfo)
+	}
+
+	var html_builder strings.Builder
+	html_builder.WriteString("<!DOCTYPE html>\n<html>\n<head><meta charset=\"UTF-8\">")
+	html_builder.WriteString("<title>RSS Feed Notifier</title></head>\n")
+	html_builder.WriteString("<body style=\"font-family: Roboto, Arial, sans-serif;\">\n<h3>RSS Feed Notifier</h3>\n")
+	for _, group := range groups {
+		if 0 == len(groups_feeds[group]) {
+			continue
+		}
+
+		html_builder.WriteString("<h4>" + html.EscapeString(group) + "</h4>\n<table>\n")
+		html_builder.WriteString("<tr><th>Nº</th><th>Tipo</th><th>URL</th><th>Etiquetas</th>" +
+			"<th>Última verificação</th><th>Notificados</th></tr>\n")
+		for _, feedInfo := range groups_feeds[group] {
+			var last_check string = "nunca"
+			if feedState := getFeedState(feedInfo.Feed_num); 0 != feedState.Last_check {
+				last_check = time.Unix(feedState.Last_check, 0).Format(Utils.DATE_TIME_FORMAT)
+			}
+			html_builder.WriteString("<tr><td>" + strconv.Itoa(feedInfo.Feed_num) + "</td><td>" +
+				html.EscapeString(feedInfo.Feed_type) + "</td><td>" + html.EscapeString(feedInfo.Feed_url) +
+				"</td><td>" + html.EscapeString(strings.Join(getFeedTags(feedInfo), ", ")) + "</td><td>" +
+				last_check + "</td><td>" + strconv.Itoa(len(readNotifiedItems(feedInfo.Feed_num))) + "</td></tr>\n")
+		}
+		html_builder.WriteString("</table>\n")
+	}
+	html_builder.WriteString("</body>\n</html>\n")
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, _ = w.Write([]byte(html_builder.String()))
+}
+
+/*
+handleOutputFeed serves an output feed, generated on the moment from the notified items history.
+*/
+func handleOutputFeed(w http.ResponseWriter, r *http.Request) {
+	var file_name string = strings.TrimPrefix(r.URL.Path, "/feeds/")
+	var idx_dot int = strings.LastIndex(file_name, ".")
+	if idx_dot < 0 {
+		http.NotFound(w, r)
+
+		return
+	}
+	var name string = file_name[:idx_dot]
+	var format string = file_name[idx_dot+1:]
+
+	var modUserInfo _ModUserInfo
+	if !moduleInfo_GL.GetModUserInfo(&modUserInfo) {
+		http.Error(w, "Error getting the module user info", http.StatusInternalServerError)
+
+		return
+	}
+
+	var self_url string = "http://" + r.Host + r.URL.Path
+	for _, outputFeed := range getOutputFeeds(getOutputFeedsMaxItems(modUserInfo)) {
+		if outputFeed.name != name {
+			continue
+		}
+
+		rendered, mime_type := renderOutputFeed(outputFeed, format, self_url)
+		if "" == mime_type {
+			break
+		}
+		w.Header().Set("Content-Type", mime_type)
+		_, _ = w.Write([]byte(rendered))
+
+		return
+	}
+
+	http.NotFound(w, r)
+}
+
+/*
+handleSearch serves the search of the notified items: a page with a search form and the results of the query on the "q"
+parameter (see searchNotifiedItems()), or only the results in JSON with "format=json". The "limit" parameter is the
+maximum number of results.
+*/
+func handleSearch(w http.ResponseWriter, r *http.Request) {
+	var query string = strings.TrimSpace(r.URL.Query().Get("q"))
+	var limit int = _SEARCH_MAX_RESULTS_DEF
+	if limit_str := r.URL.Query().Get("limit"); "" != limit_str {
+		var err error
+		limit, err = strconv.Atoi(limit_str)
+		if nil != err || limit < 0 {
+			http.Error(w, "Invalid limit: "+limit_str, http.StatusBadRequest)
+
+			return
+		}
+	}
+
+	var searchResults []_SearchResult = nil
+	if "" != query {
+		searchResults = searchNotifiedItems(query, limit)
+	}
+
+	if "json" == r.URL.Query().Get("format") {
+		type _SearchResultJson struct {
+			Feed_num   int      `json:"feed_num"`
+			Feed_title string   `json:"feed_title"`
+			Tags       []string `json:"tags"`
+			Title      string   `json:"title"`
+			Url        string   `json:"url"`
+			Author     string   `json:"author,omitempty"`
+			Date       string   `json:"date"`
+			Score      int      `json:"score"`
+		}
+		var results_json []_SearchResultJson = []_SearchResultJson{}
+		for _, searchResult := range searchResults {
+			var notifiedItem _NotifiedItem = searchResult.notifiedItem
+			results_json = append(results_json, _SearchResultJson{
+				Feed_num:   notifiedItem.Feed_num,
+				Feed_title: notifiedItem.Feed_title,
+				Tags:       notifiedItem.Tags,
+				Title:      notifiedItem.Title,
+				Url:        notifiedItem.Url,
+				Author:     notifiedItem.Author,
+				Date:       getItemDate(notifiedItem).Format(time.RFC3339),
+				Score:      searchResult.score,
+			})
+		}
+		search_json, err := json.Marshal(results_json)
+		if nil != err {
+			http.Error(w, "Error encoding the results", http.StatusInternalServerError)
+
+			return
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		_, _ = w.Write(search_json)
+
+		return
+	}
+
+	var html_builder strings.Builder
+	html_builder.WriteString("<!DOCTYPE html>\n<html>\n<head><meta charset=\"UTF-8\">")
+	html_builder.WriteString("<title>Pesquisa - RSS Feed Notifier</title></head>\n")
+	html_builder.WriteString("<body style=\"font-family: Roboto, Arial, sans-serif;\">\n<h3>Pesquisa</h3>\n")
+	html_builder.WriteString("<form action=\"/search\" method=\"get\"><input type=\"text\" name=\"q\" size=\"60\" " +
+		"value=\"" + html.EscapeString(query) + "\"> <input type=\"submit\" value=\"Pesquisar\"></form>\n")
+	html_builder.WriteString("<p><small>Palavras, \"frases\", feed:&lt;número ou título&gt;, tag:&lt;etiqueta&gt;, " +
+		"since:&lt;data&gt; e until:&lt;data&gt; (datas como 2023-03-01 ou 2023-03).</small></p>\n")
+	if "" != query {
+		if 0 == len(searchResults) {
+			html_builder.WriteString("<p>Nenhum resultado.</p>\n")
+		}
+		html_builder.WriteString("<ul>\n")
+		for _, searchResult := range searchResults {
+			var notifiedItem _NotifiedItem = searchResult.notifiedItem
+			var snippet string = notifiedItem.Description
+			if runes := []rune(snippet); len(runes) > 200 {
+				snippet = string(runes[:200]) + "…"
+			}
+			html_builder.WriteString("<li><a href=\"" + html.EscapeString(notifiedItem.Url) + "\">" +
+				html.EscapeString(notifiedItem.Title) + "</a><br>\n<small>" +
+				html.EscapeString(notifiedItem.Feed_title) + " • " +
+				getItemDate(notifiedItem).Local().Format(Utils.DATE_TIME_FORMAT) + "</small><br>\n" +
+				html.EscapeString(snippet) + "</li>\n")
+		}
+		html_builder.WriteString("</ul>\n")
+	}
+	html_builder.WriteString("</body>\n</html>\n")
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, _ = w.Write([]byte(html_builder.String()))
+}
+
+/*
+getOutputFeedsMaxItems gets the maximum number of items of each output feed.
+
+-----------------------------------------------------------
+
+– Params:
+  - modUserInfo – the module user info
+
+– Returns:
+  - Output_feeds_max_items or _OUTPUT_MAX_ITEMS_DEF if it's not set
+*/
+func getOutputFeedsMaxItems(modUserInfo _ModUserInfo) int {
+	if modUserInfo.Output_feeds_max_items > 0 {
+		return modUserInfo.Output_feeds_max_items
+	}
+
+	return _OUTPUT_MAX_ITEMS_DEF
+}
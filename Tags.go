@@ -0,0 +1,187 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// _NO_TAG_GROUP is the name of the group of the feeds without tags (on digests and on the status page).
+const _NO_TAG_GROUP string = "Sem etiqueta"
+
+/*
+getFeedTags gets the tags of a feed, normalized (lower case, no spaces around, no repetitions).
+
+-----------------------------------------------------------
+
+– Params:
+  - feedInfo – the information of the feed
+
+– Returns:
+  - the tags of the feed
+*/
+func getFeedTags(feedInfo _FeedInfo) []string {
+	var tags []string = nil
+	for _, tag := range feedInfo.Tags {
+		tag = normalizeTag(tag)
+		if "" != tag && !containsTag(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+
+	return tags
+}
+
+/*
+getFeedGroup gets the group of a feed for digests and the status page: its first tag.
+
+-----------------------------------------------------------
+
+– Params:
+  - feedInfo – the information of the feed
+
+– Returns:
+  - the first tag of the feed or _NO_TAG_GROUP if it has none
+*/
+func getFeedGroup(feedInfo _FeedInfo) string {
+	var tags []string = getFeedTags(feedInfo)
+	if 0 == len(tags) {
+		return _NO_TAG_GROUP
+	}
+
+	return tags[0]
+}
+
+/*
+feedHasTag checks if a feed has a tag.
+
+-----------------------------------------------------------
+
+– Params:
+  - feedInfo – the information of the feed
+  - tag – the tag (not case-sensitive)
+
+– Returns:
+  - true if the feed has the tag, false otherwise
+*/
+func feedHasTag(feedInfo _FeedInfo, tag string) bool {
+	return containsTag(getFeedTags(feedInfo), normalizeTag(tag))
+}
+
+/*
+getAllTags gets all the tags used by the feeds.
+
+-----------------------------------------------------------
+
+– Params:
+  - feedsInfo – the information of the feeds
+
+– Returns:
+  - the tags, sorted
+*/
+func getAllTags(feedsInfo []_FeedInfo) []string {
+	var tags []string = nil
+	for _, feedInfo := range feedsInfo {
+		for _, tag := range getFeedTags(feedInfo) {
+			if !containsTag(tags, tag) {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	sort.Strings(tags)
+
+	return tags
+}
+
+/*
+getRecipients gets the emails to send a feed's notifications to: all of Mails_to plus the ones of Recipients which want
+any of the feed's tags (or all feeds, if they have no tags).
+
+-----------------------------------------------------------
+
+– Params:
+  - modUserInfo – the module user info
+  - feed_tags – the tags of the feed
+
+– Returns:
+  - the recipients, without repeated emails
+*/
+func getRecipients(modUserInfo _ModUserInfo, feed_tags []string) []_Recipient {
+	var recipients []_Recipient = nil
+	var addRecipient func(recipient _Recipient) = func(recipient _Recipient) {
+		for _, recipient_added := range recipients {
+			if strings.EqualFold(recipient_added.Mail_to, recipient.Mail_to) {
+				return
+			}
+		}
+		recipients = append(recipients, recipient)
+	}
+
+	for _, mail_to := range modUserInfo.Mails_to {
+		var recipient _Recipient = _Recipient{Mail_to: mail_to}
+		// If the email is also on Recipients, its quiet hours and rate limit still apply.
+		for _, recipient_info := range modUserInfo.Recipients {
+			if strings.EqualFold(recipient_info.Mail_to, mail_to) {
+				recipient.Time_zone = recipient_info.Time_zone
+				recipient.Quiet_hours = recipient_info.Quiet_hours
+				recipient.Max_per_hour = recipient_info.Max_per_hour
+
+				break
+			}
+		}
+		addRecipient(recipient)
+	}
+	for _, recipient := range modUserInfo.Recipients {
+		if "" == recipient.Mail_to {
+			continue
+		}
+
+		var wanted bool = 0 == len(recipient.Tags)
+		for _, tag := range recipient.Tags {
+			if containsTag(feed_tags, normalizeTag(tag)) {
+				wanted = true
+
+				break
+			}
+		}
+		if wanted {
+			addRecipient(recipient)
+		}
+	}
+
+	return recipients
+}
+
+func normalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimSpace(tag))
+}
+
+func containsTag(tags []string, tag string) bool {
+	for _, tag_in := range tags {
+		if tag_in == tag {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,845 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+
+	"Utils"
+)
+
+// _WEBSUB_LEASE_S is the lease asked to the hubs in seconds (they may give another one).
+const _WEBSUB_LEASE_S int = 10 * 24 * 60 * 60
+// _WEBSUB_RENEW_BEFORE is how long before the end of the lease the subscription is renewed.
+const _WEBSUB_RENEW_BEFORE time.Duration = 24 * time.Hour
+// _WEBSUB_RETRY_AFTER is after how long a subscription not verified by the hub is requested again.
+const _WEBSUB_RETRY_AFTER time.Duration = 1 * time.Hour
+// _WEBSUB_REDISCOVER_AFTER is after how long a feed without a hub (or whose subscription was denied) is checked again.
+const _WEBSUB_REDISCOVER_AFTER time.Duration = 24 * time.Hour
+// _WEBSUB_MAX_BODY is the maximum size of the content pushed by the hubs and of the feeds checked for a hub.
+const _WEBSUB_MAX_BODY int64 = 10 << 20
+
+// _YT_WEBSUB_HUB is the hub of the YouTube channels.
+const _YT_WEBSUB_HUB string = "https://pubsubhubbub.appspot.com/subscribe"
+
+// Modes of the WebSub requests.
+const (
+	_WEBSUB_MODE_SUBSCRIBE   string = "subscribe"
+	_WEBSUB_MODE_UNSUBSCRIBE string = "unsubscribe"
+	_WEBSUB_MODE_DENIED      string = "denied"
+)
+
+// Statuses of the WebSub subscriptions.
+const (
+	_WEBSUB_PENDING  string = "pending"  // Requested and waiting for the hub's verification
+	_WEBSUB_ACTIVE   string = "active"   // Verified by the hub - the new items are pushed
+	_WEBSUB_INACTIVE string = "inactive" // The feed has no hub or the hub denied the subscription (only polling)
+)
+
+// _WebSubSubscription is the WebSub subscription of a feed. It's exported to JSON, so the fields are exported.
+type _WebSubSubscription struct {
+	// Feed_url is the URL of the feed, to know if it changed
+	Feed_url string
+	// Hub is the URL of the hub ("" if the feed has none)
+	Hub string
+	// Topic is the URL subscribed to (the feed's "self" URL, which may not be Feed_url)
+	Topic string
+	// Secret is the secret of the HMAC signatures of the pushed content
+	Secret string
+	// Status is one of the _WEBSUB_ statuses
+	Status string
+	// Requested is when the subscription was last requested (or the feed checked for a hub) in Unix seconds
+	Requested int64
+	// Lease_end is when the lease given by the hub ends in Unix seconds (0 if never verified)
+	Lease_end int64
+}
+
+// webSubMutex_GL protects the WebSub subscriptions (the hubs call the server on its own goroutines).
+var webSubMutex_GL sync.Mutex
+// webSubSubscriptions_GL has the WebSub subscriptions mapped by feed number, read from their file on the first use.
+// Must be used with webSubMutex_GL locked.
+var webSubSubscriptions_GL map[int]_WebSubSubscription = nil
+
+// webSubPushHandler_GL is what is done with the content pushed by a hub (with a valid signature).
+var webSubPushHandler_GL func(feed_num int, pushed_feed *gofeed.Feed) = checkPushedFeed
+
+/*
+updateWebSubSubscriptions subscribes the feeds with a WebSub hub to it, renews the subscriptions about to end and
+cancels the ones of the feeds that were removed. Only done if there's a Websub_callback_url and the HTTP server is
+running. The feeds are still polled - the pushed items are only notified sooner.
+
+-----------------------------------------------------------
+
+– Params:
+  - feedsInfo – the information of all the feeds
+*/
+func updateWebSubSubscriptions(feedsInfo []_FeedInfo) {
+	var modUserInfo _ModUserInfo
+	if !statusServerStarted_GL || !moduleInfo_GL.GetModUserInfo(&modUserInfo) ||
+				"" == modUserInfo.Websub_callback_url {
+		return
+	}
+
+	var feeds_nums map[int]bool = make(map[int]bool)
+	for _, feedInfo := range feedsInfo {
+		var feed_url string = getWebSubFeedUrl(feedInfo)
+		if "" == feed_url {
+			continue
+		}
+		feeds_nums[feedInfo.Feed_num] = true
+
+		webSubMutex_GL.Lock()
+		subscription, ok := readWebSubSubscriptions()[feedInfo.Feed_num]
+		webSubMutex_GL.Unlock()
+		if ok && subscription.Feed_url == feed_url && !isWebSubRenewalDue(subscription) {
+			continue
+		}
+
+		subscribeWebSub(modUserInfo, feedInfo, feed_url)
+	}
+
+	// The subscriptions of the feeds that were removed (or changed type) are not wanted anymore.
+	webSubMutex_GL.Lock()
+	var subscriptions map[int]_WebSubSubscription = readWebSubSubscriptions()
+	var removed_subscriptions map[int]_WebSubSubscription = make(map[int]_WebSubSubscription)
+	for feed_num, subscription := range subscriptions {
+		if !feeds_nums[feed_num] {
+			removed_subscriptions[feed_num] = subscription
+			delete(subscriptions, feed_num)
+		}
+	}
+	if 0 != len(removed_subscriptions) {
+		writeWebSubSubscriptions(subscriptions)
+	}
+	webSubMutex_GL.Unlock()
+
+	for feed_num, subscription := range removed_subscriptions {
+		if "" == subscription.Hub {
+			continue
+		}
+		// Confirmed by the callback, since the subscription is no longer there.
+		if err := requestWebSub(modUserInfo, feed_num, subscription, _WEBSUB_MODE_UNSUBSCRIBE); nil != err {
+			fmt.Println("Error cancelling the WebSub subscription of feed " + strconv.Itoa(feed_num) + ": " +
+				err.Error())
+		}
+	}
+}
+
+/*
+subscribeWebSub subscribes a feed to its WebSub hub (or renews the subscription), if it has one.
+
+-----------------------------------------------------------
+
+– Params:
+  - modUserInfo – the module user info
+  - feedInfo – the information of the feed
+  - feed_url – the URL of the feed (see getWebSubFeedUrl())
+*/
+func subscribeWebSub(modUserInfo _ModUserInfo, feedInfo _FeedInfo, feed_url string) {
+	var subscription _WebSubSubscription = _WebSubSubscription{
+		Feed_url:  feed_url,
+		Status:    _WEBSUB_PENDING,
+		Requested: time.Now().Unix(),
+	}
+	subscription.Hub, subscription.Topic = getWebSubHub(feedInfo, feed_url)
+	if "" == subscription.Hub {
+		subscription.Status = _WEBSUB_INACTIVE
+	}
+
+	webSubMutex_GL.Lock()
+	var subscriptions map[int]_WebSubSubscription = readWebSubSubscriptions()
+	if old_subscription, ok := subscriptions[feedInfo.Feed_num]; ok && _WEBSUB_ACTIVE == old_subscription.Status &&
+				old_subscription.Hub == subscription.Hub && old_subscription.Topic == subscription.Topic {
+		// A renewal - the subscription stays active meanwhile, so the content pushed until the hub verifies it again is
+		// still accepted.
+		subscription.Secret = old_subscription.Secret
+		subscription.Status = _WEBSUB_ACTIVE
+		subscription.Lease_end = old_subscription.Lease_end
+	} else if _WEBSUB_PENDING == subscription.Status {
+		subscription.Secret = newWebSubSecret()
+	}
+	subscriptions[feedInfo.Feed_num] = subscription
+	writeWebSubSubscriptions(subscriptions)
+	webSubMutex_GL.Unlock()
+
+	if _WEBSUB_INACTIVE == subscription.Status {
+		return
+	}
+
+	// The hub may verify the subscription before answering, so it must be stored before the request.
+	fmt.Println("Requesting the WebSub subscription of feed " + strconv.Itoa(feedInfo.Feed_num) + " to " +
+		subscription.Hub)
+	if err := requestWebSub(modUserInfo, feedInfo.Feed_num, subscription, _WEBSUB_MODE_SUBSCRIBE); nil != err {
+		// Requested again after _WEBSUB_RETRY_AFTER (or the feed is still polled until the lease ends).
+		fmt.Println("Error requesting the WebSub subscription of feed " + strconv.Itoa(feedInfo.Feed_num) + ": " +
+			err.Error())
+	}
+}
+
+/*
+requestWebSub sends a subscription or unsubscription request to a WebSub hub.
+
+-----------------------------------------------------------
+
+– Params:
+  - modUserInfo – the module user info
+  - feed_num – the number of the feed
+  - subscription – the subscription
+  - mode – _WEBSUB_MODE_SUBSCRIBE or _WEBSUB_MODE_UNSUBSCRIBE
+
+– Returns:
+  - an error if the request failed or the hub refused it, nil otherwise (the hub then verifies it on the callback)
+*/
+func requestWebSub(modUserInfo _ModUserInfo, feed_num int, subscription _WebSubSubscription, mode string) error {
+	var form url.Values = url.Values{}
+	form.Set("hub.callback", getWebSubCallbackUrl(modUserInfo, feed_num))
+	form.Set("hub.mode", mode)
+	form.Set("hub.topic", subscription.Topic)
+	if _WEBSUB_MODE_SUBSCRIBE == mode {
+		form.Set("hub.secret", subscription.Secret)
+		form.Set("hub.lease_seconds", strconv.Itoa(_WEBSUB_LEASE_S))
+	}
+
+	request, err := http.NewRequest(http.MethodPost, subscription.Hub, strings.NewReader(form.Encode()))
+	if nil != err {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	response, err := doWebSubRequest(request)
+	if nil != err {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		var reason []byte = nil
+		reason, _ = io.ReadAll(io.LimitReader(response.Body, 1000))
+
+		return errors.New("HTTP error " + strconv.Itoa(response.StatusCode) + " from the hub: " +
+			strings.TrimSpace(string(reason)))
+	}
+
+	return nil
+}
+
+/*
+handleWebSubCallback serves the callbacks of the WebSub hubs on /websub/callback/<feed num>: the verification of the
+(un)subscriptions (GET) and the pushed content (POST).
+*/
+func handleWebSubCallback(w http.ResponseWriter, r *http.Request) {
+	feed_num, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/websub/callback/"))
+	if nil != err {
+		http.NotFound(w, r)
+
+		return
+	}
+
+	switch r.Method {
+		case http.MethodGet: {
+			verifyWebSubIntent(w, r, feed_num)
+		}
+		case http.MethodPost: {
+			receiveWebSubContent(w, r, feed_num)
+		}
+		default: {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+/*
+verifyWebSubIntent answers the verification of a subscription or unsubscription by a hub: the challenge is echoed only
+if it's wanted (a subscription that is pending or being renewed, or an unsubscription of one that was removed).
+
+-----------------------------------------------------------
+
+– Params:
+  - w – the response writer
+  - r – the request of the hub
+  - feed_num – the number of the feed of the callback
+*/
+func verifyWebSubIntent(w http.ResponseWriter, r *http.Request, feed_num int) {
+	var query url.Values = r.URL.Query()
+	var mode string = query.Get("hub.mode")
+	var topic string = query.Get("hub.topic")
+
+	webSubMutex_GL.Lock()
+	defer webSubMutex_GL.Unlock()
+
+	var subscriptions map[int]_WebSubSubscription = readWebSubSubscriptions()
+	subscription, ok := subscriptions[feed_num]
+	var wanted bool = ok && _WEBSUB_INACTIVE != subscription.Status && subscription.Topic == topic
+
+	switch mode {
+		case _WEBSUB_MODE_SUBSCRIBE: {
+			if !wanted {
+				http.NotFound(w, r)
+
+				return
+			}
+
+			lease_s, err := strconv.Atoi(query.Get("hub.lease_seconds"))
+			if nil != err || lease_s <= 0 {
+				lease_s = _WEBSUB_LEASE_S
+			}
+			subscription.Status = _WEBSUB_ACTIVE
+			subscription.Lease_end = time.Now().Add(time.Duration(lease_s) * time.Second).Unix()
+			subscriptions[feed_num] = subscription
+			writeWebSubSubscriptions(subscriptions)
+			fmt.Println("WebSub subscription of feed " + strconv.Itoa(feed_num) + " verified (lease of " +
+				strconv.Itoa(lease_s) + " s)")
+		}
+		case _WEBSUB_MODE_UNSUBSCRIBE: {
+			if wanted {
+				http.NotFound(w, r)
+
+				return
+			}
+			fmt.Println("WebSub unsubscription of feed " + strconv.Itoa(feed_num) + " verified")
+		}
+		case _WEBSUB_MODE_DENIED: {
+			// Only polled until the subscription is requested again (after _WEBSUB_REDISCOVER_AFTER).
+			if wanted {
+				subscription.Status = _WEBSUB_INACTIVE
+				subscriptions[feed_num] = subscription
+				writeWebSubSubscriptions(subscriptions)
+			}
+			fmt.Println("WebSub subscription of feed " + strconv.Itoa(feed_num) + " denied by the hub: " +
+				query.Get("hub.reason"))
+			w.WriteHeader(http.StatusOK)
+
+			return
+		}
+		default: {
+			http.Error(w, "Invalid hub.mode", http.StatusBadRequest)
+
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/plain")
+	_, _ = w.Write([]byte(query.Get("hub.challenge")))
+}
+
+/*
+receiveWebSubContent receives the content pushed by a hub and, if its signature is valid, checks the feed with it (see
+checkPushedFeed()).
+
+-----------------------------------------------------------
+
+– Params:
+  - w – the response writer
+  - r – the request of the hub
+  - feed_num – the number of the feed of the callback
+*/
+func receiveWebSubContent(w http.ResponseWriter, r *http.Request, feed_num int) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, _WEBSUB_MAX_BODY))
+	if nil != err {
+		http.Error(w, "Error reading the content", http.StatusBadRequest)
+
+		return
+	}
+
+	webSubMutex_GL.Lock()
+	subscription, ok := readWebSubSubscriptions()[feed_num]
+	webSubMutex_GL.Unlock()
+
+	// Content that is not wanted is still acknowledged, as the specification says (the hub would only retry it).
+	w.WriteHeader(http.StatusAccepted)
+
+	if !ok || _WEBSUB_ACTIVE != subscription.Status {
+		fmt.Println("WebSub content ignored - feed " + strconv.Itoa(feed_num) + " is not subscribed")
+
+		return
+	}
+	if !isWebSubSignatureValid(r.Header.Get("X-Hub-Signature"), subscription.Secret, body) {
+		fmt.Println("WebSub content ignored - invalid signature for feed " + strconv.Itoa(feed_num))
+
+		return
+	}
+
+	// Without content (or if it's not a feed), it's only a notification that the feed changed.
+	var pushed_feed *gofeed.Feed = nil
+	if 0 != len(bytes.TrimSpace(body)) {
+		pushed_feed, err = gofeed.NewParser().Parse(bytes.NewReader(body))
+		if nil != err {
+			fmt.Println("Error parsing the WebSub content of feed " + strconv.Itoa(feed_num) + ": " + err.Error())
+			pushed_feed = nil
+		}
+	}
+
+	fmt.Println("WebSub content received for feed " + strconv.Itoa(feed_num))
+	go webSubPushHandler_GL(feed_num, pushed_feed)
+}
+
+/*
+checkPushedFeed checks a feed with the content pushed by its hub, with the module locked (between the cycles and the
+commands), and processes the emails right away.
+
+-----------------------------------------------------------
+
+– Params:
+  - feed_num – the number of the feed
+  - pushed_feed – the pushed feed or nil to get the feed
+*/
+func checkPushedFeed(feed_num int, pushed_feed *gofeed.Feed) {
+	feedInfo, ok := getFeedInfo(feed_num)
+	if !ok {
+		return
+	}
+
+	if !lockModule() {
+		return
+	}
+	defer unlockModule()
+
+	if !isPushedFeedUsable(feedInfo, pushed_feed) {
+		pushed_feed = nil
+	}
+
+	checkFeed(feedInfo, pushed_feed)
+	processOutbox()
+	processPodcastDownloads()
+	processArchiveQueue()
+	endScrapeCacheCycle()
+	updateOutputs()
+}
+
+/*
+isPushedFeedUsable checks if the content pushed for a feed can be checked instead of getting the feed.
+
+-----------------------------------------------------------
+
+– Params:
+  - feedInfo – the information of the feed
+  - pushed_feed – the pushed feed (nil if it was only a notification or if it couldn't be parsed)
+
+– Returns:
+  - true if the pushed feed can be checked, false if the feed must be got
+*/
+func isPushedFeedUsable(feedInfo _FeedInfo, pushed_feed *gofeed.Feed) bool {
+	// YouTube only pushes the IDs and titles of the videos (not the thumbnails and descriptions the treatment needs)
+	// and the hubs may push only the changed parts of the feed, so the feed is got instead.
+	return nil != pushed_feed && 0 != len(pushed_feed.Items) &&
+		_TYPE_1_YOUTUBE != getFeedType(feedInfo.Feed_type).type_1
+}
+
+/*
+getWebSubFeedUrl gets the URL of a feed to subscribe to on a WebSub hub.
+
+-----------------------------------------------------------
+
+– Params:
+  - feedInfo – the information of the feed
+
+– Returns:
+  - the URL or "" if the feed type can't be pushed (the ones got from APIs or without hubs)
+*/
+func getWebSubFeedUrl(feedInfo _FeedInfo) string {
+	var feedType _FeedType = getFeedType(feedInfo.Feed_type)
+	switch feedType.type_1 {
+		case _TYPE_1_GENERAL, _TYPE_1_PODCAST: {
+			return feedInfo.Feed_url
+		}
+		case _TYPE_1_YOUTUBE: {
+			// YouTube only pushes the uploads of the channels (which also bring the feeds of the channel tabs).
+			if isYTChannelFeed(feedType) {
+				return "https://www.youtube.com/xml/feeds/videos.xml?channel_id=" + feedInfo.Feed_url
+			}
+		}
+	}
+
+	return ""
+}
+
+/*
+getWebSubHub gets the WebSub hub of a feed and the topic to subscribe to: the ones of the feed's Websub_hub or of
+YouTube, or the ones advertised by the feed.
+
+-----------------------------------------------------------
+
+– Params:
+  - feedInfo – the information of the feed
+  - feed_url – the URL of the feed (see getWebSubFeedUrl())
+
+– Returns:
+  - the URL of the hub or "" if the feed has none
+  - the topic
+*/
+func getWebSubHub(feedInfo _FeedInfo, feed_url string) (string, string) {
+	if "" != feedInfo.Websub_hub {
+		return feedInfo.Websub_hub, feed_url
+	}
+	if _TYPE_1_YOUTUBE == getFeedType(feedInfo.Feed_type).type_1 {
+		return _YT_WEBSUB_HUB, feed_url
+	}
+
+	return discoverWebSubHub(feed_url)
+}
+
+/*
+discoverWebSubHub discovers the WebSub hub of a feed, from the "Link" headers of the response or from the feed's
+<link rel="hub"> (<atom:link> on RSS feeds).
+
+-----------------------------------------------------------
+
+– Params:
+  - feed_url – the URL of the feed
+
+– Returns:
+  - the URL of the hub or "" if none was found
+  - the topic: the "self" URL of the feed, or feed_url if it has none
+*/
+func discoverWebSubHub(feed_url string) (string, string) {
+	request, err := http.NewRequest(http.MethodGet, feed_url, nil)
+	if nil != err {
+		return "", feed_url
+	}
+	response, err := doWebSubRequest(request)
+	if nil != err {
+		fmt.Println("Error checking the feed for a WebSub hub: " + err.Error())
+
+		return "", feed_url
+	}
+	defer response.Body.Close()
+
+	if http.StatusOK != response.StatusCode {
+		return "", feed_url
+	}
+
+	var hub string = ""
+	var topic string = ""
+	var setLink func(rel string, href string) = func(rel string, href string) {
+		for _, rel_value := range strings.Fields(strings.ToLower(rel)) {
+			if "hub" == rel_value && "" == hub {
+				hub = href
+			} else if "self" == rel_value && "" == topic {
+				topic = href
+			}
+		}
+	}
+
+	// Link: <https://hub.example.com/>; rel="hub", <https://example.com/feed>; rel="self"
+	for _, link_header := range response.Header.Values("Link") {
+		for _, link := range strings.Split(link_header, ",") {
+			href, params, _ := strings.Cut(link, ";")
+			href = strings.Trim(strings.TrimSpace(href), "<>")
+			for _, param := range strings.Split(params, ";") {
+				if rel, found := strings.CutPrefix(strings.TrimSpace(param), "rel="); found {
+					setLink(strings.Trim(rel, "\""), href)
+				}
+			}
+		}
+	}
+
+	// The links of the feed are before its items, so the rest is not read.
+	var decoder *xml.Decoder = xml.NewDecoder(io.LimitReader(response.Body, _WEBSUB_MAX_BODY))
+	decoder.Strict = false
+	decoder.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		// Only the ASCII URLs are needed.
+		return input, nil
+	}
+	for "" == hub || "" == topic {
+		token, err := decoder.Token()
+		if nil != err {
+			break
+		}
+		start_element, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if "entry" == start_element.Name.Local || "item" == start_element.Name.Local {
+			break
+		}
+		if "link" != start_element.Name.Local {
+			continue
+		}
+
+		var rel string = ""
+		var href string = ""
+		for _, attr := range start_element.Attr {
+			if "rel" == attr.Name.Local {
+				rel = attr.Value
+			} else if "href" == attr.Name.Local {
+				href = attr.Value
+			}
+		}
+		if "" != href {
+			setLink(rel, href)
+		}
+	}
+
+	if "" == topic {
+		topic = feed_url
+	}
+
+	return hub, topic
+}
+
+/*
+isWebSubRenewalDue checks if a subscription must be requested again: if its lease is about to end, if the hub didn't
+verify it or, for the feeds without a hub, if it's time to check for one again.
+
+-----------------------------------------------------------
+
+– Params:
+  - subscription – the subscription
+
+– Returns:
+  - true if it must be requested again, false otherwise
+*/
+func isWebSubRenewalDue(subscription _WebSubSubscription) bool {
+	var since_requested time.Duration = time.Since(time.Unix(subscription.Requested, 0))
+	switch subscription.Status {
+		case _WEBSUB_ACTIVE: {
+			return time.Until(time.Unix(subscription.Lease_end, 0)) < _WEBSUB_RENEW_BEFORE &&
+				since_requested > _WEBSUB_RETRY_AFTER
+		}
+		case _WEBSUB_PENDING: {
+			return since_requested > _WEBSUB_RETRY_AFTER
+		}
+	}
+
+	return since_requested > _WEBSUB_REDISCOVER_AFTER
+}
+
+/*
+isWebSubSignatureValid checks the HMAC signature of the content pushed by a hub.
+
+-----------------------------------------------------------
+
+– Params:
+  - signature – the X-Hub-Signature header, like "sha256=<hex>"
+  - secret – the secret of the subscription
+  - body – the content
+
+– Returns:
+  - true if the signature is valid, false otherwise (also if there's none)
+*/
+func isWebSubSignatureValid(signature string, secret string, body []byte) bool {
+	method, signature_hex, found := strings.Cut(signature, "=")
+	if !found {
+		return false
+	}
+
+	var newHash func() hash.Hash = nil
+	switch method {
+		case "sha1": {
+			newHash = sha1.New
+		}
+		case "sha256": {
+			newHash = sha256.New
+		}
+		case "sha384": {
+			newHash = sha512.New384
+		}
+		case "sha512": {
+			newHash = sha512.New
+		}
+		default: {
+			return false
+		}
+	}
+	signature_bytes, err := hex.DecodeString(signature_hex)
+	if nil != err {
+		return false
+	}
+
+	var mac hash.Hash = hmac.New(newHash, []byte(secret))
+	mac.Write(body)
+
+	return hmac.Equal(mac.Sum(nil), signature_bytes)
+}
+
+/*
+getWebSubSignature gets the X-Hub-Signature of pushed content (SHA-256).
+
+-----------------------------------------------------------
+
+– Params:
+  - secret – the secret of the subscription
+  - body – the content
+
+– Returns:
+  - the signature, like "sha256=<hex>"
+*/
+func getWebSubSignature(secret string, body []byte) string {
+	var mac hash.Hash = hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+/*
+newWebSubSecret generates a random secret (or challenge).
+
+-----------------------------------------------------------
+
+– Returns:
+  - the secret, in hexadecimal
+*/
+func newWebSubSecret() string {
+	var secret_bytes []byte = make([]byte, 24)
+	if _, err := rand.Read(secret_bytes); nil != err {
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+
+	return hex.EncodeToString(secret_bytes)
+}
+
+/*
+getWebSubCallbackUrl gets the callback URL of a feed.
+
+-----------------------------------------------------------
+
+– Params:
+  - modUserInfo – the module user info
+  - feed_num – the number of the feed
+
+– Returns:
+  - the URL
+*/
+func getWebSubCallbackUrl(modUserInfo _ModUserInfo, feed_num int) string {
+	return strings.TrimSuffix(modUserInfo.Websub_callback_url, "/") + "/websub/callback/" + strconv.Itoa(feed_num)
+}
+
+/*
+doWebSubRequest does a request to a hub, a feed or a callback.
+
+-----------------------------------------------------------
+
+– Params:
+  - request – the request
+
+– Returns:
+  - the response (to be closed)
+  - an error if the request failed, nil otherwise
+*/
+func doWebSubRequest(request *http.Request) (*http.Response, error) {
+	request.Header.Set("User-Agent", _USER_AGENT)
+
+	var client http.Client = http.Client{
+		Timeout: 60 * time.Second,
+	}
+
+	return client.Do(request)
+}
+
+/*
+getWebSubSubscriptions gets the WebSub subscriptions of all feeds.
+
+-----------------------------------------------------------
+
+– Returns:
+  - a copy of the subscriptions mapped by feed number
+*/
+func getWebSubSubscriptions() map[int]_WebSubSubscription {
+	webSubMutex_GL.Lock()
+	defer webSubMutex_GL.Unlock()
+
+	var subscriptions map[int]_WebSubSubscription = make(map[int]_WebSubSubscription)
+	for feed_num, subscription := range readWebSubSubscriptions() {
+		subscriptions[feed_num] = subscription
+	}
+
+	return subscriptions
+}
+
+/*
+readWebSubSubscriptions reads the WebSub subscriptions, from their file on the first time. Must be called with
+webSubMutex_GL locked, and changes to the map must be saved with writeWebSubSubscriptions().
+
+-----------------------------------------------------------
+
+– Returns:
+  - the subscriptions mapped by feed number (empty if there are none or if an error occurs)
+*/
+func readWebSubSubscriptions() map[int]_WebSubSubscription {
+	if nil != webSubSubscriptions_GL {
+		return webSubSubscriptions_GL
+	}
+
+	webSubSubscriptions_GL = make(map[int]_WebSubSubscription)
+	var p_subscriptions_json *string = getWebSubSubscriptionsPath().ReadTextFile()
+	if nil == p_subscriptions_json {
+		return webSubSubscriptions_GL
+	}
+	if err := json.Unmarshal([]byte(*p_subscriptions_json), &webSubSubscriptions_GL); nil != err {
+		fmt.Println("Error reading the WebSub subscriptions: " + err.Error())
+
+		webSubSubscriptions_GL = make(map[int]_WebSubSubscription)
+	}
+
+	return webSubSubscriptions_GL
+}
+
+/*
+writeWebSubSubscriptions saves the WebSub subscriptions, also to their file. Must be called with webSubMutex_GL locked.
+
+-----------------------------------------------------------
+
+– Params:
+  - subscriptions – the subscriptions mapped by feed number
+*/
+func writeWebSubSubscriptions(subscriptions map[int]_WebSubSubscription) {
+	webSubSubscriptions_GL = subscriptions
+
+	subscriptions_json, err := json.MarshalIndent(subscriptions, "", "\t")
+	if nil != err {
+		fmt.Println("Error writing the WebSub subscriptions: " + err.Error())
+
+		return
+	}
+	getWebSubSubscriptionsPath().WriteTextFile(string(subscriptions_json))
+}
+
+/*
+getWebSubSubscriptionsPath gets the path of the WebSub subscriptions file.
+
+-----------------------------------------------------------
+
+– Returns:
+  - the path of the file
+*/
+func getWebSubSubscriptionsPath() Utils.GPath {
+	return moduleInfo_GL.ModDirsInfo.UserData.Add2("websub_subscriptions.json")
+}
@@ -0,0 +1,443 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+)
+
+// _TEST_HUB_LEASE_S is the lease given by the test hub in seconds.
+const _TEST_HUB_LEASE_S int = 60 * 60
+
+// _TEST_WEBSUB_FEED is the feed published on the test hub.
+const _TEST_WEBSUB_FEED string = `<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+	<title>Blog</title>
+	<id>urn:blog</id>
+	<updated>2023-03-15T12:00:00Z</updated>
+	<entry>
+		<title>New post</title>
+		<id>urn:blog:1</id>
+		<link href="https://example.com/new-post"/>
+		<updated>2023-03-15T12:00:00Z</updated>
+	</entry>
+</feed>`
+
+// _TestWebSubHub is a WebSub hub for the tests: it takes (un)subscriptions, verifies them on the callbacks (before
+// answering) and, when a topic is published ("hub.mode=publish&hub.url=<topic>"), gets it and pushes it signed to the
+// subscribers.
+type _TestWebSubHub struct {
+	mutex sync.Mutex
+	// secrets has the secrets of the subscriptions mapped by topic and then by callback
+	secrets map[string]map[string]string
+	// verifications is the number of (un)subscriptions confirmed by the callbacks
+	verifications int
+}
+
+func (hub *_TestWebSubHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); nil != err {
+		http.Error(w, "Invalid form", http.StatusBadRequest)
+
+		return
+	}
+
+	switch r.PostForm.Get("hub.mode") {
+		case _WEBSUB_MODE_SUBSCRIBE, _WEBSUB_MODE_UNSUBSCRIBE: {
+			if !hub.verifyIntent(r.PostForm) {
+				http.Error(w, "Not confirmed by the callback", http.StatusBadRequest)
+
+				return
+			}
+		}
+		case "publish": {
+			hub.publish(r.PostForm.Get("hub.url"))
+		}
+		default: {
+			http.Error(w, "Invalid hub.mode", http.StatusBadRequest)
+
+			return
+		}
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (hub *_TestWebSubHub) verifyIntent(form url.Values) bool {
+	var mode string = form.Get("hub.mode")
+	var callback string = form.Get("hub.callback")
+	var topic string = form.Get("hub.topic")
+
+	var query url.Values = url.Values{}
+	query.Set("hub.mode", mode)
+	query.Set("hub.topic", topic)
+	query.Set("hub.challenge", "challenge-" + mode)
+	query.Set("hub.lease_seconds", strconv.Itoa(_TEST_HUB_LEASE_S))
+	response, err := http.Get(callback + "?" + query.Encode())
+	if nil != err {
+		return false
+	}
+	defer response.Body.Close()
+	body, _ := io.ReadAll(response.Body)
+	if http.StatusOK != response.StatusCode || "challenge-" + mode != string(body) {
+		return false
+	}
+
+	hub.mutex.Lock()
+	defer hub.mutex.Unlock()
+
+	hub.verifications++
+	if _WEBSUB_MODE_SUBSCRIBE == mode {
+		if nil == hub.secrets[topic] {
+			hub.secrets[topic] = make(map[string]string)
+		}
+		hub.secrets[topic][callback] = form.Get("hub.secret")
+	} else {
+		delete(hub.secrets[topic], callback)
+	}
+
+	return true
+}
+
+func (hub *_TestWebSubHub) publish(topic string) {
+	response, err := http.Get(topic)
+	if nil != err {
+		return
+	}
+	body, _ := io.ReadAll(response.Body)
+	response.Body.Close()
+
+	hub.mutex.Lock()
+	defer hub.mutex.Unlock()
+
+	for callback, secret := range hub.secrets[topic] {
+		request, _ := http.NewRequest(http.MethodPost, callback, bytes.NewReader(body))
+		request.Header.Set("Content-Type", "application/atom+xml")
+		request.Header.Set("X-Hub-Signature", getWebSubSignature(secret, body))
+		if response, err := http.DefaultClient.Do(request); nil == err {
+			response.Body.Close()
+		}
+	}
+}
+
+// _TestPush is a content pushed to a feed's callback.
+type _TestPush struct {
+	feed_num    int
+	pushed_feed *gofeed.Feed
+}
+
+/*
+setTestWebSub replaces the WebSub subscriptions with empty ones and what is done with the pushed content with sending
+it to a channel, until the end of the test.
+
+-----------------------------------------------------------
+
+– Returns:
+  - the channel with the pushed content
+*/
+func setTestWebSub(t *testing.T) chan _TestPush {
+	var old_subscriptions map[int]_WebSubSubscription = webSubSubscriptions_GL
+	var old_pushHandler func(feed_num int, pushed_feed *gofeed.Feed) = webSubPushHandler_GL
+	t.Cleanup(func() {
+		webSubSubscriptions_GL = old_subscriptions
+		webSubPushHandler_GL = old_pushHandler
+	})
+
+	var pushes chan _TestPush = make(chan _TestPush, 10)
+	webSubSubscriptions_GL = make(map[int]_WebSubSubscription)
+	webSubPushHandler_GL = func(feed_num int, pushed_feed *gofeed.Feed) {
+		pushes <- _TestPush{feed_num: feed_num, pushed_feed: pushed_feed}
+	}
+
+	return pushes
+}
+
+func TestWebSubSubscription(t *testing.T) {
+	var pushes chan _TestPush = setTestWebSub(t)
+
+	var hub *_TestWebSubHub = &_TestWebSubHub{secrets: make(map[string]map[string]string)}
+	var mux *http.ServeMux = http.NewServeMux()
+	mux.Handle("/hub", hub)
+	mux.HandleFunc("/feed", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/atom+xml")
+		_, _ = w.Write([]byte(_TEST_WEBSUB_FEED))
+	})
+	var hub_server *httptest.Server = httptest.NewServer(mux)
+	defer hub_server.Close()
+	var callback_server *httptest.Server = httptest.NewServer(http.HandlerFunc(handleWebSubCallback))
+	defer callback_server.Close()
+
+	var modUserInfo _ModUserInfo = _ModUserInfo{
+		Websub_callback_url: callback_server.URL + "/",
+	}
+	var topic string = hub_server.URL + "/feed"
+	var feedInfo _FeedInfo = _FeedInfo{
+		Feed_num:   1,
+		Feed_type:  _TYPE_1_GENERAL,
+		Feed_url:   topic,
+		Websub_hub: hub_server.URL + "/hub",
+	}
+	var callback string = callback_server.URL + "/websub/callback/1"
+
+	// The subscription is verified on the callback before the hub answers.
+	subscribeWebSub(modUserInfo, feedInfo, getWebSubFeedUrl(feedInfo))
+	var subscription _WebSubSubscription = getWebSubSubscriptions()[1]
+	if _WEBSUB_ACTIVE != subscription.Status || topic != subscription.Topic || "" == subscription.Secret {
+		t.Fatalf("subscription not verified: %+v", subscription)
+	}
+	if hub.secrets[topic][callback] != subscription.Secret {
+		t.Fatalf("hub secret %q != %q", hub.secrets[topic][callback], subscription.Secret)
+	}
+	var lease_end time.Time = time.Now().Add(time.Duration(_TEST_HUB_LEASE_S) * time.Second)
+	if d := lease_end.Sub(time.Unix(subscription.Lease_end, 0)); d < -2 * time.Second || d > 2 * time.Second {
+		t.Errorf("lease ends at %d, expected about %d", subscription.Lease_end, lease_end.Unix())
+	}
+
+	// The published feed reaches the push handler.
+	response, err := http.PostForm(hub_server.URL + "/hub", url.Values{"hub.mode": {"publish"}, "hub.url": {topic}})
+	if nil != err {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+	select {
+		case push := <-pushes: {
+			if 1 != push.feed_num || nil == push.pushed_feed || 1 != len(push.pushed_feed.Items) ||
+						"New post" != push.pushed_feed.Items[0].Title {
+				t.Errorf("wrong push: %+v", push)
+			}
+			if !isPushedFeedUsable(feedInfo, push.pushed_feed) {
+				t.Errorf("pushed feed not usable")
+			}
+		}
+		case <-time.After(5 * time.Second): {
+			t.Fatal("no push received")
+		}
+	}
+
+	// Not due yet - the lease ends in less than _WEBSUB_RENEW_BEFORE, but it was just requested.
+	if isWebSubRenewalDue(subscription) {
+		t.Errorf("renewal due right after the subscription")
+	}
+	// The renewal keeps the subscription active and the secret, and the hub verifies it again.
+	subscription.Requested = time.Now().Add(-2 * _WEBSUB_RETRY_AFTER).Unix()
+	webSubSubscriptions_GL[1] = subscription
+	if !isWebSubRenewalDue(subscription) {
+		t.Fatalf("renewal not due")
+	}
+	subscribeWebSub(modUserInfo, feedInfo, getWebSubFeedUrl(feedInfo))
+	var renewed _WebSubSubscription = getWebSubSubscriptions()[1]
+	if _WEBSUB_ACTIVE != renewed.Status || subscription.Secret != renewed.Secret ||
+				renewed.Requested <= subscription.Requested || 2 != hub.verifications {
+		t.Errorf("wrong renewal: %+v (%d verifications)", renewed, hub.verifications)
+	}
+
+	// A subscription still wanted can't be cancelled by others - only once it's removed.
+	if nil == requestWebSub(modUserInfo, 1, renewed, _WEBSUB_MODE_UNSUBSCRIBE) {
+		t.Errorf("unsubscription of a wanted subscription confirmed")
+	}
+	delete(webSubSubscriptions_GL, 1)
+	if err = requestWebSub(modUserInfo, 1, renewed, _WEBSUB_MODE_UNSUBSCRIBE); nil != err {
+		t.Errorf("unsubscription not confirmed: %v", err)
+	}
+	if 0 != len(hub.secrets[topic]) {
+		t.Errorf("still subscribed on the hub: %v", hub.secrets[topic])
+	}
+}
+
+func TestVerifyWebSubIntent(t *testing.T) {
+	setTestWebSub(t)
+	webSubSubscriptions_GL[1] = _WebSubSubscription{
+		Topic:  "https://example.com/feed",
+		Status: _WEBSUB_PENDING,
+	}
+
+	var tests = []struct {
+		name     string
+		mode     string
+		topic    string
+		expected int
+	}{
+		{"other topic", _WEBSUB_MODE_SUBSCRIBE, "https://example.com/other", http.StatusNotFound},
+		{"wanted unsubscription", _WEBSUB_MODE_UNSUBSCRIBE, "https://example.com/feed", http.StatusNotFound},
+		{"invalid mode", "publish", "https://example.com/feed", http.StatusBadRequest},
+		{"subscription", _WEBSUB_MODE_SUBSCRIBE, "https://example.com/feed", http.StatusOK},
+	}
+	for _, test := range tests {
+		var query url.Values = url.Values{}
+		query.Set("hub.mode", test.mode)
+		query.Set("hub.topic", test.topic)
+		query.Set("hub.challenge", "abc")
+		var recorder *httptest.ResponseRecorder = httptest.NewRecorder()
+		handleWebSubCallback(recorder, httptest.NewRequest(http.MethodGet, "/websub/callback/1?" + query.Encode(), nil))
+		if recorder.Code != test.expected {
+			t.Errorf("%s: HTTP %d, expected %d", test.name, recorder.Code, test.expected)
+		}
+		if http.StatusOK == test.expected && "abc" != recorder.Body.String() {
+			t.Errorf("%s: challenge not echoed: %q", test.name, recorder.Body.String())
+		}
+	}
+	if _WEBSUB_ACTIVE != webSubSubscriptions_GL[1].Status {
+		t.Errorf("subscription not active after the verification")
+	}
+
+	// A denied subscription is only polled.
+	var recorder *httptest.ResponseRecorder = httptest.NewRecorder()
+	handleWebSubCallback(recorder, httptest.NewRequest(http.MethodGet,
+		"/websub/callback/1?hub.mode=denied&hub.topic=" + url.QueryEscape("https://example.com/feed"), nil))
+	if http.StatusOK != recorder.Code || _WEBSUB_INACTIVE != webSubSubscriptions_GL[1].Status {
+		t.Errorf("denial not applied: HTTP %d, %+v", recorder.Code, webSubSubscriptions_GL[1])
+	}
+}
+
+func TestReceiveWebSubContent(t *testing.T) {
+	var pushes chan _TestPush = setTestWebSub(t)
+	webSubSubscriptions_GL[1] = _WebSubSubscription{
+		Secret: "secret",
+		Status: _WEBSUB_ACTIVE,
+	}
+
+	var push = func(feed_num int, signature string) {
+		var request *http.Request = httptest.NewRequest(http.MethodPost, "/websub/callback/" + strconv.Itoa(feed_num),
+			strings.NewReader(_TEST_WEBSUB_FEED))
+		request.Header.Set("X-Hub-Signature", signature)
+		var recorder *httptest.ResponseRecorder = httptest.NewRecorder()
+		handleWebSubCallback(recorder, request)
+		if http.StatusAccepted != recorder.Code {
+			t.Errorf("HTTP %d, expected %d", recorder.Code, http.StatusAccepted)
+		}
+	}
+
+	// Ignored: a wrong signature and a feed that is not subscribed.
+	push(1, getWebSubSignature("other secret", []byte(_TEST_WEBSUB_FEED)))
+	push(2, getWebSubSignature("secret", []byte(_TEST_WEBSUB_FEED)))
+	push(1, getWebSubSignature("secret", []byte(_TEST_WEBSUB_FEED)))
+
+	select {
+		case push := <-pushes: {
+			if 1 != push.feed_num || nil == push.pushed_feed || 1 != len(push.pushed_feed.Items) {
+				t.Errorf("wrong push: %+v", push)
+			}
+		}
+		case <-time.After(5 * time.Second): {
+			t.Fatal("no push received")
+		}
+	}
+	select {
+		case push := <-pushes: {
+			t.Errorf("invalid push accepted: %+v", push)
+		}
+		case <-time.After(100 * time.Millisecond): {
+		}
+	}
+}
+
+func TestIsWebSubSignatureValid(t *testing.T) {
+	var body []byte = []byte("content")
+	var mac = hmac.New(sha1.New, []byte("secret"))
+	mac.Write(body)
+	var sha1_signature string = "sha1=" + hex.EncodeToString(mac.Sum(nil))
+
+	var tests = []struct {
+		name      string
+		signature string
+		secret    string
+		body      []byte
+		expected  bool
+	}{
+		{"sha256", getWebSubSignature("secret", body), "secret", body, true},
+		{"sha1", sha1_signature, "secret", body, true},
+		{"wrong secret", getWebSubSignature("secret", body), "other", body, false},
+		{"changed body", getWebSubSignature("secret", body), "secret", []byte("other content"), false},
+		{"no signature", "", "secret", body, false},
+		{"no method", "abcdef", "secret", body, false},
+		{"unknown method", "md5=" + strings.TrimPrefix(getWebSubSignature("secret", body), "sha256="), "secret",
+			body, false},
+		{"invalid hex", "sha256=xyz", "secret", body, false},
+	}
+	for _, test := range tests {
+		if isWebSubSignatureValid(test.signature, test.secret, test.body) != test.expected {
+			t.Errorf("%s: isWebSubSignatureValid() != %v", test.name, test.expected)
+		}
+	}
+}
+
+func TestIsWebSubRenewalDue(t *testing.T) {
+	var now time.Time = time.Now()
+	var tests = []struct {
+		name         string
+		subscription _WebSubSubscription
+		expected     bool
+	}{
+		{"active with a long lease", _WebSubSubscription{Status: _WEBSUB_ACTIVE,
+			Requested: now.Add(-48 * time.Hour).Unix(), Lease_end: now.Add(5 * 24 * time.Hour).Unix()}, false},
+		{"active ending soon", _WebSubSubscription{Status: _WEBSUB_ACTIVE,
+			Requested: now.Add(-48 * time.Hour).Unix(), Lease_end: now.Add(time.Hour).Unix()}, true},
+		{"active ending soon just renewed", _WebSubSubscription{Status: _WEBSUB_ACTIVE,
+			Requested: now.Add(-time.Minute).Unix(), Lease_end: now.Add(time.Hour).Unix()}, false},
+		{"active expired", _WebSubSubscription{Status: _WEBSUB_ACTIVE,
+			Requested: now.Add(-48 * time.Hour).Unix(), Lease_end: now.Add(-time.Hour).Unix()}, true},
+		{"pending", _WebSubSubscription{Status: _WEBSUB_PENDING, Requested: now.Add(-time.Minute).Unix()}, false},
+		{"pending not verified", _WebSubSubscription{Status: _WEBSUB_PENDING,
+			Requested: now.Add(-2 * time.Hour).Unix()}, true},
+		{"inactive", _WebSubSubscription{Status: _WEBSUB_INACTIVE, Requested: now.Add(-2 * time.Hour).Unix()}, false},
+		{"inactive to check again", _WebSubSubscription{Status: _WEBSUB_INACTIVE,
+			Requested: now.Add(-25 * time.Hour).Unix()}, true},
+	}
+	for _, test := range tests {
+		if isWebSubRenewalDue(test.subscription) != test.expected {
+			t.Errorf("%s: isWebSubRenewalDue() != %v", test.name, test.expected)
+		}
+	}
+}
+
+func TestIsPushedFeedUsable(t *testing.T) {
+	var pushed_feed *gofeed.Feed = &gofeed.Feed{Items: []*gofeed.Item{{Title: "New post"}}}
+	var tests = []struct {
+		name        string
+		feed_type   string
+		pushed_feed *gofeed.Feed
+		expected    bool
+	}{
+		{"general", _TYPE_1_GENERAL, pushed_feed, true},
+		{"podcast", _TYPE_1_PODCAST, pushed_feed, true},
+		{"YouTube", _TYPE_1_YOUTUBE + " " + _TYPE_2_YT_CHANNEL, pushed_feed, false},
+		{"only a notification", _TYPE_1_GENERAL, nil, false},
+		{"no items", _TYPE_1_GENERAL, &gofeed.Feed{}, false},
+	}
+	for _, test := range tests {
+		if isPushedFeedUsable(_FeedInfo{Feed_type: test.feed_type}, test.pushed_feed) != test.expected {
+			t.Errorf("%s: isPushedFeedUsable() != %v", test.name, test.expected)
+		}
+	}
+}
@@ -0,0 +1,357 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// _YT_API_QUOTA_PAUSE is how long the API stops being used after its quota is exceeded (it's reset daily).
+const _YT_API_QUOTA_PAUSE time.Duration = 1 * time.Hour
+
+// _YT_API_MAX_RESULTS is the maximum number of results per API request (the maximum the API allows).
+const _YT_API_MAX_RESULTS int = 50
+
+// ytApiBaseUrl_GL is the base of the YouTube Data API URLs (a variable only to be able to point it to a local fake).
+var ytApiBaseUrl_GL string = "https://www.googleapis.com/youtube/v3"
+
+// ytApiQuotaExceededUntil_GL is until when the API is not to be used because its quota was exceeded.
+var ytApiQuotaExceededUntil_GL time.Time = time.Time{}
+
+var errYTApiQuotaExceeded error = errors.New("YouTube Data API quota exceeded")
+
+// _YTDataApiSource is the metadata source that uses the YouTube Data API v3.
+type _YTDataApiSource struct {
+	api_key string
+}
+
+func (_YTDataApiSource) name() string {
+	return "YouTube Data API"
+}
+
+func (source _YTDataApiSource) videoPageInfo(video_id string) (_VideoPageInfo, error) {
+	var videoPageInfo _VideoPageInfo = _VideoPageInfo{
+		length: _VID_TIME_DEF,
+		liveInfo: _LiveInfo{
+			status: _LIVE_STATUS_NONE,
+		},
+	}
+
+	var response struct {
+		Items []struct {
+			Snippet struct {
+				LiveBroadcastContent string `json:"liveBroadcastContent"`
+			} `json:"snippet"`
+			ContentDetails struct {
+				Duration string `json:"duration"`
+			} `json:"contentDetails"`
+			LiveStreamingDetails *struct {
+				ScheduledStartTime string `json:"scheduledStartTime"`
+				ActualStartTime    string `json:"actualStartTime"`
+				ActualEndTime      string `json:"actualEndTime"`
+			} `json:"liveStreamingDetails"`
+		} `json:"items"`
+	}
+	err := source.request("videos", url.Values{
+		"part": {"snippet,contentDetails,liveStreamingDetails"},
+		"id":   {video_id},
+	}, &response)
+	if nil != err {
+		return videoPageInfo, err
+	}
+	if 0 == len(response.Items) {
+		return videoPageInfo, errors.New("video not found: " + video_id)
+	}
+
+	var item = response.Items[0]
+	var length_seconds int = isoDurationToSeconds(item.ContentDetails.Duration)
+	if length_seconds >= 0 {
+		videoPageInfo.length = SecondsToTimeStr(strconv.Itoa(length_seconds))
+	}
+
+	if nil != item.LiveStreamingDetails {
+		var details = item.LiveStreamingDetails
+		switch item.Snippet.LiveBroadcastContent {
+			case "upcoming":
+				videoPageInfo.liveInfo.status = _LIVE_STATUS_UPCOMING
+			case "live":
+				videoPageInfo.liveInfo.status = _LIVE_STATUS_LIVE
+			default:
+				if "" != details.ActualEndTime {
+					videoPageInfo.liveInfo.status = _LIVE_STATUS_ENDED
+				}
+		}
+
+		// The API doesn't say what's a premiere, but a premiere's video is already uploaded before it starts, so it
+		// already has a duration - livestreams don't. After they end, both have one, so then the page is asked, like the
+		// scraper does always (it's only once per video - finished videos are kept on the scrape cache).
+		if _LIVE_STATUS_ENDED != videoPageInfo.liveInfo.status {
+			videoPageInfo.liveInfo.is_premiere = length_seconds > 0
+		} else if scrapedInfo, ok := scrapeVideoPageInfo(video_id); ok {
+			videoPageInfo.liveInfo.is_premiere = scrapedInfo.liveInfo.is_premiere
+		}
+
+		var start_time, err = time.Parse(time.RFC3339, details.ScheduledStartTime)
+		if nil == err {
+			videoPageInfo.liveInfo.scheduled_start = start_time
+		}
+	}
+
+	return videoPageInfo, nil
+}
+
+func (source _YTDataApiSource) channelImageUrl(channel_code string) (string, error) {
+	var response struct {
+		Items []struct {
+			Snippet struct {
+				Thumbnails map[string]struct {
+					Url string `json:"url"`
+				} `json:"thumbnails"`
+			} `json:"snippet"`
+		} `json:"items"`
+	}
+	err := source.request("channels", url.Values{
+		"part": {"snippet"},
+		"id":   {channel_code},
+	}, &response)
+	if nil != err {
+		return "", err
+	}
+	if 0 == len(response.Items) {
+		return "", errors.New("channel not found: " + channel_code)
+	}
+
+	// Best quality first
+	for _, quality := range []string{"high", "medium", "default"} {
+		if thumbnail, ok := response.Items[0].Snippet.Thumbnails[quality]; ok && "" != thumbnail.Url {
+			return thumbnail.Url, nil
+		}
+	}
+
+	return "", errors.New("channel image not found: " + channel_code)
+}
+
+func (source _YTDataApiSource) playlistVideos(playlist_id string,
+			is_known func(videoInfo _VideoInfo) bool) ([]_VideoInfo, error) {
+	var videos_info []_VideoInfo = nil
+	var page_token string = ""
+	var known_found bool = false
+	for page_num := 0; page_num < _MAX_PLAYLIST_PAGES && !known_found; page_num++ {
+		var response struct {
+			NextPageToken string `json:"nextPageToken"`
+			Items []struct {
+				Snippet struct {
+					Title      string `json:"title"`
+					Thumbnails map[string]struct {
+						Url string `json:"url"`
+					} `json:"thumbnails"`
+				} `json:"snippet"`
+				ContentDetails struct {
+					VideoId string `json:"videoId"`
+				} `json:"contentDetails"`
+			} `json:"items"`
+		}
+		var params url.Values = url.Values{
+			"part":       {"snippet,contentDetails"},
+			"playlistId": {playlist_id},
+			"maxResults": {strconv.Itoa(_YT_API_MAX_RESULTS)},
+		}
+		if "" != page_token {
+			params.Set("pageToken", page_token)
+		}
+		if err := source.request("playlistItems", params, &response); nil != err {
+			return nil, err
+		}
+
+		var page_videos []_VideoInfo = make([]_VideoInfo, 0, len(response.Items))
+		for _, item := range response.Items {
+			var videoInfo _VideoInfo = _VideoInfo{
+				Id:     item.ContentDetails.VideoId,
+				Title:  item.Snippet.Title,
+				Length: _GEN_ERROR,
+				Image:  _GEN_ERROR,
+			}
+			for _, quality := range []string{"maxres", "standard", "high", "medium", "default"} {
+				if thumbnail, ok := item.Snippet.Thumbnails[quality]; ok && "" != thumbnail.Url {
+					videoInfo.Image = thumbnail.Url
+
+					break
+				}
+			}
+			page_videos = append(page_videos, videoInfo)
+		}
+		// No need for more pages (nor the durations) after a known video.
+		page_videos, known_found = cutAtKnownVideo(page_videos, is_known)
+
+		// The playlist items don't have the durations - get them all at once.
+		var video_ids []string = make([]string, 0, len(page_videos))
+		for _, videoInfo := range page_videos {
+			video_ids = append(video_ids, videoInfo.Id)
+		}
+		durations, err := source.videosDurations(video_ids)
+		if nil != err {
+			return nil, err
+		}
+		for i := range page_videos {
+			if duration, ok := durations[page_videos[i].Id]; ok {
+				page_videos[i].Length = duration
+			}
+		}
+		videos_info = append(videos_info, page_videos...)
+
+		page_token = response.NextPageToken
+		if "" == page_token {
+			break
+		}
+	}
+
+	return videos_info, nil
+}
+
+/*
+videosDurations gets the durations of many videos at once.
+
+-----------------------------------------------------------
+
+– Params:
+  - video_ids – the IDs of the videos (maximum of _YT_API_MAX_RESULTS)
+
+– Returns:
+  - the durations in the SecondsToTimeStr() format mapped by video ID
+  - an error if the request failed
+*/
+func (source _YTDataApiSource) videosDurations(video_ids []string) (map[string]string, error) {
+	var durations map[string]string = make(map[string]string, len(video_ids))
+	if 0 == len(video_ids) {
+		return durations, nil
+	}
+
+	var response struct {
+		Items []struct {
+			Id             string `json:"id"`
+			ContentDetails struct {
+				Duration string `json:"duration"`
+			} `json:"contentDetails"`
+		} `json:"items"`
+	}
+	err := source.request("videos", url.Values{
+		"part": {"contentDetails"},
+		"id":   {strings.Join(video_ids, ",")},
+	}, &response)
+	if nil != err {
+		return nil, err
+	}
+
+	for _, item := range response.Items {
+		var length_seconds int = isoDurationToSeconds(item.ContentDetails.Duration)
+		if length_seconds >= 0 {
+			durations[item.Id] = SecondsToTimeStr(strconv.Itoa(length_seconds))
+		}
+	}
+
+	return durations, nil
+}
+
+/*
+request makes a request to the YouTube Data API.
+
+-----------------------------------------------------------
+
+– Params:
+  - resource – the resource to request (like "videos")
+  - params – the parameters of the request (the API key is added here)
+  - p_response – pointer to where to decode the JSON response to
+
+– Returns:
+  - errYTApiQuotaExceeded (wrapped) if the quota was exceeded, other error if the request failed, nil otherwise
+*/
+func (source _YTDataApiSource) request(resource string, params url.Values, p_response any) error {
+	params.Set("key", source.api_key)
+
+	var client http.Client = http.Client{
+		Timeout: 30 * time.Second,
+	}
+	resp, err := client.Get(ytApiBaseUrl_GL + "/" + resource + "?" + params.Encode())
+	if nil != err {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if http.StatusOK != resp.StatusCode {
+		var error_response struct {
+			Error struct {
+				Message string `json:"message"`
+				Errors  []struct {
+					Reason string `json:"reason"`
+				} `json:"errors"`
+			} `json:"error"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&error_response)
+		for _, api_error := range error_response.Error.Errors {
+			if "quotaExceeded" == api_error.Reason || "dailyLimitExceeded" == api_error.Reason ||
+						"rateLimitExceeded" == api_error.Reason {
+				return errors.Join(errYTApiQuotaExceeded, errors.New(error_response.Error.Message))
+			}
+		}
+
+		return errors.New("YouTube Data API error " + strconv.Itoa(resp.StatusCode) + ": " + error_response.Error.Message)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(p_response)
+}
+
+var isoDurationRegex_GL *regexp.Regexp = regexp.MustCompile(`^P(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+
+/*
+isoDurationToSeconds converts an ISO 8601 duration as given by the API (like "PT1H2M3S") to seconds.
+
+-----------------------------------------------------------
+
+– Params:
+  - duration – the ISO 8601 duration
+
+– Returns:
+  - the number of seconds or -1 if the duration is not valid
+*/
+func isoDurationToSeconds(duration string) int {
+	var matches []string = isoDurationRegex_GL.FindStringSubmatch(duration)
+	if nil == matches {
+		return -1
+	}
+
+	var seconds int = 0
+	for i, multiplier := range []int{24 * 60 * 60, 60 * 60, 60, 1} {
+		if "" != matches[i+1] {
+			value, _ := strconv.Atoi(matches[i+1])
+			seconds += value * multiplier
+		}
+	}
+
+	return seconds
+}
@@ -0,0 +1,279 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testApiVideos_GL are the videos of the Data API stand-in: the JSON of their snippet and liveStreamingDetails and
+// their duration.
+var testApiVideos_GL map[string][3]string = map[string][3]string{
+	"plain": {`{"liveBroadcastContent": "none"}`, "", "PT1H2M3S"},
+	"premiere": {`{"liveBroadcastContent": "upcoming"}`, `{"scheduledStartTime": "2023-11-20T10:00:00Z"}`, "PT10M"},
+	"stream": {`{"liveBroadcastContent": "live"}`, `{"actualStartTime": "2023-11-20T10:00:00Z"}`, "P0D"},
+	"ended-premiere": {`{"liveBroadcastContent": "none"}`, `{"actualEndTime": "2023-11-20T10:10:00Z"}`, "PT10M"},
+	"ended-stream": {`{"liveBroadcastContent": "none"}`, `{"actualEndTime": "2023-11-20T12:00:00Z"}`, "PT2H"},
+}
+
+// newTestDataApiServer starts a stand-in of the YouTube Data API with the testApiVideos_GL, a 3-page playlist ("PLapi",
+// with the videos a1 to a5) and a channel ("UCtest"). The requests are counted by resource on requests.
+func newTestDataApiServer(t *testing.T, requests map[string]int) *httptest.Server {
+	var playlist_pages map[string][2]string = map[string][2]string{
+		"":   {"a1,a2", "p2"},
+		"p2": {"a3,a4", "p3"},
+		"p3": {"a5", ""},
+	}
+
+	var mux *http.ServeMux = http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		var query = r.URL.Query()
+		if "test-key" != query.Get("key") {
+			t.Errorf("request without the API key: %s", r.URL)
+		}
+		var resource string = strings.TrimPrefix(r.URL.Path, "/")
+		requests[resource]++
+
+		var items []string = nil
+		switch resource {
+			case "videos": {
+				for _, video_id := range strings.Split(query.Get("id"), ",") {
+					var duration string = "PT1M" + strings.TrimPrefix(video_id, "a") + "S"
+					var item string = `{"id": "` + video_id + `"`
+					if video, ok := testApiVideos_GL[video_id]; ok {
+						duration = video[2]
+						item += `, "snippet": ` + video[0]
+						if "" != video[1] {
+							item += `, "liveStreamingDetails": ` + video[1]
+						}
+					}
+					items = append(items, item + `, "contentDetails": {"duration": "` + duration + `"}}`)
+				}
+			}
+			case "playlistItems": {
+				page, ok := playlist_pages[query.Get("pageToken")]
+				if !ok || "PLapi" != query.Get("playlistId") {
+					http.NotFound(w, r)
+
+					return
+				}
+				for _, video_id := range strings.Split(page[0], ",") {
+					items = append(items, `{"snippet": {"title": "Title ` + video_id + `", "thumbnails": ` +
+						`{"default": {"url": "small.jpg"}, "high": {"url": "big-` + video_id + `.jpg"}}}, ` +
+						`"contentDetails": {"videoId": "` + video_id + `"}}`)
+				}
+				_, _ = fmt.Fprint(w, `{"nextPageToken": "` + page[1] + `", "items": [` + strings.Join(items, ", ") +
+					`]}`)
+
+				return
+			}
+			case "channels": {
+				items = append(items, `{"snippet": {"thumbnails": {"medium": {"url": "channel.jpg"}}}}`)
+			}
+		}
+		_, _ = fmt.Fprint(w, `{"items": [` + strings.Join(items, ", ") + `]}`)
+	})
+
+	return httptest.NewServer(mux)
+}
+
+// setTestYTApiBaseUrl points the API source to a stand-in of the API until the end of the test.
+func setTestYTApiBaseUrl(t *testing.T, base_url string) {
+	var old_base_url string = ytApiBaseUrl_GL
+	ytApiBaseUrl_GL = base_url
+	t.Cleanup(func() {
+		ytApiBaseUrl_GL = old_base_url
+	})
+}
+
+func TestYTDataApiVideoPageInfo(t *testing.T) {
+	var api_server *httptest.Server = newTestDataApiServer(t, map[string]int{})
+	defer api_server.Close()
+	setTestYTApiBaseUrl(t, api_server.URL)
+
+	// The ended events are asked to the pages, like the scraper does.
+	var watch_server *httptest.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter,
+				r *http.Request) {
+		var is_live_content bool = "ended-stream" == r.URL.Query().Get("v")
+		_, _ = fmt.Fprintf(w, `<script>var ytInitialPlayerResponse = {"videoDetails": {"isLiveContent": %t}, ` +
+			`"liveBroadcastDetails": {"startTimestamp": "2023-11-20T10:00:00+00:00", ` +
+			`"endTimestamp": "2023-11-20T12:00:00+00:00"}};</script>`, is_live_content)
+	}))
+	defer watch_server.Close()
+	setTestYTBaseUrl(t, watch_server.URL)
+
+	var tests = []struct {
+		video_id    string
+		length      string
+		status      string
+		is_premiere bool
+	}{
+		{"plain", "01:02:03", _LIVE_STATUS_NONE, false},
+		{"premiere", "10:00", _LIVE_STATUS_UPCOMING, true},
+		{"stream", "00:00", _LIVE_STATUS_LIVE, false},
+		{"ended-premiere", "10:00", _LIVE_STATUS_ENDED, true},
+		{"ended-stream", "02:00:00", _LIVE_STATUS_ENDED, false},
+	}
+	var source _YTDataApiSource = _YTDataApiSource{api_key: "test-key"}
+	for _, test := range tests {
+		videoPageInfo, err := source.videoPageInfo(test.video_id)
+		if nil != err {
+			t.Errorf("%s: %v", test.video_id, err)
+
+			continue
+		}
+		if test.length != videoPageInfo.length || test.status != videoPageInfo.liveInfo.status ||
+					test.is_premiere != videoPageInfo.liveInfo.is_premiere {
+			t.Errorf("%s: got length %q, status %q and premiere %t, expected %q, %q and %t", test.video_id,
+				videoPageInfo.length, videoPageInfo.liveInfo.status, videoPageInfo.liveInfo.is_premiere, test.length,
+				test.status, test.is_premiere)
+		}
+	}
+
+	videoPageInfo, _ := source.videoPageInfo("premiere")
+	if !videoPageInfo.liveInfo.scheduled_start.Equal(time.Date(2023, 11, 20, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("wrong scheduled start: %v", videoPageInfo.liveInfo.scheduled_start)
+	}
+
+	image_url, err := source.channelImageUrl("UCtest")
+	if nil != err || "channel.jpg" != image_url {
+		t.Errorf("got channel image %q (%v)", image_url, err)
+	}
+}
+
+func TestYTDataApiPlaylistVideos(t *testing.T) {
+	var requests map[string]int = map[string]int{}
+	var api_server *httptest.Server = newTestDataApiServer(t, requests)
+	defer api_server.Close()
+	setTestYTApiBaseUrl(t, api_server.URL)
+
+	var source _YTDataApiSource = _YTDataApiSource{api_key: "test-key"}
+	videos_info, err := source.playlistVideos("PLapi", nil)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if 5 != len(videos_info) || 3 != requests["playlistItems"] {
+		t.Fatalf("got %d videos with %d requests, expected 5 with 3: %v", len(videos_info), requests["playlistItems"],
+			videos_info)
+	}
+	for i, videoInfo := range videos_info {
+		var video_id string = fmt.Sprintf("a%d", i + 1)
+		var expected _VideoInfo = _VideoInfo{
+			Id:     video_id,
+			Title:  "Title " + video_id,
+			Length: fmt.Sprintf("01:%02d", i + 1),
+			Image:  "big-" + video_id + ".jpg",
+		}
+		if expected != videoInfo {
+			t.Errorf("got %+v, expected %+v", videoInfo, expected)
+		}
+	}
+
+	// Only until the first known video - no more pages nor durations (a3 is the first of the 2nd page, so only the
+	// durations of the 1st page are needed).
+	requests["playlistItems"] = 0
+	requests["videos"] = 0
+	videos_info, err = source.playlistVideos("PLapi", func(videoInfo _VideoInfo) bool {
+		return "a3" == videoInfo.Id
+	})
+	if nil != err || 2 != len(videos_info) || "a2" != videos_info[1].Id {
+		t.Errorf("got %v (%v), expected a1 and a2", videos_info, err)
+	}
+	if 2 != requests["playlistItems"] || 1 != requests["videos"] {
+		t.Errorf("got %d playlist and %d videos requests, expected 2 and 1", requests["playlistItems"],
+			requests["videos"])
+	}
+}
+
+func TestYTDataApiQuotaFallback(t *testing.T) {
+	var requests int = 0
+	var api_server *httptest.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusForbidden)
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"error": map[string]any{
+				"message": "The request cannot be completed because you have exceeded your quota.",
+				"errors":  []map[string]string{{"reason": "quotaExceeded"}},
+			},
+		})
+	}))
+	defer api_server.Close()
+	setTestYTApiBaseUrl(t, api_server.URL)
+
+	var yt_server *httptest.Server = newTestPlaylistServer(t, nil)
+	defer yt_server.Close()
+	setTestYTBaseUrl(t, yt_server.URL)
+
+	var old_quota_exceeded_until time.Time = ytApiQuotaExceededUntil_GL
+	defer func() {
+		ytApiQuotaExceededUntil_GL = old_quota_exceeded_until
+	}()
+
+	var sources []_YouTubeMetadataSource = getYTMetadataSourcesWithKey("test-key")
+	if 2 != len(sources) {
+		t.Fatalf("expected the API and the scraper, got %d sources", len(sources))
+	}
+	videos_info, ok := getFromYTMetadataSources(sources, func(source _YouTubeMetadataSource) ([]_VideoInfo, error) {
+		return source.playlistVideos("PLtest", nil)
+	})
+	if !ok || 5 != len(videos_info) || 1 != requests {
+		t.Fatalf("expected the 5 videos from the scraper after 1 API request, got %v (%d requests)", videos_info,
+			requests)
+	}
+
+	// The API is not to be used again for a while.
+	if !ytApiQuotaExceededUntil_GL.After(time.Now()) {
+		t.Error("the API quota was not marked as exceeded")
+	}
+	sources = getYTMetadataSourcesWithKey("test-key")
+	if 1 != len(sources) || "scraper" != sources[0].name() {
+		t.Errorf("expected only the scraper while the quota is exceeded, got %v", sources)
+	}
+}
+
+func TestIsoDurationToSeconds(t *testing.T) {
+	var tests = []struct {
+		duration string
+		expected int
+	}{
+		{"PT1H2M3S", 3723},
+		{"PT45S", 45},
+		{"PT10M", 600},
+		{"P1DT1S", 86401},
+		{"P0D", 0},
+		{"PT0S", 0},
+		{"", -1},
+		{"1H2M", -1},
+		{"PT1.5S", -1},
+	}
+	for _, test := range tests {
+		if seconds := isoDurationToSeconds(test.duration); test.expected != seconds {
+			t.Errorf("isoDurationToSeconds(%q) = %d, expected %d", test.duration, seconds, test.expected)
+		}
+	}
+}
@@ -22,35 +22,40 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/mmcdole/gofeed"
-
-	"Utils"
 )
 
-type _PlaylistPage struct {
-	id               string
-	videos_info_json []string
-}
-
+// _VideoInfo is the information about a playlist video. It's exported to JSON (scrape cache), so the fields are
+// exported.
 type _VideoInfo struct {
-	id     string
-	title  string
-	length string
-	image  string
+	Id     string
+	Title  string
+	Length string
+	Image  string
 }
 
 const _YT_TIME_DATE_FORMAT string = "2006-01-02T15:04:05-07:00"
 
-var playlistPage_GL _PlaylistPage = _PlaylistPage{}
+// _MAX_PLAYLIST_PAGES is the maximum number of continuation pages got from a playlist (YouTube gives 100 videos per page).
+// It's just a protection against looping forever if YouTube keeps giving continuations.
+const _MAX_PLAYLIST_PAGES int = 100
 
 /*
 ytPlaylistScraping scrapes the YT playlist page to get the video information and reads the video list backwards to get
 the latest videos - because this function is to be used *only* if scrapingNeeded() returns true.
 
+The whole playlist is got (see getPlaylistVideos()), not only the first page, so that the latest videos of big playlists
+are got too.
+
 -----------------------------------------------------------
 
 – Params:
@@ -63,70 +68,322 @@ the latest videos - because this function is to be used *only* if scrapingNeeded
 */
 func ytPlaylistScraping(playlist_id string, item_num int, item_count int) _VideoInfo {
 	var videoInfo _VideoInfo = _VideoInfo{
-		id:     _GEN_ERROR,
-		title:  _GEN_ERROR,
-		length: _GEN_ERROR,
-		image:  _GEN_ERROR,
+		Id:     _GEN_ERROR,
+		Title:  _GEN_ERROR,
+		Length: _GEN_ERROR,
+		Image:  _GEN_ERROR,
+	}
+
+	// The latest videos are the last ones here, so the whole playlist is needed.
+	var videos_info []_VideoInfo = getPlaylistVideos(playlist_id, nil)
+	if nil == videos_info {
+		return videoInfo
+	}
+
+	var index int = len(videos_info) - item_count + item_num
+	if index < 0 || index >= len(videos_info) {
+		// This should never happen - but it has, somehow xD (len was 0, item_num 0 and item_count 15...). So here is
+		// the prevention.
+		return videoInfo
+	}
+
+	return videos_info[index]
+}
+
+/*
+crawlPlaylist gets the information of the videos of a playlist.
+
+The playlist page only has the first ~100 videos. The rest is got the same way the page itself gets it when scrolling:
+by following the continuation tokens through YouTube's internal browse endpoint - until a known video is found, if
+is_known is given.
+
+-----------------------------------------------------------
+
+– Params:
+  - playlist_id – the ID of the playlist
+  - is_known – the function that says if a video is already known, or nil to get the whole playlist
+
+– Returns:
+  - the information of the videos in the playlist order (until the first known one, exclusive), or nil if the playlist
+    page couldn't be got (if only a continuation fails, the videos got until then are returned)
+*/
+func crawlPlaylist(playlist_id string, is_known func(videoInfo _VideoInfo) bool) []_VideoInfo {
+	var p_page_html *string = getPageHtmlCached(ytBaseUrl_GL + "/playlist?list=" + playlist_id)
+	if nil == p_page_html {
+		return nil
 	}
+	var page_html string = *p_page_html
 
-	var videos_info_json []string = playlistPage_GL.videos_info_json
-	// This is here to make sure the page is only scraped once
-	if playlistPage_GL.id != playlist_id {
-		var playlist_url string = "https://www.youtube.com/playlist?list=" + playlist_id
-		var page_html *string = Utils.GetPageHtmlTIMEDATE(playlist_url)
-		if nil == page_html {
-			playlistPage_GL.id = ""
+	// The data is on a JavaScript variable --> CAN CHANGE (checked on 2023-11-20).
+	var text_to_find string = "var ytInitialData = "
+	var idx_begin int = strings.Index(page_html, text_to_find)
+	if idx_begin < 0 {
+		return nil
+	}
+	var initial_data any = nil
+	if err := json.NewDecoder(strings.NewReader(page_html[idx_begin+len(text_to_find):])).Decode(&initial_data); nil != err {
+		return nil
+	}
+
+	var videos_info []_VideoInfo = make([]_VideoInfo, 0, 100)
+	var continuation string = ""
+	videos_info, continuation = getPlaylistRenderers(initial_data, videos_info)
+	var known_found bool = false
+	videos_info, known_found = cutAtKnownVideo(videos_info, is_known)
+	if known_found {
+		return videos_info
+	}
 
-			return videoInfo
+	// The key may not be needed anymore, but the page still has it, so it's sent too.
+	var api_key string = findPageJsonValue(page_html, "INNERTUBE_API_KEY")
+	var client_version string = findPageJsonValue(page_html, "INNERTUBE_CLIENT_VERSION")
+	for page_num := 1; "" != continuation && page_num < _MAX_PLAYLIST_PAGES; page_num++ {
+		var continuation_data any = getPlaylistContinuation(continuation, api_key, client_version)
+		if nil == continuation_data {
+			fmt.Println("Error getting playlist continuation: " + playlist_id)
+
+			break
 		}
 
-		videos_info_json = strings.Split(*page_html, "{\"playlistVideoRenderer\":")
+		videos_info, continuation = getPlaylistRenderers(continuation_data, videos_info)
+		videos_info, known_found = cutAtKnownVideo(videos_info, is_known)
+		if known_found {
+			break
+		}
+	}
 
-		// Separate and remove the rest of the page from the last item
-		var last_json string = videos_info_json[len(videos_info_json)-1]
-		videos_info_json[len(videos_info_json)-1] = strings.Split(last_json, "],\"playlistId\":")[0]
+	return videos_info
+}
 
-		// Remove the first too (it's also the rest of the page)
-		videos_info_json = videos_info_json[1:]
-		for i := 0; i < len(videos_info_json); i++ {
-			// Remove the last "}," from the string (it's part of the main JSON object)
-			videos_info_json[i] = videos_info_json[i][:strings.LastIndex(videos_info_json[i], "}")]
+/*
+cutAtKnownVideo cuts a list of videos at the first known one.
+
+-----------------------------------------------------------
+
+– Params:
+  - videos_info – the list of videos
+  - is_known – the function that says if a video is already known, or nil if none is
+
+– Returns:
+  - the videos before the first known one (all of them if none is known)
+  - true if a known video was found, false otherwise
+*/
+func cutAtKnownVideo(videos_info []_VideoInfo, is_known func(videoInfo _VideoInfo) bool) ([]_VideoInfo, bool) {
+	if nil == is_known {
+		return videos_info, false
+	}
+
+	for i, videoInfo := range videos_info {
+		if is_known(videoInfo) {
+			return videos_info[:i], true
 		}
+	}
+
+	return videos_info, false
+}
+
+/*
+getPlaylistContinuation gets the next page of a playlist from YouTube's internal browse endpoint.
+
+-----------------------------------------------------------
 
-		playlistPage_GL.id = playlist_id
-		playlistPage_GL.videos_info_json = videos_info_json
+– Params:
+  - continuation – the continuation token
+  - api_key – the INNERTUBE_API_KEY of the playlist page
+  - client_version – the INNERTUBE_CLIENT_VERSION of the playlist page
+
+– Returns:
+  - the decoded JSON response or nil if an error occurs
+*/
+func getPlaylistContinuation(continuation string, api_key string, client_version string) any {
+	var request_body map[string]any = map[string]any{
+		"context": map[string]any{
+			"client": map[string]any{
+				"clientName":    "WEB",
+				"clientVersion": client_version,
+			},
+		},
+		"continuation": continuation,
+	}
+	request_json, err := json.Marshal(request_body)
+	if nil != err {
+		return nil
 	}
 
-	var index int = len(videos_info_json) - item_count + item_num
-	if index < 0 {
-		// This should never happen - but it has, somehow xD (len was 0, item_num 0 and item_count 15...). So here is
-		// the prevention.
-		return videoInfo
+	var browse_url string = ytBaseUrl_GL + "/youtubei/v1/browse"
+	if "" != api_key {
+		browse_url += "?key=" + url.QueryEscape(api_key)
+	}
+
+	var client http.Client = http.Client{
+		Timeout: 60 * time.Second,
 	}
-	var video_info_json string = videos_info_json[index]
-	var json_decoded any = nil
-	err := json.NewDecoder(strings.NewReader(video_info_json)).Decode(&json_decoded)
+	resp, err := client.Post(browse_url, "application/json", bytes.NewReader(request_json))
 	if nil != err {
-		return videoInfo
+		return nil
+	}
+	defer resp.Body.Close()
+	if http.StatusOK != resp.StatusCode {
+		return nil
+	}
+
+	var response_data any = nil
+	if err = json.NewDecoder(resp.Body).Decode(&response_data); nil != err {
+		return nil
+	}
+
+	return response_data
+}
+
+/*
+getPlaylistRenderers looks recursively for the playlistVideoRenderer objects and for the continuation token on decoded
+playlist JSON data (be it from the page or from a continuation).
+
+-----------------------------------------------------------
+
+– Params:
+  - json_decoded – the decoded JSON data
+  - videos_info – the list to append the videos to
+
+– Returns:
+  - the list with the videos appended in the order they were found
+  - the continuation token or "" if there's none
+*/
+func getPlaylistRenderers(json_decoded any, videos_info []_VideoInfo) ([]_VideoInfo, string) {
+	var continuation string = ""
+
+	switch value := json_decoded.(type) {
+		case []any: {
+			// Arrays keep the order of the videos.
+			for _, element := range value {
+				var element_continuation string = ""
+				videos_info, element_continuation = getPlaylistRenderers(element, videos_info)
+				if "" != element_continuation {
+					continuation = element_continuation
+				}
+			}
+		}
+		case map[string]any: {
+			if renderer, ok := value["playlistVideoRenderer"]; ok {
+				if renderer_map, ok := renderer.(map[string]any); ok {
+					videos_info = append(videos_info, getRendererVideoInfo(renderer_map))
+				}
+
+				return videos_info, ""
+			}
+			if renderer, ok := value["continuationItemRenderer"]; ok {
+				return videos_info, findContinuationToken(renderer)
+			}
+
+			for _, key := range getSortedKeys(value) {
+				var child_continuation string = ""
+				videos_info, child_continuation = getPlaylistRenderers(value[key], videos_info)
+				if "" != child_continuation {
+					continuation = child_continuation
+				}
+			}
+		}
+	}
+
+	return videos_info, continuation
+}
+
+/*
+findContinuationToken finds the token on a continuationItemRenderer object
+(continuationEndpoint.continuationCommand.token).
+
+-----------------------------------------------------------
+
+– Params:
+  - renderer – the continuationItemRenderer object
+
+– Returns:
+  - the token or "" if it wasn't found
+*/
+func findContinuationToken(renderer any) string {
+	switch value := renderer.(type) {
+		case []any: {
+			for _, element := range value {
+				if token := findContinuationToken(element); "" != token {
+					return token
+				}
+			}
+		}
+		case map[string]any: {
+			if command, ok := value["continuationCommand"].(map[string]any); ok {
+				if token, ok := command["token"].(string); ok {
+					return token
+				}
+			}
+			for _, key := range getSortedKeys(value) {
+				if token := findContinuationToken(value[key]); "" != token {
+					return token
+				}
+			}
+		}
+	}
+
+	return ""
+}
+
+/*
+getSortedKeys gets the keys of a JSON object sorted, so that it's walked always in the same order (the maps' order is
+random).
+
+-----------------------------------------------------------
+
+– Params:
+  - json_object – the JSON object
+
+– Returns:
+  - the sorted keys
+*/
+func getSortedKeys(json_object map[string]any) []string {
+	var keys []string = make([]string, 0, len(json_object))
+	for key := range json_object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+/*
+getRendererVideoInfo gets the video information from a playlistVideoRenderer object.
+
+-----------------------------------------------------------
+
+– Params:
+  - json_decoded – the playlistVideoRenderer object
+
+– Returns:
+  - the video info, with _GEN_ERROR on the fields that couldn't be got
+*/
+func getRendererVideoInfo(json_decoded map[string]any) _VideoInfo {
+	var videoInfo _VideoInfo = _VideoInfo{
+		Id:     _GEN_ERROR,
+		Title:  _GEN_ERROR,
+		Length: _GEN_ERROR,
+		Image:  _GEN_ERROR,
 	}
 
 	// Video ID
-	var val, ok = toMap(json_decoded)["videoId"]
+	var val, ok = json_decoded["videoId"]
 	if ok {
-		videoInfo.id = val.(string)
+		videoInfo.Id = val.(string)
 
 		// toMap(json_decoded)["videoId"].(string)
 	}
 
 	// Video title
-	val, ok = toMap(json_decoded)["title"]
+	val, ok = json_decoded["title"]
 	if ok {
 		val, ok = toMap(val)["runs"]
 		if ok {
 			if len(toArr(val)) > 0 {
 				val, ok = toMap(toArr(val)[0])["text"]
 				if ok {
-					videoInfo.title = val.(string)
+					videoInfo.Title = val.(string)
 
 					// toMap(toArr(toMap(toMap(json_decoded)["title"])["runs"])[0])["text"].(string)
 				}
@@ -135,15 +392,15 @@ func ytPlaylistScraping(playlist_id string, item_num int, item_count int) _Video
 	}
 
 	// Video length
-	val, ok = toMap(json_decoded)["lengthSeconds"]
+	val, ok = json_decoded["lengthSeconds"]
 	if ok {
-		videoInfo.length = SecondsToTimeStr(val.(string))
+		videoInfo.Length = SecondsToTimeStr(val.(string))
 
 		// SecondsToTimeStr(toStr(toMap(json_decoded)["lengthSeconds"]))
 	}
 
 	// Video thumbnail
-	val, ok = toMap(json_decoded)["thumbnail"]
+	val, ok = json_decoded["thumbnail"]
 	if ok {
 		val, ok = toMap(val)["thumbnails"]
 		if ok {
@@ -155,7 +412,7 @@ func ytPlaylistScraping(playlist_id string, item_num int, item_count int) _Video
 				// The last element is the highest quality thumbnail
 				val, ok = toMap(array[len(array)-1])["url"]
 				if ok {
-					videoInfo.image = val.(string)
+					videoInfo.Image = val.(string)
 
 					// toMap(array[len(array)-1])["url"].(string)
 				}
@@ -0,0 +1,210 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// getTestRenderers gets the JSON of playlistVideoRenderer objects of the given video IDs, followed by a
+// continuationItemRenderer if there's a continuation token.
+func getTestRenderers(videos_ids []string, continuation string) string {
+	var renderers []string = nil
+	for _, video_id := range videos_ids {
+		renderers = append(renderers, `{"playlistVideoRenderer": {"videoId": "` + video_id + `", ` +
+			`"title": {"runs": [{"text": "Title ` + video_id + `"}]}, "lengthSeconds": "61", ` +
+			`"thumbnail": {"thumbnails": [{"url": "small.jpg"}, {"url": "big-` + video_id + `.jpg"}]}}}`)
+	}
+	if "" != continuation {
+		renderers = append(renderers, `{"continuationItemRenderer": ` +
+			`{"trigger": "CONTINUATION_TRIGGER_ON_ITEM_SHOWN", "continuationEndpoint": {"clickTrackingParams": "x", ` +
+			`"continuationCommand": {"token": "` + continuation + `", ` +
+			`"request": "CONTINUATION_REQUEST_TYPE_BROWSE"}}}}`)
+	}
+
+	return "[" + strings.Join(renderers, ", ") + "]"
+}
+
+// newTestPlaylistServer starts a stand-in of YouTube with a playlist page and two continuation pages on the browse
+// endpoint. The browse requests are counted on p_browse_requests, if it's not nil.
+func newTestPlaylistServer(t *testing.T, p_browse_requests *int) *httptest.Server {
+	var continuations map[string]string = map[string]string{
+		"token-1": getTestRenderers([]string{"vid3", "vid4"}, "token-2"),
+		"token-2": getTestRenderers([]string{"vid5"}, ""),
+	}
+
+	var mux *http.ServeMux = http.NewServeMux()
+	mux.HandleFunc("/playlist", func(w http.ResponseWriter, r *http.Request) {
+		if "PLtest" != r.URL.Query().Get("list") {
+			http.NotFound(w, r)
+
+			return
+		}
+		_, _ = fmt.Fprint(w, `<html><script>ytcfg.set({"INNERTUBE_API_KEY":"test-key",` +
+			`"INNERTUBE_CLIENT_VERSION":"2.20231120"});</script><script>var ytInitialData = {"contents": ` +
+			`{"twoColumnBrowseResultsRenderer": {"tabs": [{"tabRenderer": {"content": {"playlistVideoListRenderer": ` +
+			`{"contents": ` + getTestRenderers([]string{"vid1", "vid2"}, "token-1") + `}}}}]}}};</script></html>`)
+	})
+	mux.HandleFunc("/youtubei/v1/browse", func(w http.ResponseWriter, r *http.Request) {
+		if nil != p_browse_requests {
+			*p_browse_requests++
+		}
+		var request_body struct {
+			Context struct {
+				Client struct {
+					ClientVersion string `json:"clientVersion"`
+				} `json:"client"`
+			} `json:"context"`
+			Continuation string `json:"continuation"`
+		}
+		if http.MethodPost != r.Method || "test-key" != r.URL.Query().Get("key") ||
+					nil != json.NewDecoder(r.Body).Decode(&request_body) ||
+					"2.20231120" != request_body.Context.Client.ClientVersion {
+			t.Errorf("unexpected browse request: %s %s", r.Method, r.URL)
+			http.Error(w, "bad request", http.StatusBadRequest)
+
+			return
+		}
+		renderers, ok := continuations[request_body.Continuation]
+		if !ok {
+			http.NotFound(w, r)
+
+			return
+		}
+		_, _ = fmt.Fprint(w, `{"onResponseReceivedActions": [{"appendContinuationItemsAction": ` +
+			`{"continuationItems": ` + renderers + `}}]}`)
+	})
+
+	return httptest.NewServer(mux)
+}
+
+// setTestYTBaseUrl points the scraper to a stand-in of YouTube until the end of the test.
+func setTestYTBaseUrl(t *testing.T, base_url string) {
+	var old_base_url string = ytBaseUrl_GL
+	ytBaseUrl_GL = base_url
+	t.Cleanup(func() {
+		ytBaseUrl_GL = old_base_url
+	})
+}
+
+func TestCrawlPlaylist(t *testing.T) {
+	var server *httptest.Server = newTestPlaylistServer(t, nil)
+	defer server.Close()
+	setTestYTBaseUrl(t, server.URL)
+
+	var videos_info []_VideoInfo = crawlPlaylist("PLtest", nil)
+	var expected_ids []string = []string{"vid1", "vid2", "vid3", "vid4", "vid5"}
+	if len(videos_info) != len(expected_ids) {
+		t.Fatalf("got %d videos, expected %d: %v", len(videos_info), len(expected_ids), videos_info)
+	}
+	for i, videoInfo := range videos_info {
+		if expected_ids[i] != videoInfo.Id {
+			t.Errorf("video %d: got ID %q, expected %q", i, videoInfo.Id, expected_ids[i])
+		}
+		if "Title " + expected_ids[i] != videoInfo.Title || "01:01" != videoInfo.Length ||
+					"big-" + expected_ids[i] + ".jpg" != videoInfo.Image {
+			t.Errorf("video %d: wrong information: %+v", i, videoInfo)
+		}
+	}
+
+	if nil != crawlPlaylist("PLmissing", nil) {
+		t.Error("expected nil for a playlist whose page can't be got")
+	}
+}
+
+func TestCrawlPlaylistStopsAtKnownVideo(t *testing.T) {
+	var browse_requests int = 0
+	var server *httptest.Server = newTestPlaylistServer(t, &browse_requests)
+	defer server.Close()
+	setTestYTBaseUrl(t, server.URL)
+
+	var tests = []struct {
+		known_id         string
+		expected_ids     string
+		expected_browses int
+	}{
+		{"vid2", "vid1", 0},
+		{"vid4", "vid1,vid2,vid3", 1},
+		{"none", "vid1,vid2,vid3,vid4,vid5", 2},
+	}
+	for _, test := range tests {
+		browse_requests = 0
+		var videos_info []_VideoInfo = crawlPlaylist("PLtest", func(videoInfo _VideoInfo) bool {
+			return test.known_id == videoInfo.Id
+		})
+		var ids []string = nil
+		for _, videoInfo := range videos_info {
+			ids = append(ids, videoInfo.Id)
+		}
+		if test.expected_ids != strings.Join(ids, ",") || test.expected_browses != browse_requests {
+			t.Errorf("known %s: got %v with %d continuations, expected %s with %d", test.known_id, ids,
+				browse_requests, test.expected_ids, test.expected_browses)
+		}
+	}
+}
+
+func TestFindContinuationToken(t *testing.T) {
+	var tests = []struct {
+		json     string
+		expected string
+	}{
+		{`{"continuationEndpoint": {"continuationCommand": {"token": "abc"}}}`, "abc"},
+		{`{"a": [{"b": 1}, {"continuationCommand": {"token": "deep"}}]}`, "deep"},
+		{`{"continuationCommand": {"request": "x"}}`, ""},
+		{`[]`, ""},
+	}
+	for _, test := range tests {
+		var decoded any = nil
+		if err := json.Unmarshal([]byte(test.json), &decoded); nil != err {
+			t.Fatal(err)
+		}
+		if token := findContinuationToken(decoded); test.expected != token {
+			t.Errorf("findContinuationToken(%s) = %q, expected %q", test.json, token, test.expected)
+		}
+	}
+}
+
+func TestGetPlaylistRenderersOrder(t *testing.T) {
+	// The renderers are in different keys of the same object - the order must not depend on the maps' order.
+	var json_str string = `{"z": {"contents": ` + getTestRenderers([]string{"c", "d"}, "next") + `}, ` +
+		`"a": {"contents": ` + getTestRenderers([]string{"a", "b"}, "") + `}}`
+	var decoded any = nil
+	if err := json.Unmarshal([]byte(json_str), &decoded); nil != err {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 20; i++ {
+		videos_info, continuation := getPlaylistRenderers(decoded, nil)
+		var ids []string = nil
+		for _, videoInfo := range videos_info {
+			ids = append(ids, videoInfo.Id)
+		}
+		if "a,b,c,d" != strings.Join(ids, ",") || "next" != continuation {
+			t.Fatalf("got %v and continuation %q", ids, continuation)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+/*
+probeShort checks if a video is a Short by requesting its /shorts/ URL: Shorts are shown there (200), while normal
+videos are redirected to /watch.
+
+The verdicts are cached, so each video is only probed once.
+
+-----------------------------------------------------------
+
+– Params:
+  - video_id – the ID of the video
+
+– Returns:
+  - true if the video is a Short, false otherwise
+  - true if the verdict is known, false if the probe failed (then the 1st value is meaningless)
+*/
+func probeShort(video_id string) (bool, bool) {
+	var is_short bool = false
+	if scrapeCacheGet(_CACHE_KIND_SHORT_VERDICT, video_id, &is_short) {
+		return is_short, true
+	}
+
+	var client http.Client = http.Client{
+		Timeout: 30 * time.Second,
+		// Don't follow the redirect - the redirect itself is the answer.
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	req, err := http.NewRequest(http.MethodHead, ytBaseUrl_GL+"/shorts/"+video_id, nil)
+	if nil != err {
+		return false, false
+	}
+	resp, err := client.Do(req)
+	if nil != err {
+		fmt.Println("Error probing Short: " + err.Error())
+
+		return false, false
+	}
+	_ = resp.Body.Close()
+
+	if http.StatusOK == resp.StatusCode {
+		is_short = true
+	} else if resp.StatusCode >= 300 && resp.StatusCode < 400 &&
+				strings.Contains(resp.Header.Get("Location"), "/watch") {
+		is_short = false
+	} else {
+		// Anything else (like a redirect to the cookies consent page) says nothing about the video.
+		return false, false
+	}
+
+	scrapeCacheSet(_CACHE_KIND_SHORT_VERDICT, video_id, is_short)
+
+	return is_short, true
+}
@@ -0,0 +1,94 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestProbeShort(t *testing.T) {
+	var probes int = 0
+	var server *httptest.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		probes++
+		switch r.URL.Path {
+			case "/shorts/probeShort1": {
+				w.WriteHeader(http.StatusOK)
+			}
+			case "/shorts/probeNormal1": {
+				http.Redirect(w, r, "/watch?v=probeNormal1", http.StatusSeeOther)
+			}
+			default: {
+				// Like the redirect to the cookies consent page.
+				http.Redirect(w, r, "https://consent.youtube.com/m", http.StatusFound)
+			}
+		}
+	}))
+	defer server.Close()
+	setTestYTBaseUrl(t, server.URL)
+
+	if is_short, ok := probeShort("probeShort1"); !ok || !is_short {
+		t.Errorf("Short: got %t (known: %t), expected a Short", is_short, ok)
+	}
+	if is_short, ok := probeShort("probeNormal1"); !ok || is_short {
+		t.Errorf("normal video: got %t (known: %t), expected not a Short", is_short, ok)
+	}
+	if _, ok := probeShort("probeConsent1"); ok {
+		t.Error("consent redirect: expected an unknown verdict")
+	}
+	if 3 != probes {
+		t.Errorf("got %d probes, expected 3", probes)
+	}
+
+	// The verdicts are cached, so the videos aren't probed again - but the unknown one is.
+	if is_short, ok := probeShort("probeShort1"); !ok || !is_short {
+		t.Errorf("cached Short: got %t (known: %t), expected a Short", is_short, ok)
+	}
+	if is_short, ok := probeShort("probeNormal1"); !ok || is_short {
+		t.Errorf("cached normal video: got %t (known: %t), expected not a Short", is_short, ok)
+	}
+	probeShort("probeConsent1")
+	if 4 != probes {
+		t.Errorf("got %d probes, expected 4", probes)
+	}
+
+	// The probe wins over the texts and the length.
+	if isShort("probeNormal1", []string{"Title #shorts"}, "00:30") {
+		t.Error("normal video with Short texts: expected not a Short")
+	}
+
+	// If the probe fails, the texts and the length are used.
+	server.Close()
+	if _, ok := probeShort("probeError1"); ok {
+		t.Error("probe error: expected an unknown verdict")
+	}
+	if !isShort("probeError1", []string{"Title #shorts"}, _VID_TIME_DEF) {
+		t.Error("probe error with Short texts: expected a Short")
+	}
+	if !isShort("probeError1", []string{"Title"}, "00:45") {
+		t.Error("probe error with a short length: expected a Short")
+	}
+	if isShort("probeError1", []string{"Title"}, "12:00") {
+		t.Error("probe error with a long length: expected not a Short")
+	}
+}
@@ -0,0 +1,481 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"Utils"
+)
+
+const (
+	_LIVE_STATUS_NONE     = "none"     // Normal video
+	_LIVE_STATUS_UPCOMING = "upcoming" // Scheduled livestream or premiere that didn't start yet
+	_LIVE_STATUS_LIVE     = "live"     // Livestream or premiere happening right now
+	_LIVE_STATUS_ENDED    = "ended"    // Livestream or premiere that already ended (a replay)
+)
+
+// _UPCOMING_CHECK_MARGIN is how long before the scheduled start time the video page starts being checked again.
+const _UPCOMING_CHECK_MARGIN time.Duration = 5 * time.Minute
+// _UPCOMING_KEEP_ENDED is how long an ended event is kept on the file (so that it's not detected again as new).
+const _UPCOMING_KEEP_ENDED time.Duration = 7 * 24 * time.Hour
+// _UPCOMING_GIVE_UP is how long an event that didn't end is kept being checked, counting from its last status change
+// or, for scheduled events, from the scheduled start time if later (cancelled, rescheduled to a far date or an endless
+// livestream).
+const _UPCOMING_GIVE_UP time.Duration = 7 * 24 * time.Hour
+// _UPCOMING_MAX_FAILED_CHECKS is after how many checks in a row without live info on the video's page (page not got,
+// or the video was deleted or made private) an event is forgotten.
+const _UPCOMING_MAX_FAILED_CHECKS int = 10
+
+// _LiveInfo is the livestream/premiere information of a video.
+type _LiveInfo struct {
+	// status is one of the _LIVE_STATUS_ constants
+	status string
+	// is_premiere is true if the video is a premiere, false if it's a livestream
+	is_premiere bool
+	// scheduled_start is the scheduled start time of the event (zero if unknown)
+	scheduled_start time.Time
+}
+
+// _UpcomingEvent is a livestream or premiere being tracked until it ends. It's exported to JSON, so the fields are
+// exported.
+type _UpcomingEvent struct {
+	// Video_id is the ID of the video
+	Video_id string
+	// Feed_num is the number of the feed the video came from
+	Feed_num int
+	// Status is one of the _LIVE_STATUS_ constants
+	Status string
+	// Is_premiere is true if the event is a premiere, false if it's a livestream
+	Is_premiere bool
+	// Scheduled_start is the scheduled start time of the event in Unix seconds (0 if unknown)
+	Scheduled_start int64
+	// Status_changed is the last time Status changed in Unix seconds
+	Status_changed int64
+	// Failed_checks is the number of checks in a row in which no live info was found on the video's page
+	Failed_checks int
+	// Things_replace is the email model information of the video, ready to be used to notify the event start
+	Things_replace map[string]string
+}
+
+/*
+getLiveInfo gets the livestream/premiere information of a video by looking for it on the video's page HTML.
+
+-----------------------------------------------------------
+
+– Params:
+  - page_html – the HTML of the video's page
+
+– Returns:
+  - the live info of the video
+*/
+func getLiveInfo(page_html string) _LiveInfo {
+	var liveInfo _LiveInfo = _LiveInfo{
+		status: _LIVE_STATUS_NONE,
+	}
+
+	// All these are on the JSON data of the page (videoDetails, microformat and the offline slate of scheduled events)
+	// --> CAN CHANGE (checked on 2023-11-20).
+	var is_upcoming bool = "true" == findPageJsonValue(page_html, "isUpcoming")
+	var is_live_now bool = "true" == findPageJsonValue(page_html, "isLiveNow") ||
+		"true" == findPageJsonValue(page_html, "isLive")
+	var is_live_content bool = "true" == findPageJsonValue(page_html, "isLiveContent")
+	var start_timestamp string = findPageJsonValue(page_html, "startTimestamp")
+	var end_timestamp string = findPageJsonValue(page_html, "endTimestamp")
+
+	if !is_upcoming && !is_live_now && "" == start_timestamp {
+		// No liveBroadcastDetails at all - normal video.
+		return liveInfo
+	}
+
+	// Premieres are not live content - only livestreams are.
+	liveInfo.is_premiere = !is_live_content
+
+	var scheduled_start string = findPageJsonValue(page_html, "scheduledStartTime")
+	if "" != scheduled_start {
+		scheduled_secs, err := strconv.ParseInt(scheduled_start, 10, 64)
+		if nil == err {
+			liveInfo.scheduled_start = time.Unix(scheduled_secs, 0)
+		}
+	}
+	if liveInfo.scheduled_start.IsZero() && "" != start_timestamp {
+		start_time, err := time.Parse(time.RFC3339, start_timestamp)
+		if nil == err {
+			liveInfo.scheduled_start = start_time
+		}
+	}
+
+	if is_upcoming {
+		liveInfo.status = _LIVE_STATUS_UPCOMING
+	} else if is_live_now {
+		liveInfo.status = _LIVE_STATUS_LIVE
+	} else if "" != end_timestamp {
+		liveInfo.status = _LIVE_STATUS_ENDED
+	}
+
+	return liveInfo
+}
+
+/*
+findPageJsonValue finds the value of the first occurrence of a key on the JSON data inside a page's HTML.
+
+-----------------------------------------------------------
+
+– Params:
+  - page_html – the HTML of the page
+  - key – the key to look for
+
+– Returns:
+  - the value without quotes if it was found (strings, numbers and booleans), "" otherwise
+*/
+func findPageJsonValue(page_html string, key string) string {
+	var text_to_find string = "\"" + key + "\":"
+	var idx_begin int = strings.Index(page_html, text_to_find)
+	if idx_begin < 0 {
+		return ""
+	}
+	idx_begin += len(text_to_find)
+
+	// Pretty-printed JSON has spaces after the colon.
+	var rest string = strings.TrimLeft(page_html[idx_begin:], " \t\r\n")
+	if strings.HasPrefix(rest, "\"") {
+		var idx_end int = strings.Index(rest[1:], "\"")
+		if idx_end < 0 {
+			return ""
+		}
+
+		return rest[1 : 1+idx_end]
+	}
+
+	var idx_end int = strings.IndexAny(rest, ",}]")
+	if idx_end < 0 {
+		return ""
+	}
+
+	return strings.TrimSpace(rest[:idx_end])
+}
+
+/*
+setUpcomingThingsReplace prepares the email model information for the "scheduled for" notice of an event.
+
+-----------------------------------------------------------
+
+– Params:
+  - things_replace – the email model information of the video
+  - liveInfo – the live info of the video
+  - vid_title – the (possibly trimmed) title of the video
+
+– Returns:
+  - the subject of the email
+*/
+func setUpcomingThingsReplace(things_replace map[string]string, liveInfo _LiveInfo, vid_title string) string {
+	var start_str string = "breve"
+	if !liveInfo.scheduled_start.IsZero() {
+		start_str = liveInfo.scheduled_start.Local().Format(Utils.DATE_TIME_FORMAT)
+	}
+
+	var event_str string = "uma transmissão em direto"
+	if liveInfo.is_premiere {
+		event_str = "a estreia de um vídeo"
+	}
+
+	var msg_subject string = "📅 " + things_replace[Utils.MODEL_YT_VIDEO_CHANNEL_NAME_EMAIL] + " agendou " + event_str +
+		" para " + start_str + ": " + vid_title
+	things_replace[Utils.MODEL_YT_VIDEO_HTML_TITLE_EMAIL] = msg_subject
+
+	things_replace[Utils.MODEL_YT_VIDEO_VIDEO_TIME_COLOR_EMAIL] = _VIDEO_COLOR
+	things_replace[Utils.MODEL_YT_VIDEO_VIDEO_TIME_EMAIL] = "AGENDADO"
+
+	return msg_subject
+}
+
+/*
+setLiveThingsReplace prepares the email model information for the "em direto" notification of an event.
+
+-----------------------------------------------------------
+
+– Params:
+  - things_replace – the email model information of the video
+  - vid_title – the (possibly trimmed) title of the video
+
+– Returns:
+  - the subject of the email
+*/
+func setLiveThingsReplace(things_replace map[string]string, vid_title string) string {
+	var msg_subject string = "🔴 " + things_replace[Utils.MODEL_YT_VIDEO_CHANNEL_NAME_EMAIL] +
+		" está agora em direto: " + vid_title + "!"
+	things_replace[Utils.MODEL_YT_VIDEO_HTML_TITLE_EMAIL] = "Em direto no YouTube: " +
+		things_replace[Utils.MODEL_YT_VIDEO_CHANNEL_NAME_EMAIL] + " – " + vid_title + "!"
+
+	// Change the length rectangle
+	things_replace[Utils.MODEL_YT_VIDEO_VIDEO_TIME_COLOR_EMAIL] = _LIVE_COLOR
+	things_replace[Utils.MODEL_YT_VIDEO_VIDEO_TIME_EMAIL] = "LIVE" // Change the video length to "LIVE"
+
+	return msg_subject
+}
+
+/*
+trackUpcomingEvent adds or updates an event on the upcoming events file so that it's checked until it ends.
+
+Calling it more than once for the same video only updates the event.
+
+-----------------------------------------------------------
+
+– Params:
+  - video_id – the ID of the video
+  - feed_num – the number of the feed the video came from
+  - liveInfo – the live info of the video
+  - things_replace – the email model information of the video (a copy is stored)
+*/
+func trackUpcomingEvent(video_id string, feed_num int, liveInfo _LiveInfo, things_replace map[string]string) {
+	var events map[string]_UpcomingEvent = readUpcomingEvents()
+
+	var things_replace_copy map[string]string = make(map[string]string, len(things_replace))
+	for key, value := range things_replace {
+		things_replace_copy[key] = value
+	}
+
+	var scheduled_start int64 = 0
+	if !liveInfo.scheduled_start.IsZero() {
+		scheduled_start = liveInfo.scheduled_start.Unix()
+	}
+
+	var status_changed int64 = time.Now().Unix()
+	if event, ok := events[video_id]; ok && event.Status == liveInfo.status {
+		status_changed = event.Status_changed
+	}
+
+	events[video_id] = _UpcomingEvent{
+		Video_id:        video_id,
+		Feed_num:        feed_num,
+		Status:          liveInfo.status,
+		Is_premiere:     liveInfo.is_premiere,
+		Scheduled_start: scheduled_start,
+		Status_changed:  status_changed,
+		Things_replace:  things_replace_copy,
+	}
+
+	writeUpcomingEvents(events)
+}
+
+/*
+checkUpcomingEvents checks all the tracked events: notifies the ones that started and marks as ended the ones that
+ended. Events that ended a while ago, that never started or ended or whose page keeps having no live info are
+forgotten.
+
+Only events about to start or already live have their page checked, to not get pages uselessly.
+*/
+func checkUpcomingEvents() {
+	var events map[string]_UpcomingEvent = readUpcomingEvents()
+	if 0 == len(events) {
+		return
+	}
+
+	if updateUpcomingEvents(events, time.Now(), notifyEventLive) {
+		writeUpcomingEvents(events)
+	}
+}
+
+/*
+updateUpcomingEvents updates the tracked events as explained on checkUpcomingEvents().
+
+-----------------------------------------------------------
+
+– Params:
+  - events – the tracked events mapped by video ID, modified in place
+  - now – the current time
+  - notifyLive – the function that notifies an event that just started, returning true if the notification was queued
+
+– Returns:
+  - true if the events were modified, false otherwise
+*/
+func updateUpcomingEvents(events map[string]_UpcomingEvent, now time.Time,
+			notifyLive func(event _UpcomingEvent) bool) bool {
+	var events_modified bool = false
+	for video_id, event := range events {
+		var scheduled_start time.Time = time.Unix(event.Scheduled_start, 0)
+		var status_changed time.Time = time.Unix(event.Status_changed, 0)
+
+		if _LIVE_STATUS_ENDED == event.Status {
+			if now.Sub(status_changed) > _UPCOMING_KEEP_ENDED {
+				delete(events, video_id)
+				events_modified = true
+			}
+
+			continue
+		}
+
+		var give_up_from time.Time = status_changed
+		if _LIVE_STATUS_UPCOMING == event.Status && 0 != event.Scheduled_start && scheduled_start.After(give_up_from) {
+			give_up_from = scheduled_start
+		}
+		if now.Sub(give_up_from) > _UPCOMING_GIVE_UP {
+			fmt.Println("Giving up on " + event.Status + " event: " + video_id)
+			delete(events, video_id)
+			events_modified = true
+
+			continue
+		}
+		if _LIVE_STATUS_UPCOMING == event.Status && 0 != event.Scheduled_start &&
+					scheduled_start.Sub(now) > _UPCOMING_CHECK_MARGIN {
+			// Still too early to check.
+			continue
+		}
+
+		var liveInfo _LiveInfo = getVideoPageInfo(video_id).liveInfo
+		if _LIVE_STATUS_NONE == liveInfo.status {
+			// Page not got or no live info on it (deleted or private video) - try again next time, but not forever.
+			event.Failed_checks++
+			if event.Failed_checks >= _UPCOMING_MAX_FAILED_CHECKS {
+				fmt.Println("Giving up on event without live info: " + video_id)
+				delete(events, video_id)
+			} else {
+				events[video_id] = event
+			}
+			events_modified = true
+
+			continue
+		}
+		event.Failed_checks = 0
+		if !liveInfo.scheduled_start.IsZero() {
+			event.Scheduled_start = liveInfo.scheduled_start.Unix()
+		}
+		if liveInfo.status == event.Status {
+			events[video_id] = event
+			events_modified = true
+
+			continue
+		}
+
+		if _LIVE_STATUS_LIVE == liveInfo.status && _LIVE_STATUS_UPCOMING == event.Status {
+			fmt.Println("Upcoming event started: " + video_id)
+			if !notifyLive(event) {
+				// Try again next time.
+				events[video_id] = event
+				events_modified = true
+
+				continue
+			}
+		} else if _LIVE_STATUS_ENDED == liveInfo.status {
+			fmt.Println("Event ended: " + video_id)
+		}
+
+		event.Status = liveInfo.status
+		event.Status_changed = now.Unix()
+		events[video_id] = event
+		events_modified = true
+	}
+
+	return events_modified
+}
+
+/*
+notifyEventLive sends the "em direto" notification of an event that just started.
+
+-----------------------------------------------------------
+
+– Params:
+  - event – the event
+
+– Returns:
+  - true if the notification was queued, false otherwise
+*/
+func notifyEventLive(event _UpcomingEvent) bool {
+	var things_replace map[string]string = event.Things_replace
+	var msg_subject string = setLiveThingsReplace(things_replace,
+		things_replace[Utils.MODEL_YT_VIDEO_VIDEO_TITLE_EMAIL])
+
+	var email_info Utils.EmailInfo = Utils.GetModelFileEMAIL(Utils.MODEL_FILE_YT_VIDEO, things_replace)
+	email_info.Subject = msg_subject
+
+	fmt.Println("Queuing email: " + email_info.Subject)
+
+	// If the feed was removed meanwhile, it's sent only to the recipients of all feeds.
+	feedInfo, _ := getFeedInfo(event.Feed_num)
+
+	var newsInfo _NewsInfo = _NewsInfo{
+		title: things_replace[Utils.MODEL_YT_VIDEO_VIDEO_TITLE_EMAIL],
+		url:   "https://www.youtube.com/watch?v=" + event.Video_id,
+		live:  true,
+	}
+
+	return queueEmailAllRecps(feedInfo, newsInfo, email_info.Sender, email_info.Subject, email_info.Html)
+}
+
+/*
+readUpcomingEvents reads the tracked events from the upcoming events file.
+
+-----------------------------------------------------------
+
+– Returns:
+  - the tracked events mapped by video ID (empty if there are none or if an error occurs)
+*/
+func readUpcomingEvents() map[string]_UpcomingEvent {
+	var events map[string]_UpcomingEvent = make(map[string]_UpcomingEvent)
+
+	var p_events_json *string = getUpcomingEventsPath().ReadTextFile()
+	if nil == p_events_json {
+		return events
+	}
+	if err := json.Unmarshal([]byte(*p_events_json), &events); nil != err {
+		fmt.Println("Error reading upcoming events: " + err.Error())
+
+		return make(map[string]_UpcomingEvent)
+	}
+
+	return events
+}
+
+/*
+writeUpcomingEvents writes the tracked events to the upcoming events file.
+
+-----------------------------------------------------------
+
+– Params:
+  - events – the tracked events mapped by video ID
+*/
+func writeUpcomingEvents(events map[string]_UpcomingEvent) {
+	events_json, err := json.MarshalIndent(events, "", "\t")
+	if nil != err {
+		fmt.Println("Error writing upcoming events: " + err.Error())
+
+		return
+	}
+
+	getUpcomingEventsPath().WriteTextFile(string(events_json))
+}
+
+/*
+getUpcomingEventsPath gets the path of the upcoming events file.
+
+-----------------------------------------------------------
+
+– Returns:
+  - the path of the file
+*/
+func getUpcomingEventsPath() Utils.GPath {
+	return moduleInfo_GL.ModDirsInfo.UserData.Add2("upcoming_events.json")
+}
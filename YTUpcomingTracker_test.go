@@ -0,0 +1,219 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestFindPageJsonValue(t *testing.T) {
+	var page_html string = `<script>var data = {"videoDetails": {"videoId": "vid1", "isLiveContent": true, ` +
+		`"viewCount": 42}, "list": [1, 2], "isUpcoming":false}</script>`
+
+	var tests map[string]string = map[string]string{
+		"videoId":       "vid1",
+		"isLiveContent": "true",
+		"viewCount":     "42",
+		"isUpcoming":    "false",
+		"missing":       "",
+	}
+	for key, expected := range tests {
+		if value := findPageJsonValue(page_html, key); expected != value {
+			t.Errorf("findPageJsonValue(%q) = %q, expected %q", key, value, expected)
+		}
+	}
+
+	// Unterminated values are not returned.
+	if value := findPageJsonValue(`{"title": "no end`, "title"); "" != value {
+		t.Errorf("findPageJsonValue() of an unterminated string = %q", value)
+	}
+	if value := findPageJsonValue(`{"count": 3`, "count"); "" != value {
+		t.Errorf("findPageJsonValue() of an unterminated number = %q", value)
+	}
+}
+
+func TestGetLiveInfo(t *testing.T) {
+	var scheduled_start time.Time = time.Unix(1700000000, 0)
+
+	var tests []struct {
+		name     string
+		page     string
+		expected _LiveInfo
+	} = []struct {
+		name     string
+		page     string
+		expected _LiveInfo
+	}{
+		{"normal video", `{"videoId": "vid1", "isLiveContent": false}`, _LiveInfo{status: _LIVE_STATUS_NONE}},
+		{
+			"upcoming livestream",
+			`{"isUpcoming": true, "isLiveContent": true, "scheduledStartTime": "1700000000"}`,
+			_LiveInfo{status: _LIVE_STATUS_UPCOMING, scheduled_start: scheduled_start},
+		},
+		{
+			"upcoming premiere",
+			`{"isUpcoming": true, "isLiveContent": false, "scheduledStartTime": "1700000000"}`,
+			_LiveInfo{status: _LIVE_STATUS_UPCOMING, is_premiere: true, scheduled_start: scheduled_start},
+		},
+		{
+			"live with only the start timestamp",
+			`{"isLiveNow": true, "isLiveContent": true, "startTimestamp": "2023-11-14T22:13:20+00:00"}`,
+			_LiveInfo{status: _LIVE_STATUS_LIVE, scheduled_start: scheduled_start},
+		},
+		{
+			"ended",
+			`{"isLiveContent": true, "startTimestamp": "2023-11-14T22:13:20+00:00", ` +
+				`"endTimestamp": "2023-11-14T23:13:20+00:00"}`,
+			_LiveInfo{status: _LIVE_STATUS_ENDED, scheduled_start: scheduled_start},
+		},
+	}
+	for _, test := range tests {
+		var liveInfo _LiveInfo = getLiveInfo(test.page)
+		if test.expected.status != liveInfo.status || test.expected.is_premiere != liveInfo.is_premiere ||
+					!test.expected.scheduled_start.Equal(liveInfo.scheduled_start) {
+			t.Errorf("%s: getLiveInfo() = %+v, expected %+v", test.name, liveInfo, test.expected)
+		}
+	}
+}
+
+// newTestWatchServer starts a stand-in of YouTube serving the watch pages of the given videos (404 for the others)
+// and counting the pages requested.
+func newTestWatchServer(t *testing.T, pages map[string]string, p_requests *int) *httptest.Server {
+	t.Helper()
+
+	var mux *http.ServeMux = http.NewServeMux()
+	mux.HandleFunc("/watch", func(w http.ResponseWriter, r *http.Request) {
+		if nil != p_requests {
+			*p_requests++
+		}
+		page, ok := pages[r.URL.Query().Get("v")]
+		if !ok {
+			http.NotFound(w, r)
+
+			return
+		}
+		_, _ = w.Write([]byte(page))
+	})
+
+	return httptest.NewServer(mux)
+}
+
+func TestUpdateUpcomingEvents(t *testing.T) {
+	var now time.Time = time.Now()
+	var now_str string = strconv.FormatInt(now.Unix(), 10)
+	var days_ago int64 = now.Add(-8 * 24 * time.Hour).Unix()
+
+	var pages map[string]string = map[string]string{
+		"starts":    `{"isLiveNow": true, "isLiveContent": true, "scheduledStartTime": "` + now_str + `"}`,
+		"not_sent":  `{"isLiveNow": true, "isLiveContent": true, "scheduledStartTime": "` + now_str + `"}`,
+		"ends":      `{"isLiveContent": true, "startTimestamp": "x", "endTimestamp": "y"}`,
+		"still":     `{"isUpcoming": true, "isLiveContent": true, "scheduledStartTime": "` + now_str + `"}`,
+		"private":   `{"videoId": "private", "isLiveContent": false}`,
+		"last_try":  `{"videoId": "last_try", "isLiveContent": false}`,
+	}
+	var requests int = 0
+	var server *httptest.Server = newTestWatchServer(t, pages, &requests)
+	defer server.Close()
+	setTestYTBaseUrl(t, server.URL)
+
+	var events map[string]_UpcomingEvent = map[string]_UpcomingEvent{
+		"starts":   {Video_id: "starts", Status: _LIVE_STATUS_UPCOMING, Scheduled_start: now.Unix(),
+			Status_changed: now.Unix(), Failed_checks: 3},
+		"not_sent": {Video_id: "not_sent", Status: _LIVE_STATUS_UPCOMING, Scheduled_start: now.Unix(),
+			Status_changed: now.Unix()},
+		"ends":     {Video_id: "ends", Status: _LIVE_STATUS_LIVE, Status_changed: now.Unix()},
+		"still":    {Video_id: "still", Status: _LIVE_STATUS_UPCOMING, Status_changed: now.Unix()},
+		"private":  {Video_id: "private", Status: _LIVE_STATUS_UPCOMING, Status_changed: now.Unix()},
+		"last_try": {Video_id: "last_try", Status: _LIVE_STATUS_UPCOMING, Status_changed: now.Unix(),
+			Failed_checks: _UPCOMING_MAX_FAILED_CHECKS - 1},
+		"deleted":  {Video_id: "deleted", Status: _LIVE_STATUS_LIVE, Status_changed: now.Unix(),
+			Failed_checks: _UPCOMING_MAX_FAILED_CHECKS - 1},
+		// Not checked: too early, ended recently.
+		"later":       {Video_id: "later", Status: _LIVE_STATUS_UPCOMING,
+			Scheduled_start: now.Add(time.Hour).Unix(), Status_changed: days_ago},
+		"ended_new":   {Video_id: "ended_new", Status: _LIVE_STATUS_ENDED, Status_changed: now.Unix()},
+		// Forgotten without being checked.
+		"ended_old":   {Video_id: "ended_old", Status: _LIVE_STATUS_ENDED, Status_changed: days_ago},
+		"unscheduled": {Video_id: "unscheduled", Status: _LIVE_STATUS_UPCOMING, Status_changed: days_ago},
+		"never":       {Video_id: "never", Status: _LIVE_STATUS_UPCOMING, Scheduled_start: days_ago,
+			Status_changed: days_ago},
+		"endless":     {Video_id: "endless", Status: _LIVE_STATUS_LIVE, Status_changed: days_ago},
+	}
+
+	var notified []string = nil
+	var notifyLive func(event _UpcomingEvent) bool = func(event _UpcomingEvent) bool {
+		notified = append(notified, event.Video_id)
+
+		return "not_sent" != event.Video_id
+	}
+	if !updateUpcomingEvents(events, now, notifyLive) {
+		t.Fatal("updateUpcomingEvents() reported no changes")
+	}
+
+	if 7 != requests {
+		t.Errorf("got %d pages, expected 7", requests)
+	}
+	if 2 != len(notified) {
+		t.Errorf("notified %v, expected starts and not_sent", notified)
+	}
+
+	var expected map[string]struct {
+		status        string
+		failed_checks int
+	} = map[string]struct {
+		status        string
+		failed_checks int
+	}{
+		"starts":    {_LIVE_STATUS_LIVE, 0},
+		"not_sent":  {_LIVE_STATUS_UPCOMING, 0},
+		"ends":      {_LIVE_STATUS_ENDED, 0},
+		"still":     {_LIVE_STATUS_UPCOMING, 0},
+		"private":   {_LIVE_STATUS_UPCOMING, 1},
+		"later":     {_LIVE_STATUS_UPCOMING, 0},
+		"ended_new": {_LIVE_STATUS_ENDED, 0},
+	}
+	if len(expected) != len(events) {
+		t.Errorf("got %d events, expected %d: %v", len(events), len(expected), events)
+	}
+	for video_id, expected_event := range expected {
+		event, ok := events[video_id]
+		if !ok {
+			t.Errorf("event %s was forgotten", video_id)
+
+			continue
+		}
+		if expected_event.status != event.Status || expected_event.failed_checks != event.Failed_checks {
+			t.Errorf("event %s = %s with %d failed checks, expected %s with %d", video_id, event.Status,
+				event.Failed_checks, expected_event.status, expected_event.failed_checks)
+		}
+	}
+	if events["starts"].Status_changed != now.Unix() || events["not_sent"].Status_changed != now.Unix() {
+		t.Error("the status change time was not updated correctly")
+	}
+	if events["still"].Scheduled_start != now.Unix() {
+		t.Errorf("the scheduled start of still was not updated: %d", events["still"].Scheduled_start)
+	}
+}
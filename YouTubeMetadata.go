@@ -0,0 +1,291 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// _YouTubeMetadataSource is a source of YouTube metadata: the page scraper or the YouTube Data API.
+type _YouTubeMetadataSource interface {
+	// name is the name of the source, for logging
+	name() string
+	// videoPageInfo gets the duration and the livestream/premiere information of a video
+	videoPageInfo(video_id string) (_VideoPageInfo, error)
+	// channelImageUrl gets the URL of the image of a channel
+	channelImageUrl(channel_code string) (string, error)
+	// playlistVideos gets the information of the videos of a playlist, in the playlist order, until the first one
+	// is_known() returns true for (all of them if it's nil)
+	playlistVideos(playlist_id string, is_known func(videoInfo _VideoInfo) bool) ([]_VideoInfo, error)
+}
+
+// _CachedVideoInfo is the _VideoPageInfo as stored on the scrape cache (exported to JSON, so the fields are exported).
+type _CachedVideoInfo struct {
+	Length          string
+	Live_status     string
+	Is_premiere     bool
+	Scheduled_start int64
+}
+
+/*
+getYTMetadataSources gets the YouTube metadata sources to use, in the order they're to be tried.
+
+-----------------------------------------------------------
+
+– Returns:
+  - the YouTube Data API (only if there's an API key and its quota isn't exceeded) and the scraper, always last
+*/
+func getYTMetadataSources() []_YouTubeMetadataSource {
+	var modUserInfo _ModUserInfo
+	if !moduleInfo_GL.GetModUserInfo(&modUserInfo) {
+		return getYTMetadataSourcesWithKey("")
+	}
+
+	return getYTMetadataSourcesWithKey(modUserInfo.Yt_api_key)
+}
+
+/*
+getYTMetadataSourcesWithKey is getYTMetadataSources() with the API key given instead of read from the user settings.
+
+-----------------------------------------------------------
+
+– Params:
+  - api_key – the YouTube Data API key or "" if there's none
+*/
+func getYTMetadataSourcesWithKey(api_key string) []_YouTubeMetadataSource {
+	var sources []_YouTubeMetadataSource = nil
+	if "" != api_key && time.Now().After(ytApiQuotaExceededUntil_GL) {
+		sources = append(sources, _YTDataApiSource{api_key: api_key})
+	}
+
+	return append(sources, _YTScraperSource{})
+}
+
+/*
+getFromYTMetadataSources gets something from the first metadata source that works, logging the ones that don't.
+
+-----------------------------------------------------------
+
+– Params:
+  - sources – the sources to try, in order (see getYTMetadataSources())
+  - get – the function that gets the wanted thing from a source
+
+– Returns:
+  - what the first working source gave
+  - true if a source worked, false otherwise
+*/
+func getFromYTMetadataSources[T any](sources []_YouTubeMetadataSource,
+			get func(source _YouTubeMetadataSource) (T, error)) (T, bool) {
+	for _, source := range sources {
+		result, err := get(source)
+		if nil != err {
+			ytMetadataSourceFailed(source, err)
+
+			continue
+		}
+
+		return result, true
+	}
+
+	var none T
+
+	return none, false
+}
+
+/*
+getVideoPageInfo gets the duration and the livestream/premiere information of a video from the first metadata source
+that works. Information of finished videos is kept on the scrape cache.
+
+-----------------------------------------------------------
+
+– Params:
+  - video_id – the ID of the video
+
+– Returns:
+  - the video page info (length on _VID_TIME_DEF and liveInfo.status on _LIVE_STATUS_NONE if no source worked)
+*/
+func getVideoPageInfo(video_id string) _VideoPageInfo {
+	var videoPageInfo _VideoPageInfo = _VideoPageInfo{
+		length: _VID_TIME_DEF,
+		liveInfo: _LiveInfo{
+			status: _LIVE_STATUS_NONE,
+		},
+	}
+
+	var cachedInfo _CachedVideoInfo = _CachedVideoInfo{}
+	if scrapeCacheGet(_CACHE_KIND_VIDEO_INFO, video_id, &cachedInfo) {
+		videoPageInfo.length = cachedInfo.Length
+		videoPageInfo.liveInfo.status = cachedInfo.Live_status
+		videoPageInfo.liveInfo.is_premiere = cachedInfo.Is_premiere
+		if 0 != cachedInfo.Scheduled_start {
+			videoPageInfo.liveInfo.scheduled_start = time.Unix(cachedInfo.Scheduled_start, 0)
+		}
+
+		return videoPageInfo
+	}
+
+	var sources []_YouTubeMetadataSource = getYTMetadataSources()
+	source_info, ok := getFromYTMetadataSources(sources, func(source _YouTubeMetadataSource) (_VideoPageInfo, error) {
+		return source.videoPageInfo(video_id)
+	})
+	if ok {
+		videoPageInfo = source_info
+	}
+
+	// Scheduled and ongoing events still change, so only the rest is kept for long.
+	if _LIVE_STATUS_UPCOMING != videoPageInfo.liveInfo.status && _LIVE_STATUS_LIVE != videoPageInfo.liveInfo.status &&
+				_VID_TIME_DEF != videoPageInfo.length {
+		cachedInfo = _CachedVideoInfo{
+			Length:      videoPageInfo.length,
+			Live_status: videoPageInfo.liveInfo.status,
+			Is_premiere: videoPageInfo.liveInfo.is_premiere,
+		}
+		if !videoPageInfo.liveInfo.scheduled_start.IsZero() {
+			cachedInfo.Scheduled_start = videoPageInfo.liveInfo.scheduled_start.Unix()
+		}
+		scrapeCacheSet(_CACHE_KIND_VIDEO_INFO, video_id, cachedInfo)
+	}
+
+	return videoPageInfo
+}
+
+/*
+getChannelImageUrl gets the URL of the channel image from the first metadata source that works. The URL is kept on the
+scrape cache.
+
+-----------------------------------------------------------
+
+– Params:
+  - channel_code – the code of the channel
+
+– Returns:
+  - the URL of the channel image if it was found, _GEN_ERROR otherwise
+*/
+func getChannelImageUrl(channel_code string) string {
+	var image_url string = ""
+	if scrapeCacheGet(_CACHE_KIND_CHANNEL_IMAGE, channel_code, &image_url) {
+		return image_url
+	}
+
+	var sources []_YouTubeMetadataSource = getYTMetadataSources()
+	source_url, ok := getFromYTMetadataSources(sources, func(source _YouTubeMetadataSource) (string, error) {
+		return source.channelImageUrl(channel_code)
+	})
+	if !ok {
+		return _GEN_ERROR
+	}
+	scrapeCacheSet(_CACHE_KIND_CHANNEL_IMAGE, channel_code, source_url)
+
+	return source_url
+}
+
+/*
+getPlaylistVideos gets the information of the videos of a playlist from the first metadata source that works.
+
+If is_known is given, the playlist is only got until the first video it returns true for (the playlist is assumed to be
+newest first), so that big playlists don't have to be got whole (and don't spend the API quota) only for the few new
+videos. Otherwise the whole playlist is got and kept on the scrape cache, so that it's only got once for all the items
+of the feed.
+
+-----------------------------------------------------------
+
+– Params:
+  - playlist_id – the ID of the playlist
+  - is_known – the function that says if a video is already known, or nil to get the whole playlist
+
+– Returns:
+  - the information of the videos in the playlist order, or nil if no source worked
+*/
+func getPlaylistVideos(playlist_id string, is_known func(videoInfo _VideoInfo) bool) []_VideoInfo {
+	var videos_info []_VideoInfo = nil
+	if nil == is_known && scrapeCacheGet(_CACHE_KIND_PLAYLIST, playlist_id, &videos_info) {
+		return videos_info
+	}
+
+	var sources []_YouTubeMetadataSource = getYTMetadataSources()
+	videos_info, ok := getFromYTMetadataSources(sources, func(source _YouTubeMetadataSource) ([]_VideoInfo, error) {
+		return source.playlistVideos(playlist_id, is_known)
+	})
+	if !ok {
+		return nil
+	}
+	if nil == is_known {
+		scrapeCacheSet(_CACHE_KIND_PLAYLIST, playlist_id, videos_info)
+	}
+
+	return videos_info
+}
+
+/*
+ytMetadataSourceFailed logs the failure of a metadata source and, if it was the API quota that got exceeded, stops using
+the API for a while.
+
+-----------------------------------------------------------
+
+– Params:
+  - source – the source that failed
+  - err – the error
+*/
+func ytMetadataSourceFailed(source _YouTubeMetadataSource, err error) {
+	fmt.Println("YouTube metadata source " + source.name() + " failed: " + err.Error())
+
+	if errors.Is(err, errYTApiQuotaExceeded) {
+		ytApiQuotaExceededUntil_GL = time.Now().Add(_YT_API_QUOTA_PAUSE)
+	}
+}
+
+// _YTScraperSource is the metadata source that scrapes YouTube's pages.
+type _YTScraperSource struct{}
+
+func (_YTScraperSource) name() string {
+	return "scraper"
+}
+
+func (_YTScraperSource) videoPageInfo(video_id string) (_VideoPageInfo, error) {
+	videoPageInfo, ok := scrapeVideoPageInfo(video_id)
+	if !ok {
+		return videoPageInfo, errors.New("error getting the video page")
+	}
+
+	return videoPageInfo, nil
+}
+
+func (_YTScraperSource) channelImageUrl(channel_code string) (string, error) {
+	var image_url string = scrapeChannelImageUrl(channel_code)
+	if _GEN_ERROR == image_url {
+		return "", errors.New("channel image not found")
+	}
+
+	return image_url, nil
+}
+
+func (_YTScraperSource) playlistVideos(playlist_id string,
+			is_known func(videoInfo _VideoInfo) bool) ([]_VideoInfo, error) {
+	var videos_info []_VideoInfo = crawlPlaylist(playlist_id, is_known)
+	if nil == videos_info {
+		return nil, errors.New("error getting the playlist page")
+	}
+
+	return videos_info, nil
+}
@@ -33,17 +33,35 @@ import (
 )
 
 const _VID_TIME_DEF string = "--:--"
-const _VID_TIME_LIVE string = "00:00" // Live videos have 00:00 as duration
 const _VID_TITLE_MAX_LEN int = 67
 // The max length of the video description on the email preview (YouTube used to trim after 27 chars)
 const _VID_DESC_MAX_LEN int = _VID_TITLE_MAX_LEN // Better with 67 chars. 27 is too little.
 
+// ytBaseUrl_GL is the base of the URLs used to get YouTube pages (a variable only to be able to point it to a local
+// stand-in server).
+var ytBaseUrl_GL string = "https://www.youtube.com"
+
+// Kinds of YouTube content that can be chosen on _FeedInfo.Yt_content.
+const (
+	_YT_CONTENT_VIDEOS    string = "videos"    // Normal uploads
+	_YT_CONTENT_SHORTS    string = "shorts"    // Shorts
+	_YT_CONTENT_LIVES     string = "lives"     // Scheduled and ongoing livestreams
+	_YT_CONTENT_REPLAYS   string = "replays"   // Livestreams that already ended
+	_YT_CONTENT_PREMIERES string = "premieres" // Premieres (scheduled, ongoing or ended)
+)
+
+const (
+	_VIDEO_COLOR string = "#212121" // Default video color (sort of black)
+	_LIVE_COLOR  string = "#E62117" // Default live color (sort of red)
+)
+
 /*
 youTubeTreatment processes the YouTube feed.
 
 -----------------------------------------------------------
 
 – Params:
+  - feedInfo – the information of the feed
   - feedType – the type of the feed
   - parsed_feed – the parsed feed
   - item_num – the number of the current item in the feed
@@ -56,14 +74,12 @@ All EmailInfo fields are empty if an error occurs, if the video is to be ignored
 title_url_only is true. In the 1st case, the _NewsInfo fields are also empty. In the 2nd case, the _NewsInfo fields are
 still filled with the video info. To check for errors, check if the video URL is empty on NewsInfo (that one must always
 have a value).
-*/
-func youTubeTreatment(feedType _FeedType, parsed_feed *gofeed.Feed, item_num int, title_url_only bool) (Utils.EmailInfo,
-			_NewsInfo) {
-	const (
-		VIDEO_COLOR string = "#212121" // Default video color (sort of black)
-		LIVE_COLOR  string = "#E62117" // Default live color (sort of red)
-	)
 
+Scheduled livestreams and premieres of channels are given to the upcoming events tracker, which notifies them when they
+actually start. Here they're only notified with a "scheduled for" notice if the feed wants it.
+*/
+func youTubeTreatment(feedInfo _FeedInfo, feedType _FeedType, parsed_feed *gofeed.Feed, item_num int,
+			title_url_only bool) (Utils.EmailInfo, _NewsInfo) {
 	var things_replace = map[string]string{
 		Utils.MODEL_YT_VIDEO_HTML_TITLE_EMAIL:        _GEN_ERROR,
 		Utils.MODEL_YT_VIDEO_CHANNEL_NAME_EMAIL:      parsed_feed.Authors[0].Name,
@@ -74,7 +90,7 @@ func youTubeTreatment(feedType _FeedType, parsed_feed *gofeed.Feed, item_num int
 		Utils.MODEL_YT_VIDEO_VIDEO_CODE_EMAIL:        _GEN_ERROR,
 		Utils.MODEL_YT_VIDEO_VIDEO_IMAGE_EMAIL:       _GEN_ERROR,
 		Utils.MODEL_YT_VIDEO_VIDEO_TIME_EMAIL:        _GEN_ERROR,
-		Utils.MODEL_YT_VIDEO_VIDEO_TIME_COLOR_EMAIL:  VIDEO_COLOR,
+		Utils.MODEL_YT_VIDEO_VIDEO_TIME_COLOR_EMAIL:  _VIDEO_COLOR,
 		Utils.MODEL_YT_VIDEO_PLAYLIST_CODE_EMAIL:     "", // Leave empty if it's not playlist
 		Utils.MODEL_YT_VIDEO_SUBSCRIPTION_LINK_EMAIL: _GEN_ERROR,
 		Utils.MODEL_YT_VIDEO_SUBSCRIPTION_NAME_EMAIL: parsed_feed.Title,
@@ -83,53 +99,84 @@ func youTubeTreatment(feedType _FeedType, parsed_feed *gofeed.Feed, item_num int
 		things_replace[Utils.MODEL_YT_VIDEO_CHANNEL_IMAGE_EMAIL] = getChannelImageUrl(things_replace[Utils.MODEL_YT_VIDEO_CHANNEL_CODE_EMAIL])
 	}
 
-	if feedType.type_2 == _TYPE_2_YT_CHANNEL {
+	// Channel tabs are playlists too, but they're presented as the channel.
+	var playlist_id string = ""
+	if isYTPlaylistFeed(feedType) {
+		playlist_id = parsed_feed.Extensions["yt"]["playlistId"][0].Value
+	}
+
+	if isYTChannelFeed(feedType) {
 		// The last part is what YouTube used to put in the URLs (taken from the original model)
 		things_replace[Utils.MODEL_YT_VIDEO_SUBSCRIPTION_LINK_EMAIL] = "channel/" + things_replace[Utils.MODEL_YT_VIDEO_CHANNEL_CODE_EMAIL] + "%3Ffeature%3Dem-uploademail"
 	} else if feedType.type_2 == _TYPE_2_YT_PLAYLIST {
-		things_replace[Utils.MODEL_YT_VIDEO_PLAYLIST_CODE_EMAIL] = parsed_feed.Extensions["yt"]["playlistId"][0].Value
+		things_replace[Utils.MODEL_YT_VIDEO_PLAYLIST_CODE_EMAIL] = playlist_id
 		things_replace[Utils.MODEL_YT_VIDEO_SUBSCRIPTION_LINK_EMAIL] = "playlist?list=" + things_replace[Utils.MODEL_YT_VIDEO_PLAYLIST_CODE_EMAIL]
 	}
 
-	if feedType.type_2 == _TYPE_2_YT_PLAYLIST && scrapingNeeded(parsed_feed) {
+	var liveInfo _LiveInfo = _LiveInfo{
+		status: _LIVE_STATUS_NONE,
+	}
+	var published time.Time = time.Time{}
+	if "" != playlist_id && scrapingNeeded(parsed_feed) {
 		// Scraping is only needed for video information. The feed has the rest.
 		// For scraping we only use the number of the item to guide through the video array. The rest comes from the
 		// playlist page.
-		var video_info _VideoInfo = ytPlaylistScraping(things_replace[Utils.MODEL_YT_VIDEO_PLAYLIST_CODE_EMAIL], item_num, len(parsed_feed.Items))
-		if video_info.id == _GEN_ERROR {
+		var video_info _VideoInfo = ytPlaylistScraping(playlist_id, item_num, len(parsed_feed.Items))
+		if video_info.Id == _GEN_ERROR {
 			return Utils.EmailInfo{}, _NewsInfo{}
 		}
 
-		things_replace[Utils.MODEL_YT_VIDEO_VIDEO_TITLE_EMAIL] = video_info.title
-		things_replace[Utils.MODEL_YT_VIDEO_VIDEO_CODE_EMAIL] = video_info.id
-		things_replace[Utils.MODEL_YT_VIDEO_VIDEO_IMAGE_EMAIL] = video_info.image
-		things_replace[Utils.MODEL_YT_VIDEO_VIDEO_TIME_EMAIL] = video_info.length
+		things_replace[Utils.MODEL_YT_VIDEO_VIDEO_TITLE_EMAIL] = video_info.Title
+		things_replace[Utils.MODEL_YT_VIDEO_VIDEO_CODE_EMAIL] = video_info.Id
+		things_replace[Utils.MODEL_YT_VIDEO_VIDEO_IMAGE_EMAIL] = video_info.Image
+		things_replace[Utils.MODEL_YT_VIDEO_VIDEO_TIME_EMAIL] = video_info.Length
 
 		// No way to get the description from the playlist visual page unless the video appears on the RSS feed.
 		things_replace[Utils.MODEL_YT_VIDEO_VIDEO_DESCRIPTION_EMAIL] = _GEN_ERROR
 		for _, item := range parsed_feed.Items {
-			if item.Extensions["yt"]["videoId"][0].Value == video_info.id {
+			if item.Extensions["yt"]["videoId"][0].Value == video_info.Id {
 				things_replace[Utils.MODEL_YT_VIDEO_VIDEO_DESCRIPTION_EMAIL] = item.Extensions["media"]["group"][0].Children["description"][0].Value
+				if nil != item.PublishedParsed {
+					published = *item.PublishedParsed
+				}
 
 				break
 			}
 		}
+		if !title_url_only {
+			// The playlist page has no live info, and lives have no duration on it - the video page has both.
+			var videoPageInfo _VideoPageInfo = getVideoPageInfo(video_info.Id)
+			if _GEN_ERROR == video_info.Length {
+				things_replace[Utils.MODEL_YT_VIDEO_VIDEO_TIME_EMAIL] = videoPageInfo.length
+			}
+			liveInfo = videoPageInfo.liveInfo
+		}
 	} else {
 		var feed_item *gofeed.Item = parsed_feed.Items[item_num]
 		things_replace[Utils.MODEL_YT_VIDEO_VIDEO_TITLE_EMAIL] = feed_item.Title
 		things_replace[Utils.MODEL_YT_VIDEO_VIDEO_CODE_EMAIL] = feed_item.Extensions["yt"]["videoId"][0].Value
 		things_replace[Utils.MODEL_YT_VIDEO_VIDEO_IMAGE_EMAIL] = feed_item.Extensions["media"]["group"][0].Children["thumbnail"][0].Attrs["url"]
 		things_replace[Utils.MODEL_YT_VIDEO_VIDEO_DESCRIPTION_EMAIL] = feed_item.Extensions["media"]["group"][0].Children["description"][0].Value
+		if nil != feed_item.PublishedParsed {
+			published = *feed_item.PublishedParsed
+		}
 		if !title_url_only {
-			things_replace[Utils.MODEL_YT_VIDEO_VIDEO_TIME_EMAIL] = getVideoDuration(feed_item.Link)
+			var videoPageInfo _VideoPageInfo = getVideoPageInfo(things_replace[Utils.MODEL_YT_VIDEO_VIDEO_CODE_EMAIL])
+			things_replace[Utils.MODEL_YT_VIDEO_VIDEO_TIME_EMAIL] = videoPageInfo.length
+			liveInfo = videoPageInfo.liveInfo
 		}
 	}
 
-	var is_short bool = isShort([]string{things_replace[Utils.MODEL_YT_VIDEO_VIDEO_TITLE_EMAIL], things_replace[Utils.MODEL_YT_VIDEO_VIDEO_DESCRIPTION_EMAIL]}, things_replace[Utils.MODEL_YT_VIDEO_VIDEO_TIME_EMAIL])
+	// Scheduled and ongoing lives have 00:00 as duration, so they'd be seen as Shorts.
+	var is_short bool = false
+	if !title_url_only && _LIVE_STATUS_UPCOMING != liveInfo.status && _LIVE_STATUS_LIVE != liveInfo.status {
+		is_short = isShort(things_replace[Utils.MODEL_YT_VIDEO_VIDEO_CODE_EMAIL], []string{things_replace[Utils.MODEL_YT_VIDEO_VIDEO_TITLE_EMAIL], things_replace[Utils.MODEL_YT_VIDEO_VIDEO_DESCRIPTION_EMAIL]}, things_replace[Utils.MODEL_YT_VIDEO_VIDEO_TIME_EMAIL])
+	}
 
-	// If it's not to include Shorts and the video is a Short, return only the news info (to ignore the notification but
-	// memorize that the video is to be ignored).
-	if (feedType.type_3 != _TYPE_3_YT_INC_SHORTS && is_short) || title_url_only {
+	// If the feed doesn't want this kind of content (like Shorts by default), return only the news info (to ignore the
+	// notification but memorize that the video is to be ignored).
+	if title_url_only || !isYTContentWanted(feedInfo, feedType, getYTContentKind(is_short, liveInfo),
+				things_replace[Utils.MODEL_YT_VIDEO_VIDEO_TIME_EMAIL]) {
 		return Utils.EmailInfo{}, _NewsInfo{
 			title: things_replace[Utils.MODEL_YT_VIDEO_VIDEO_TITLE_EMAIL],
 			url: "https://www.youtube.com/watch?v=" + things_replace[Utils.MODEL_YT_VIDEO_VIDEO_CODE_EMAIL],
@@ -150,16 +197,32 @@ func youTubeTreatment(feedType _FeedType, parsed_feed *gofeed.Feed, item_num int
 		video_short = "vídeo"
 	}
 
+	var vid_desc_original string = things_replace[Utils.MODEL_YT_VIDEO_VIDEO_DESCRIPTION_EMAIL]
+	if len(things_replace[Utils.MODEL_YT_VIDEO_VIDEO_DESCRIPTION_EMAIL]) > _VID_DESC_MAX_LEN {
+		things_replace[Utils.MODEL_YT_VIDEO_VIDEO_DESCRIPTION_EMAIL] = things_replace[Utils.MODEL_YT_VIDEO_VIDEO_DESCRIPTION_EMAIL][:_VID_DESC_MAX_LEN] + "..."
+	}
+
 	var msg_subject string = _GEN_ERROR
-	if feedType.type_2 == _TYPE_2_YT_CHANNEL {
-		if _VID_TIME_LIVE == things_replace[Utils.MODEL_YT_VIDEO_VIDEO_TIME_EMAIL] {
-			// Live video
-			msg_subject = "🔴 " + things_replace[Utils.MODEL_YT_VIDEO_CHANNEL_NAME_EMAIL] + " está agora em direto: " + vid_title + "!"
-			things_replace[Utils.MODEL_YT_VIDEO_HTML_TITLE_EMAIL] = "Em direto no YouTube: " + things_replace[Utils.MODEL_YT_VIDEO_CHANNEL_NAME_EMAIL] + " – " + vid_title + "!"
-
-			// Change the length rectangle
-			things_replace[Utils.MODEL_YT_VIDEO_VIDEO_TIME_COLOR_EMAIL] = LIVE_COLOR
-			things_replace[Utils.MODEL_YT_VIDEO_VIDEO_TIME_EMAIL] = "LIVE" // Change the video length to "LIVE"
+	if isYTChannelFeed(feedType) {
+		if _LIVE_STATUS_UPCOMING == liveInfo.status {
+			// Scheduled livestream or premiere - the tracker notifies it when it starts.
+			trackUpcomingEvent(things_replace[Utils.MODEL_YT_VIDEO_VIDEO_CODE_EMAIL], feedInfo.Feed_num, liveInfo,
+				things_replace)
+
+			if !feedInfo.Notify_upcoming {
+				return Utils.EmailInfo{}, _NewsInfo{
+					title: vid_title_original,
+					url:   "https://www.youtube.com/watch?v=" + things_replace[Utils.MODEL_YT_VIDEO_VIDEO_CODE_EMAIL],
+				}
+			}
+
+			msg_subject = setUpcomingThingsReplace(things_replace, liveInfo, vid_title)
+		} else if _LIVE_STATUS_LIVE == liveInfo.status {
+			// Live video - still tracked to be marked as ended later.
+			trackUpcomingEvent(things_replace[Utils.MODEL_YT_VIDEO_VIDEO_CODE_EMAIL], feedInfo.Feed_num, liveInfo,
+				things_replace)
+
+			msg_subject = setLiveThingsReplace(things_replace, vid_title)
 		} else {
 			// Normal video
 			msg_subject = things_replace[Utils.MODEL_YT_VIDEO_CHANNEL_NAME_EMAIL] + " acabou de carregar um " + video_short
@@ -171,40 +234,129 @@ func youTubeTreatment(feedType _FeedType, parsed_feed *gofeed.Feed, item_num int
 		things_replace[Utils.MODEL_YT_VIDEO_HTML_TITLE_EMAIL] = msg_subject
 	}
 
-	if len(things_replace[Utils.MODEL_YT_VIDEO_VIDEO_DESCRIPTION_EMAIL]) > _VID_DESC_MAX_LEN {
-		things_replace[Utils.MODEL_YT_VIDEO_VIDEO_DESCRIPTION_EMAIL] = things_replace[Utils.MODEL_YT_VIDEO_VIDEO_DESCRIPTION_EMAIL][:_VID_DESC_MAX_LEN] + "..."
-	}
-
 	var email_info Utils.EmailInfo = Utils.GetModelFileEMAIL(Utils.MODEL_FILE_YT_VIDEO, things_replace)
 	email_info.Subject = msg_subject
 
 	return email_info,
 	_NewsInfo{
-		title: vid_title_original,
-		url:   "https://www.youtube.com/watch?v=" + things_replace[Utils.MODEL_YT_VIDEO_VIDEO_CODE_EMAIL],
+		title:       vid_title_original,
+		url:         "https://www.youtube.com/watch?v=" + things_replace[Utils.MODEL_YT_VIDEO_VIDEO_CODE_EMAIL],
+		author:      things_replace[Utils.MODEL_YT_VIDEO_CHANNEL_NAME_EMAIL],
+		description: vid_desc_original,
+		image:       things_replace[Utils.MODEL_YT_VIDEO_VIDEO_IMAGE_EMAIL],
+		published:   published,
+		live:        isYTChannelFeed(feedType) && _LIVE_STATUS_LIVE == liveInfo.status,
 	}
 }
 
+// _VideoPageInfo is the information about a video got from its page (or from the YouTube Data API).
+type _VideoPageInfo struct {
+	// length is the duration of the video in the SecondsToTimeStr() format, or _VID_TIME_DEF if it wasn't found
+	length string
+	// liveInfo is the livestream/premiere information of the video
+	liveInfo _LiveInfo
+}
+
 /*
-getVideoDuration gets the duration of the video by getting the video's page and looking for the duration (scraping).
+isYTChannelFeed checks if a YouTube feed is about a channel (all its videos or only one of its tabs).
 
-The format returned is the same as the one from the SecondsToTimeStr() function.
+-----------------------------------------------------------
+
+– Params:
+  - feedType – the type of the feed
+
+– Returns:
+  - true if it's a channel feed, false otherwise
+*/
+func isYTChannelFeed(feedType _FeedType) bool {
+	return _TYPE_2_YT_CHANNEL == feedType.type_2 || "" != ytChannelTabsPrefixes_GL[feedType.type_2]
+}
+
+/*
+isYTPlaylistFeed checks if a YouTube feed is got from a playlist (a normal playlist or a channel tab).
 
 -----------------------------------------------------------
 
 – Params:
-  - video_url – the URL of the video
+  - feedType – the type of the feed
 
 – Returns:
-  - the duration of the video if it was found, _VID_TIME_DEF otherwise
+  - true if it's a playlist feed, false otherwise
+*/
+func isYTPlaylistFeed(feedType _FeedType) bool {
+	return _TYPE_2_YT_PLAYLIST == feedType.type_2 || "" != ytChannelTabsPrefixes_GL[feedType.type_2]
+}
+
+/*
+getYTChannelTabPlaylistId gets the ID of the auto-generated playlist of a channel tab.
+
+Each channel with a "UC" ID has playlists with the same ID but other prefixes: "UULF" for the long-form uploads, "UUSH"
+for the Shorts and "UULV" for the lives.
+
+-----------------------------------------------------------
+
+– Params:
+  - channel_id – the ID of the channel
+  - type_2 – one of the channel tab _TYPE_2_ constants
+
+– Returns:
+  - the ID of the playlist or "" if the channel ID or the type are not valid
+*/
+func getYTChannelTabPlaylistId(channel_id string, type_2 string) string {
+	var prefix string = ytChannelTabsPrefixes_GL[type_2]
+	if "" == prefix || !strings.HasPrefix(channel_id, "UC") {
+		return ""
+	}
+
+	return prefix + channel_id[len("UC"):]
+}
+
+/*
+scrapeVideoPageInfo gets the duration and the livestream/premiere information of the video by getting the video's page
+(scraping) - all with only one page request.
+
+-----------------------------------------------------------
+
+– Params:
+  - video_id – the ID of the video
+
+– Returns:
+  - the video page info
+  - true if the page was got, false otherwise
 */
-func getVideoDuration(video_url string) string {
-	var p_page_html *string = Utils.GetPageHtmlTIMEDATE(video_url)
+func scrapeVideoPageInfo(video_id string) (_VideoPageInfo, bool) {
+	var videoPageInfo _VideoPageInfo = _VideoPageInfo{
+		length: _VID_TIME_DEF,
+		liveInfo: _LiveInfo{
+			status: _LIVE_STATUS_NONE,
+		},
+	}
+
+	var p_page_html *string = getPageHtmlCached(ytBaseUrl_GL + "/watch?v=" + video_id)
 	if nil == p_page_html {
-		return _VID_TIME_DEF
+		return videoPageInfo, false
 	}
-	var page_html string = *p_page_html
 
+	videoPageInfo.length = getVideoDuration(*p_page_html)
+	videoPageInfo.liveInfo = getLiveInfo(*p_page_html)
+
+	return videoPageInfo, true
+}
+
+/*
+getVideoDuration gets the duration of the video by looking for it on the video's page HTML.
+
+The format returned is the same as the one from the SecondsToTimeStr() function.
+
+-----------------------------------------------------------
+
+– Params:
+  - page_html – the HTML of the video's page
+
+– Returns:
+  - the duration of the video if it was found, _VID_TIME_DEF otherwise
+*/
+func getVideoDuration(page_html string) string {
 	// I think the data is in JSON, so I got the lengthSeconds that I found randomly looking for the seconds. It also a
 	// double quote after the number ("lengthSeconds":"47" for 47 seconds) --> CAN CHANGE (checked on 2023-07-04).
 	text_to_find := "\"lengthSeconds\":\""
@@ -244,7 +396,7 @@ func SecondsToTimeStr(seconds_str string) string {
 }
 
 /*
-getChannelImageUrl gets the URL of the channel image of by getting the channel's page and looking for the image
+scrapeChannelImageUrl gets the URL of the channel image of by getting the channel's page and looking for the image
 (scraping).
 
 -----------------------------------------------------------
@@ -255,8 +407,8 @@ getChannelImageUrl gets the URL of the channel image of by getting the channel's
 – Returns:
   - the URL of the channel image if it was found, _GEN_ERROR otherwise
 */
-func getChannelImageUrl(channel_code string) string {
-	var p_page_html *string = Utils.GetPageHtmlTIMEDATE("https://www.youtube.com/channel/" + channel_code)
+func scrapeChannelImageUrl(channel_code string) string {
+	var p_page_html *string = getPageHtmlCached(ytBaseUrl_GL + "/channel/" + channel_code)
 	if nil == p_page_html {
 		return _GEN_ERROR
 	}
@@ -280,16 +432,24 @@ func getChannelImageUrl(channel_code string) string {
 /*
 isShort checks if the video is a Short.
 
+The video's /shorts/ URL is probed first (see probeShort()). Only if that fails, the video texts and length are used to
+guess.
+
 -----------------------------------------------------------
 
 – Params:
+  - video_id – the ID of the video
   - video_texts – the texts of the video like title and description
   - video_len – the length of the video from getVideoDuration()
 
 – Returns:
-  - true if the video is a short, false otherwise (also false if video_len is _VID_TIME_DEF)
+  - true if the video is a short, false otherwise (also false if the probe failed and video_len is _VID_TIME_DEF)
  */
-func isShort(video_texts []string, video_len string) bool {
+func isShort(video_id string, video_texts []string, video_len string) bool {
+	if is_short, ok := probeShort(video_id); ok {
+		return is_short
+	}
+
 	// If any of the video texts has the #short or #shorts tag, mark as Short.
 	for _, video_text := range video_texts {
 		video_text_words := strings.Split(strings.ToLower(video_text), " ")
@@ -305,14 +465,115 @@ func isShort(video_texts []string, video_len string) bool {
 
 	// Lastly, if none of the others worked (a video can be a Short and not have the tags), if the video is 1 minute or
 	// less long, mark it as Short.
-	var length_seconds = 0
-	if len(Utils.FindAllIndexesGENERAL(video_len, ":")) == 1 {
-		length_parsed, _ := time.Parse("04:05", video_len)
-		length_seconds = length_parsed.Minute()*60 + length_parsed.Second()
-	} else {
-		length_parsed, _ := time.Parse("15:04:05", video_len)
-		length_seconds = length_parsed.Hour()*60*60 + length_parsed.Minute()*60 + length_parsed.Second()
+	var length_seconds int = timeStrToSeconds(video_len)
+	if length_seconds < 0 {
+		// Same as above.
+		return false
 	}
 
 	return length_seconds <= 60
 }
+
+/*
+timeStrToSeconds converts a video duration string to seconds - the opposite of SecondsToTimeStr().
+
+-----------------------------------------------------------
+
+– Params:
+  - video_len – the length of the video in the SecondsToTimeStr() format
+
+– Returns:
+  - the number of seconds or -1 if the string is not a duration (like _VID_TIME_DEF)
+ */
+func timeStrToSeconds(video_len string) int {
+	if len(Utils.FindAllIndexesGENERAL(video_len, ":")) == 1 {
+		length_parsed, err := time.Parse("04:05", video_len)
+		if nil != err {
+			return -1
+		}
+
+		return length_parsed.Minute()*60 + length_parsed.Second()
+	}
+
+	length_parsed, err := time.Parse("15:04:05", video_len)
+	if nil != err {
+		return -1
+	}
+
+	return length_parsed.Hour()*60*60 + length_parsed.Minute()*60 + length_parsed.Second()
+}
+
+/*
+getYTContentKind gets the kind of content of a video.
+
+-----------------------------------------------------------
+
+– Params:
+  - is_short – whether the video is a Short
+  - liveInfo – the live info of the video
+
+– Returns:
+  - one of the _YT_CONTENT_ constants
+ */
+func getYTContentKind(is_short bool, liveInfo _LiveInfo) string {
+	if _LIVE_STATUS_NONE != liveInfo.status {
+		if liveInfo.is_premiere {
+			return _YT_CONTENT_PREMIERES
+		}
+		if _LIVE_STATUS_ENDED == liveInfo.status {
+			return _YT_CONTENT_REPLAYS
+		}
+
+		return _YT_CONTENT_LIVES
+	}
+
+	if is_short {
+		return _YT_CONTENT_SHORTS
+	}
+
+	return _YT_CONTENT_VIDEOS
+}
+
+/*
+isYTContentWanted checks if the feed wants to be notified about a video, based on its kind and duration.
+
+If the feed has no Yt_content list, the default is all kinds except Shorts, which are only included with the
+_TYPE_3_YT_INC_SHORTS type or on the channel's Shorts tab.
+
+-----------------------------------------------------------
+
+– Params:
+  - feedInfo – the information of the feed
+  - feedType – the type of the feed
+  - content_kind – one of the _YT_CONTENT_ constants
+  - video_len – the length of the video from getVideoPageInfo()
+
+– Returns:
+  - true if the video is to be notified, false if it's to be ignored
+ */
+func isYTContentWanted(feedInfo _FeedInfo, feedType _FeedType, content_kind string, video_len string) bool {
+	var yt_content []string = feedInfo.Yt_content
+	if 0 == len(yt_content) {
+		yt_content = []string{_YT_CONTENT_VIDEOS, _YT_CONTENT_LIVES, _YT_CONTENT_REPLAYS, _YT_CONTENT_PREMIERES}
+		if _TYPE_3_YT_INC_SHORTS == feedType.type_3 || _TYPE_2_YT_CH_SHORTS == feedType.type_2 {
+			yt_content = append(yt_content, _YT_CONTENT_SHORTS)
+		}
+	}
+	if !slices.Contains(yt_content, content_kind) {
+		return false
+	}
+
+	// Lives (scheduled or ongoing) have no duration yet. For the others, if the duration is unknown, can't filter.
+	var length_seconds int = timeStrToSeconds(video_len)
+	if _YT_CONTENT_LIVES == content_kind || length_seconds < 0 {
+		return true
+	}
+	if feedInfo.Min_duration_s > 0 && length_seconds < feedInfo.Min_duration_s {
+		return false
+	}
+	if feedInfo.Max_duration_s > 0 && length_seconds > feedInfo.Max_duration_s {
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,203 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+	ext "github.com/mmcdole/gofeed/extensions"
+)
+
+func TestTimeStrToSeconds(t *testing.T) {
+	var tests = []struct {
+		video_len string
+		expected  int
+	}{
+		{"00:00", 0},
+		{"01:01", 61},
+		{"59:59", 3599},
+		{"01:02:03", 3723},
+		{"12:00:00", 43200},
+		{_VID_TIME_DEF, -1},
+		{"", -1},
+		{"1:2:3:4", -1},
+		{"ab:cd", -1},
+	}
+	for _, test := range tests {
+		if seconds := timeStrToSeconds(test.video_len); test.expected != seconds {
+			t.Errorf("timeStrToSeconds(%q) = %d, expected %d", test.video_len, seconds, test.expected)
+		}
+	}
+}
+
+func TestGetYTContentKind(t *testing.T) {
+	var tests = []struct {
+		is_short bool
+		liveInfo _LiveInfo
+		expected string
+	}{
+		{false, _LiveInfo{status: _LIVE_STATUS_NONE}, _YT_CONTENT_VIDEOS},
+		{true, _LiveInfo{status: _LIVE_STATUS_NONE}, _YT_CONTENT_SHORTS},
+		{false, _LiveInfo{status: _LIVE_STATUS_UPCOMING}, _YT_CONTENT_LIVES},
+		{false, _LiveInfo{status: _LIVE_STATUS_LIVE}, _YT_CONTENT_LIVES},
+		{false, _LiveInfo{status: _LIVE_STATUS_ENDED}, _YT_CONTENT_REPLAYS},
+		{false, _LiveInfo{status: _LIVE_STATUS_UPCOMING, is_premiere: true}, _YT_CONTENT_PREMIERES},
+		{false, _LiveInfo{status: _LIVE_STATUS_ENDED, is_premiere: true}, _YT_CONTENT_PREMIERES},
+	}
+	for _, test := range tests {
+		if kind := getYTContentKind(test.is_short, test.liveInfo); test.expected != kind {
+			t.Errorf("getYTContentKind(%t, %+v) = %q, expected %q", test.is_short, test.liveInfo, kind, test.expected)
+		}
+	}
+}
+
+func TestIsYTContentWanted(t *testing.T) {
+	var channel _FeedType = _FeedType{type_1: _TYPE_1_YOUTUBE, type_2: _TYPE_2_YT_CHANNEL}
+	var tests = []struct {
+		feedInfo     _FeedInfo
+		feedType     _FeedType
+		content_kind string
+		video_len    string
+		expected     bool
+	}{
+		// Defaults: all but Shorts.
+		{_FeedInfo{}, channel, _YT_CONTENT_VIDEOS, "10:00", true},
+		{_FeedInfo{}, channel, _YT_CONTENT_SHORTS, "00:30", false},
+		{_FeedInfo{}, _FeedType{type_1: _TYPE_1_YOUTUBE, type_2: _TYPE_2_YT_CHANNEL, type_3: _TYPE_3_YT_INC_SHORTS},
+			_YT_CONTENT_SHORTS, "00:30", true},
+		{_FeedInfo{}, _FeedType{type_1: _TYPE_1_YOUTUBE, type_2: _TYPE_2_YT_CH_SHORTS}, _YT_CONTENT_SHORTS, "00:30",
+			true},
+		// Explicit kinds.
+		{_FeedInfo{Yt_content: []string{_YT_CONTENT_LIVES}}, channel, _YT_CONTENT_VIDEOS, "10:00", false},
+		{_FeedInfo{Yt_content: []string{_YT_CONTENT_LIVES}}, channel, _YT_CONTENT_LIVES, _VID_TIME_DEF, true},
+		// Duration bounds (not applied to lives nor to unknown durations).
+		{_FeedInfo{Min_duration_s: 120}, channel, _YT_CONTENT_VIDEOS, "01:59", false},
+		{_FeedInfo{Min_duration_s: 120}, channel, _YT_CONTENT_VIDEOS, "02:00", true},
+		{_FeedInfo{Max_duration_s: 3600}, channel, _YT_CONTENT_VIDEOS, "01:00:01", false},
+		{_FeedInfo{Max_duration_s: 3600}, channel, _YT_CONTENT_REPLAYS, "01:00:00", true},
+		{_FeedInfo{Max_duration_s: 60}, channel, _YT_CONTENT_LIVES, "02:00", true},
+		{_FeedInfo{Min_duration_s: 120}, channel, _YT_CONTENT_VIDEOS, _VID_TIME_DEF, true},
+	}
+	for i, test := range tests {
+		var wanted bool = isYTContentWanted(test.feedInfo, test.feedType, test.content_kind, test.video_len)
+		if test.expected != wanted {
+			t.Errorf("test %d: isYTContentWanted(%s, %s) = %t, expected %t", i, test.content_kind, test.video_len,
+				wanted, test.expected)
+		}
+	}
+}
+
+// getTestPlaylistFeed gets a parsed feed of a channel tab playlist with 15 items in ascending order, so that the
+// playlist page has to be scraped.
+func getTestPlaylistFeed(playlist_id string) *gofeed.Feed {
+	var parsed_feed *gofeed.Feed = &gofeed.Feed{
+		Title:   "Lives of Channel",
+		Authors: []*gofeed.Person{{Name: "Channel"}},
+		Extensions: ext.Extensions{
+			"yt": {"playlistId": []ext.Extension{{Value: playlist_id}}},
+		},
+	}
+	var first_date time.Time = time.Date(2023, 11, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 15; i++ {
+		var video_id string = "old" + strconv.Itoa(i)
+		parsed_feed.Items = append(parsed_feed.Items, &gofeed.Item{
+			Title:     "Title " + video_id,
+			Published: first_date.AddDate(0, 0, i).Format(_YT_TIME_DATE_FORMAT),
+			Extensions: ext.Extensions{
+				"yt": {
+					"videoId":   []ext.Extension{{Value: video_id}},
+					"channelId": []ext.Extension{{Value: "UCtest"}},
+				},
+				"media": {"group": []ext.Extension{{Children: map[string][]ext.Extension{
+					"description": {{Value: "Description " + video_id}},
+					"thumbnail":   {{Attrs: map[string]string{"url": "image.jpg"}}},
+				}}}},
+			},
+		})
+	}
+
+	return parsed_feed
+}
+
+func TestYouTubeTreatmentScrapedLiveInfo(t *testing.T) {
+	var mux *http.ServeMux = http.NewServeMux()
+	mux.HandleFunc("/playlist", func(w http.ResponseWriter, r *http.Request) {
+		if "UULVtest" != r.URL.Query().Get("list") {
+			http.NotFound(w, r)
+
+			return
+		}
+		_, _ = fmt.Fprint(w, `<html><script>var ytInitialData = {"contents": {"twoColumnBrowseResultsRenderer": ` +
+			`{"tabs": [{"tabRenderer": {"content": {"playlistVideoListRenderer": {"contents": ` +
+			getTestRenderers([]string{"live1", "vid2"}, "") + `}}}}]}}};</script></html>`)
+	})
+	mux.HandleFunc("/watch", func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("v") {
+			case "live1": {
+				_, _ = fmt.Fprint(w, `{"isLiveNow":true,"isLiveContent":true,` +
+					`"startTimestamp":"2023-11-20T10:00:00+00:00"}`)
+			}
+			case "vid2": {
+				_, _ = fmt.Fprint(w, `{"lengthSeconds":"600","isLiveContent":false}`)
+			}
+			default: {
+				http.NotFound(w, r)
+			}
+		}
+	})
+	mux.HandleFunc("/shorts/", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/watch?v="+strings.TrimPrefix(r.URL.Path, "/shorts/"), http.StatusSeeOther)
+	})
+	var server *httptest.Server = httptest.NewServer(mux)
+	defer server.Close()
+	setTestYTBaseUrl(t, server.URL)
+
+	var feedType _FeedType = getFeedType("YT " + _TYPE_2_YT_CH_LIVES)
+	var parsed_feed *gofeed.Feed = getTestPlaylistFeed("UULVtest")
+
+	// The playlist has 2 videos and the feed 15 items, so the items 13 and 14 are the playlist's 1st and 2nd videos.
+	emailInfo, newsInfo := youTubeTreatment(_FeedInfo{Feed_num: 1}, feedType, parsed_feed, 13, false)
+	if "https://www.youtube.com/watch?v=live1" != newsInfo.url || !newsInfo.live ||
+				!strings.HasPrefix(emailInfo.Subject, "🔴 Channel está agora em direto") {
+		t.Errorf("live video: subject %q, news info %+v", emailInfo.Subject, newsInfo)
+	}
+
+	emailInfo, newsInfo = youTubeTreatment(_FeedInfo{Feed_num: 1}, feedType, parsed_feed, 14, false)
+	if "https://www.youtube.com/watch?v=vid2" != newsInfo.url || newsInfo.live ||
+				"Channel acabou de carregar um vídeo" != emailInfo.Subject {
+		t.Errorf("normal video: subject %q, news info %+v", emailInfo.Subject, newsInfo)
+	}
+
+	// The live is not wanted if the feed only wants videos.
+	var feedInfo _FeedInfo = _FeedInfo{Feed_num: 1, Yt_content: []string{_YT_CONTENT_VIDEOS}}
+	emailInfo, newsInfo = youTubeTreatment(feedInfo, feedType, parsed_feed, 13, false)
+	if "" != emailInfo.Subject || "https://www.youtube.com/watch?v=live1" != newsInfo.url {
+		t.Errorf("unwanted live: subject %q, news info %+v", emailInfo.Subject, newsInfo)
+	}
+}
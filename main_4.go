@@ -24,6 +24,7 @@ package main
 import (
 	"context"
 	"fmt"
+	"os"
 	"strconv"
 	"strings"
 	"time"
@@ -47,14 +48,55 @@ import (
 var allowed_feed_types_1_GL []string = []string{
 	_TYPE_1_GENERAL,
 	_TYPE_1_YOUTUBE,
+	_TYPE_1_PODCAST,
+	_TYPE_1_REDDIT,
+	_TYPE_1_GITHUB,
+	_TYPE_1_MASTODON,
+	_TYPE_1_AGGREGATOR,
+	_TYPE_1_STACKEXCHANGE,
 }
 const (
 	_TYPE_1_GENERAL = "General"
 	_TYPE_1_YOUTUBE = "YouTube"
+	_TYPE_1_PODCAST = "Podcast"
+	_TYPE_1_REDDIT        = "Reddit"
+	_TYPE_1_GITHUB        = "GitHub"
+	_TYPE_1_MASTODON      = "Mastodon"
+	_TYPE_1_AGGREGATOR    = "Aggregator"
+	_TYPE_1_STACKEXCHANGE = "StackExchange"
 )
 const (
 	_TYPE_2_YT_CHANNEL  = "CH"
 	_TYPE_2_YT_PLAYLIST = "PL"
+	// Channel tabs - these are got from the channel's auto-generated playlists
+	_TYPE_2_YT_CH_VIDEOS = "CH-V" // Only the long-form uploads
+	_TYPE_2_YT_CH_SHORTS = "CH-S" // Only the Shorts
+	_TYPE_2_YT_CH_LIVES  = "CH-L" // Only the lives
+)
+// ytChannelTabsPrefixes_GL maps the channel tab types to the prefixes of their playlists' IDs.
+var ytChannelTabsPrefixes_GL map[string]string = map[string]string{
+	_TYPE_2_YT_CH_VIDEOS: "UULF",
+	_TYPE_2_YT_CH_SHORTS: "UUSH",
+	_TYPE_2_YT_CH_LIVES:  "UULV",
+}
+const (
+	_TYPE_2_REDDIT_SUB    = "SUB"    // A subreddit (or more, like "golang+rust")
+	_TYPE_2_REDDIT_USER   = "USER"   // The posts of a user
+	_TYPE_2_REDDIT_SEARCH = "SEARCH" // A search on all of Reddit
+)
+const (
+	_TYPE_2_GH_RELEASES = "RELEASES" // The releases of a repository (the default)
+	_TYPE_2_GH_TAGS     = "TAGS"     // The tags of a repository
+	_TYPE_2_GH_COMMITS  = "COMMITS"  // The commits on a branch of a repository
+)
+const (
+	_TYPE_2_AGG_HN       = "HN"       // Hacker News
+	_TYPE_2_AGG_LOBSTERS = "LOBSTERS" // Lobsters
+)
+const (
+	_TYPE_2_SE_SITE     = "SITE"     // All the questions of a site (the default)
+	_TYPE_2_SE_TAG      = "TAG"      // The questions with a tag
+	_TYPE_2_SE_QUESTION = "QUESTION" // The answers (and comments) of a question
 )
 const (
 	_TYPE_3_YT_INC_SHORTS = "+S"
@@ -74,6 +116,15 @@ type _FeedType struct {
 type _NewsInfo struct {
 	url string
 	title string
+	// The rest is optional and only used to keep the notified news history
+	author string
+	description string
+	image string
+	published time.Time
+	// live is true if the news is a livestream that is live now (may break through quiet hours)
+	live bool
+	// episode is the feed item of a podcast episode (to download it once notified - nil for other types)
+	episode *gofeed.Item
 }
 
 // _MAX_URLS_STORED is the maximum number of URLs stored in the file. This is to avoid having a file with too many URLs.
@@ -86,153 +137,49 @@ var (
 	realMain Utils.RealMain = nil
 	moduleInfo_GL Utils.ModuleInfo[_MGIModSpecInfo]
 )
+
 func main() {Utils.ModStartup[_MGIModSpecInfo](Utils.NUM_MOD_RssFeedNotifier, realMain)}
 func init() {realMain =
 	func(realMain_param_1 any) {
 		moduleInfo_GL = realMain_param_1.(Utils.ModuleInfo[_MGIModSpecInfo])
 
+		if len(os.Args) > 1 {
+			// A command was given - run it instead of the normal loop.
+			runCommand(os.Args[1:])
+
+			return
+		}
+
 		for {
+			if !lockModule() {
+				return
+			}
+
 			var feedsInfo []_FeedInfo = getFeedsInfo()
 			if nil == feedsInfo {
 				fmt.Println("Error getting feeds info")
+				unlockModule()
 
 				goto end_loop
 			}
 
-			for _, feedInfo := range getFeedsInfo() {
+			for _, feedInfo := range feedsInfo {
 				// if 8 != feedInfo.Feed_num {
 				//	continue
 				// }
-				fmt.Println("__________________________BEGINNING__________________________")
-
-				var feedType _FeedType = getFeedType(feedInfo.Feed_type)
-
-				if !Utils.ContainsSLICES(allowed_feed_types_1_GL, feedType.type_1) {
-					fmt.Println("Feed type not allowed: " + feedInfo.Feed_type)
-					fmt.Println("__________________________ENDING__________________________")
-
-					continue
-				}
-
-				if _TYPE_1_YOUTUBE == feedType.type_1 {
-					// If the feed is a YouTube feed, the feed URL is the channel or playlist ID, so we need to change it to
-					// the correct URL.
-					if _TYPE_2_YT_CHANNEL == feedType.type_2 {
-						feedInfo.Feed_url = "https://www.youtube.com/feeds/videos.xml?channel_id=" + feedInfo.Feed_url
-					} else if _TYPE_2_YT_PLAYLIST == feedType.type_2 {
-						feedInfo.Feed_url = "https://www.youtube.com/feeds/videos.xml?playlist_id=" + feedInfo.Feed_url
-					}
-				}
-
-				fmt.Println("feed_num: " + strconv.Itoa(feedInfo.Feed_num))
-				fmt.Println("feed_url: " + feedInfo.Feed_url)
-				fmt.Println("feed_type: " + feedInfo.Feed_type)
-				fmt.Println("feedType.type_1: " + feedType.type_1)
-				fmt.Println("feedType.type_2: " + feedType.type_2)
-				fmt.Println("feedType.type_3: " + feedType.type_3)
-
-				var notif_news_file_path Utils.GPath = moduleInfo_GL.ModDirsInfo.UserData.Add2("urls_notified_news/",
-					strconv.Itoa(feedInfo.Feed_num)+".txt")
-				var newsInfo_list []_NewsInfo = nil
-				var notified_news_list []string = nil
-				if notif_news_file_path.Exists() {
-					newsInfo_list = make([]_NewsInfo, 0, _MAX_URLS_STORED)
-					var notified_news string = *notif_news_file_path.ReadTextFile()
-					notified_news_list = strings.Split(notified_news, "\n")
-					for _, line := range notified_news_list {
-						var line_split []string = strings.Split(line, " \\\\// ")
-						if 2 == len(line_split) {
-							newsInfo_list = append(newsInfo_list, _NewsInfo{
-								url:   line_split[0],
-								title: line_split[1],
-							})
-						}
-					}
-				}
-
-				var new_feed bool = false
-				if 0 == len(newsInfo_list) {
-					new_feed = true
-				}
-
-				ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
-				parsed_feed, err := gofeed.NewParser().ParseURLWithContext(feedInfo.Feed_url, ctx)
-				cancel()
-				if nil != err {
-					fmt.Println("Error parsing feed: " + err.Error())
-					continue
-				}
-
-				var notified_news_list_modified bool = false
-				for item_num, item := range parsed_feed.Items {
-
-					var check_skipping_later bool = true
-
-					// Check if the news is new, and if it's not, skip it. But only if it's not a YouTube playlist, because
-					// those may need the order of the items reversed and so the ones got from this loop are wrong. Or if it
-					// is playlist, then only if the feed item ordering is correct (no scraping needed).
-					// This is also here and not just in the end to prevent useless item processing (optimized).
-					if _TYPE_2_YT_PLAYLIST != feedType.type_2 || !scrapingNeeded(parsed_feed) {
-						check_skipping_later = false
-						if !isNewNews(newsInfo_list, item.Title, item.Link) {
-							// If the news is not new, don't notify.
-							continue
-						}
-					}
-
-					var email_info Utils.EmailInfo = Utils.EmailInfo{}
-					var newsInfo _NewsInfo = _NewsInfo{}
-
-					switch feedType.type_1 {
-						case _TYPE_1_YOUTUBE: {
-							email_info, newsInfo = youTubeTreatment(feedType, parsed_feed, item_num, new_feed)
-						}
-						case _TYPE_1_GENERAL: {
-							email_info, newsInfo = generalTreatment(parsed_feed, item_num, new_feed,
-								feedInfo.Custom_msg_subject)
-						}
-						default: {
-							fmt.Println("Unknown feed type_1: " + feedType.type_1)
-							continue
-						}
-					}
-
-					var ignore_video bool = "" == email_info.Html
-
-					if "" == newsInfo.url { // Some error occurred
-						continue
-					}
-
-					if check_skipping_later && !isNewNews(newsInfo_list, newsInfo.title, newsInfo.url) {
-						// If the news is not new, don't notify.
-						continue
-					}
-
-					var error_notifying bool = false
-
-					fmt.Println("New news: " + newsInfo.title)
-					if !new_feed && !ignore_video {
-						// If the feed is a newly added one, don't send emails for ALL the items in the feed - which are
-						// being treated for the first time.
-						fmt.Println("Queuing email: " + email_info.Subject)
-						error_notifying = !queueEmailAllRecps(email_info.Sender, email_info.Subject, email_info.Html)
-					}
-
-					if !error_notifying {
-						notified_news_list = append(notified_news_list, newsInfo.url+" \\\\// "+newsInfo.title)
-						if len(notified_news_list) > _MAX_URLS_STORED {
-							notified_news_list = notified_news_list[1:]
-						}
-						notified_news_list_modified = true
-					}
-				}
-				if notified_news_list_modified {
-					notif_news_file_path.WriteTextFile(strings.Join(notified_news_list, "\n"))
-				}
-
-				fmt.Println("__________________________ENDING__________________________")
+				checkFeed(feedInfo, nil)
 			}
 
+			checkUpcomingEvents()
+			deliverHeldEmails()
+			processOutbox()
+			// Not on other goroutines - everything is done with the module locked.
+			processPodcastDownloads()
+			processArchiveQueue()
+			endScrapeCacheCycle()
+			updateOutputs()
+			unlockModule()
+
 			end_loop:
 
 			return
@@ -244,6 +191,354 @@ func init() {realMain =
 	}
 }
 
+/*
+checkFeed checks a feed for news and notifies them.
+
+-----------------------------------------------------------
+
+– Params:
+  - feedInfo – the information of the feed
+  - pushed_feed – the feed pushed by the feed's WebSub hub (only with the new items) or nil to get the feed
+*/
+func checkFeed(feedInfo _FeedInfo, pushed_feed *gofeed.Feed) {
+	fmt.Println("__________________________BEGINNING__________________________")
+
+	var feedType _FeedType = getFeedType(feedInfo.Feed_type)
+
+	if !Utils.ContainsSLICES(allowed_feed_types_1_GL, feedType.type_1) {
+		fmt.Println("Feed type not allowed: " + feedInfo.Feed_type)
+		fmt.Println("__________________________ENDING__________________________")
+
+		return
+	}
+
+	if _TYPE_1_YOUTUBE == feedType.type_1 {
+		// If the feed is a YouTube feed, the feed URL is the channel or playlist ID, so we need to change it to
+		// the correct URL.
+		if _TYPE_2_YT_CHANNEL == feedType.type_2 {
+			feedInfo.Feed_url = "https://www.youtube.com/feeds/videos.xml?channel_id=" + feedInfo.Feed_url
+		} else if _TYPE_2_YT_PLAYLIST == feedType.type_2 {
+			feedInfo.Feed_url = "https://www.youtube.com/feeds/videos.xml?playlist_id=" + feedInfo.Feed_url
+		} else if isYTChannelFeed(feedType) {
+			var playlist_id string = getYTChannelTabPlaylistId(feedInfo.Feed_url, feedType.type_2)
+			if "" == playlist_id {
+				fmt.Println("Invalid channel ID for a channel tab: " + feedInfo.Feed_url)
+				fmt.Println("__________________________ENDING__________________________")
+
+				return
+			}
+			feedInfo.Feed_url = "https://www.youtube.com/feeds/videos.xml?playlist_id=" + playlist_id
+		}
+	} else if _TYPE_1_REDDIT == feedType.type_1 {
+		// Same for Reddit feeds: the feed URL is the subreddit, user or search query.
+		feedInfo.Feed_url = getRedditListingUrl(feedInfo, feedType)
+		if "" == feedInfo.Feed_url {
+			fmt.Println("Invalid Reddit feed type: " + feedInfo.Feed_type)
+			fmt.Println("__________________________ENDING__________________________")
+
+			return
+		}
+	} else if _TYPE_1_GITHUB == feedType.type_1 {
+		// And for GitHub feeds: the feed URL is the repository.
+		feedInfo.Feed_url = getGitHubFeedUrl(feedInfo, feedType)
+		if "" == feedInfo.Feed_url {
+			fmt.Println("Invalid GitHub repository or feed type: " + feedInfo.Feed_type)
+			fmt.Println("__________________________ENDING__________________________")
+
+			return
+		}
+	} else if _TYPE_1_MASTODON == feedType.type_1 {
+		// And for Mastodon feeds: the feed URL is the account, resolved through WebFinger.
+		feedInfo.Feed_url = getMastodonStatusesUrl(feedInfo)
+		if "" == feedInfo.Feed_url {
+			fmt.Println("__________________________ENDING__________________________")
+
+			return
+		}
+	} else if _TYPE_1_STACKEXCHANGE == feedType.type_1 {
+		// And for StackExchange feeds: the feed URL is the site (and the tag or the question).
+		feedInfo.Feed_url = getStackExchangeFeedUrl(feedInfo, feedType)
+		if "" == feedInfo.Feed_url {
+			fmt.Println("Invalid StackExchange site, tag, question or feed type: " + feedInfo.Feed_type)
+			fmt.Println("__________________________ENDING__________________________")
+
+			return
+		}
+	}
+
+	fmt.Println("feed_num: " + strconv.Itoa(feedInfo.Feed_num))
+	fmt.Println("feed_url: " + feedInfo.Feed_url)
+	fmt.Println("feed_type: " + feedInfo.Feed_type)
+	fmt.Println("feedType.type_1: " + feedType.type_1)
+	fmt.Println("feedType.type_2: " + feedType.type_2)
+	fmt.Println("feedType.type_3: " + feedType.type_3)
+
+	var notif_news_file_path Utils.GPath = getNotifiedNewsPath(feedInfo.Feed_num)
+	var newsInfo_list []_NewsInfo = nil
+	var notified_news_list []string = nil
+	if notif_news_file_path.Exists() {
+		newsInfo_list = make([]_NewsInfo, 0, _MAX_URLS_STORED)
+		var notified_news string = *notif_news_file_path.ReadTextFile()
+		notified_news_list = strings.Split(notified_news, "\n")
+		for _, line := range notified_news_list {
+			var line_split []string = strings.Split(line, " \\\\// ")
+			if 2 == len(line_split) {
+				newsInfo_list = append(newsInfo_list, _NewsInfo{
+					url:   line_split[0],
+					title: line_split[1],
+				})
+			}
+		}
+	}
+
+	var new_feed bool = false
+	if 0 == len(newsInfo_list) {
+		new_feed = true
+		// The Initial_sync policy is applied to the whole feed.
+		pushed_feed = nil
+	}
+
+	var feedState _FeedState = getFeedState(feedInfo.Feed_num)
+	var last_check time.Time = time.Time{}
+	if 0 != feedState.Last_check {
+		last_check = time.Unix(feedState.Last_check, 0)
+	}
+	var check_time time.Time = time.Now()
+
+	var parsed_feed *gofeed.Feed = pushed_feed
+	if nil == parsed_feed {
+		var err error = nil
+		parsed_feed, err = getParsedFeed(feedInfo, feedType)
+		if nil != err {
+			fmt.Println("Error parsing feed: " + err.Error())
+			return
+		}
+	} else {
+		fmt.Println("Pushed items: " + strconv.Itoa(len(parsed_feed.Items)))
+	}
+
+	// If the module was stopped for a while, items may have left the feed already without being notified.
+	// Those are appended to the feed items, so they're treated like the others (except scraped playlists,
+	// which are got whole already).
+	var feed_items_len int = len(parsed_feed.Items)
+	// The pushed feeds only have the new items, so they're never checked for gaps.
+	if !new_feed && nil == pushed_feed && !(isYTPlaylistFeed(feedType) && scrapingNeeded(parsed_feed)) &&
+				isFeedGapPossible(parsed_feed, newsInfo_list, last_check) {
+		fmt.Println("Possible gap in the feed - catching up")
+		var catch_up_items []*gofeed.Item = getCatchUpItems(feedInfo, feedType, parsed_feed, newsInfo_list,
+			_MAX_URLS_STORED-feed_items_len)
+		fmt.Println("Catch-up items: " + strconv.Itoa(len(catch_up_items)))
+		parsed_feed.Items = append(parsed_feed.Items, catch_up_items...)
+	}
+
+	// On the first run of a feed, only the items chosen by its Initial_sync policy are notified.
+	var initial_sync_items map[int]bool = nil
+	if new_feed {
+		initial_sync_items = getInitialSyncItems(feedInfo, feedType, parsed_feed)
+	}
+
+	// Catch-up items to be sent all in one email, if the feed wants it
+	var digest_items []_DigestItem = nil
+	// The other news to notify, sent after all are got (to collapse bursts and apply the feed's rate limit)
+	var pending_emails []Utils.EmailInfo = nil
+	var pending_news []_NewsInfo = nil
+
+	var notified_news_list_modified bool = false
+	// The URLs of the news recorded as notified on this check
+	var notified_urls map[string]bool = make(map[string]bool)
+	var addNotifiedNews func(newsInfo _NewsInfo) = func(newsInfo _NewsInfo) {
+		notified_news_list = append(notified_news_list, newsInfo.url+" \\\\// "+newsInfo.title)
+		if len(notified_news_list) > _MAX_URLS_STORED {
+			notified_news_list = notified_news_list[1:]
+		}
+		notified_news_list_modified = true
+		notified_urls[newsInfo.url] = true
+	}
+	for item_num, item := range parsed_feed.Items {
+
+		var check_skipping_later bool = true
+
+		// Check if the news is new, and if it's not, skip it. But only if it's not a YouTube playlist, because
+		// those may need the order of the items reversed and so the ones got from this loop are wrong. Or if it
+		// is playlist, then only if the feed item ordering is correct (no scraping needed).
+		// This is also here and not just in the end to prevent useless item processing (optimized).
+		if !isYTPlaylistFeed(feedType) || !scrapingNeeded(parsed_feed) {
+			check_skipping_later = false
+			if !isNewNews(newsInfo_list, item.Title, item.Link) {
+				// If the news is not new, don't notify.
+				continue
+			}
+		}
+
+		var notify_item bool = !new_feed || initial_sync_items[item_num]
+
+		var email_info Utils.EmailInfo = Utils.EmailInfo{}
+		var newsInfo _NewsInfo = _NewsInfo{}
+
+		switch feedType.type_1 {
+			case _TYPE_1_YOUTUBE: {
+				email_info, newsInfo = youTubeTreatment(feedInfo, feedType, parsed_feed, item_num, !notify_item)
+			}
+			case _TYPE_1_GENERAL: {
+				email_info, newsInfo = generalTreatment(feedInfo, parsed_feed, item_num, !notify_item, nil)
+			}
+			case _TYPE_1_PODCAST: {
+				email_info, newsInfo = podcastTreatment(feedInfo, parsed_feed, item_num, !notify_item)
+			}
+			case _TYPE_1_REDDIT: {
+				email_info, newsInfo = redditTreatment(feedInfo, feedType, parsed_feed, item_num, !notify_item)
+			}
+			case _TYPE_1_GITHUB: {
+				email_info, newsInfo = gitHubTreatment(feedInfo, feedType, parsed_feed, item_num, !notify_item)
+			}
+			case _TYPE_1_MASTODON: {
+				email_info, newsInfo = mastodonTreatment(feedInfo, parsed_feed, item_num, !notify_item)
+			}
+			case _TYPE_1_AGGREGATOR: {
+				email_info, newsInfo = aggregatorTreatment(feedInfo, parsed_feed, item_num, !notify_item)
+			}
+			case _TYPE_1_STACKEXCHANGE: {
+				email_info, newsInfo = stackExchangeTreatment(feedInfo, feedType, parsed_feed, item_num, !notify_item)
+			}
+			default: {
+				fmt.Println("Unknown feed type_1: " + feedType.type_1)
+				continue
+			}
+		}
+
+		var ignore_video bool = "" == email_info.Html
+
+		if "" == newsInfo.url { // Some error occurred
+			continue
+		}
+
+		if check_skipping_later && !isNewNews(newsInfo_list, newsInfo.title, newsInfo.url) {
+			// If the news is not new, don't notify.
+			continue
+		}
+
+		fmt.Println("New news: " + newsInfo.title)
+		if notify_item && !ignore_video && feedInfo.Catch_up_digest && item_num >= feed_items_len {
+			// Only recorded as notified after the digest is sent.
+			digest_items = append(digest_items, _DigestItem{
+				group:    getFeedGroup(feedInfo),
+				newsInfo: newsInfo,
+			})
+
+			continue
+		}
+		if notify_item && !ignore_video {
+			// If the feed is a newly added one, don't send emails for ALL the items in the feed - which are
+			// being treated for the first time (unless its Initial_sync policy says so).
+			// Only recorded as notified after the email is sent.
+			pending_emails = append(pending_emails, email_info)
+			pending_news = append(pending_news, newsInfo)
+
+			continue
+		}
+
+		addNotifiedNews(newsInfo)
+	}
+
+	// Bursts (more than Burst_threshold news in one check) are notified in one email, like the catch-up digest.
+	var burst bool = feedInfo.Burst_threshold > 0 && len(pending_news) > feedInfo.Burst_threshold
+	if burst {
+		for _, newsInfo := range pending_news {
+			digest_items = append(digest_items, _DigestItem{
+				group:    getFeedGroup(feedInfo),
+				newsInfo: newsInfo,
+			})
+		}
+		pending_emails = nil
+		pending_news = nil
+	}
+
+	var feed_rate_key string = getFeedRateKey(feedInfo.Feed_num)
+	if 0 != len(digest_items) {
+		var subject string = strconv.Itoa(len(digest_items)) + " publicações em falta de " + parsed_feed.Title
+		if burst {
+			subject = strconv.Itoa(len(digest_items)) + " novas publicações em " + parsed_feed.Title
+		}
+		fmt.Println("Queuing email: " + subject)
+		var digest_html string = getDigestHtml(subject, digest_items)
+		if !rateLimitAllows(feed_rate_key, feedInfo.Max_per_hour) {
+			fmt.Println("Feed rate limit reached - digest postponed")
+		} else if queueEmailAllRecps(feedInfo, _NewsInfo{title: subject}, parsed_feed.Title, subject, digest_html) {
+			registerRateLimitSend(feed_rate_key)
+			for _, digestItem := range digest_items {
+				recordNotifiedItem(feedInfo, feedType, parsed_feed.Title, digestItem.newsInfo)
+				addNotifiedNews(digestItem.newsInfo)
+			}
+		}
+	}
+	for i, email_info := range pending_emails {
+		// The news not notified because of the rate limit are not recorded as notified, so they're got again on the
+		// next checks (or by the catch-up, if they leave the feed meanwhile).
+		if !rateLimitAllows(feed_rate_key, feedInfo.Max_per_hour) {
+			fmt.Println("Feed rate limit reached - " + strconv.Itoa(len(pending_emails)-i) + " news postponed")
+
+			break
+		}
+
+		fmt.Println("Queuing email: " + email_info.Subject)
+		if queueEmailAllRecps(feedInfo, pending_news[i], email_info.Sender, email_info.Subject, email_info.Html) {
+			registerRateLimitSend(feed_rate_key)
+			recordNotifiedItem(feedInfo, feedType, parsed_feed.Title, pending_news[i])
+			addNotifiedNews(pending_news[i])
+		}
+	}
+	if notified_news_list_modified {
+		notif_news_file_path.WriteTextFile(strings.Join(notified_news_list, "\n"))
+	}
+	if _TYPE_1_AGGREGATOR == feedType.type_1 && 0 != len(notified_urls) {
+		// Only now - a story crossing the thresholds whose notification failed must still be watched.
+		unwatchNotifiedStories(feedInfo.Feed_num, parsed_feed, notified_urls)
+	}
+
+	if nil == pushed_feed {
+		feedState.Last_check = check_time.Unix()
+		setFeedState(feedInfo.Feed_num, feedState)
+	}
+
+	fmt.Println("__________________________ENDING__________________________")
+}
+
+/*
+getParsedFeed gets and parses a feed. Most feeds are parsed from their URL - the ones of sites whose feeds lack
+information are got from their APIs instead and converted to the same structure.
+
+-----------------------------------------------------------
+
+– Params:
+  - feedInfo – the information of the feed (with the final URL)
+  - feedType – the type of the feed
+
+– Returns:
+  - the parsed feed
+  - an error if the feed couldn't be got or parsed
+*/
+func getParsedFeed(feedInfo _FeedInfo, feedType _FeedType) (*gofeed.Feed, error) {
+	switch feedType.type_1 {
+		case _TYPE_1_REDDIT: {
+			return getRedditFeed(feedInfo.Feed_url, feedType)
+		}
+		case _TYPE_1_MASTODON: {
+			return getMastodonFeed(feedInfo.Feed_url)
+		}
+		case _TYPE_1_AGGREGATOR: {
+			return getAggregatorFeed(feedInfo, feedType)
+		}
+		case _TYPE_1_STACKEXCHANGE: {
+			return getStackExchangeFeed(feedInfo, feedType)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	return gofeed.NewParser().ParseURLWithContext(feedInfo.Feed_url, ctx)
+}
+
 /*
 getFeedType gets the _FeedType information from _FeedInfo.Feed_type.
 
@@ -298,6 +593,36 @@ func isNewNews(newsInfo_list []_NewsInfo, title string, url string) bool {
 	return true
 }
 
+/*
+updateOutputs updates what the module outputs besides the emails (like the output feeds) - to be called at the end of
+each cycle.
+*/
+func updateOutputs() {
+	var modUserInfo _ModUserInfo
+	if !moduleInfo_GL.GetModUserInfo(&modUserInfo) {
+		return
+	}
+
+	if "" != modUserInfo.Output_feeds_dir {
+		writeOutputFeeds(modUserInfo.Output_feeds_dir, getOutputFeedsMaxItems(modUserInfo))
+	}
+}
+
+/*
+getNotifiedNewsPath gets the path of the file with the notified news of a feed.
+
+-----------------------------------------------------------
+
+– Params:
+  - feed_num – the number of the feed
+
+– Returns:
+  - the path of the file
+*/
+func getNotifiedNewsPath(feed_num int) Utils.GPath {
+	return moduleInfo_GL.ModDirsInfo.UserData.Add2("urls_notified_news/", strconv.Itoa(feed_num)+".txt")
+}
+
 /*
 getFeedsInfo gets the information of the feeds.
 
@@ -316,59 +641,166 @@ func getFeedsInfo() []_FeedInfo {
 }
 
 /*
-queueEmailAllRecps queues an email to be sent to all recipients.
+getFeedInfo gets the information of a feed by its number.
+
+-----------------------------------------------------------
+
+– Params:
+  - feed_num – the number of the feed
+
+– Returns:
+  - the information of the feed
+  - true if the feed was found, false otherwise
+*/
+func getFeedInfo(feed_num int) (_FeedInfo, bool) {
+	for _, feedInfo := range getFeedsInfo() {
+		if feedInfo.Feed_num == feed_num {
+			return feedInfo, true
+		}
+	}
+
+	return _FeedInfo{}, false
+}
+
+/*
+queueEmailAllRecps queues an email to be sent to all recipients of a feed (see getRecipients()). For the recipients in
+quiet hours or over their rate limit, the email is held until they can receive it (see deliverHeldEmails()) - except
+if it's about a live video and the feed has Live_breaks_quiet, or if it can't be held.
 
 -----------------------------------------------------------
 
 – Params:
+  - feedInfo – the information of the feed
+  - newsInfo – the news the email is about (only the title and the URL are needed - no URL if it's not about one news)
   - sender_name – the name of the sender
   - subject – the subject of the email
   - html – the HTML of the email
+
+– Returns:
+  - true if the email was stored in the outbox (see addToOutbox()) or held, false otherwise
  */
-func queueEmailAllRecps(sender_name string, subject string, html string) bool {
+func queueEmailAllRecps(feedInfo _FeedInfo, newsInfo _NewsInfo, sender_name string, subject string, html string) bool {
 	var modUserInfo _ModUserInfo
 	if !moduleInfo_GL.GetModUserInfo(&modUserInfo) {
 		return false
 	}
 
-	for _, mail_to := range modUserInfo.Mails_to {
-		// This is to add the images to the email using CIDs instead of using URLs which could/can go down at any time.
-		// Except most email clients don't support CIDs... So I'll leave this here in case the images stop working with
-		// the URLs and then either this or embeded Base64 on the src attribute of the <img> tag or hosted in the server
-		// or something.
-		// Still, the CID way seems better than the Base64 one. With CIDs, only Gmail Notified Pro wasn't showing them.
-		// With Base64, Gmail (web or app) wasn't showing them (don't remember about the notifier). But I didn't test in
-		// Hotmail or others.
-		//var multiparts []Utils.Multipart = nil
-		//if youtube {
-		//	// Add the YouTube images to the email instead of using URLs which could/can go down at any time.
-		//	var files_add []string = []string{"transparent_pixel.png", "twitter_email_icon_grey.png",
-		//		"youtube_email_icon_grey.png", "youtubelogo_60.png"}
-		//	for _, file_add := range files_add {
-		//		var multipart Utils.Multipart = Utils.Multipart{
-		//			Content_type: "image/png",
-		//			Content_transfer_encoding: "base64",
-		//			Content_id: file_add,
-		//		}
-		//		data, _ := os.ReadFile(modStartInfo_GL.Dir.Add("", "yt_email_images/", file_add).
-		//			GPathToStringConversion())
-		//		multipart.Body = base64.StdEncoding.EncodeToString(data)
-		//
-		//		multiparts = append(multiparts, multipart)
-		//	}
-		//}
-
-		// Write the HTML to a file in case debugging is needed.
-		moduleInfo_GL.ModDirsInfo.Temp.Add2("last_html_queued.html").WriteTextFile(html)
-
-		Utils.QueueEmailEMAIL(Utils.EmailInfo{
-			Sender:  sender_name,
-			Mail_to: mail_to,
-			Subject: subject,
-			Html:    html,
-			Multiparts: nil,
-		})
+	var break_quiet bool = newsInfo.live && feedInfo.Live_breaks_quiet
+	var now time.Time = time.Now()
+	var destinations []string = nil
+	for _, recipient := range getRecipients(modUserInfo, getFeedTags(feedInfo)) {
+		var quiet bool = isInQuietHours(recipient, now)
+		if !break_quiet && (quiet || !rateLimitAllows(getMailRateKey(recipient.Mail_to), recipient.Max_per_hour)) {
+			if quiet {
+				fmt.Println("Holding email for " + recipient.Mail_to + " (quiet hours)")
+			} else {
+				fmt.Println("Holding email for " + recipient.Mail_to + " (rate limit)")
+			}
+			var held bool = holdEmail(_HeldEmail{
+				Mail_to: recipient.Mail_to,
+				Sender:  sender_name,
+				Subject: subject,
+				Html:    html,
+				Title:   newsInfo.title,
+				Url:     newsInfo.url,
+				Group:   getFeedGroup(feedInfo),
+			})
+			if held {
+				continue
+			}
+			// Better sent now than lost.
+			fmt.Println("Error holding email for " + recipient.Mail_to + " - sending it now")
+		}
+
+		destinations = append(destinations, getEmailDestination(recipient.Mail_to))
+		registerRateLimitSend(getMailRateKey(recipient.Mail_to))
 	}
 
-	return true
+	return addToOutbox(sender_name, subject, html, destinations)
+}
+
+/*
+queueEmail queues an email to be sent to a recipient (through the outbox).
+
+-----------------------------------------------------------
+
+– Params:
+  - mail_to – the recipient
+  - sender_name – the name of the sender
+  - subject – the subject of the email
+  - html – the HTML of the email
+
+– Returns:
+  - true if the email was stored in the outbox, false otherwise
+*/
+func queueEmail(mail_to string, sender_name string, subject string, html string) bool {
+	return addToOutbox(sender_name, subject, html, []string{getEmailDestination(mail_to)})
+}
+
+/*
+getEmailDestination gets the outbox destination of an email.
+
+-----------------------------------------------------------
+
+– Params:
+  - mail_to – the email
+
+– Returns:
+  - the destination
+*/
+func getEmailDestination(mail_to string) string {
+	return "email:" + mail_to
+}
+
+/*
+sendEmail queues an email on the Email Sender module's queue (used by the outbox's email backend).
+
+-----------------------------------------------------------
+
+– Params:
+  - mail_to – the recipient
+  - sender_name – the name of the sender
+  - subject – the subject of the email
+  - html – the HTML of the email
+
+– Returns:
+  - the error of queuing the email, if any
+*/
+func sendEmail(mail_to string, sender_name string, subject string, html string) error {
+	// This is to add the images to the email using CIDs instead of using URLs which could/can go down at any time.
+	// Except most email clients don't support CIDs... So I'll leave this here in case the images stop working with
+	// the URLs and then either this or embeded Base64 on the src attribute of the <img> tag or hosted in the server
+	// or something.
+	// Still, the CID way seems better than the Base64 one. With CIDs, only Gmail Notified Pro wasn't showing them.
+	// With Base64, Gmail (web or app) wasn't showing them (don't remember about the notifier). But I didn't test in
+	// Hotmail or others.
+	//var multiparts []Utils.Multipart = nil
+	//if youtube {
+	//	// Add the YouTube images to the email instead of using URLs which could/can go down at any time.
+	//	var files_add []string = []string{"transparent_pixel.png", "twitter_email_icon_grey.png",
+	//		"youtube_email_icon_grey.png", "youtubelogo_60.png"}
+	//	for _, file_add := range files_add {
+	//		var multipart Utils.Multipart = Utils.Multipart{
+	//			Content_type: "image/png",
+	//			Content_transfer_encoding: "base64",
+	//			Content_id: file_add,
+	//		}
+	//		data, _ := os.ReadFile(modStartInfo_GL.Dir.Add("", "yt_email_images/", file_add).
+	//			GPathToStringConversion())
+	//		multipart.Body = base64.StdEncoding.EncodeToString(data)
+	//
+	//		multiparts = append(multiparts, multipart)
+	//	}
+	//}
+
+	// Write the HTML to a file in case debugging is needed.
+	moduleInfo_GL.ModDirsInfo.Temp.Add2("last_html_queued.html").WriteTextFile(html)
+
+	return Utils.QueueEmailEMAIL(Utils.EmailInfo{
+		Sender:  sender_name,
+		Mail_to: mail_to,
+		Subject: subject,
+		Html:    html,
+		Multiparts: nil,
+	})
 }